@@ -175,6 +175,7 @@ func (b *Base) InitBase() {
 		log.Panic("Cannot initialize an already initialized Collection.")
 	}
 	b.Sizeb = 0
+	b.threadsafe = true
 	b.init = true
 }
 
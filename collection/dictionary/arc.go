@@ -0,0 +1,376 @@
+// This module implements an ARC, conforming to the Cache interface, which
+// adapts its eviction policy between recency and frequency.
+//
+// See Megiddo and Modha, "ARC: A Self-Tuning, Low Overhead Replacement Cache".
+
+package dictionary
+
+import (
+	"container/list"
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"github.com/michalpiszczek/nonstdlib/util/math"
+	"log"
+)
+
+// Which of an ARC's four lists an entry is on. t1 and t2 hold entries;
+// b1 and b2 hold only the keys of entries recently evicted from them.
+const (
+	t1 = iota
+	t2
+	b1
+	b2
+)
+
+// An entry in an ARC. Ghost entries (on b1 or b2) have a nil value.
+type arcEntry struct {
+	key   interface{}
+	value interface{}
+	where int
+	elem  *list.Element
+}
+
+// An ARC implements Cache, using the Adaptive Replacement Cache policy.
+//
+// Entries used once live on a recency list (t1), and entries used more than
+// once on a frequency list (t2). The keys of entries evicted from each are
+// remembered on ghost lists (b1, b2), and a hit on a ghost shifts the target
+// size of t1 towards whichever list would have kept that entry. This makes
+// ARC resistant to scans, which flush a plain LRU, while still adapting to
+// shifts in popularity, which a plain LFU is slow to follow.
+//
+// Only the ghost lists hold keys without values, so an ARC remembers at most
+// 2 * Capacity() keys.
+//
+// Behavior unspecified if an ARC is not created using NewARC(), NewARCUnsafe().
+//
+type ARC struct {
+	collection.Base
+	capacity int
+	p        int // The target size of t1.
+	m        map[interface{}]*arcEntry
+	lists    [4]*list.List
+	stats    CacheStats
+}
+
+// Returns a pointer to a new ARC holding at most capacity entries.
+//
+// Panics if the given capacity is not positive.
+func NewARC(capacity int) *ARC {
+	s := &ARC{capacity: capacity}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe ARC holding at most capacity entries.
+//
+// Panics if the given capacity is not positive.
+func NewARCUnsafe(capacity int) *ARC {
+	s := &ARC{capacity: capacity}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *ARC) Init() {
+	if s.capacity <= 0 {
+		log.Panic("Non-positive capacity. Use NewARC().")
+	}
+	s.InitBase()
+
+	s.reset()
+}
+
+func (s *ARC) InitUnsafe() {
+	if s.capacity <= 0 {
+		log.Panic("Non-positive capacity. Use NewARCUnsafe().")
+	}
+	s.InitBaseUnsafe()
+
+	s.reset()
+}
+
+func (s *ARC) Capacity() int {
+	s.CheckInit()
+	return s.capacity
+}
+
+func (s *ARC) Stats() CacheStats {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.stats
+}
+
+func (s *ARC) ResetStats() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.stats = CacheStats{}
+}
+
+// Inserting counts as a use of the given key. If this ARC is full and does
+// not yet hold the given key, evicts an entry to make room.
+func (s *ARC) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	e, ok := s.m[key]
+	if !ok {
+		s.admit(key, value)
+		return nil
+	}
+
+	switch e.where {
+	case t1, t2:
+		old := e.value
+		e.value = value
+		s.move(e, t2)
+		return old
+	case b1:
+		s.p = math.Min(s.capacity, s.p+math.Max(s.lists[b2].Len()/s.lists[b1].Len(), 1))
+		s.replace(false)
+	case b2:
+		s.p = math.Max(0, s.p-math.Max(s.lists[b1].Len()/s.lists[b2].Len(), 1))
+		s.replace(true)
+	}
+
+	e.value = value
+	s.move(e, t2)
+	s.Sizeb += 1
+	return nil
+}
+
+// Locating counts as a use of the given key, and is recorded in Stats().
+func (s *ARC) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	e, ok := s.m[key]
+	if !ok || e.where == b1 || e.where == b2 {
+		s.stats.Misses += 1
+		return nil
+	}
+
+	s.stats.Hits += 1
+	s.move(e, t2)
+	return e.value
+}
+
+// Removes the given key from this ARC entirely, forgetting any history of it.
+func (s *ARC) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	e, ok := s.m[key]
+	if !ok {
+		return nil
+	}
+
+	s.lists[e.where].Remove(e.elem)
+	delete(s.m, key)
+	if e.where == b1 || e.where == b2 {
+		return nil
+	}
+	s.Sizeb -= 1
+	return e.value
+}
+
+// Does not count as a use of the given keys.
+func (s *ARC) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, key := range keys {
+		if key == nil {
+			log.Panic("Nil key.")
+		}
+		if e, ok := s.m[key]; !ok || e.where == b1 || e.where == b2 {
+			return false
+		}
+	}
+	return true
+}
+
+// The copy has the same capacity, entries and history, but fresh Stats().
+func (s *ARC) Copy() Dictionary {
+	s.CheckInit()
+
+	var c *ARC
+	if s.Threadsafe() {
+		c = NewARC(s.capacity)
+	} else {
+		c = NewARCUnsafe(s.capacity)
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for where, l := range s.lists {
+		for el := l.Front(); el != nil; el = el.Next() {
+			e := el.Value.(*arcEntry)
+			ce := &arcEntry{key: e.key, value: e.value, where: where}
+			ce.elem = c.lists[where].PushBack(ce)
+			c.m[e.key] = ce
+		}
+	}
+	c.p = s.p
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Maps over KeyValues, without counting as a use of any entry.
+func (s *ARC) Map(f func(interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	ok := true
+	for _, where := range []int{t1, t2} {
+		for el := s.lists[where].Front(); el != nil && ok; el = el.Next() {
+			e := el.Value.(*arcEntry)
+			ok = f(&KeyValue{e.key, e.value})
+		}
+	}
+	return ok
+}
+
+// Returns a slice of pointers to KeyValue structs.
+func (s *ARC) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	for _, where := range []int{t1, t2} {
+		for el := s.lists[where].Front(); el != nil; el = el.Next() {
+			e := el.Value.(*arcEntry)
+			slice = append(slice, &KeyValue{e.key, e.value})
+		}
+	}
+	return &slice
+}
+
+// Clears all entries and history, but not Stats().
+func (s *ARC) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.reset()
+}
+
+func (s *ARC) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *ARC) reset() {
+	s.m = make(map[interface{}]*arcEntry)
+	for i := range s.lists {
+		s.lists[i] = list.New()
+	}
+	s.p = 0
+	s.Sizeb = 0
+}
+
+// Admits a key this ARC has no history of. Case IV in the paper.
+func (s *ARC) admit(key interface{}, value interface{}) {
+	c := s.capacity
+	l1 := s.lists[t1].Len() + s.lists[b1].Len()
+	l2 := s.lists[t2].Len() + s.lists[b2].Len()
+
+	if l1 >= c {
+		if s.lists[t1].Len() < c {
+			s.drop(s.lists[b1].Back())
+			s.replace(false)
+		} else {
+			s.drop(s.lists[t1].Back())
+			s.Sizeb -= 1
+			s.stats.Evictions += 1
+		}
+	} else if l1+l2 >= c {
+		if l1+l2 >= 2*c {
+			s.drop(s.lists[b2].Back())
+		}
+		s.replace(false)
+	}
+
+	e := &arcEntry{key: key, value: value, where: t1}
+	e.elem = s.lists[t1].PushFront(e)
+	s.m[key] = e
+	s.Sizeb += 1
+}
+
+// Evicts the least recently used entry of t1 or t2 to its ghost list,
+// making room for one more entry. inB2 is true if the entry being made
+// room for was found on b2. Does nothing if there is already room, as
+// there may be after a Remove().
+func (s *ARC) replace(inB2 bool) {
+	if s.Sizeb < s.capacity {
+		return
+	}
+	n1 := s.lists[t1].Len()
+
+	var e *arcEntry
+	if n1 > 0 && ((inB2 && n1 == s.p) || n1 > s.p) {
+		e = s.lists[t1].Back().Value.(*arcEntry)
+		s.move(e, b1)
+	} else if s.lists[t2].Len() > 0 {
+		e = s.lists[t2].Back().Value.(*arcEntry)
+		s.move(e, b2)
+	} else {
+		e = s.lists[t1].Back().Value.(*arcEntry)
+		s.move(e, b1)
+	}
+	e.value = nil
+	s.Sizeb -= 1
+	s.stats.Evictions += 1
+}
+
+// Moves the given entry to the front of the given list.
+func (s *ARC) move(e *arcEntry, where int) {
+	s.lists[e.where].Remove(e.elem)
+	e.where = where
+	e.elem = s.lists[where].PushFront(e)
+}
+
+// Forgets the entry at the given element entirely.
+func (s *ARC) drop(el *list.Element) {
+	e := el.Value.(*arcEntry)
+	s.lists[e.where].Remove(el)
+	delete(s.m, e.key)
+}
@@ -0,0 +1,131 @@
+// This module contains tests for arc.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"testing"
+)
+
+func TestNewEmptyARC(t *testing.T) {
+	s := NewARC(3)
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertEqual(t, s.Capacity(), 3, "Wrong capacity.")
+}
+
+func TestInsertLocateARC(t *testing.T) {
+	s := NewARC(3)
+
+	test.AssertNil(t, s.Insert("a", 1), "")
+	test.AssertEqual(t, s.Insert("a", 2), 1, "Wrong previous value.")
+	test.AssertEqual(t, s.Locate("a"), 2, "Wrong value.")
+	test.AssertNil(t, s.Locate("b"), "Located a missing key.")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after inserting.")
+
+	stats := s.Stats()
+	test.AssertEqual(t, stats.Hits, 1, "Wrong hits.")
+	test.AssertEqual(t, stats.Misses, 1, "Wrong misses.")
+}
+
+func TestNeverExceedsCapacityARC(t *testing.T) {
+	s := NewARC(10)
+	r := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		k := r.Intn(50)
+		switch r.Intn(4) {
+		case 0:
+			s.Remove(k)
+		case 1:
+			s.Locate(k)
+		default:
+			s.Insert(k, k)
+		}
+
+		if s.Size() > 10 || s.Size() != len(*s.Slice()) {
+			t.Fatalf("Wrong size %d, with %d entries.", s.Size(), len(*s.Slice()))
+		}
+		if len(s.m) > 20 {
+			t.Fatalf("Remembers %d keys.", len(s.m))
+		}
+	}
+}
+
+func TestFrequentSurvivesScanARC(t *testing.T) {
+	s := NewARC(4)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+	s.Locate("a")
+	s.Locate("b")
+
+	for i := 0; i < 100; i++ {
+		s.Insert(i, i)
+	}
+
+	test.AssertTrue(t, s.Contains("a", "b"), "Scan flushed frequently used entries.")
+	test.AssertEqual(t, s.Size(), 4, "Wrong size after scan.")
+}
+
+func TestGhostHitReadmitsARC(t *testing.T) {
+	s := NewARC(2)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+	s.Insert("c", 3)
+
+	test.AssertFalse(t, s.Contains("a"), "Least recently used entry not evicted.")
+	test.AssertNil(t, s.Locate("a"), "Located an evicted key.")
+
+	s.Insert("a", 4)
+
+	test.AssertEqual(t, s.Locate("a"), 4, "Ghost not readmitted.")
+	test.AssertEqual(t, s.Size(), 2, "Wrong size after readmitting.")
+}
+
+func TestRemoveARC(t *testing.T) {
+	s := NewARC(2)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+
+	test.AssertEqual(t, s.Remove("a"), 1, "Wrong removed value.")
+	test.AssertNil(t, s.Remove("a"), "Removed a missing key.")
+
+	s.Insert("c", 3)
+
+	test.AssertTrue(t, s.Contains("b", "c"), "Evicted despite room after Remove.")
+	test.AssertEqual(t, s.Stats().Evictions, 0, "Remove counted as an eviction.")
+}
+
+func TestCopyARC(t *testing.T) {
+	s := NewARC(2)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+
+	c := s.Copy().(*ARC)
+	c.Insert("c", 3)
+
+	test.AssertTrue(t, s.Contains("a", "b"), "Copy shares state with original.")
+	test.AssertEqual(t, c.Size(), 2, "Wrong size of copy.")
+	test.AssertTrue(t, c.Contains("c"), "Copy lost an insert.")
+}
+
+func TestClearARC(t *testing.T) {
+	s := NewARC(2)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+	s.Clear()
+
+	test.AssertEqual(t, s.Size(), 0, "Wrong size after clearing.")
+	test.AssertFalse(t, s.Contains("a"), "Cleared entry present.")
+}
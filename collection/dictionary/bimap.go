@@ -0,0 +1,326 @@
+// This module implements a BiMap, conforming to the Dictionary interface,
+// with the additional guarantee that values, as well as keys, are unique.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+)
+
+// A BiMap implements Dictionary, with the additional guarantee that no two
+// keys are associated with the same value, so it can be looked up in
+// either direction.
+//
+// Every BiMap has an Inverse(), a BiMap mapping its values to its keys. The
+// two are views of the same entries, sharing a single lock and size, so a
+// change through either is immediately visible through the other.
+//
+// Behavior unspecified if a BiMap is not created using NewBiMap(), NewBiMapUnsafe()
+// or if BiMap.Init() / BiMap.InitUnsafe(), is not first called on a new &BiMap{}.
+//
+type BiMap struct {
+	collection.Base
+	fwd     map[interface{}]interface{}
+	bwd     map[interface{}]interface{}
+	inverse *BiMap
+	owner   *BiMap // Holds the lock and size; this BiMap, or the one it inverts.
+}
+
+// Returns a pointer to a new BiMap.
+func NewBiMap() *BiMap {
+	s := &BiMap{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe BiMap.
+func NewBiMapUnsafe() *BiMap {
+	s := &BiMap{}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *BiMap) Init() {
+	s.InitBase()
+
+	s.reset()
+}
+
+func (s *BiMap) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.reset()
+}
+
+// Returns the inverse view of this BiMap, mapping its values to its keys.
+// The inverse of the inverse is this BiMap.
+func (s *BiMap) Inverse() *BiMap {
+	s.CheckInit()
+	return s.inverse
+}
+
+// Returns the number of entries, shared with the inverse.
+func (s *BiMap) Size() int {
+	s.CheckInit()
+	return s.owner.Base.Size()
+}
+
+func (s *BiMap) Empty() bool {
+	s.CheckInit()
+	return s.owner.Base.Empty()
+}
+
+func (s *BiMap) Threadsafe() bool {
+	s.CheckInit()
+	return s.owner.Base.Threadsafe()
+}
+
+// Locks the lock shared with the inverse.
+func (s *BiMap) Lock() {
+	s.CheckInit()
+	s.owner.Base.Lock()
+}
+
+func (s *BiMap) Unlock() {
+	s.CheckInit()
+	s.owner.Base.Unlock()
+}
+
+func (s *BiMap) RLock() {
+	s.CheckInit()
+	s.owner.Base.RLock()
+}
+
+func (s *BiMap) RUnlock() {
+	s.CheckInit()
+	s.owner.Base.RUnlock()
+}
+
+// Panics if the given value is already associated with a different key.
+// Use ForceInsert() to replace that association instead.
+func (s *BiMap) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if value == nil {
+		log.Panic("Nil value.")
+	}
+	if s.Threadsafe() {
+		s.owner.Lockb.Lock()
+		defer s.owner.Lockb.Unlock()
+	}
+
+	if k, ok := s.bwd[value]; ok && k != key {
+		log.Panicf("Value %v is already associated with key %v.", value, k)
+	}
+
+	return s.insert(key, value)
+}
+
+// Inserts the given value associated with the given key into this BiMap,
+// first removing any entry associating a different key with the given
+// value. Returns the previous value associated with the given key, or nil,
+// if none existed.
+//
+// Panics if the given key or value are nil.
+func (s *BiMap) ForceInsert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if value == nil {
+		log.Panic("Nil value.")
+	}
+	if s.Threadsafe() {
+		s.owner.Lockb.Lock()
+		defer s.owner.Lockb.Unlock()
+	}
+
+	if k, ok := s.bwd[value]; ok && k != key {
+		delete(s.fwd, k)
+		delete(s.bwd, value)
+		s.owner.Sizeb -= 1
+	}
+
+	return s.insert(key, value)
+}
+
+func (s *BiMap) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.owner.Lockb.RLock()
+		defer s.owner.Lockb.RUnlock()
+	}
+
+	return s.fwd[key]
+}
+
+// Returns the key associated with the given value, or nil, if there is none.
+//
+// Panics if the given value is nil.
+func (s *BiMap) LocateKey(value interface{}) interface{} {
+	return s.inverse.Locate(value)
+}
+
+func (s *BiMap) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.owner.Lockb.Lock()
+		defer s.owner.Lockb.Unlock()
+	}
+
+	value, ok := s.fwd[key]
+	if !ok {
+		return nil
+	}
+
+	delete(s.fwd, key)
+	delete(s.bwd, value)
+	s.owner.Sizeb -= 1
+	return value
+}
+
+// Removes the entry for the given value, and returns the key it was
+// associated with, or nil, if there was none.
+//
+// Panics if the given value is nil.
+func (s *BiMap) RemoveValue(value interface{}) interface{} {
+	return s.inverse.Remove(value)
+}
+
+func (s *BiMap) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.owner.Lockb.RLock()
+		defer s.owner.Lockb.RUnlock()
+	}
+
+	for _, key := range keys {
+		if key == nil {
+			log.Panic("Nil key.")
+		}
+		if _, ok := s.fwd[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns true if all the given values have entries in this BiMap, false
+// otherwise.
+//
+// Panics if any of the given values are nil.
+func (s *BiMap) ContainsValue(values ...interface{}) bool {
+	return s.inverse.Contains(values...)
+}
+
+// The copy is independent of this BiMap, and of its inverse.
+func (s *BiMap) Copy() Dictionary {
+	s.CheckInit()
+
+	var c *BiMap
+	if s.Threadsafe() {
+		c = NewBiMap()
+	} else {
+		c = NewBiMapUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.owner.Lockb.RLock()
+		defer s.owner.Lockb.RUnlock()
+	}
+
+	for k, v := range s.fwd {
+		c.fwd[k] = v
+		c.bwd[v] = k
+	}
+	c.Sizeb = s.owner.Sizeb
+	return c
+}
+
+// Maps over KeyValues
+func (s *BiMap) Map(f func(interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.owner.Lockb.RLock()
+		defer s.owner.Lockb.RUnlock()
+	}
+
+	ok := true
+	for k, v := range s.fwd {
+		if ok = f(&KeyValue{k, v}); !ok {
+			break
+		}
+	}
+	return ok
+}
+
+// Returns a slice of pointers to KeyValue structs.
+func (s *BiMap) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.owner.Lockb.RLock()
+		defer s.owner.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, len(s.fwd))
+	for k, v := range s.fwd {
+		slice = append(slice, &KeyValue{k, v})
+	}
+	return &slice
+}
+
+// Also clears the inverse.
+func (s *BiMap) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.owner.Lockb.Lock()
+		defer s.owner.Lockb.Unlock()
+	}
+
+	for k := range s.fwd {
+		delete(s.fwd, k)
+	}
+	for v := range s.bwd {
+		delete(s.bwd, v)
+	}
+	s.owner.Sizeb = 0
+}
+
+func (s *BiMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Creates the maps, and the inverse view sharing them. The inverse's own
+// Base is only used to check it was initialized.
+func (s *BiMap) reset() {
+	s.fwd = make(map[interface{}]interface{})
+	s.bwd = make(map[interface{}]interface{})
+	s.owner = s
+	s.inverse = &BiMap{fwd: s.bwd, bwd: s.fwd, inverse: s, owner: s}
+	s.inverse.InitBaseUnsafe()
+}
+
+// Associates the given key and value, replacing any value previously
+// associated with the key. The value must not be associated with another key.
+func (s *BiMap) insert(key interface{}, value interface{}) interface{} {
+	old, ok := s.fwd[key]
+	if ok {
+		delete(s.bwd, old)
+	} else {
+		s.owner.Sizeb += 1
+	}
+
+	s.fwd[key] = value
+	s.bwd[value] = key
+	return old
+}
@@ -0,0 +1,149 @@
+// This module contains tests for bimap.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"testing"
+	"time"
+)
+
+func TestNewEmptyBiMap(t *testing.T) {
+	s := NewBiMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertTrue(t, s.Inverse().Empty(), "New inverse is not empty.")
+	test.AssertTrue(t, s.Inverse().Inverse() == s, "Inverse of inverse is not the original.")
+}
+
+func TestInsertLocateBiMap(t *testing.T) {
+	s := NewBiMap()
+
+	test.AssertNil(t, s.Insert(1, "one"), "")
+	test.AssertNil(t, s.Insert(2, "two"), "")
+
+	test.AssertEqual(t, s.Locate(1), "one", "Wrong value.")
+	test.AssertEqual(t, s.LocateKey("two"), 2, "Wrong key.")
+	test.AssertNil(t, s.LocateKey("three"), "Located a missing value.")
+	test.AssertTrue(t, s.ContainsValue("one", "two"), "Value missing.")
+	test.AssertEqual(t, s.Size(), 2, "Wrong size after inserting.")
+}
+
+func TestReinsertBiMap(t *testing.T) {
+	s := NewBiMap()
+
+	s.Insert(1, "one")
+	old := s.Insert(1, "uno")
+
+	test.AssertEqual(t, old, "one", "Wrong previous value.")
+	test.AssertNil(t, s.LocateKey("one"), "Replaced value still present.")
+	test.AssertEqual(t, s.LocateKey("uno"), 1, "Wrong key.")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after reinserting.")
+
+	s.Insert(1, "uno")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after reinserting the same pair.")
+}
+
+func TestInsertConflictPanicsBiMap(t *testing.T) {
+	s := NewBiMap()
+	s.Insert(1, "one")
+
+	defer func() {
+		test.AssertNonNil(t, recover(), "Conflicting value did not panic.")
+		test.AssertEqual(t, s.Locate(1), "one", "Conflicting insert changed entries.")
+		test.AssertNil(t, s.Locate(2), "Conflicting insert changed entries.")
+	}()
+	s.Insert(2, "one")
+}
+
+func TestForceInsertBiMap(t *testing.T) {
+	s := NewBiMap()
+
+	s.Insert(1, "one")
+	s.Insert(2, "two")
+	old := s.ForceInsert(2, "one")
+
+	test.AssertEqual(t, old, "two", "Wrong previous value.")
+	test.AssertFalse(t, s.Contains(1), "Conflicting entry not evicted.")
+	test.AssertFalse(t, s.ContainsValue("two"), "Replaced value still present.")
+	test.AssertEqual(t, s.LocateKey("one"), 2, "Wrong key.")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after forcing.")
+}
+
+func TestRemoveBiMap(t *testing.T) {
+	s := NewBiMap()
+
+	s.Insert(1, "one")
+	s.Insert(2, "two")
+
+	test.AssertEqual(t, s.Remove(1), "one", "Wrong removed value.")
+	test.AssertEqual(t, s.RemoveValue("two"), 2, "Wrong removed key.")
+	test.AssertNil(t, s.RemoveValue("two"), "Removed a missing value.")
+	test.AssertEqual(t, s.Size(), 0, "Wrong size after removing.")
+	test.AssertFalse(t, s.ContainsValue("one"), "Removed value present.")
+}
+
+func TestInverseStaysInSyncBiMap(t *testing.T) {
+	s := NewBiMap()
+	inv := s.Inverse()
+
+	s.Insert(1, "one")
+	inv.Insert("two", 2)
+	inv.ForceInsert("uno", 1)
+
+	test.AssertEqual(t, s.Locate(2), "two", "Insert through inverse missing.")
+	test.AssertEqual(t, s.Locate(1), "uno", "Force insert through inverse missing.")
+	test.AssertEqual(t, inv.Size(), 2, "Inverse has the wrong size.")
+
+	s.Remove(2)
+	test.AssertFalse(t, inv.Contains("two"), "Removed entry present in inverse.")
+
+	inv.Clear()
+	test.AssertTrue(t, s.Empty(), "Clearing the inverse did not clear the original.")
+
+	var _ Dictionary = inv
+}
+
+func TestInverseSharesLockBiMap(t *testing.T) {
+	s := NewBiMapUnsafe()
+	inv := s.Inverse()
+
+	inv.Lock()
+	locked := make(chan bool)
+	go func() {
+		s.Lock()
+		locked <- true
+		s.Unlock()
+	}()
+
+	select {
+	case <-locked:
+		t.Fatal("Locked the original while the inverse was locked.")
+	case <-time.After(10 * time.Millisecond):
+	}
+	inv.Unlock()
+	<-locked
+
+	s.Insert(1, "one")
+	test.AssertEqual(t, inv.Size(), 1, "Inverse has the wrong size.")
+	test.AssertFalse(t, inv.Empty(), "Inverse is empty.")
+}
+
+func TestCopyBiMap(t *testing.T) {
+	s := NewBiMap()
+
+	s.Insert(1, "one")
+	c := s.Copy().(*BiMap)
+	c.Insert(2, "two")
+	c.Inverse().Remove("one")
+
+	test.AssertTrue(t, s.Contains(1), "Copy shares state with original.")
+	test.AssertFalse(t, s.ContainsValue("two"), "Copy shares state with original.")
+	test.AssertEqual(t, c.Size(), 1, "Wrong size of copy.")
+	test.AssertEqual(t, c.LocateKey("two"), 2, "Copy has a broken inverse.")
+}
@@ -0,0 +1,613 @@
+// This module implements an in-memory B-tree backed Dictionary, conforming to
+// Navigable, with the additional stipulation that all Keys must implement
+// collection.Comparer.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"sort"
+)
+
+// The default minimum degree of a BTreeMap.
+const DefaultDegree = 32
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// bnode definition, and associated helper functions.
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// The node struct for the B-tree. Holds its keys in order, each with its
+// value, and, unless it is a leaf, one more child than keys: C[i] holds the
+// keys between K[i-1] and K[i].
+type bnode struct {
+	K []collection.Comparer
+	V []interface{}
+	C []*bnode
+}
+
+// Returns true if the given node has no children.
+func (n *bnode) leaf() bool {
+	return len(n.C) == 0
+}
+
+// Returns the index of the first key in the given node greater than or equal
+// to k, and true if it is equal.
+func (n *bnode) search(k collection.Comparer) (int, bool) {
+	i := sort.Search(len(n.K), func(i int) bool {
+		return k.Compare(n.K[i]) <= 0
+	})
+	return i, i < len(n.K) && k.Compare(n.K[i]) == 0
+}
+
+// Inserts the given key and value into the given node at index i.
+func (n *bnode) insertAt(i int, k collection.Comparer, v interface{}) {
+	n.K = append(n.K, nil)
+	copy(n.K[i+1:], n.K[i:])
+	n.K[i] = k
+	n.V = append(n.V, nil)
+	copy(n.V[i+1:], n.V[i:])
+	n.V[i] = v
+}
+
+// Removes and returns the key and value at index i of the given node.
+func (n *bnode) removeAt(i int) (collection.Comparer, interface{}) {
+	k, v := n.K[i], n.V[i]
+	copy(n.K[i:], n.K[i+1:])
+	n.K[len(n.K)-1] = nil
+	n.K = n.K[:len(n.K)-1]
+	copy(n.V[i:], n.V[i+1:])
+	n.V[len(n.V)-1] = nil
+	n.V = n.V[:len(n.V)-1]
+	return k, v
+}
+
+// Inserts the given child into the given node at index i.
+func (n *bnode) insertChild(i int, c *bnode) {
+	n.C = append(n.C, nil)
+	copy(n.C[i+1:], n.C[i:])
+	n.C[i] = c
+}
+
+// Removes and returns the child at index i of the given node.
+func (n *bnode) removeChild(i int) *bnode {
+	c := n.C[i]
+	copy(n.C[i:], n.C[i+1:])
+	n.C[len(n.C)-1] = nil
+	n.C = n.C[:len(n.C)-1]
+	return c
+}
+
+// Returns a deep copy of the subtree rooted at the given node.
+func (n *bnode) copy() *bnode {
+	c := &bnode{
+		K: append([]collection.Comparer(nil), n.K...),
+		V: append([]interface{}(nil), n.V...),
+	}
+	if !n.leaf() {
+		c.C = make([]*bnode, len(n.C))
+		for i, child := range n.C {
+			c.C[i] = child.copy()
+		}
+	}
+	return c
+}
+
+// Applies the given function to the KeyValue of every key in the subtree
+// rooted at n from from, inclusive, to to, exclusive, in order, or of every
+// key if from and to are nil. Skips subtrees wholly outside that range.
+// Returns false once the function returns false, or a key reaches to.
+func (n *bnode) scan(from collection.Comparer, to collection.Comparer, f func(item interface{}) bool) bool {
+	i := 0
+	if from != nil {
+		i, _ = n.search(from)
+	}
+
+	for ; i <= len(n.K); i++ {
+		if !n.leaf() && !n.C[i].scan(from, to, f) {
+			return false
+		}
+		if i == len(n.K) {
+			break
+		}
+		if to != nil && to.Compare(n.K[i]) <= 0 {
+			return false
+		}
+		if !f(&KeyValue{n.K[i], n.V[i]}) {
+			return false
+		}
+		// Every later key in this subtree is at least from.
+		from = nil
+	}
+	return true
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// end node stuff
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// A BTreeMap implements Navigable. It offers the same ordered behavior as
+// TreeMap, but stores many keys in each node: every node but the root holds
+// between degree - 1 and 2 * degree - 1 keys. Fewer, larger nodes make better
+// use of CPU caches than a binary tree's.
+//
+// Behavior unspecified if a BTreeMap is not created using NewBTreeMap(),
+// NewBTreeMapUnsafe() or if BTreeMap.Init() / BTreeMap.InitUnsafe(), is not
+// first called on a new &BTreeMap{}.
+//
+type BTreeMap struct {
+	collection.Base
+	root   *bnode
+	degree int
+}
+
+// Returns a pointer to a new BTreeMap with DefaultDegree.
+func NewBTreeMap() *BTreeMap {
+	s := &BTreeMap{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe BTreeMap with DefaultDegree.
+func NewBTreeMapUnsafe() *BTreeMap {
+	s := &BTreeMap{}
+	s.InitUnsafe()
+	return s
+}
+
+// Returns a pointer to a new BTreeMap with the given minimum degree.
+//
+// Panics if degree is less than 2.
+func NewBTreeMapWithDegree(degree int) *BTreeMap {
+	s := &BTreeMap{degree: degree}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe BTreeMap with the given minimum degree.
+//
+// Panics if degree is less than 2.
+func NewBTreeMapWithDegreeUnsafe(degree int) *BTreeMap {
+	s := &BTreeMap{degree: degree}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *BTreeMap) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *BTreeMap) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Returns the minimum degree of this BTreeMap.
+func (s *BTreeMap) Degree() int {
+	s.CheckInit()
+	return s.degree
+}
+
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *BTreeMap) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if s.full(s.root) {
+		root := &bnode{C: []*bnode{s.root}}
+		s.split(root, 0)
+		s.root = root
+	}
+
+	// Split every full node on the way down, so there is always room.
+	n := s.root
+	for {
+		i, found := n.search(kc)
+		if found {
+			old := n.V[i]
+			n.V[i] = value
+			return old
+		}
+		if n.leaf() {
+			n.insertAt(i, kc, value)
+			s.Sizeb += 1
+			return nil
+		}
+
+		if s.full(n.C[i]) {
+			s.split(n, i)
+			if c := kc.Compare(n.K[i]); c == 0 {
+				old := n.V[i]
+				n.V[i] = value
+				return old
+			} else if c > 0 {
+				i++
+			}
+		}
+		n = n.C[i]
+	}
+}
+
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *BTreeMap) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	n := s.root
+	for {
+		i, found := n.search(kc)
+		if found {
+			return n.V[i]
+		}
+		if n.leaf() {
+			return nil
+		}
+		n = n.C[i]
+	}
+}
+
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *BTreeMap) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	old, removed := s.delete(s.root, kc)
+	if len(s.root.K) == 0 && !s.root.leaf() {
+		s.root = s.root.C[0]
+	}
+	if !removed {
+		return nil
+	}
+
+	s.Sizeb -= 1
+	return old
+}
+
+func (s *BTreeMap) Contains(keys ...interface{}) bool {
+	if len(keys) == 0 {
+		return false
+	}
+
+	for _, k := range keys {
+		if s.Locate(k) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a pointer to the KeyValue with the smallest key, or nil if this
+// BTreeMap is empty.
+func (s *BTreeMap) First() *KeyValue {
+	return s.end(0)
+}
+
+// Returns a pointer to the KeyValue with the largest key, or nil if this
+// BTreeMap is empty.
+func (s *BTreeMap) Last() *KeyValue {
+	return s.end(1)
+}
+
+// Returns a pointer to the KeyValue with the largest key less than or equal
+// to the given key, or nil if there is none.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *BTreeMap) Floor(key interface{}) *KeyValue {
+	return s.nearest(key, 0)
+}
+
+// Returns a pointer to the KeyValue with the smallest key greater than or
+// equal to the given key, or nil if there is none.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *BTreeMap) Ceiling(key interface{}) *KeyValue {
+	return s.nearest(key, 1)
+}
+
+// Attempts to apply the given function to a pointer to the KeyValue of
+// every key from from, inclusive, to to, exclusive, in ascending order.
+// Stops once all those KeyValues have been processed, or once the function
+// returns false, whichever occurs first.
+//
+// Panics if either key is nil or doesn't implement collection.Comparer.
+func (s *BTreeMap) Range(from interface{}, to interface{}, f func(item interface{}) bool) bool {
+	s.CheckInit()
+	fc, tc := comparerKey(from), comparerKey(to)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	ok := true
+	s.root.scan(fc, tc, func(kv interface{}) bool {
+		ok = f(kv)
+		return ok
+	})
+	return ok
+}
+
+func (s *BTreeMap) Copy() Dictionary {
+	s.CheckInit()
+
+	c := &BTreeMap{degree: s.degree}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.root = s.root.copy()
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to a pointer to a KeyValue for every
+// entry in this BTreeMap, in ascending order of their keys. Stops once all
+// entries have been processed, or once the function returns false,
+// whichever occurs first.
+func (s *BTreeMap) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.root.scan(nil, nil, f)
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of keys.
+func (s *BTreeMap) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(kv interface{}) bool {
+		slice = append(slice, kv)
+		return true
+	})
+	return &slice
+}
+
+func (s *BTreeMap) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.root = &bnode{}
+	s.Sizeb = 0
+}
+
+func (s *BTreeMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *BTreeMap) setup() {
+	if s.degree == 0 {
+		s.degree = DefaultDegree
+	}
+	if s.degree < 2 {
+		log.Panic("Degree must be at least 2.")
+	}
+	s.root = &bnode{}
+}
+
+// Returns true if the given node holds as many keys as it may.
+func (s *BTreeMap) full(n *bnode) bool {
+	return len(n.K) == 2*s.degree-1
+}
+
+// Splits the full child at index i of the given node in two around its
+// median key, which moves up into the given node.
+func (s *BTreeMap) split(n *bnode, i int) {
+	t := s.degree
+	y := n.C[i]
+
+	z := &bnode{
+		K: append(make([]collection.Comparer, 0, 2*t-1), y.K[t:]...),
+		V: append(make([]interface{}, 0, 2*t-1), y.V[t:]...),
+	}
+	if !y.leaf() {
+		z.C = append(make([]*bnode, 0, 2*t), y.C[t:]...)
+		for j := t; j < len(y.C); j++ {
+			y.C[j] = nil
+		}
+		y.C = y.C[:t]
+	}
+
+	n.insertAt(i, y.K[t-1], y.V[t-1])
+	n.insertChild(i+1, z)
+
+	for j := t - 1; j < len(y.K); j++ {
+		y.K[j], y.V[j] = nil, nil
+	}
+	y.K, y.V = y.K[:t-1], y.V[:t-1]
+}
+
+// Merges the child at index i+1 of the given node, and the key between them,
+// into the child at index i.
+func (s *BTreeMap) merge(n *bnode, i int) {
+	y, z := n.C[i], n.removeChild(i+1)
+	k, v := n.removeAt(i)
+
+	y.K = append(append(y.K, k), z.K...)
+	y.V = append(append(y.V, v), z.V...)
+	y.C = append(y.C, z.C...)
+}
+
+// Makes sure the child at index i of the given node holds at least degree
+// keys, by borrowing a key from a sibling or merging with one. Returns the
+// index of the child that now holds its keys.
+func (s *BTreeMap) grow(n *bnode, i int) int {
+	c := n.C[i]
+
+	if i > 0 && len(n.C[i-1].K) >= s.degree {
+		// Rotate the left sibling's last key up, and n's key down.
+		l := n.C[i-1]
+		k, v := l.removeAt(len(l.K) - 1)
+		c.insertAt(0, n.K[i-1], n.V[i-1])
+		n.K[i-1], n.V[i-1] = k, v
+		if !l.leaf() {
+			c.insertChild(0, l.removeChild(len(l.C)-1))
+		}
+		return i
+	}
+
+	if i < len(n.K) && len(n.C[i+1].K) >= s.degree {
+		// Rotate the right sibling's first key up, and n's key down.
+		r := n.C[i+1]
+		k, v := r.removeAt(0)
+		c.insertAt(len(c.K), n.K[i], n.V[i])
+		n.K[i], n.V[i] = k, v
+		if !r.leaf() {
+			c.insertChild(len(c.C), r.removeChild(0))
+		}
+		return i
+	}
+
+	if i < len(n.K) {
+		s.merge(n, i)
+		return i
+	}
+	s.merge(n, i-1)
+	return i - 1
+}
+
+// Removes the given key from the subtree rooted at n, which holds at least
+// degree keys unless it is the root. Returns the value associated with the
+// key, and true if it was present.
+func (s *BTreeMap) delete(n *bnode, k collection.Comparer) (interface{}, bool) {
+	for {
+		i, found := n.search(k)
+
+		if n.leaf() {
+			if !found {
+				return nil, false
+			}
+			_, v := n.removeAt(i)
+			return v, true
+		}
+
+		if found {
+			old := n.V[i]
+			switch {
+			case len(n.C[i].K) >= s.degree:
+				// Replace the key with its predecessor, and remove that.
+				p := n.C[i]
+				for !p.leaf() {
+					p = p.C[len(p.C)-1]
+				}
+				n.K[i], n.V[i] = p.K[len(p.K)-1], p.V[len(p.V)-1]
+				s.delete(n.C[i], n.K[i])
+			case len(n.C[i+1].K) >= s.degree:
+				// Replace the key with its successor, and remove that.
+				p := n.C[i+1]
+				for !p.leaf() {
+					p = p.C[0]
+				}
+				n.K[i], n.V[i] = p.K[0], p.V[0]
+				s.delete(n.C[i+1], n.K[i])
+			default:
+				s.merge(n, i)
+				s.delete(n.C[i], k)
+			}
+			return old, true
+		}
+
+		if len(n.C[i].K) < s.degree {
+			i = s.grow(n, i)
+		}
+		n = n.C[i]
+	}
+}
+
+// Returns a pointer to the KeyValue with the smallest (dir 0) or largest
+// (dir 1) key, or nil if this BTreeMap is empty.
+func (s *BTreeMap) end(dir int) *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	n := s.root
+	if len(n.K) == 0 {
+		return nil
+	}
+	for {
+		i := 0
+		if dir == 1 {
+			i = len(n.K)
+		}
+		if n.leaf() {
+			if dir == 1 {
+				i--
+			}
+			return &KeyValue{n.K[i], n.V[i]}
+		}
+		n = n.C[i]
+	}
+}
+
+// Returns a pointer to the KeyValue with the largest key less than or equal
+// to (dir 0), or the smallest key greater than or equal to (dir 1), the given
+// key, or nil if there is none.
+func (s *BTreeMap) nearest(key interface{}, dir int) *KeyValue {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	var best *KeyValue
+	for n := s.root; n != nil; {
+		i, found := n.search(kc)
+		if found {
+			return &KeyValue{n.K[i], n.V[i]}
+		}
+		if dir == 0 && i > 0 {
+			best = &KeyValue{n.K[i-1], n.V[i-1]}
+		} else if dir == 1 && i < len(n.K) {
+			best = &KeyValue{n.K[i], n.V[i]}
+		}
+		if n.leaf() {
+			break
+		}
+		n = n.C[i]
+	}
+	return best
+}
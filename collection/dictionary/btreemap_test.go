@@ -0,0 +1,134 @@
+// This module contains tests for btreemap.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"testing"
+)
+
+// Checks that the subtree rooted at n holds keys strictly between lo and hi
+// (either may be nil) in order, that every node but the root holds between
+// degree - 1 and 2 * degree - 1 keys, and that every leaf is at the same
+// depth. Returns its size and height.
+func checkBTree(t *testing.T, s *BTreeMap, n *bnode, lo collection.Comparer, hi collection.Comparer) (size int, height int) {
+	if n != s.root && (len(n.K) < s.degree-1 || len(n.K) > 2*s.degree-1) {
+		t.Fatalf("Node %v holds %d keys.", n.K, len(n.K))
+	}
+	if len(n.V) != len(n.K) || (!n.leaf() && len(n.C) != len(n.K)+1) {
+		t.Fatalf("Node %v has the wrong number of values or children.", n.K)
+	}
+	for i, k := range n.K {
+		if (i > 0 && n.K[i-1].Compare(k) >= 0) || (lo != nil && lo.Compare(k) >= 0) ||
+			(hi != nil && hi.Compare(k) <= 0) {
+			t.Fatalf("Node %v is out of order.", n.K)
+		}
+	}
+
+	size = len(n.K)
+	if n.leaf() {
+		return size, 0
+	}
+	height = -1
+	for i, c := range n.C {
+		l, h := lo, hi
+		if i > 0 {
+			l = n.K[i-1]
+		}
+		if i < len(n.K) {
+			h = n.K[i]
+		}
+		cs, ch := checkBTree(t, s, c, l, h)
+		if height != -1 && ch != height {
+			t.Fatalf("Leaves under %v are at different depths.", n.K)
+		}
+		size, height = size+cs, ch
+	}
+	return size, height + 1
+}
+
+func TestNewEmptyBTreeMap(t *testing.T) {
+	s := NewBTreeMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertEqual(t, s.Degree(), DefaultDegree, "Wrong degree.")
+	test.AssertTrue(t, s.First() == nil && s.Last() == nil, "Empty dictionary has ends.")
+	test.AssertTrue(t, s.Floor(compInt{1}) == nil && s.Ceiling(compInt{1}) == nil, "Empty dictionary has neighbors.")
+}
+
+func TestInvalidDegreeBTreeMap(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("A degree of 1 did not panic.")
+		}
+	}()
+	NewBTreeMapWithDegree(1)
+}
+
+func TestLargeRandomBTreeMap(t *testing.T) {
+	r := rand.New(rand.NewSource(46))
+
+	for _, degree := range []int{2, 3, 8} {
+		s := NewBTreeMapWithDegree(degree)
+		kvs := make(map[int]int)
+
+		for i := 0; i < 20000; i++ {
+			k := r.Intn(2000)
+			if r.Intn(2) == 0 {
+				s.Insert(compInt{k}, i)
+				kvs[k] = i
+			} else {
+				removed := s.Remove(compInt{k})
+				if v, ok := kvs[k]; ok {
+					test.AssertEqual(t, removed, v, "Removed wrong value.")
+				} else {
+					test.AssertNil(t, removed, "Removed a missing key.")
+				}
+				delete(kvs, k)
+			}
+
+			if i%1000 == 0 {
+				size, _ := checkBTree(t, s, s.root, nil, nil)
+				test.AssertEqual(t, size, len(kvs), "Tree lost keys.")
+			}
+		}
+
+		test.AssertEqual(t, s.Size(), len(kvs), "Wrong size.")
+		for k, v := range kvs {
+			test.AssertEqual(t, s.Locate(compInt{k}), v, "Retrieved wrong value.")
+		}
+
+		for k := range kvs {
+			s.Remove(compInt{k})
+		}
+		test.AssertTrue(t, s.Empty() && s.root.leaf() && len(s.root.K) == 0, "Removing every key left keys.")
+	}
+}
+
+func TestCopyClearBTreeMap(t *testing.T) {
+	s := NewBTreeMapWithDegree(3)
+	for _, k := range rand.Perm(500) {
+		s.Insert(compInt{k}, k)
+	}
+	c := s.Copy().(*BTreeMap)
+
+	size, _ := checkBTree(t, c, c.root, nil, nil)
+	test.AssertEqual(t, size, 500, "Copy lost keys.")
+	test.AssertEqual(t, c.Degree(), 3, "Copy has the wrong degree.")
+	c.Remove(compInt{1})
+	s.Insert(compInt{1000}, 1000)
+	test.AssertEqual(t, s.Locate(compInt{1}), 1, "Copy shares nodes with the original.")
+	test.AssertNil(t, c.Locate(compInt{1000}), "Copy shares nodes with the original.")
+
+	s.Clear()
+	test.AssertTrue(t, s.Empty() && s.First() == nil, "Clear did not empty the dictionary.")
+	s.Insert(compInt{1}, 1)
+	test.AssertEqual(t, s.Locate(compInt{1}), 1, "A cleared dictionary is not usable.")
+}
@@ -0,0 +1,55 @@
+// This module defines the Cache interface, implemented by Dictionaries that
+// hold a bounded number of entries and evict some to make room for others.
+
+package dictionary
+
+import (
+	"fmt"
+)
+
+// CacheStats records how well a Cache has been serving lookups.
+type CacheStats struct {
+	Hits      int // Locate()s that found an entry
+	Misses    int // Locate()s that did not
+	Evictions int // entries dropped to make room for others
+}
+
+// Returns the fraction of Locate()s that found an entry, or 0 if there have
+// been none.
+func (s CacheStats) HitRatio() float64 {
+	if s.Hits+s.Misses == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(s.Hits+s.Misses)
+}
+
+func (s CacheStats) String() string {
+	return fmt.Sprintf("{hits: %d, misses: %d, evictions: %d}", s.Hits, s.Misses, s.Evictions)
+}
+
+// Defines the interface for Caches. Caches are Dictionaries holding at most
+// Capacity() entries. Inserting into a full Cache evicts an entry chosen
+// by the Cache's policy.
+//
+// Locate() counts as a use of the entry, and is recorded in Stats(), while
+// Contains() does not, and is not.
+//
+type Cache interface {
+	Dictionary
+
+	// Returns the maximum number of entries this Cache will hold.
+	//
+	// Panics if this Cache has not been initialized.
+	Capacity() int
+
+	// Returns the hits, misses and evictions recorded by this Cache since
+	// it was initialized, or since ResetStats() was last called.
+	//
+	// Panics if this Cache has not been initialized.
+	Stats() CacheStats
+
+	// Zeroes this Cache's hits, misses and evictions.
+	//
+	// Panics if this Cache has not been initialized.
+	ResetStats()
+}
@@ -0,0 +1,111 @@
+// This module contains trace-driven tests for the Caches in this package.
+//
+// Note:
+//  Traces are seeded, so hit ratios are deterministic.
+
+package dictionary
+
+import (
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Returns a trace of n requests over the given number of keys, whose
+// popularity follows a Zipf distribution with the given skew. Key 0 is the
+// most popular, unless shifted by offset.
+func zipfTrace(seed int64, n int, keys uint64, skew float64, offset int) []int {
+	r := rand.New(rand.NewSource(seed))
+	z := rand.NewZipf(r, skew, 1, keys-1)
+
+	trace := make([]int, n)
+	for i := range trace {
+		trace[i] = int(z.Uint64()) + offset
+	}
+	return trace
+}
+
+// Replays the given trace against the given Cache, inserting on every miss,
+// and returns the Cache's hit ratio.
+func replay(c Cache, trace []int) float64 {
+	for _, k := range trace {
+		if c.Locate(k) == nil {
+			c.Insert(k, k)
+		}
+	}
+	return c.Stats().HitRatio()
+}
+
+// Returns the hit ratio of a cache that held, all along, the given number
+// of keys requested most often in the given trace.
+func staticOptimal(trace []int, capacity int) float64 {
+	counts := make(map[int]int)
+	for _, k := range trace {
+		counts[k] += 1
+	}
+
+	sorted := make([]int, 0, len(counts))
+	for _, count := range counts {
+		sorted = append(sorted, count)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sorted)))
+
+	hits := 0
+	for i := 0; i < capacity && i < len(sorted); i++ {
+		hits += sorted[i]
+	}
+	return float64(hits) / float64(len(trace))
+}
+
+func TestCache(t *testing.T) {
+	var _ Cache = NewLFU(1)
+	var _ Cache = NewARC(1)
+}
+
+func TestZipfHitRatios(t *testing.T) {
+	const capacity = 100
+
+	for _, skew := range []float64{1.1, 1.5} {
+		trace := zipfTrace(1, 100000, 10000, skew, 0)
+		optimal := staticOptimal(trace, capacity)
+
+		lfu := replay(NewLFU(capacity), trace)
+		arc := replay(NewARC(capacity), trace)
+		t.Logf("skew %.1f: optimal %.3f, LFU %.3f, ARC %.3f", skew, optimal, lfu, arc)
+
+		if lfu < 0.9*optimal {
+			t.Errorf("LFU hit ratio %.3f too far below optimal %.3f.", lfu, optimal)
+		}
+		if arc < 0.8*optimal {
+			t.Errorf("ARC hit ratio %.3f too far below optimal %.3f.", arc, optimal)
+		}
+	}
+}
+
+func TestShiftingZipfHitRatios(t *testing.T) {
+	const capacity = 100
+
+	// Popularity moves to a disjoint set of keys halfway through, leaving
+	// the LFU holding entries with large, stale counts.
+	trace := append(zipfTrace(1, 50000, 10000, 1.1, 0), zipfTrace(2, 50000, 10000, 1.1, 10000)...)
+
+	lfu := replay(NewLFU(capacity), trace)
+	arc := replay(NewARC(capacity), trace)
+	t.Logf("LFU %.3f, ARC %.3f", lfu, arc)
+
+	if arc <= lfu {
+		t.Errorf("ARC hit ratio %.3f not above LFU %.3f after a shift.", arc, lfu)
+	}
+}
+
+func BenchmarkZipfLFU(b *testing.B) {
+	trace := zipfTrace(1, b.N, 10000, 1.1, 0)
+	b.ResetTimer()
+	replay(NewLFU(100), trace)
+}
+
+func BenchmarkZipfARC(b *testing.B) {
+	trace := zipfTrace(1, b.N, 10000, 1.1, 0)
+	b.ResetTimer()
+	replay(NewARC(100), trace)
+}
@@ -0,0 +1,480 @@
+// This module implements a lock-free, concurrent skip list backed Dictionary,
+// conforming to Dictionary, with the additional stipulation that all Keys
+// must implement collection.Comparer.
+//
+// See Herlihy and Shavit, "The Art of Multiprocessor Programming", 14.4.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"math/rand"
+	"sync/atomic"
+	"unsafe"
+)
+
+// An immutable reference to the next node on some level, and whether the
+// node holding the reference has been logically removed from that level.
+// Swapping whole references lets a single compare-and-swap check both.
+type markRef struct {
+	node   *cnode
+	marked bool
+}
+
+// Holds a node's value, so values may be swapped atomically.
+type valueBox struct {
+	v interface{}
+}
+
+// Replaces the value of a node once Remove() has claimed it. Claiming the
+// value is what removes the entry; marking and unlinking the node follow.
+var removedValue = &valueBox{}
+
+// An atomically accessed pointer to a markRef.
+type refPointer struct {
+	p unsafe.Pointer
+}
+
+func (a *refPointer) Load() *markRef {
+	return (*markRef)(atomic.LoadPointer(&a.p))
+}
+
+func (a *refPointer) Store(r *markRef) {
+	atomic.StorePointer(&a.p, unsafe.Pointer(r))
+}
+
+func (a *refPointer) CompareAndSwap(old *markRef, new *markRef) bool {
+	return atomic.CompareAndSwapPointer(&a.p, unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+// An atomically accessed pointer to a valueBox.
+type valuePointer struct {
+	p unsafe.Pointer
+}
+
+func (a *valuePointer) Load() *valueBox {
+	return (*valueBox)(atomic.LoadPointer(&a.p))
+}
+
+func (a *valuePointer) Store(v *valueBox) {
+	atomic.StorePointer(&a.p, unsafe.Pointer(v))
+}
+
+func (a *valuePointer) CompareAndSwap(old *valueBox, new *valueBox) bool {
+	return atomic.CompareAndSwapPointer(&a.p, unsafe.Pointer(old), unsafe.Pointer(new))
+}
+
+// The node struct for the concurrent skip list. Nodes are never unmarked.
+// A node whose value is removedValue has been removed, and is marked on
+// level 0 soon after.
+type cnode struct {
+	K    collection.Comparer
+	V    valuePointer
+	next []refPointer
+}
+
+// Returns a pointer to a new node of the given height, linked to nothing.
+func newCNode(k collection.Comparer, v interface{}, height int) *cnode {
+	n := &cnode{K: k, next: make([]refPointer, height)}
+	n.V.Store(&valueBox{v})
+	for i := range n.next {
+		n.next[i].Store(&markRef{})
+	}
+	return n
+}
+
+// A ConcurrentSkipListMap implements Dictionary, with the additional guarantee
+// of storing its KeyValues in sorted order, as defined by the Key's Compare()
+// method (Keys should implement collection.Comparer).
+//
+// Unlike other Collections, it does not guard its state with a lock. Every
+// operation is lock-free, and Locate() and Contains() never wait at all, so
+// many goroutines can read and write it at once. Map() and Slice() are weakly
+// consistent: they see every entry present for the whole traversal, and may
+// or may not see entries inserted or removed during it. Size() is exact only
+// when no writes are in progress.
+//
+// A ConcurrentSkipListMap is always thread-safe: InitUnsafe() is the same
+// as Init(), and Lock(), Unlock(), RLock() and RUnlock() always Panic.
+//
+// Behavior unspecified if a ConcurrentSkipListMap is not created using
+// NewConcurrentSkipListMap() or if ConcurrentSkipListMap.Init() is not first
+// called on a new &ConcurrentSkipListMap{}.
+//
+type ConcurrentSkipListMap struct {
+	size int64 // First, to be 64-bit aligned for atomic use on 32-bit platforms.
+	init bool
+	head *cnode // Never replaced, so Clear() can't strand operations under way.
+	p    float64
+}
+
+// Returns a pointer to a new ConcurrentSkipListMap with DefaultProbability.
+func NewConcurrentSkipListMap() *ConcurrentSkipListMap {
+	s := &ConcurrentSkipListMap{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new ConcurrentSkipListMap with the given level
+// probability.
+//
+// Panics if p is not strictly between 0 and 1.
+func NewConcurrentSkipListMapWithProbability(p float64) *ConcurrentSkipListMap {
+	s := &ConcurrentSkipListMap{p: p}
+	s.Init()
+	return s
+}
+
+func (s *ConcurrentSkipListMap) Init() {
+	if s.init {
+		log.Panic("Cannot initialize an already initialized Collection.")
+	}
+	if s.p == 0 {
+		s.p = DefaultProbability
+	}
+	if s.p <= 0 || s.p >= 1 {
+		log.Panic("Level probability must be between 0 and 1.")
+	}
+
+	s.head = newCNode(nil, nil, maxLevel)
+	s.init = true
+}
+
+// Same as Init(). A ConcurrentSkipListMap is always thread-safe.
+func (s *ConcurrentSkipListMap) InitUnsafe() {
+	s.Init()
+}
+
+func (s *ConcurrentSkipListMap) CheckInit() {
+	if !s.init {
+		log.Panic("Collection not initialized!")
+	}
+}
+
+// Returns the probability of a node reaching each next level.
+func (s *ConcurrentSkipListMap) Probability() float64 {
+	s.CheckInit()
+	return s.p
+}
+
+func (s *ConcurrentSkipListMap) Size() int {
+	s.CheckInit()
+	return int(atomic.LoadInt64(&s.size))
+}
+
+func (s *ConcurrentSkipListMap) Empty() bool {
+	s.CheckInit()
+
+	for ref := s.head.next[0].Load(); ref.node != nil; ref = ref.node.next[0].Load() {
+		if ref.node.V.Load() != removedValue {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *ConcurrentSkipListMap) Threadsafe() bool {
+	s.CheckInit()
+	return true
+}
+
+func (s *ConcurrentSkipListMap) Lock() {
+	log.Panic("Cannot call Lock() on a thread-safe Collection.")
+}
+
+func (s *ConcurrentSkipListMap) Unlock() {
+	log.Panic("Cannot call Unlock() on a thread-safe Collection.")
+}
+
+func (s *ConcurrentSkipListMap) RLock() {
+	log.Panic("Cannot call RLock() on a thread-safe Collection.")
+}
+
+func (s *ConcurrentSkipListMap) RUnlock() {
+	log.Panic("Cannot call RUnlock() on a thread-safe Collection.")
+}
+
+// Panic if key doesn't implement collection.Comparer
+func (s *ConcurrentSkipListMap) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	var preds, succs [maxLevel]*cnode
+	height := s.randomLevel()
+
+retry:
+	for {
+		if s.find(kc, &preds, &succs) {
+			n := succs[0]
+			for {
+				old := n.V.Load()
+				if old == removedValue {
+					continue retry // Being removed; find() will help unlink it.
+				}
+				if n.V.CompareAndSwap(old, &valueBox{value}) {
+					return old.v
+				}
+			}
+		}
+
+		n := newCNode(kc, value, height)
+		for i := 0; i < height; i++ {
+			n.next[i].Store(&markRef{node: succs[i]})
+		}
+
+		// Linking on level 0 is what adds n to the map.
+		if !casRef(&preds[0].next[0], succs[0], false, n) {
+			continue
+		}
+		atomic.AddInt64(&s.size, 1)
+
+		for i := 1; i < height; i++ {
+			for {
+				ref := n.next[i].Load()
+				if ref.marked {
+					return nil // Already being removed; stop linking it.
+				}
+				if ref.node != succs[i] && !n.next[i].CompareAndSwap(ref, &markRef{node: succs[i]}) {
+					continue
+				}
+				if casRef(&preds[i].next[i], succs[i], false, n) {
+					break
+				}
+				s.find(kc, &preds, &succs)
+			}
+		}
+		return nil
+	}
+}
+
+func (s *ConcurrentSkipListMap) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if n := s.search(kc); n != nil {
+		if v := n.V.Load(); v != removedValue {
+			return v.v
+		}
+	}
+	return nil
+}
+
+func (s *ConcurrentSkipListMap) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	var preds, succs [maxLevel]*cnode
+	if !s.find(kc, &preds, &succs) {
+		return nil
+	}
+	return s.remove(succs[0], &preds, &succs)
+}
+
+// Will also Panic if any key doesn't implement collection.Comparer.
+func (s *ConcurrentSkipListMap) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+
+	for _, key := range keys {
+		if s.search(comparerKey(key)) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a pointer to the KeyValue with the smallest key, or nil if this
+// ConcurrentSkipListMap is empty.
+func (s *ConcurrentSkipListMap) First() *KeyValue {
+	s.CheckInit()
+
+	var kv *KeyValue
+	s.Map(func(item interface{}) bool {
+		kv = item.(*KeyValue)
+		return false
+	})
+	return kv
+}
+
+// Returns a pointer to a new ConcurrentSkipListMap with the same entries.
+// Entries inserted or removed during the copy may or may not be included.
+func (s *ConcurrentSkipListMap) Copy() Dictionary {
+	s.CheckInit()
+
+	c := &ConcurrentSkipListMap{p: s.p}
+	c.Init()
+
+	s.Map(func(item interface{}) bool {
+		kv := item.(*KeyValue)
+		c.Insert(kv.Key, kv.Value)
+		return true
+	})
+	return c
+}
+
+// Maps over KeyValues, in ascending order of keys.
+func (s *ConcurrentSkipListMap) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	ref := s.head.next[0].Load()
+	for ref.node != nil {
+		n := ref.node
+		ref = n.next[0].Load()
+		v := n.V.Load()
+		if v == removedValue {
+			continue
+		}
+		if !f(&KeyValue{n.K, v.v}) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of keys.
+func (s *ConcurrentSkipListMap) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(item interface{}) bool {
+		slice = append(slice, item)
+		return true
+	})
+	return &slice
+}
+
+// Removes every entry, one at a time, as Remove() does. Entries inserted
+// during the call may or may not be removed.
+func (s *ConcurrentSkipListMap) Clear() {
+	s.CheckInit()
+
+	var preds, succs [maxLevel]*cnode
+	for ref := s.head.next[0].Load(); ref.node != nil; ref = ref.node.next[0].Load() {
+		s.remove(ref.node, &preds, &succs)
+	}
+}
+
+func (s *ConcurrentSkipListMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Removes the given node, and returns its value, or nil if another call
+// removed it first. preds and succs are used to unlink it.
+func (s *ConcurrentSkipListMap) remove(n *cnode, preds *[maxLevel]*cnode, succs *[maxLevel]*cnode) interface{} {
+	// Claiming the value removes the entry, so a racing Insert() either
+	// replaced the value first, or sees it claimed and retries.
+	var old *valueBox
+	for {
+		old = n.V.Load()
+		if old == removedValue {
+			return nil
+		}
+		if n.V.CompareAndSwap(old, removedValue) {
+			break
+		}
+	}
+
+	// Mark the upper levels first, so n can't gain new successors there.
+	for i := len(n.next) - 1; i > 0; i-- {
+		for {
+			ref := n.next[i].Load()
+			if ref.marked || n.next[i].CompareAndSwap(ref, &markRef{node: ref.node, marked: true}) {
+				break
+			}
+		}
+	}
+
+	// Only the claimer marks level 0, after which find() unlinks n.
+	for {
+		ref := n.next[0].Load()
+		if n.next[0].CompareAndSwap(ref, &markRef{node: ref.node, marked: true}) {
+			break
+		}
+	}
+	atomic.AddInt64(&s.size, -1)
+	s.find(n.K, preds, succs)
+	return old.v
+}
+
+// Fills preds and succs with, on each level, the last node with a key less
+// than the given key, and the node following it. Unlinks any marked nodes
+// met on the way. Returns true if succs[0] has the given key.
+func (s *ConcurrentSkipListMap) find(k collection.Comparer, preds *[maxLevel]*cnode, succs *[maxLevel]*cnode) bool {
+retry:
+	for {
+		pred := s.head
+		var curr *cnode
+		for i := maxLevel - 1; i >= 0; i-- {
+			curr = pred.next[i].Load().node
+			for curr != nil {
+				ref := curr.next[i].Load()
+				for ref.marked {
+					if !casRef(&pred.next[i], curr, false, ref.node) {
+						continue retry
+					}
+					curr = ref.node
+					if curr == nil {
+						break
+					}
+					ref = curr.next[i].Load()
+				}
+				if curr == nil || k.Compare(curr.K) <= 0 {
+					break
+				}
+				pred = curr
+				curr = ref.node
+			}
+			preds[i] = pred
+			succs[i] = curr
+		}
+		return curr != nil && k.Compare(curr.K) == 0
+	}
+}
+
+// Returns the node with the given key, unless it has been removed, or nil.
+// Never writes, and so never retries.
+func (s *ConcurrentSkipListMap) search(k collection.Comparer) *cnode {
+	pred := s.head
+	var curr *cnode
+	for i := maxLevel - 1; i >= 0; i-- {
+		curr = pred.next[i].Load().node
+		for curr != nil {
+			ref := curr.next[i].Load()
+			if ref.marked {
+				curr = ref.node
+				continue
+			}
+			if k.Compare(curr.K) <= 0 {
+				break
+			}
+			pred = curr
+			curr = ref.node
+		}
+	}
+
+	if curr != nil && k.Compare(curr.K) == 0 && curr.V.Load() != removedValue {
+		return curr
+	}
+	return nil
+}
+
+// Returns a random level for a new node: 1, plus one more with probability p,
+// and so on, up to maxLevel.
+func (s *ConcurrentSkipListMap) randomLevel() int {
+	level := 1
+	for level < maxLevel && rand.Float64() < s.p {
+		level += 1
+	}
+	return level
+}
+
+// Atomically replaces the reference at the given pointer with an unmarked
+// reference to next, if it is still to expected, with the expected mark.
+func casRef(p *refPointer, expected *cnode, marked bool, next *cnode) bool {
+	ref := p.Load()
+	if ref.node != expected || ref.marked != marked {
+		return false
+	}
+	return p.CompareAndSwap(ref, &markRef{node: next})
+}
@@ -0,0 +1,228 @@
+// This module contains tests for concurrentskiplist.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Run with -race to check for data races.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+func TestNewEmptyConcurrentSkipListMap(t *testing.T) {
+	s := NewConcurrentSkipListMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertTrue(t, s.Threadsafe(), "Dictionary is not thread-safe.")
+	test.AssertTrue(t, s.First() == nil, "Empty dictionary has a first entry.")
+}
+
+func TestLockPanicsConcurrentSkipListMap(t *testing.T) {
+	s := NewConcurrentSkipListMap()
+
+	defer func() {
+		test.AssertNonNil(t, recover(), "Lock() did not panic.")
+	}()
+	s.Lock()
+}
+
+func TestSequentialConcurrentSkipListMap(t *testing.T) {
+	s := NewConcurrentSkipListMap()
+
+	for _, k := range []int{5, 1, 3, 2, 4} {
+		test.AssertNil(t, s.Insert(compInt{k}, k), "")
+	}
+	test.AssertEqual(t, s.Insert(compInt{3}, 30), 3, "Wrong previous value.")
+
+	test.AssertEqual(t, s.Size(), 5, "Wrong size after inserting.")
+	test.AssertEqual(t, s.Locate(compInt{3}), 30, "Wrong value.")
+	test.AssertEqual(t, s.First().Key, compInt{1}, "Wrong first key.")
+
+	test.AssertEqual(t, s.Remove(compInt{1}), 1, "Wrong removed value.")
+	test.AssertNil(t, s.Remove(compInt{1}), "Removed a missing key.")
+	test.AssertFalse(t, s.Contains(compInt{1}), "Removed element present.")
+	test.AssertTrue(t, s.Contains(compInt{2}, compInt{5}), "Remaining elements missing.")
+
+	keys := []int{2, 3, 4, 5}
+	for i, kv := range *s.Slice() {
+		test.AssertEqual(t, kv.(*KeyValue).Key.(compInt).i, keys[i], "Items returned out of order!")
+	}
+
+	c := s.Copy()
+	s.Clear()
+	test.AssertTrue(t, s.Empty(), "Dictionary not empty after clearing.")
+	test.AssertEqual(t, s.Size(), 0, "Wrong size after clearing.")
+	test.AssertEqual(t, c.Size(), 4, "Copy cleared with original.")
+}
+
+func TestConcurrentInsertConcurrentSkipListMap(t *testing.T) {
+	s := NewConcurrentSkipListMap()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < 8000; i += 8 {
+				s.Insert(compInt{i}, i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	test.AssertEqual(t, s.Size(), 8000, "Wrong size after concurrent inserts.")
+	prev := -1
+	s.Map(func(kv interface{}) bool {
+		k := kv.(*KeyValue).Key.(compInt).i
+		test.AssertEqual(t, k, prev+1, "Items out of order or missing.")
+		prev = k
+		return true
+	})
+}
+
+func TestConcurrentMixedConcurrentSkipListMap(t *testing.T) {
+	s := NewConcurrentSkipListMap()
+
+	// Each goroutine owns the keys congruent to it, so the final contents
+	// are known, while the goroutines still contend over shared nodes.
+	const goroutines = 8
+	expected := make([]map[int]int, goroutines)
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		expected[g] = make(map[int]int)
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			r := rand.New(rand.NewSource(int64(g)))
+			for i := 0; i < 5000; i++ {
+				k := r.Intn(200)*goroutines + g
+				if r.Intn(2) == 0 {
+					s.Insert(compInt{k}, i)
+					expected[g][k] = i
+				} else {
+					s.Remove(compInt{k})
+					delete(expected[g], k)
+				}
+				s.Locate(compInt{r.Intn(200 * goroutines)})
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	total := 0
+	for _, kvs := range expected {
+		total += len(kvs)
+		for k, v := range kvs {
+			test.AssertEqual(t, s.Locate(compInt{k}), v, "Retrieved wrong value.")
+		}
+	}
+	test.AssertEqual(t, s.Size(), total, "Wrong size after concurrent writes.")
+	test.AssertEqual(t, len(*s.Slice()), total, "Wrong slice length after concurrent writes.")
+}
+
+func BenchmarkParallelConcurrentSkipListMap(b *testing.B) {
+	s := NewConcurrentSkipListMap()
+
+	b.RunParallel(func(pb *testing.PB) {
+		r := rand.New(rand.NewSource(rand.Int63()))
+		for pb.Next() {
+			k := compInt{r.Intn(100000)}
+			if r.Intn(4) == 0 {
+				s.Insert(k, k)
+			} else {
+				s.Locate(k)
+			}
+		}
+	})
+}
+
+func TestRacingInsertRemoveConcurrentSkipListMap(t *testing.T) {
+	s := NewConcurrentSkipListMap()
+	const goroutines, rounds = 4, 20000
+	key := compInt{0}
+
+	// Every value Insert()ed must come back exactly once: from the Insert()
+	// that replaced it, from the Remove() that took it, or as the final value.
+	returned := make([][]interface{}, 2*goroutines)
+	var wg sync.WaitGroup
+	wg.Add(2 * goroutines)
+	for g := 0; g < goroutines; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				returned[g] = append(returned[g], s.Insert(key, g*rounds+i))
+			}
+		}(g)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < rounds; i++ {
+				returned[goroutines+g] = append(returned[goroutines+g], s.Remove(key))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	seen := make(map[interface{}]int)
+	for _, vs := range returned {
+		for _, v := range vs {
+			if v != nil {
+				seen[v]++
+			}
+		}
+	}
+	if v := s.Locate(key); v != nil {
+		seen[v]++
+		test.AssertEqual(t, s.Size(), 1, "Size doesn't match the entries.")
+	} else {
+		test.AssertEqual(t, s.Size(), 0, "Size doesn't match the entries.")
+	}
+
+	test.AssertEqual(t, len(seen), goroutines*rounds, "Inserted values were lost.")
+	for v, n := range seen {
+		if n != 1 {
+			t.Fatalf("Value %v came back %d times.", v, n)
+		}
+	}
+}
+
+func TestClearWhileWritingConcurrentSkipListMap(t *testing.T) {
+	s := NewConcurrentSkipListMap()
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for g := 0; g < 4; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 5000; i++ {
+				s.Insert(compInt{g*5000 + i}, i)
+				if i%3 == 0 {
+					s.Remove(compInt{g*5000 + i/2})
+				}
+			}
+		}(g)
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	for cleared := false; !cleared; {
+		select {
+		case <-done:
+			cleared = true
+		default:
+			s.Clear()
+		}
+	}
+
+	test.AssertEqual(t, s.Size(), len(*s.Slice()), "Size drifted from the entries.")
+	s.Clear()
+	test.AssertEqual(t, s.Size(), 0, "Clear left entries.")
+	test.AssertTrue(t, s.Empty(), "Clear left entries.")
+}
@@ -62,6 +62,50 @@ type Dictionary interface {
 	Copy() Dictionary
 }
 
+// Defines the interface for Navigable Dictionaries. Navigable Dictionaries
+// store their KeyValues in sorted order, as defined by the Key's Compare()
+// method (Keys should implement collection.Comparer). Map() and Slice()
+// visit them in ascending order.
+//
+type Navigable interface {
+	Dictionary
+
+	// Returns a pointer to the KeyValue with the smallest key, or nil if
+	// this Dictionary is empty.
+	//
+	// Panics if this Dictionary has not been initialized.
+	First() *KeyValue
+
+	// Returns a pointer to the KeyValue with the largest key, or nil if
+	// this Dictionary is empty.
+	//
+	// Panics if this Dictionary has not been initialized.
+	Last() *KeyValue
+
+	// Returns a pointer to the KeyValue with the largest key less than or
+	// equal to the given key, or nil if there is none.
+	//
+	// Panics if the given key is nil or doesn't implement collection.Comparer.
+	// Panics if this Dictionary has not been initialized.
+	Floor(key interface{}) *KeyValue
+
+	// Returns a pointer to the KeyValue with the smallest key greater than
+	// or equal to the given key, or nil if there is none.
+	//
+	// Panics if the given key is nil or doesn't implement collection.Comparer.
+	// Panics if this Dictionary has not been initialized.
+	Ceiling(key interface{}) *KeyValue
+
+	// Attempts to apply the given function to a pointer to the KeyValue of
+	// every key from from, inclusive, to to, exclusive, in ascending order.
+	// Stops once all those KeyValues have been processed, or once the
+	// function returns false, whichever occurs first.
+	//
+	// Panics if either key is nil or doesn't implement collection.Comparer.
+	// Panics if this Dictionary has not been initialized.
+	Range(from interface{}, to interface{}, f func(item interface{}) bool) bool
+}
+
 // ****************************************************************************
 //
 //  Convenience functions to be used as so: dictionary = function(dictionary).
@@ -1,6 +1,9 @@
 package dictionary
 
 import (
+	"fmt"
+	"math/rand"
+	"sort"
 	"testing"
 )
 
@@ -10,4 +13,219 @@ func TestDictionary(t *testing.T) {
 
 	tm := NewTreeMap()
 	var _ Dictionary = tm
+
+	ttl := NewTTLMap()
+	var _ Dictionary = ttl
+
+	lfu := NewLFU(1)
+	var _ Dictionary = lfu
+
+	arc := NewARC(1)
+	var _ Dictionary = arc
+
+	lhm := NewLinkedHashMap()
+	var _ Dictionary = lhm
+
+	bm := NewBiMap()
+	var _ Dictionary = bm
+
+	sl := NewSkipListMap()
+	var _ Dictionary = sl
+
+	csl := NewConcurrentSkipListMap()
+	var _ Dictionary = csl
+
+	xft := NewXFastTrie()
+	var _ Dictionary = xft
+
+	yft := NewYFastTrie()
+	var _ Dictionary = yft
+
+	rbt := NewRedBlackTreeMap()
+	var _ Navigable = rbt
+
+	bt := NewBTreeMap()
+	var _ Navigable = bt
+
+	var _ Navigable = tm
+	var _ Navigable = sl
+}
+
+// The Navigable Dictionaries that the shared tests and benchmarks below run
+// against, by name.
+var navigables = []struct {
+	name string
+	make func() Navigable
+}{
+	{"TreeMap", func() Navigable { return NewTreeMapUnsafe() }},
+	{"RedBlackTreeMap", func() Navigable { return NewRedBlackTreeMapUnsafe() }},
+	{"BTreeMap", func() Navigable { return NewBTreeMapUnsafe() }},
+	{"BTreeMapDegree2", func() Navigable { return NewBTreeMapWithDegreeUnsafe(2) }},
+	{"SkipListMap", func() Navigable { return NewSkipListMapUnsafe() }},
+}
+
+// Returns the keys of the given KeyValue, or "<nil>" if it is nil.
+func keyOf(kv *KeyValue) string {
+	if kv == nil {
+		return "<nil>"
+	}
+	return fmt.Sprint(kv.Key.(compInt).i)
+}
+
+func TestNavigable(t *testing.T) {
+	for _, nav := range navigables {
+		t.Run(nav.name, func(t *testing.T) {
+			r := rand.New(rand.NewSource(46))
+			s := nav.make()
+			kvs := make(map[int]int)
+
+			for i := 0; i < 5000; i++ {
+				k := r.Intn(1000)
+				if r.Intn(3) == 0 {
+					s.Remove(compInt{k})
+					delete(kvs, k)
+				} else {
+					s.Insert(compInt{k}, i)
+					kvs[k] = i
+				}
+			}
+
+			keys := make([]int, 0, len(kvs))
+			for k := range kvs {
+				keys = append(keys, k)
+			}
+			sort.Ints(keys)
+
+			if s.Size() != len(keys) || keyOf(s.First()) != fmt.Sprint(keys[0]) ||
+				keyOf(s.Last()) != fmt.Sprint(keys[len(keys)-1]) {
+				t.Fatalf("Wrong size or ends: %d %s %s", s.Size(), keyOf(s.First()), keyOf(s.Last()))
+			}
+
+			i := 0
+			s.Map(func(kv interface{}) bool {
+				kvc := kv.(*KeyValue)
+				if kvc.Key.(compInt).i != keys[i] || kvc.Value != kvs[keys[i]] {
+					t.Fatalf("Map visited %v at position %d.", kvc, i)
+				}
+				i++
+				return true
+			})
+
+			for k := -1; k <= 1001; k++ {
+				j := sort.SearchInts(keys, k)
+				floor, ceiling := "<nil>", "<nil>"
+				if j < len(keys) {
+					ceiling = fmt.Sprint(keys[j])
+				}
+				if j < len(keys) && keys[j] == k {
+					floor = fmt.Sprint(k)
+				} else if j > 0 {
+					floor = fmt.Sprint(keys[j-1])
+				}
+				if keyOf(s.Floor(compInt{k})) != floor || keyOf(s.Ceiling(compInt{k})) != ceiling {
+					t.Fatalf("Wrong neighbors of %d: %s %s", k, keyOf(s.Floor(compInt{k})), keyOf(s.Ceiling(compInt{k})))
+				}
+			}
+
+			for n := 0; n < 200; n++ {
+				from, to := r.Intn(1100)-50, r.Intn(1100)-50
+				var expected, got []int
+				for _, k := range keys {
+					if k >= from && k < to {
+						expected = append(expected, k)
+					}
+				}
+				s.Range(compInt{from}, compInt{to}, func(kv interface{}) bool {
+					got = append(got, kv.(*KeyValue).Key.(compInt).i)
+					return true
+				})
+				if fmt.Sprint(got) != fmt.Sprint(expected) {
+					t.Fatalf("Range(%d, %d) visited %v, expected %v", from, to, got, expected)
+				}
+			}
+
+			visited := 0
+			if s.Range(compInt{0}, compInt{1000}, func(kv interface{}) bool {
+				visited++
+				return visited < 3
+			}) || visited != 3 {
+				t.Error("Range does not stop when the function returns false.")
+			}
+		})
+	}
+}
+
+// The number of keys in each Navigable benchmarked below.
+const navigableKeys = 1 << 16
+
+// Returns a Navigable from the given constructor holding navigableKeys random
+// keys, and the keys.
+func filledNavigable(newNavigable func() Navigable) (Navigable, []compInt) {
+	r := rand.New(rand.NewSource(46))
+	s := newNavigable()
+	keys := make([]compInt, navigableKeys)
+	for i := range keys {
+		keys[i] = compInt{r.Int()}
+		s.Insert(keys[i], i)
+	}
+	return s, keys
+}
+
+func BenchmarkNavigableInsert(b *testing.B) {
+	for _, nav := range navigables {
+		b.Run(nav.name, func(b *testing.B) {
+			r := rand.New(rand.NewSource(46))
+			s := nav.make()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Insert(compInt{r.Int()}, i)
+			}
+		})
+	}
+}
+
+func BenchmarkNavigableRemove(b *testing.B) {
+	for _, nav := range navigables {
+		b.Run(nav.name, func(b *testing.B) {
+			s, keys := filledNavigable(nav.make)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Put each key back, so the Dictionary never runs dry.
+				k := keys[i%len(keys)]
+				s.Remove(k)
+				b.StopTimer()
+				s.Insert(k, i)
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+func BenchmarkNavigableLocate(b *testing.B) {
+	for _, nav := range navigables {
+		b.Run(nav.name, func(b *testing.B) {
+			s, keys := filledNavigable(nav.make)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				s.Locate(keys[i%len(keys)])
+			}
+		})
+	}
+}
+
+func BenchmarkNavigableRange(b *testing.B) {
+	for _, nav := range navigables {
+		b.Run(nav.name, func(b *testing.B) {
+			s, keys := filledNavigable(nav.make)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				// Scan 100 keys from each starting point.
+				visited := 0
+				s.Range(keys[i%len(keys)], compInt{int(^uint(0) >> 1)}, func(kv interface{}) bool {
+					visited++
+					return visited < 100
+				})
+			}
+		})
+	}
 }
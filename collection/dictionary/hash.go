@@ -0,0 +1,138 @@
+// This module provides the key hashing for the PersistentHashMap.
+
+package dictionary
+
+import (
+	"log"
+	"math"
+	"reflect"
+)
+
+// FNV-1a constants.
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// Returns a 64 bit hash of the given key, such that keys equal under == have
+// equal hashes, as they must for a Go map. Like a HashMap, treats int(1) and
+// "1" as different keys.
+//
+// Common kinds of keys are hashed by value. Anything else is hashed with
+// reflect, the way == compares it: pointers, channels and unsafe.Pointers by
+// address, not by what they point to, and structs and arrays field by field.
+//
+// Panics if the given key is nil, or of a kind that can't be a map key.
+func hashKey(key interface{}) uint64 {
+	switch k := key.(type) {
+	case nil:
+		log.Panic("Nil key.")
+		return 0
+	case string:
+		return mix64(fnv(fnvOffset, k) ^ 1)
+	case bool:
+		if k {
+			return mix64(3)
+		}
+		return mix64(2)
+	case int:
+		return mix64(uint64(k)) ^ 4
+	case int8:
+		return mix64(uint64(k)) ^ 5
+	case int16:
+		return mix64(uint64(k)) ^ 6
+	case int32:
+		return mix64(uint64(k)) ^ 7
+	case int64:
+		return mix64(uint64(k)) ^ 8
+	case uint:
+		return mix64(uint64(k)) ^ 9
+	case uint8:
+		return mix64(uint64(k)) ^ 10
+	case uint16:
+		return mix64(uint64(k)) ^ 11
+	case uint32:
+		return mix64(uint64(k)) ^ 12
+	case uint64:
+		return mix64(k) ^ 13
+	case float32:
+		return mix64(floatBits(float64(k))) ^ 14
+	case float64:
+		return mix64(floatBits(k)) ^ 15
+	default:
+		return mix64(hashValue(reflect.ValueOf(k))) ^ 16
+	}
+}
+
+// Returns a hash of the given value, such that values equal under == have
+// equal hashes.
+func hashValue(v reflect.Value) uint64 {
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return 1
+		}
+		return 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return uint64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return floatBits(v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		c := v.Complex()
+		return mix64(floatBits(real(c))) ^ floatBits(imag(c))
+	case reflect.String:
+		return fnv(fnvOffset, v.String())
+	case reflect.Ptr, reflect.Chan, reflect.UnsafePointer:
+		return uint64(v.Pointer())
+	case reflect.Interface:
+		if v.IsNil() {
+			return 0
+		}
+		return hashValue(v.Elem())
+	case reflect.Array:
+		h := uint64(fnvOffset)
+		for i := 0; i < v.Len(); i++ {
+			h = mix64(h^hashValue(v.Index(i))) * fnvPrime
+		}
+		return h
+	case reflect.Struct:
+		h := uint64(fnvOffset)
+		for i := 0; i < v.NumField(); i++ {
+			// == ignores blank fields.
+			if v.Type().Field(i).Name != "_" {
+				h = mix64(h^hashValue(v.Field(i))) * fnvPrime
+			}
+		}
+		return h
+	default:
+		log.Panicf("Unhashable key type %v.", v.Type())
+		return 0
+	}
+}
+
+// Returns the bits of the given float, with -0 folded into 0 since they are
+// equal under ==.
+func floatBits(f float64) uint64 {
+	if f == 0 {
+		return 0
+	}
+	return math.Float64bits(f)
+}
+
+// Returns the FNV-1a hash of the given string, starting from h.
+func fnv(h uint64, s string) uint64 {
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= fnvPrime
+	}
+	return h
+}
+
+// The SplitMix64 finalizer.
+func mix64(h uint64) uint64 {
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	return h ^ (h >> 31)
+}
@@ -0,0 +1,507 @@
+// This module implements an IntervalTree, a Dictionary keyed by Intervals
+// that finds the Intervals overlapping or enclosing a point or Interval.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"github.com/michalpiszczek/nonstdlib/util/math"
+	"log"
+)
+
+// An Interval is the closed range of points from Start to End, inclusive.
+//
+// Start and End must be ordered by the IntervalTree holding the Interval:
+// by default, they should implement collection.Comparer.
+//
+type Interval struct {
+	Start interface{}
+	End   interface{}
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// inode definition, and associated helper functions.
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// The node struct for the IntervalTree's AVL tree. Max is the largest End
+// of any Interval in the subtree rooted here.
+type inode struct {
+	I   Interval
+	V   interface{}
+	H   int
+	Max interface{}
+	C   [2]*inode
+}
+
+// Returns the height of the given node, or -1 if it is nil.
+func iheight(n *inode) int {
+	if n == nil {
+		return -1
+	}
+	return n.H
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// end node stuff
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// An IntervalTree implements Dictionary, mapping Intervals to values. It is
+// an AVL tree ordered by the Intervals' Start, then End, in which each node
+// also tracks the largest End below it, so that searches can skip subtrees
+// holding no Interval that ends late enough. Finding the k Intervals
+// overlapping a point or Interval takes O(min(n, k log n)) time; finding those
+// enclosing one can take O(n) time, even when none do.
+//
+// Two Intervals are the same key if they have equal Starts and Ends.
+//
+// Behavior unspecified if an IntervalTree is not created using
+// NewIntervalTree(), NewIntervalTreeUnsafe(), one of their WithComparator
+// variants, or if IntervalTree.Init() / IntervalTree.InitUnsafe(), is not
+// first called on a new &IntervalTree{}.
+//
+type IntervalTree struct {
+	collection.Base
+	root *inode
+	cmp  func(a interface{}, b interface{}) int
+}
+
+// Returns a pointer to a new IntervalTree, ordering points by their
+// Compare() method.
+func NewIntervalTree() *IntervalTree {
+	s := &IntervalTree{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe IntervalTree, ordering points by their
+// Compare() method.
+func NewIntervalTreeUnsafe() *IntervalTree {
+	s := &IntervalTree{}
+	s.InitUnsafe()
+	return s
+}
+
+// Returns a pointer to a new IntervalTree, ordering points by the given
+// function, which returns -1 if a < b, 0 if they are equal, and 1 otherwise.
+func NewIntervalTreeWithComparator(cmp func(a interface{}, b interface{}) int) *IntervalTree {
+	s := &IntervalTree{cmp: cmp}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe IntervalTree, ordering points by the
+// given function, which returns -1 if a < b, 0 if they are equal, and 1
+// otherwise.
+func NewIntervalTreeWithComparatorUnsafe(cmp func(a interface{}, b interface{}) int) *IntervalTree {
+	s := &IntervalTree{cmp: cmp}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *IntervalTree) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *IntervalTree) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Associates the given value with the given Interval. Returns the previous
+// value associated with it, or nil, if none existed.
+//
+// Panics if the given key is not an Interval, or its Start is after its End.
+func (s *IntervalTree) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	i := s.interval(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	var old interface{}
+	var added bool
+	s.root, old, added = s.insert(s.root, i, value)
+	if added {
+		s.Sizeb += 1
+	}
+	return old
+}
+
+// Returns the value associated with exactly the given Interval, or nil, if
+// none is.
+//
+// Panics if the given key is not an Interval, or its Start is after its End.
+func (s *IntervalTree) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	i := s.interval(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	n := s.root
+	for n != nil {
+		c := s.compare(i, n.I)
+		if c == 0 {
+			return n.V
+		}
+		n = n.C[math.Signum(c+1)]
+	}
+	return nil
+}
+
+// Removes and returns the value associated with exactly the given Interval,
+// or nil, if none is.
+//
+// Panics if the given key is not an Interval, or its Start is after its End.
+func (s *IntervalTree) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	i := s.interval(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	var removed *inode
+	s.root, removed = s.remove(s.root, i)
+	if removed == nil {
+		return nil
+	}
+
+	s.Sizeb -= 1
+	return removed.V
+}
+
+func (s *IntervalTree) Contains(keys ...interface{}) bool {
+	if len(keys) == 0 {
+		return false
+	}
+
+	for _, k := range keys {
+		if s.Locate(k) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns pointers to the KeyValues of every Interval containing the given
+// point, in ascending order.
+func (s *IntervalTree) OverlappingPoint(point interface{}) []*KeyValue {
+	return s.Overlapping(Interval{point, point})
+}
+
+// Returns pointers to the KeyValues of every Interval sharing at least one
+// point with the given Interval, in ascending order. Takes O(min(n, k log n))
+// time, for k results.
+//
+// Panics if the given Interval's Start is after its End.
+func (s *IntervalTree) Overlapping(i Interval) []*KeyValue {
+	s.CheckInit()
+	s.interval(i)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	// Overlapping Intervals start no later than i ends, and end no earlier
+	// than i starts.
+	var kvs []*KeyValue
+	s.search(s.root, i.End, i.Start, func(n *inode) {
+		if s.cmp(n.I.End, i.Start) >= 0 {
+			kvs = append(kvs, &KeyValue{n.I, n.V})
+		}
+	})
+	return kvs
+}
+
+// Returns pointers to the KeyValues of every Interval containing all of the
+// given Interval, in ascending order. Takes O(n) time in the worst case, even
+// with no results, as a subtree is only skipped if nothing in it ends late
+// enough, not if nothing in it encloses the given Interval.
+//
+// Panics if the given Interval's Start is after its End.
+func (s *IntervalTree) Enclosing(i Interval) []*KeyValue {
+	s.CheckInit()
+	s.interval(i)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	// Enclosing Intervals start no later than i starts, and end no earlier
+	// than i ends.
+	var kvs []*KeyValue
+	s.search(s.root, i.Start, i.End, func(n *inode) {
+		if s.cmp(n.I.End, i.End) >= 0 {
+			kvs = append(kvs, &KeyValue{n.I, n.V})
+		}
+	})
+	return kvs
+}
+
+func (s *IntervalTree) Copy() Dictionary {
+	s.CheckInit()
+
+	c := &IntervalTree{cmp: s.cmp}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.root = copyInodes(s.root)
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to a pointer to a KeyValue for every
+// Interval in this IntervalTree, in ascending order. Stops once all Intervals
+// have been processed, or once the function returns false, whichever occurs
+// first.
+func (s *IntervalTree) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return mapInodes(s.root, f)
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of
+// their Intervals.
+func (s *IntervalTree) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(kv interface{}) bool {
+		slice = append(slice, kv)
+		return true
+	})
+	return &slice
+}
+
+func (s *IntervalTree) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.root = nil
+	s.Sizeb = 0
+}
+
+func (s *IntervalTree) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *IntervalTree) setup() {
+	if s.cmp == nil {
+		s.cmp = compareComparers
+	}
+}
+
+// Compares two points by their Compare() method.
+//
+// Panics if a doesn't implement collection.Comparer.
+func compareComparers(a interface{}, b interface{}) int {
+	ac, ok := a.(collection.Comparer)
+	if !ok {
+		log.Panic("Point doesn't implement collection.Comparer.")
+	}
+	return ac.Compare(b)
+}
+
+// Returns the given key as an Interval.
+//
+// Panics if the given key is not an Interval, either of its points is nil,
+// or its Start is after its End.
+func (s *IntervalTree) interval(key interface{}) Interval {
+	i, ok := key.(Interval)
+	if !ok {
+		log.Panic("Key is not an Interval.")
+	}
+	if i.Start == nil || i.End == nil {
+		log.Panic("Interval has a nil point.")
+	}
+	if s.cmp(i.Start, i.End) > 0 {
+		log.Panic("Interval starts after it ends.")
+	}
+	return i
+}
+
+// Orders Intervals by their Starts, then their Ends.
+func (s *IntervalTree) compare(a Interval, b Interval) int {
+	if c := s.cmp(a.Start, b.Start); c != 0 {
+		return c
+	}
+	return s.cmp(a.End, b.End)
+}
+
+// Calls the given function on every node in the subtree rooted at n that
+// might hold a match: one whose Start is at most maxStart, in a subtree
+// whose Max is at least minEnd. Visits them in order.
+func (s *IntervalTree) search(n *inode, maxStart interface{}, minEnd interface{}, f func(n *inode)) {
+	if n == nil || s.cmp(n.Max, minEnd) < 0 {
+		return
+	}
+
+	s.search(n.C[0], maxStart, minEnd, f)
+	if s.cmp(n.I.Start, maxStart) > 0 {
+		// Everything to the right starts later still.
+		return
+	}
+	f(n)
+	s.search(n.C[1], maxStart, minEnd, f)
+}
+
+// Sets the height and Max of the given node from its children's.
+func (s *IntervalTree) update(n *inode) {
+	n.H = math.Max(iheight(n.C[0]), iheight(n.C[1])) + 1
+	n.Max = n.I.End
+	for _, c := range n.C {
+		if c != nil && s.cmp(c.Max, n.Max) > 0 {
+			n.Max = c.Max
+		}
+	}
+}
+
+// Rotates the given node's dir child up into its place, and returns it.
+func (s *IntervalTree) rotate(n *inode, dir int) *inode {
+	c := n.C[dir]
+	n.C[dir] = c.C[1-dir]
+	c.C[1-dir] = n
+	s.update(n)
+	s.update(c)
+	return c
+}
+
+// Updates the given node and, if its children's heights differ by more than
+// 1, rotates it back into balance. Returns the new root of its subtree.
+func (s *IntervalTree) rebalance(n *inode) *inode {
+	s.update(n)
+
+	dir := 0
+	switch b := iheight(n.C[1]) - iheight(n.C[0]); {
+	case b > 1:
+		dir = 1
+	case b < -1:
+		dir = 0
+	default:
+		return n
+	}
+
+	c := n.C[dir]
+	if iheight(c.C[1-dir]) > iheight(c.C[dir]) {
+		n.C[dir] = s.rotate(c, 1-dir)
+	}
+	return s.rotate(n, dir)
+}
+
+// Inserts the given Interval and value into the subtree rooted at n. Returns
+// the new root of the subtree, the value previously associated with the
+// Interval, if any, and true if it was not already present.
+func (s *IntervalTree) insert(n *inode, i Interval, v interface{}) (root *inode, old interface{}, added bool) {
+	if n == nil {
+		n = &inode{I: i, V: v}
+		s.update(n)
+		return n, nil, true
+	}
+
+	c := s.compare(i, n.I)
+	if c == 0 {
+		old = n.V
+		n.V = v
+		return n, old, false
+	}
+
+	dir := math.Signum(c + 1)
+	n.C[dir], old, added = s.insert(n.C[dir], i, v)
+	return s.rebalance(n), old, added
+}
+
+// Removes the leftmost node from the subtree rooted at n. Returns the new
+// root of the subtree, and the removed node.
+func (s *IntervalTree) removeMin(n *inode) (*inode, *inode) {
+	if n.C[0] == nil {
+		return n.C[1], n
+	}
+
+	var min *inode
+	n.C[0], min = s.removeMin(n.C[0])
+	return s.rebalance(n), min
+}
+
+// Removes the given Interval from the subtree rooted at n. Returns the new
+// root of the subtree, and the removed node, or nil if it was not present.
+func (s *IntervalTree) remove(n *inode, i Interval) (root *inode, removed *inode) {
+	if n == nil {
+		return nil, nil
+	}
+
+	c := s.compare(i, n.I)
+	if c != 0 {
+		dir := math.Signum(c + 1)
+		n.C[dir], removed = s.remove(n.C[dir], i)
+		if removed == nil {
+			return n, nil
+		}
+		return s.rebalance(n), removed
+	}
+
+	if n.C[0] == nil {
+		return n.C[1], n
+	}
+	if n.C[1] == nil {
+		return n.C[0], n
+	}
+
+	// Replace n with its in-order successor.
+	right, succ := s.removeMin(n.C[1])
+	succ.C[0], succ.C[1] = n.C[0], right
+	return s.rebalance(succ), n
+}
+
+// Returns a copy of the subtree rooted at the given node.
+func copyInodes(n *inode) *inode {
+	if n == nil {
+		return nil
+	}
+
+	c := *n
+	c.C[0], c.C[1] = copyInodes(n.C[0]), copyInodes(n.C[1])
+	return &c
+}
+
+// Applies the given function to the KeyValue of every node in the subtree
+// rooted at n, in order. Stops once the function returns false, and returns
+// false if it did.
+func mapInodes(n *inode, f func(item interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	return mapInodes(n.C[0], f) && f(&KeyValue{n.I, n.V}) && mapInodes(n.C[1], f)
+}
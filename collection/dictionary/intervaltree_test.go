@@ -0,0 +1,171 @@
+// This module contains tests for intervaltree.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"fmt"
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"testing"
+)
+
+// Orders plain ints, for the comparator variants.
+func compareInts(a interface{}, b interface{}) int {
+	return compInt{a.(int)}.Compare(compInt{b.(int)})
+}
+
+// Checks that the subtree rooted at n is ordered, balanced, and has the right
+// heights and Maxes. Returns its size.
+func checkIntervals(t *testing.T, s *IntervalTree, n *inode) int {
+	if n == nil {
+		return 0
+	}
+	for dir, c := range n.C {
+		if c != nil && s.compare(c.I, n.I) != dir*2-1 {
+			t.Fatalf("Interval %v is on the wrong side of %v.", c.I, n.I)
+		}
+	}
+
+	size := checkIntervals(t, s, n.C[0]) + checkIntervals(t, s, n.C[1]) + 1
+	h, max := n.H, n.Max
+	s.update(n)
+	if n.H != h || s.cmp(n.Max, max) != 0 {
+		t.Fatalf("Interval %v has a stale height or Max.", n.I)
+	}
+	if b := iheight(n.C[1]) - iheight(n.C[0]); b > 1 || b < -1 {
+		t.Fatalf("Interval %v is unbalanced.", n.I)
+	}
+	return size
+}
+
+// Returns the Intervals of the given KeyValues, formatted for comparison.
+func intervalsOf(kvs []*KeyValue) string {
+	is := make([]Interval, 0, len(kvs))
+	for _, kv := range kvs {
+		is = append(is, kv.Key.(Interval))
+	}
+	return fmt.Sprint(is)
+}
+
+func TestNewEmptyIntervalTree(t *testing.T) {
+	s := NewIntervalTree()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertNil(t, s.Locate(Interval{compInt{1}, compInt{2}}), "Empty dictionary has an entry.")
+	test.AssertEqual(t, len(s.OverlappingPoint(compInt{1})), 0, "Empty dictionary has an overlap.")
+}
+
+func TestInsertRemoveIntervalTree(t *testing.T) {
+	s := NewIntervalTreeWithComparator(compareInts)
+
+	test.AssertNil(t, s.Insert(Interval{1, 5}, "a"), "New Interval had a value.")
+	test.AssertNil(t, s.Insert(Interval{1, 3}, "b"), "New Interval had a value.")
+	test.AssertEqual(t, s.Insert(Interval{1, 5}, "c"), "a", "Replaced the wrong value.")
+	test.AssertEqual(t, s.Size(), 2, "Replacing a value changed the size.")
+	test.AssertEqual(t, s.Locate(Interval{1, 5}), "c", "Located the wrong value.")
+	test.AssertNil(t, s.Locate(Interval{1, 4}), "Located a missing Interval.")
+	test.AssertTrue(t, s.Contains(Interval{1, 3}, Interval{1, 5}), "Missing an inserted Interval.")
+
+	test.AssertNil(t, s.Remove(Interval{2, 5}), "Removed a missing Interval.")
+	test.AssertEqual(t, s.Remove(Interval{1, 3}), "b", "Removed the wrong value.")
+	test.AssertEqual(t, s.Size(), 1, "Removing didn't shrink the dictionary.")
+	test.AssertEqual(t, intervalsOf(s.OverlappingPoint(2)), fmt.Sprint([]Interval{{1, 5}}), "Removed Interval still overlaps.")
+}
+
+func TestQueriesIntervalTree(t *testing.T) {
+	s := NewIntervalTreeWithComparatorUnsafe(compareInts)
+	for _, i := range []Interval{{0, 2}, {1, 9}, {3, 4}, {3, 7}, {5, 5}, {8, 10}} {
+		s.Insert(i, nil)
+	}
+
+	test.AssertEqual(t, intervalsOf(s.OverlappingPoint(4)),
+		fmt.Sprint([]Interval{{1, 9}, {3, 4}, {3, 7}}), "Wrong Intervals contain a point.")
+	test.AssertEqual(t, intervalsOf(s.OverlappingPoint(5)),
+		fmt.Sprint([]Interval{{1, 9}, {3, 7}, {5, 5}}), "Endpoints aren't inclusive.")
+	test.AssertEqual(t, intervalsOf(s.Overlapping(Interval{5, 8})),
+		fmt.Sprint([]Interval{{1, 9}, {3, 7}, {5, 5}, {8, 10}}), "Wrong Intervals overlap.")
+	test.AssertEqual(t, len(s.Overlapping(Interval{11, 12})), 0, "Intervals overlap past the end.")
+	test.AssertEqual(t, intervalsOf(s.Enclosing(Interval{3, 7})),
+		fmt.Sprint([]Interval{{1, 9}, {3, 7}}), "Wrong Intervals enclose.")
+	test.AssertEqual(t, len(s.Enclosing(Interval{0, 10})), 0, "Intervals enclose a wider one.")
+}
+
+func TestBadIntervalsPanicIntervalTree(t *testing.T) {
+	s := NewIntervalTreeWithComparator(compareInts)
+	for _, key := range []interface{}{Interval{2, 1}, Interval{nil, 1}, 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Inserting %v did not panic.", key)
+				}
+			}()
+			s.Insert(key, nil)
+		}()
+	}
+}
+
+func TestCopyIntervalTree(t *testing.T) {
+	s := NewIntervalTree()
+	s.Insert(Interval{compInt{1}, compInt{4}}, 1)
+	s.Insert(Interval{compInt{2}, compInt{3}}, 2)
+
+	c := s.Copy().(*IntervalTree)
+	c.Remove(Interval{compInt{1}, compInt{4}})
+	c.Insert(Interval{compInt{2}, compInt{3}}, 3)
+
+	test.AssertEqual(t, s.Size(), 2, "Changing a copy changed the original.")
+	test.AssertEqual(t, s.Locate(Interval{compInt{2}, compInt{3}}), 2, "Changing a copy changed the original.")
+	test.AssertEqual(t, len(c.OverlappingPoint(compInt{1})), 0, "Copy kept a removed Interval.")
+	checkIntervals(t, s, s.root)
+	checkIntervals(t, c, c.root)
+}
+
+func TestLargeRandomIntervalTree(t *testing.T) {
+	r := rand.New(rand.NewSource(47))
+	s := NewIntervalTreeWithComparator(compareInts)
+	kvs := make(map[Interval]int)
+
+	for j := 0; j < 4000; j++ {
+		start := r.Intn(1000)
+		i := Interval{start, start + r.Intn(50)}
+		if r.Intn(3) == 0 {
+			s.Remove(i)
+			delete(kvs, i)
+		} else {
+			s.Insert(i, j)
+			kvs[i] = j
+		}
+	}
+	test.AssertEqual(t, checkIntervals(t, s, s.root), len(kvs), "Tree size doesn't match its entries.")
+	test.AssertEqual(t, s.Size(), len(kvs), "Size doesn't match the entries.")
+
+	for j := 0; j < 200; j++ {
+		start := r.Intn(1100)
+		q := Interval{start, start + r.Intn(20)}
+
+		overlapping, enclosing := 0, 0
+		for i := range kvs {
+			if i.Start.(int) <= q.End.(int) && i.End.(int) >= q.Start.(int) {
+				overlapping++
+			}
+			if i.Start.(int) <= q.Start.(int) && i.End.(int) >= q.End.(int) {
+				enclosing++
+			}
+		}
+
+		found := s.Overlapping(q)
+		test.AssertEqual(t, len(found), overlapping, "Found the wrong number of overlaps.")
+		for k, kv := range found {
+			test.AssertEqual(t, kv.Value, kvs[kv.Key.(Interval)], "Overlap has the wrong value.")
+			if k > 0 {
+				test.AssertTrue(t, s.compare(found[k-1].Key.(Interval), kv.Key.(Interval)) < 0, "Overlaps are out of order.")
+			}
+		}
+		test.AssertEqual(t, len(s.Enclosing(q)), enclosing, "Found the wrong number of enclosures.")
+	}
+}
@@ -0,0 +1,328 @@
+// This module implements an LFU, conforming to the Cache interface, which
+// evicts the least frequently used entry once full.
+
+package dictionary
+
+import (
+	"container/list"
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+)
+
+// An entry in an LFU. elem is its position in the list for its frequency.
+type lfuEntry struct {
+	key   interface{}
+	value interface{}
+	freq  int
+	elem  *list.Element
+}
+
+// An LFU implements Cache. Once full, inserting a new key evicts the entry
+// that has been used least often, breaking ties by evicting the entry among
+// them that was used least recently.
+//
+// Every operation runs in O(1): entries are kept in one list per frequency,
+// ordered by recency, and the lowest frequency with any entries is tracked.
+//
+// Behavior unspecified if an LFU is not created using NewLFU(), NewLFUUnsafe().
+//
+type LFU struct {
+	collection.Base
+	capacity int
+	m        map[interface{}]*lfuEntry
+	freqs    map[int]*list.List
+	minFreq  int
+	stats    CacheStats
+}
+
+// Returns a pointer to a new LFU holding at most capacity entries.
+//
+// Panics if the given capacity is not positive.
+func NewLFU(capacity int) *LFU {
+	s := &LFU{capacity: capacity}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe LFU holding at most capacity entries.
+//
+// Panics if the given capacity is not positive.
+func NewLFUUnsafe(capacity int) *LFU {
+	s := &LFU{capacity: capacity}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *LFU) Init() {
+	if s.capacity <= 0 {
+		log.Panic("Non-positive capacity. Use NewLFU().")
+	}
+	s.InitBase()
+
+	s.m = make(map[interface{}]*lfuEntry)
+	s.freqs = make(map[int]*list.List)
+}
+
+func (s *LFU) InitUnsafe() {
+	if s.capacity <= 0 {
+		log.Panic("Non-positive capacity. Use NewLFUUnsafe().")
+	}
+	s.InitBaseUnsafe()
+
+	s.m = make(map[interface{}]*lfuEntry)
+	s.freqs = make(map[int]*list.List)
+}
+
+func (s *LFU) Capacity() int {
+	s.CheckInit()
+	return s.capacity
+}
+
+func (s *LFU) Stats() CacheStats {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.stats
+}
+
+func (s *LFU) ResetStats() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.stats = CacheStats{}
+}
+
+// Inserting counts as a use of the given key. If this LFU is full and does
+// not yet hold the given key, evicts an entry to make room.
+func (s *LFU) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if e, ok := s.m[key]; ok {
+		old := e.value
+		e.value = value
+		s.touch(e)
+		return old
+	}
+
+	if s.Sizeb == s.capacity {
+		s.unlink(s.freqs[s.minFreq].Front().Value.(*lfuEntry))
+		s.stats.Evictions += 1
+	}
+
+	e := &lfuEntry{key: key, value: value, freq: 1}
+	e.elem = s.list(1).PushBack(e)
+	s.m[key] = e
+	s.minFreq = 1
+	s.Sizeb += 1
+	return nil
+}
+
+// Locating counts as a use of the given key, and is recorded in Stats().
+func (s *LFU) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	e, ok := s.m[key]
+	if !ok {
+		s.stats.Misses += 1
+		return nil
+	}
+
+	s.stats.Hits += 1
+	s.touch(e)
+	return e.value
+}
+
+// Returns how many times the entry for the given key has been used, or 0
+// if there is no such entry.
+//
+// Panics if the given key is nil.
+func (s *LFU) Frequency(key interface{}) int {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if e, ok := s.m[key]; ok {
+		return e.freq
+	}
+	return 0
+}
+
+func (s *LFU) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	e, ok := s.m[key]
+	if !ok {
+		return nil
+	}
+
+	s.unlink(e)
+	return e.value
+}
+
+// Does not count as a use of the given keys.
+func (s *LFU) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, key := range keys {
+		if key == nil {
+			log.Panic("Nil key.")
+		}
+		if _, ok := s.m[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// The copy has the same capacity, entries and frequencies, but fresh Stats().
+func (s *LFU) Copy() Dictionary {
+	s.CheckInit()
+
+	var c *LFU
+	if s.Threadsafe() {
+		c = NewLFU(s.capacity)
+	} else {
+		c = NewLFUUnsafe(s.capacity)
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for freq, l := range s.freqs {
+		for el := l.Front(); el != nil; el = el.Next() {
+			e := el.Value.(*lfuEntry)
+			ce := &lfuEntry{key: e.key, value: e.value, freq: freq}
+			ce.elem = c.list(freq).PushBack(ce)
+			c.m[e.key] = ce
+		}
+	}
+	c.minFreq = s.minFreq
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Maps over KeyValues, without counting as a use of any entry.
+func (s *LFU) Map(f func(interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	ok := true
+	for k, e := range s.m {
+		if ok = f(&KeyValue{k, e.value}); !ok {
+			break
+		}
+	}
+	return ok
+}
+
+// Returns a slice of pointers to KeyValue structs.
+func (s *LFU) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, len(s.m))
+	for k, e := range s.m {
+		slice = append(slice, &KeyValue{k, e.value})
+	}
+	return &slice
+}
+
+// Clears all entries, but not Stats().
+func (s *LFU) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.m = make(map[interface{}]*lfuEntry)
+	s.freqs = make(map[int]*list.List)
+	s.minFreq = 0
+	s.Sizeb = 0
+}
+
+func (s *LFU) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Returns the list of entries with the given frequency, creating it if need be.
+func (s *LFU) list(freq int) *list.List {
+	l, ok := s.freqs[freq]
+	if !ok {
+		l = list.New()
+		s.freqs[freq] = l
+	}
+	return l
+}
+
+// Moves the given entry to the list for its next frequency.
+func (s *LFU) touch(e *lfuEntry) {
+	l := s.freqs[e.freq]
+	l.Remove(e.elem)
+	if l.Len() == 0 {
+		delete(s.freqs, e.freq)
+		if s.minFreq == e.freq {
+			s.minFreq += 1
+		}
+	}
+
+	e.freq += 1
+	e.elem = s.list(e.freq).PushBack(e)
+}
+
+// Removes the given entry entirely. minFreq may be left stale, which is
+// fine, as it is only consulted when full, and reset by every new entry.
+func (s *LFU) unlink(e *lfuEntry) {
+	l := s.freqs[e.freq]
+	l.Remove(e.elem)
+	if l.Len() == 0 {
+		delete(s.freqs, e.freq)
+	}
+	delete(s.m, e.key)
+	s.Sizeb -= 1
+}
@@ -0,0 +1,112 @@
+// This module contains tests for lfu.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"testing"
+)
+
+func TestNewEmptyLFU(t *testing.T) {
+	s := NewLFU(3)
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertEqual(t, s.Capacity(), 3, "Wrong capacity.")
+}
+
+func TestInsertLocateLFU(t *testing.T) {
+	s := NewLFU(3)
+
+	test.AssertNil(t, s.Insert("a", 1), "")
+	test.AssertEqual(t, s.Insert("a", 2), 1, "Wrong previous value.")
+	test.AssertEqual(t, s.Locate("a"), 2, "Wrong value.")
+	test.AssertNil(t, s.Locate("b"), "Located a missing key.")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after inserting.")
+	test.AssertEqual(t, s.Frequency("a"), 3, "Wrong frequency.")
+
+	stats := s.Stats()
+	test.AssertEqual(t, stats.Hits, 1, "Wrong hits.")
+	test.AssertEqual(t, stats.Misses, 1, "Wrong misses.")
+}
+
+func TestEvictsLeastFrequentLFU(t *testing.T) {
+	s := NewLFU(3)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+	s.Insert("c", 3)
+	s.Locate("a")
+	s.Locate("a")
+	s.Locate("c")
+
+	s.Insert("d", 4)
+
+	test.AssertFalse(t, s.Contains("b"), "Least frequently used entry not evicted.")
+	test.AssertTrue(t, s.Contains("a", "c", "d"), "Wrong entry evicted.")
+	test.AssertEqual(t, s.Size(), 3, "Wrong size after evicting.")
+	test.AssertEqual(t, s.Stats().Evictions, 1, "Wrong evictions.")
+}
+
+func TestEvictsLeastRecentOnTieLFU(t *testing.T) {
+	s := NewLFU(2)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+	s.Insert("c", 3)
+
+	test.AssertFalse(t, s.Contains("a"), "Least recently used entry not evicted.")
+	test.AssertTrue(t, s.Contains("b", "c"), "Wrong entry evicted.")
+}
+
+func TestRemoveLFU(t *testing.T) {
+	s := NewLFU(2)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+	s.Locate("b")
+
+	test.AssertEqual(t, s.Remove("a"), 1, "Wrong removed value.")
+	test.AssertNil(t, s.Remove("a"), "Removed a missing key.")
+
+	s.Insert("c", 3)
+	s.Insert("d", 4)
+
+	test.AssertTrue(t, s.Contains("b", "d"), "Wrong entry evicted after Remove.")
+	test.AssertEqual(t, s.Stats().Evictions, 1, "Remove counted as an eviction.")
+}
+
+func TestCopyLFU(t *testing.T) {
+	s := NewLFU(2)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+	s.Locate("a")
+
+	c := s.Copy().(*LFU)
+	c.Insert("c", 3)
+
+	test.AssertTrue(t, c.Contains("a", "c"), "Copy lost its frequencies.")
+	test.AssertTrue(t, s.Contains("a", "b"), "Copy shares state with original.")
+	test.AssertEqual(t, c.Stats().Hits, 0, "Copy shares stats with original.")
+}
+
+func TestClearLFU(t *testing.T) {
+	s := NewLFU(2)
+
+	s.Insert("a", 1)
+	s.Insert("b", 2)
+	s.Clear()
+
+	test.AssertEqual(t, s.Size(), 0, "Wrong size after clearing.")
+	test.AssertEqual(t, len(*s.Slice()), 0, "Wrong slice after clearing.")
+
+	s.Insert("c", 3)
+	s.Insert("d", 4)
+	s.Insert("e", 5)
+	test.AssertEqual(t, s.Size(), 2, "Wrong size after refilling.")
+}
@@ -0,0 +1,353 @@
+// This module implements a LinkedHashMap, conforming to the Dictionary
+// interface, with the additional guarantee of iterating over its KeyValues
+// in insertion order, or optionally in access order.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+)
+
+// The node struct for the LinkedHashMap's doubly-linked list.
+type lnode struct {
+	key   interface{}
+	value interface{}
+	prev  *lnode
+	next  *lnode
+}
+
+// A LinkedHashMap implements Dictionary, with the additional guarantee of
+// Map(), Slice() and String() visiting KeyValues in the order their keys
+// were first inserted. Re-inserting a key does not change its position.
+//
+// In access order mode (see SetAccessOrder()), every Insert() or Locate()
+// of a key instead moves it to the back, so the front is always the least
+// recently used entry.
+//
+// Behavior unspecified if a LinkedHashMap is not created using NewLinkedHashMap(),
+// NewLinkedHashMapUnsafe() or if LinkedHashMap.Init() / LinkedHashMap.InitUnsafe(),
+// is not first called on a new &LinkedHashMap{}.
+//
+type LinkedHashMap struct {
+	collection.Base
+	m           map[interface{}]*lnode
+	head        *lnode // Sentinel. head.next is the front, head.prev the back.
+	accessOrder bool
+}
+
+// Returns a pointer to a new LinkedHashMap.
+func NewLinkedHashMap() *LinkedHashMap {
+	s := &LinkedHashMap{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe LinkedHashMap.
+func NewLinkedHashMapUnsafe() *LinkedHashMap {
+	s := &LinkedHashMap{}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *LinkedHashMap) Init() {
+	s.InitBase()
+
+	s.reset()
+}
+
+func (s *LinkedHashMap) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.reset()
+}
+
+// Puts this LinkedHashMap in access order mode if the given bool is true,
+// or back in insertion order mode otherwise. Existing entries keep their
+// current order.
+func (s *LinkedHashMap) SetAccessOrder(accessOrder bool) {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.accessOrder = accessOrder
+}
+
+// Returns true if this LinkedHashMap is in access order mode.
+func (s *LinkedHashMap) AccessOrder() bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.accessOrder
+}
+
+// New keys are inserted at the back.
+func (s *LinkedHashMap) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if n, ok := s.m[key]; ok {
+		old := n.value
+		n.value = value
+		if s.accessOrder {
+			s.unlink(n)
+			s.linkBefore(n, s.head)
+		}
+		return old
+	}
+
+	n := &lnode{key: key, value: value}
+	s.linkBefore(n, s.head)
+	s.m[key] = n
+	s.Sizeb += 1
+	return nil
+}
+
+// In access order mode, moves the given key to the back.
+func (s *LinkedHashMap) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	// May reorder, so takes the write lock.
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	n, ok := s.m[key]
+	if !ok {
+		return nil
+	}
+
+	if s.accessOrder {
+		s.unlink(n)
+		s.linkBefore(n, s.head)
+	}
+	return n.value
+}
+
+func (s *LinkedHashMap) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	n, ok := s.m[key]
+	if !ok {
+		return nil
+	}
+
+	s.unlink(n)
+	delete(s.m, key)
+	s.Sizeb -= 1
+	return n.value
+}
+
+// Does not change the order, even in access order mode.
+func (s *LinkedHashMap) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, key := range keys {
+		if key == nil {
+			log.Panic("Nil key.")
+		}
+		if _, ok := s.m[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Moves the entry for the given key to the front. Returns false if there
+// is no such entry.
+//
+// Panics if the given key is nil.
+func (s *LinkedHashMap) MoveToFront(key interface{}) bool {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	n, ok := s.m[key]
+	if !ok {
+		return false
+	}
+
+	s.unlink(n)
+	s.linkBefore(n, s.head.next)
+	return true
+}
+
+// Moves the entry for the given key to the back. Returns false if there
+// is no such entry.
+//
+// Panics if the given key is nil.
+func (s *LinkedHashMap) MoveToBack(key interface{}) bool {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	n, ok := s.m[key]
+	if !ok {
+		return false
+	}
+
+	s.unlink(n)
+	s.linkBefore(n, s.head)
+	return true
+}
+
+// Returns a pointer to the KeyValue at the front, or nil if this
+// LinkedHashMap is empty.
+func (s *LinkedHashMap) First() *KeyValue {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.head.next == s.head {
+		return nil
+	}
+	return &KeyValue{s.head.next.key, s.head.next.value}
+}
+
+// Returns a pointer to the KeyValue at the back, or nil if this
+// LinkedHashMap is empty.
+func (s *LinkedHashMap) Last() *KeyValue {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.head.prev == s.head {
+		return nil
+	}
+	return &KeyValue{s.head.prev.key, s.head.prev.value}
+}
+
+// The copy has the same order and mode.
+func (s *LinkedHashMap) Copy() Dictionary {
+	s.CheckInit()
+
+	var c *LinkedHashMap
+	if s.Threadsafe() {
+		c = NewLinkedHashMap()
+	} else {
+		c = NewLinkedHashMapUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for n := s.head.next; n != s.head; n = n.next {
+		cn := &lnode{key: n.key, value: n.value}
+		c.linkBefore(cn, c.head)
+		c.m[n.key] = cn
+	}
+	c.accessOrder = s.accessOrder
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Maps over KeyValues, front to back.
+func (s *LinkedHashMap) Map(f func(interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	ok := true
+	for n := s.head.next; n != s.head && ok; n = n.next {
+		ok = f(&KeyValue{n.key, n.value})
+	}
+	return ok
+}
+
+// Returns a slice of pointers to KeyValue structs, front to back.
+func (s *LinkedHashMap) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	for n := s.head.next; n != s.head; n = n.next {
+		slice = append(slice, &KeyValue{n.key, n.value})
+	}
+	return &slice
+}
+
+// Does not change the mode.
+func (s *LinkedHashMap) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.reset()
+}
+
+func (s *LinkedHashMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *LinkedHashMap) reset() {
+	s.m = make(map[interface{}]*lnode)
+	s.head = &lnode{}
+	s.head.prev = s.head
+	s.head.next = s.head
+	s.Sizeb = 0
+}
+
+// Links the given node into the list, just before the given mark.
+func (s *LinkedHashMap) linkBefore(n *lnode, mark *lnode) {
+	n.prev = mark.prev
+	n.next = mark
+	mark.prev.next = n
+	mark.prev = n
+}
+
+// Unlinks the given node from the list.
+func (s *LinkedHashMap) unlink(n *lnode) {
+	n.prev.next = n.next
+	n.next.prev = n.prev
+	n.prev = nil
+	n.next = nil
+}
@@ -0,0 +1,127 @@
+// This module contains tests for linkedhashmap.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"testing"
+)
+
+// Returns the keys of the given LinkedHashMap, front to back.
+func linkedKeys(s *LinkedHashMap) []interface{} {
+	keys := make([]interface{}, 0)
+	for _, kv := range *s.Slice() {
+		keys = append(keys, kv.(*KeyValue).Key)
+	}
+	return keys
+}
+
+func assertKeys(t *testing.T, s *LinkedHashMap, keys ...interface{}) {
+	actual := linkedKeys(s)
+	if len(actual) != len(keys) {
+		t.Fatalf("Expected keys %v, got %v.", keys, actual)
+	}
+	for i := range keys {
+		test.AssertEqual(t, actual[i], keys[i], "Keys out of order.")
+	}
+}
+
+func TestNewEmptyLinkedHashMap(t *testing.T) {
+	s := NewLinkedHashMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertTrue(t, s.First() == nil, "Empty dictionary has a first entry.")
+	test.AssertTrue(t, s.Last() == nil, "Empty dictionary has a last entry.")
+}
+
+func TestInsertionOrderLinkedHashMap(t *testing.T) {
+	s := NewLinkedHashMap()
+
+	for _, k := range []string{"c", "a", "d", "b"} {
+		s.Insert(k, k)
+	}
+	old := s.Insert("a", "A")
+	s.Locate("c")
+
+	test.AssertEqual(t, old, "a", "Wrong previous value.")
+	test.AssertEqual(t, s.Size(), 4, "Wrong size after reinserting.")
+	assertKeys(t, s, "c", "a", "d", "b")
+}
+
+func TestRemoveLinkedHashMap(t *testing.T) {
+	s := NewLinkedHashMap()
+
+	for _, k := range []string{"a", "b", "c"} {
+		s.Insert(k, k)
+	}
+
+	test.AssertEqual(t, s.Remove("b"), "b", "Wrong removed value.")
+	test.AssertNil(t, s.Remove("b"), "Removed a missing key.")
+	s.Insert("b", "b")
+
+	assertKeys(t, s, "a", "c", "b")
+	test.AssertEqual(t, s.Size(), 3, "Wrong size after removing.")
+}
+
+func TestAccessOrderLinkedHashMap(t *testing.T) {
+	s := NewLinkedHashMap()
+	s.SetAccessOrder(true)
+
+	for _, k := range []string{"a", "b", "c"} {
+		s.Insert(k, k)
+	}
+	s.Locate("a")
+	s.Insert("b", "B")
+	s.Contains("c")
+
+	test.AssertTrue(t, s.AccessOrder(), "Not in access order mode.")
+	assertKeys(t, s, "c", "a", "b")
+}
+
+func TestMoveLinkedHashMap(t *testing.T) {
+	s := NewLinkedHashMap()
+
+	for _, k := range []string{"a", "b", "c"} {
+		s.Insert(k, k)
+	}
+
+	test.AssertTrue(t, s.MoveToFront("c"), "Did not move an existing key.")
+	assertKeys(t, s, "c", "a", "b")
+	test.AssertTrue(t, s.MoveToBack("c"), "Did not move an existing key.")
+	assertKeys(t, s, "a", "b", "c")
+	test.AssertTrue(t, s.MoveToFront("a"), "Did not move an existing key.")
+	assertKeys(t, s, "a", "b", "c")
+	test.AssertFalse(t, s.MoveToBack("d"), "Moved a missing key.")
+
+	test.AssertEqual(t, s.First().Key, "a", "Wrong first entry.")
+	test.AssertEqual(t, s.Last().Value, "c", "Wrong last entry.")
+}
+
+func TestCopyLinkedHashMap(t *testing.T) {
+	s := NewLinkedHashMap()
+
+	for _, k := range []string{"b", "a", "c"} {
+		s.Insert(k, k)
+	}
+	c := s.Copy().(*LinkedHashMap)
+	c.Remove("a")
+
+	assertKeys(t, c, "b", "c")
+	assertKeys(t, s, "b", "a", "c")
+}
+
+func TestClearLinkedHashMap(t *testing.T) {
+	s := NewLinkedHashMap()
+
+	s.Insert("a", "a")
+	s.Clear()
+	s.Insert("b", "b")
+
+	assertKeys(t, s, "b")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after clearing.")
+}
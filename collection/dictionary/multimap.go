@@ -0,0 +1,417 @@
+// This module implements a MultiMap, a Collection associating each key
+// with any number of values.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+)
+
+// How a MultiMap treats the values associated with a single key.
+type Semantics int
+
+const (
+	// Values are kept in the order they were Put(), duplicates included.
+	ListSemantics Semantics = iota
+
+	// Values are kept in the order they were first Put(), and Putting a
+	// value already associated with a key does nothing.
+	SetSemantics
+)
+
+// The values associated with a single key in a MultiMap. members is only
+// used under SetSemantics, to check whether a value is present in O(1).
+// Removing a value still scans list, to keep the order they were Put().
+type values struct {
+	list    []interface{}
+	members map[interface{}]struct{}
+}
+
+// Returns the index of the first occurrence of the given value, or -1.
+func (vs *values) index(v interface{}) int {
+	for i, x := range vs.list {
+		if x == v {
+			return i
+		}
+	}
+	return -1
+}
+
+// A MultiMap is a Collection of (key, value) pairs, in which each key may
+// be associated with many values. Its Size() is the number of pairs, and
+// Map() and Slice() visit a pointer to a KeyValue for each pair.
+//
+// A MultiMap is either hash-backed, visiting keys in no particular order,
+// or tree-backed, visiting keys in sorted order, in which case all keys must
+// implement collection.Comparer. Values for a single key are visited in the
+// order they were Put().
+//
+// A tree-backed MultiMap panics when given a key that doesn't implement
+// collection.Comparer, rather than letting the TreeMap exit the process.
+//
+// Behavior unspecified if a MultiMap is not created using NewHashMultiMap(),
+// NewHashMultiMapUnsafe(), NewTreeMultiMap() or NewTreeMultiMapUnsafe(), or
+// if MultiMap.Init() / MultiMap.InitUnsafe(), is not first called on a new
+// &MultiMap{}, which is hash-backed, with ListSemantics.
+//
+type MultiMap struct {
+	collection.Base
+	m         Dictionary // key -> *values, always unsafe.
+	sorted    bool
+	semantics Semantics
+	keys      int
+}
+
+// Returns a pointer to a new hash-backed MultiMap.
+func NewHashMultiMap(semantics Semantics) *MultiMap {
+	s := &MultiMap{semantics: semantics}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe hash-backed MultiMap.
+func NewHashMultiMapUnsafe(semantics Semantics) *MultiMap {
+	s := &MultiMap{semantics: semantics}
+	s.InitUnsafe()
+	return s
+}
+
+// Returns a pointer to a new tree-backed MultiMap.
+func NewTreeMultiMap(semantics Semantics) *MultiMap {
+	s := &MultiMap{sorted: true, semantics: semantics}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe tree-backed MultiMap.
+func NewTreeMultiMapUnsafe(semantics Semantics) *MultiMap {
+	s := &MultiMap{sorted: true, semantics: semantics}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *MultiMap) Init() {
+	s.InitBase()
+
+	s.reset()
+}
+
+func (s *MultiMap) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.reset()
+}
+
+// Returns how this MultiMap treats the values associated with a single key.
+func (s *MultiMap) Semantics() Semantics {
+	s.CheckInit()
+	return s.semantics
+}
+
+// Associates the given value with the given key. Returns false if, under
+// SetSemantics, the value was already associated with the key, and true
+// otherwise.
+//
+// Panics if the given key or value are nil.
+// Panics if this MultiMap is tree-backed, and the given key does not
+// implement collection.Comparer.
+func (s *MultiMap) Put(key interface{}, value interface{}) bool {
+	s.CheckInit()
+	s.checkKey(key)
+	if value == nil {
+		log.Panic("Nil value.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	vs, _ := s.m.Locate(key).(*values)
+	if vs == nil {
+		vs = &values{}
+		if s.semantics == SetSemantics {
+			vs.members = make(map[interface{}]struct{})
+		}
+		s.m.Insert(key, vs)
+		s.keys += 1
+	}
+
+	if vs.members != nil {
+		if _, ok := vs.members[value]; ok {
+			return false
+		}
+		vs.members[value] = struct{}{}
+	}
+
+	vs.list = append(vs.list, value)
+	s.Sizeb += 1
+	return true
+}
+
+// Returns a new slice of all the values associated with the given key, in
+// the order they were Put(), or an empty slice if there are none.
+//
+// Panics if the given key is nil.
+func (s *MultiMap) Get(key interface{}) []interface{} {
+	s.CheckInit()
+	s.checkKey(key)
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	vs, _ := s.m.Locate(key).(*values)
+	if vs == nil {
+		return []interface{}{}
+	}
+
+	result := make([]interface{}, len(vs.list))
+	copy(result, vs.list)
+	return result
+}
+
+// Removes the given value from those associated with the given key. Under
+// ListSemantics, removes only its first occurrence. Returns true if the
+// value was associated with the key, false otherwise. Takes time linear in
+// the number of values associated with the key.
+//
+// Panics if the given key is nil.
+func (s *MultiMap) RemoveValue(key interface{}, value interface{}) bool {
+	s.CheckInit()
+	s.checkKey(key)
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	vs, _ := s.m.Locate(key).(*values)
+	if vs == nil {
+		return false
+	}
+
+	if vs.members != nil {
+		if _, ok := vs.members[value]; !ok {
+			return false
+		}
+		delete(vs.members, value)
+	}
+
+	i := vs.index(value)
+	if i < 0 {
+		return false
+	}
+	vs.list = append(vs.list[:i], vs.list[i+1:]...)
+	s.Sizeb -= 1
+
+	if len(vs.list) == 0 {
+		s.m.Remove(key)
+		s.keys -= 1
+	}
+	return true
+}
+
+// Removes and returns all the values associated with the given key, in the
+// order they were Put(), or an empty slice if there are none.
+//
+// Panics if the given key is nil.
+func (s *MultiMap) RemoveAll(key interface{}) []interface{} {
+	s.CheckInit()
+	s.checkKey(key)
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	vs, _ := s.m.Remove(key).(*values)
+	if vs == nil {
+		return []interface{}{}
+	}
+
+	s.Sizeb -= len(vs.list)
+	s.keys -= 1
+	return vs.list
+}
+
+// Returns true if all the given keys are associated with at least one
+// value, false otherwise.
+//
+// Panics if any of the given keys are nil, or, if this MultiMap is
+// tree-backed, don't implement collection.Comparer.
+func (s *MultiMap) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+	for _, key := range keys {
+		s.checkKey(key)
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.m.Contains(keys...)
+}
+
+// Returns true if the given value is associated with the given key.
+//
+// Panics if the given key is nil.
+func (s *MultiMap) ContainsValue(key interface{}, value interface{}) bool {
+	s.CheckInit()
+	s.checkKey(key)
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	vs, _ := s.m.Locate(key).(*values)
+	if vs == nil {
+		return false
+	}
+	if vs.members != nil {
+		_, ok := vs.members[value]
+		return ok
+	}
+	return vs.index(value) >= 0
+}
+
+// Returns the number of distinct keys in this MultiMap.
+func (s *MultiMap) KeyCount() int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.keys
+}
+
+// Returns the number of (key, value) pairs in this MultiMap. Same as Size().
+func (s *MultiMap) ValueCount() int {
+	return s.Size()
+}
+
+// Returns a new slice of the distinct keys in this MultiMap, sorted if this
+// MultiMap is tree-backed.
+func (s *MultiMap) Keys() []interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	keys := make([]interface{}, 0, s.keys)
+	s.m.Map(func(kv interface{}) bool {
+		keys = append(keys, kv.(*KeyValue).Key)
+		return true
+	})
+	return keys
+}
+
+// Returns a new, initialized MultiMap, with the same backing, semantics
+// and pairs as this MultiMap.
+func (s *MultiMap) Copy() *MultiMap {
+	s.CheckInit()
+
+	c := &MultiMap{sorted: s.sorted, semantics: s.semantics}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	s.m.Map(func(kv interface{}) bool {
+		vs := kv.(*KeyValue).Value.(*values)
+		cvs := &values{list: make([]interface{}, len(vs.list))}
+		copy(cvs.list, vs.list)
+		if vs.members != nil {
+			cvs.members = make(map[interface{}]struct{}, len(vs.members))
+			for v := range vs.members {
+				cvs.members[v] = struct{}{}
+			}
+		}
+		c.m.Insert(kv.(*KeyValue).Key, cvs)
+		return true
+	})
+	c.keys = s.keys
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Maps over a KeyValue for every (key, value) pair.
+func (s *MultiMap) Map(f func(interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.m.Map(func(kv interface{}) bool {
+		key := kv.(*KeyValue).Key
+		for _, v := range kv.(*KeyValue).Value.(*values).list {
+			if !f(&KeyValue{key, v}) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Returns a slice of pointers to KeyValue structs, one for every
+// (key, value) pair.
+func (s *MultiMap) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	s.m.Map(func(kv interface{}) bool {
+		key := kv.(*KeyValue).Key
+		for _, v := range kv.(*KeyValue).Value.(*values).list {
+			slice = append(slice, &KeyValue{key, v})
+		}
+		return true
+	})
+	return &slice
+}
+
+func (s *MultiMap) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.reset()
+}
+
+func (s *MultiMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Panics if the given key is nil, or this MultiMap is tree-backed and the
+// given key does not implement collection.Comparer.
+func (s *MultiMap) checkKey(key interface{}) {
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if _, ok := key.(collection.Comparer); s.sorted && !ok {
+		log.Panic("Key doesn't implement collection.Comparer.")
+	}
+}
+
+func (s *MultiMap) reset() {
+	if s.sorted {
+		s.m = NewTreeMapUnsafe()
+	} else {
+		s.m = NewHashMapUnsafe()
+	}
+	s.keys = 0
+	s.Sizeb = 0
+}
@@ -0,0 +1,167 @@
+// This module contains tests for multimap.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"fmt"
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"testing"
+)
+
+func TestNewEmptyMultiMap(t *testing.T) {
+	zero := &MultiMap{}
+	zero.Init()
+
+	for _, s := range []*MultiMap{NewHashMultiMap(ListSemantics), NewTreeMultiMap(SetSemantics), zero} {
+		test.AssertEqual(t, s.Size(), 0, "New multimap has size != 0.")
+		test.AssertEqual(t, s.KeyCount(), 0, "New multimap has keys.")
+		test.AssertTrue(t, s.Empty(), "New multimap is not empty.")
+	}
+}
+
+func TestPutGetListMultiMap(t *testing.T) {
+	s := NewHashMultiMap(ListSemantics)
+
+	test.AssertTrue(t, s.Put("a", 1), "Put did not add a value.")
+	test.AssertTrue(t, s.Put("a", 2), "Put did not add a value.")
+	test.AssertTrue(t, s.Put("a", 1), "Put did not add a duplicate value.")
+	s.Put("b", 3)
+
+	test.AssertEqual(t, fmt.Sprint(s.Get("a")), "[1 2 1]", "Wrong values.")
+	test.AssertEqual(t, len(s.Get("c")), 0, "Missing key has values.")
+	test.AssertEqual(t, s.KeyCount(), 2, "Wrong key count.")
+	test.AssertEqual(t, s.ValueCount(), 4, "Wrong value count.")
+	test.AssertTrue(t, s.Contains("a", "b"), "Key missing.")
+	test.AssertFalse(t, s.Contains("a", "c"), "Missing key present.")
+	test.AssertTrue(t, s.ContainsValue("a", 2), "Value missing.")
+	test.AssertFalse(t, s.ContainsValue("b", 2), "Missing value present.")
+}
+
+func TestPutGetSetMultiMap(t *testing.T) {
+	s := NewHashMultiMap(SetSemantics)
+
+	test.AssertTrue(t, s.Put("a", 1), "Put did not add a value.")
+	test.AssertTrue(t, s.Put("a", 2), "Put did not add a value.")
+	test.AssertFalse(t, s.Put("a", 1), "Put added a duplicate value.")
+
+	test.AssertEqual(t, fmt.Sprint(s.Get("a")), "[1 2]", "Wrong values.")
+	test.AssertEqual(t, s.ValueCount(), 2, "Wrong value count.")
+	test.AssertTrue(t, s.ContainsValue("a", 1), "Value missing.")
+}
+
+func TestGetReturnsCopyMultiMap(t *testing.T) {
+	s := NewHashMultiMap(ListSemantics)
+
+	s.Put("a", 1)
+	vs := s.Get("a")
+	vs[0] = 2
+
+	test.AssertEqual(t, s.Get("a")[0], 1, "Get exposed internal state.")
+}
+
+func TestRemoveValueMultiMap(t *testing.T) {
+	for _, semantics := range []Semantics{ListSemantics, SetSemantics} {
+		s := NewHashMultiMap(semantics)
+
+		s.Put("a", 1)
+		s.Put("a", 2)
+		s.Put("a", 3)
+
+		test.AssertTrue(t, s.RemoveValue("a", 2), "Did not remove a value.")
+		test.AssertFalse(t, s.RemoveValue("a", 2), "Removed a missing value.")
+		test.AssertFalse(t, s.RemoveValue("b", 2), "Removed from a missing key.")
+		test.AssertEqual(t, fmt.Sprint(s.Get("a")), "[1 3]", "Wrong values after removing.")
+		test.AssertFalse(t, s.ContainsValue("a", 2), "Removed value present.")
+
+		s.RemoveValue("a", 1)
+		s.RemoveValue("a", 3)
+
+		test.AssertFalse(t, s.Contains("a"), "Key without values present.")
+		test.AssertEqual(t, s.KeyCount(), 0, "Wrong key count after removing.")
+		test.AssertEqual(t, s.Size(), 0, "Wrong size after removing.")
+	}
+}
+
+func TestRemoveAllMultiMap(t *testing.T) {
+	s := NewTreeMultiMap(ListSemantics)
+
+	s.Put(compInt{1}, "a")
+	s.Put(compInt{1}, "b")
+	s.Put(compInt{2}, "c")
+
+	test.AssertEqual(t, fmt.Sprint(s.RemoveAll(compInt{1})), "[a b]", "Wrong removed values.")
+	test.AssertEqual(t, len(s.RemoveAll(compInt{1})), 0, "Removed a missing key.")
+	test.AssertEqual(t, s.KeyCount(), 1, "Wrong key count after removing.")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after removing.")
+}
+
+func TestSortedMultiMap(t *testing.T) {
+	s := NewTreeMultiMap(ListSemantics)
+
+	s.Put(compInt{3}, "c")
+	s.Put(compInt{1}, "a")
+	s.Put(compInt{2}, "b")
+	s.Put(compInt{1}, "A")
+
+	test.AssertEqual(t, fmt.Sprint(s.Keys()), "[{1} {2} {3}]", "Keys out of order.")
+
+	pairs := ""
+	s.Map(func(kv interface{}) bool {
+		pairs += fmt.Sprint(kv.(*KeyValue).Value)
+		return true
+	})
+	test.AssertEqual(t, pairs, "aAbc", "Pairs out of order.")
+	test.AssertEqual(t, len(*s.Slice()), 4, "Wrong slice length.")
+}
+
+func TestBadKeyPanicsTreeMultiMap(t *testing.T) {
+	s := NewTreeMultiMap(ListSemantics)
+	s.Put(compInt{1}, "a")
+
+	for _, f := range []func(){
+		func() { s.Put(1, "a") },
+		func() { s.Get(1) },
+		func() { s.Contains(compInt{1}, 1) },
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Error("A key that isn't a Comparer did not panic.")
+				}
+			}()
+			f()
+		}()
+	}
+	test.AssertEqual(t, s.Size(), 1, "A rejected key changed the size.")
+}
+
+func TestCopyMultiMap(t *testing.T) {
+	s := NewHashMultiMap(SetSemantics)
+
+	s.Put("a", 1)
+	s.Put("a", 2)
+	c := s.Copy()
+	c.Put("a", 3)
+	c.Put("a", 1)
+	s.RemoveValue("a", 1)
+
+	test.AssertEqual(t, fmt.Sprint(c.Get("a")), "[1 2 3]", "Copy shares state with original.")
+	test.AssertEqual(t, fmt.Sprint(s.Get("a")), "[2]", "Original shares state with copy.")
+	test.AssertEqual(t, c.Semantics(), SetSemantics, "Copy has different semantics.")
+}
+
+func TestClearMultiMap(t *testing.T) {
+	s := NewHashMultiMap(ListSemantics)
+
+	s.Put("a", 1)
+	s.Put("b", 2)
+	s.Clear()
+
+	test.AssertEqual(t, s.Size(), 0, "Wrong size after clearing.")
+	test.AssertEqual(t, s.KeyCount(), 0, "Wrong key count after clearing.")
+	test.AssertFalse(t, s.Contains("a"), "Cleared key present.")
+}
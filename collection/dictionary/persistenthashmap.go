@@ -0,0 +1,571 @@
+// This module implements a PersistentHashMap, an immutable hash array mapped
+// trie (HAMT) whose updates return new versions sharing structure with the
+// old, and a TransientHashMap, for building one up efficiently in place.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"log"
+	"math/bits"
+	"reflect"
+)
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// hamtNode definition, and associated helper functions.
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// The number of hash bits consumed at each level of the trie, and the mask
+// selecting them.
+const (
+	hamtBits = 5
+	hamtMask = 1<<hamtBits - 1
+)
+
+// A node of the trie. Holds a slot for each set bit of its bitmap, in order.
+//
+// Once the whole hash is consumed, nodes become collision nodes, holding
+// entries whose hashes are equal in no particular order, and no bitmap.
+type hamtNode struct {
+	bitmap uint32
+	slots  []hamtSlot
+	edit   *int // The TransientHashMap allowed to modify this node in place.
+}
+
+// A slot holds either an entry, or, if child is not nil, a subtrie.
+type hamtSlot struct {
+	hash  uint64
+	key   interface{}
+	value interface{}
+	child *hamtNode
+}
+
+// Returns the bit of a node's bitmap for the given hash at the given shift.
+func bitpos(hash uint64, shift uint) uint32 {
+	return 1 << ((hash >> shift) & hamtMask)
+}
+
+// Returns the index in the given node's slots of the given bit.
+func slotIndex(n *hamtNode, bit uint32) int {
+	return bits.OnesCount32(n.bitmap & (bit - 1))
+}
+
+// Returns the given node if the given edit may modify it in place, or else
+// a copy of it that it may.
+func editable(n *hamtNode, edit *int) *hamtNode {
+	if edit != nil && n.edit == edit {
+		return n
+	}
+
+	slots := make([]hamtSlot, len(n.slots), len(n.slots)+1)
+	copy(slots, n.slots)
+	return &hamtNode{n.bitmap, slots, edit}
+}
+
+// Inserts the given slot into the given editable node at index i.
+func insertSlot(n *hamtNode, i int, sl hamtSlot) {
+	n.slots = append(n.slots, hamtSlot{})
+	copy(n.slots[i+1:], n.slots[i:])
+	n.slots[i] = sl
+}
+
+// Removes the slot at index i from the given editable node.
+func removeSlot(n *hamtNode, i int) {
+	copy(n.slots[i:], n.slots[i+1:])
+	n.slots[len(n.slots)-1] = hamtSlot{}
+	n.slots = n.slots[:len(n.slots)-1]
+}
+
+// Returns a new node at the given shift holding the two given entries, whose
+// keys differ.
+func pairNode(shift uint, a hamtSlot, b hamtSlot, edit *int) *hamtNode {
+	if shift >= 64 {
+		return &hamtNode{0, []hamtSlot{a, b}, edit}
+	}
+
+	abit, bbit := bitpos(a.hash, shift), bitpos(b.hash, shift)
+	if abit == bbit {
+		return &hamtNode{abit, []hamtSlot{{child: pairNode(shift+hamtBits, a, b, edit)}}, edit}
+	}
+	if abit > bbit {
+		a, b = b, a
+	}
+	return &hamtNode{abit | bbit, []hamtSlot{a, b}, edit}
+}
+
+// Returns the value associated with the given key in the trie rooted at n,
+// or nil, if none is.
+func lookupHAMT(n *hamtNode, hash uint64, key interface{}) interface{} {
+	for shift := uint(0); n != nil; shift += hamtBits {
+		if shift >= 64 {
+			for _, sl := range n.slots {
+				if sl.key == key {
+					return sl.value
+				}
+			}
+			return nil
+		}
+
+		bit := bitpos(hash, shift)
+		if n.bitmap&bit == 0 {
+			return nil
+		}
+		sl := n.slots[slotIndex(n, bit)]
+		if sl.child == nil {
+			if sl.key == key {
+				return sl.value
+			}
+			return nil
+		}
+		n = sl.child
+	}
+	return nil
+}
+
+// Returns the root of a version of the trie rooted at n, at the given shift,
+// with the given key associated with the given value, modifying in place
+// only nodes the given edit owns. Also returns the value previously
+// associated with the key, if any, and true if the key was not present.
+func insertHAMT(n *hamtNode, shift uint, sl hamtSlot, edit *int) (root *hamtNode, old interface{}, added bool) {
+	if n == nil {
+		n = &hamtNode{edit: edit}
+	}
+
+	if shift >= 64 {
+		for i, c := range n.slots {
+			if c.key == sl.key {
+				m := editable(n, edit)
+				m.slots[i].value = sl.value
+				return m, c.value, false
+			}
+		}
+		m := editable(n, edit)
+		m.slots = append(m.slots, sl)
+		return m, nil, true
+	}
+
+	bit := bitpos(sl.hash, shift)
+	i := slotIndex(n, bit)
+	if n.bitmap&bit == 0 {
+		m := editable(n, edit)
+		insertSlot(m, i, sl)
+		m.bitmap |= bit
+		return m, nil, true
+	}
+
+	c := n.slots[i]
+	if c.child != nil {
+		var child *hamtNode
+		child, old, added = insertHAMT(c.child, shift+hamtBits, sl, edit)
+		m := editable(n, edit)
+		m.slots[i].child = child
+		return m, old, added
+	}
+	if c.key == sl.key {
+		m := editable(n, edit)
+		m.slots[i].value = sl.value
+		return m, c.value, false
+	}
+
+	m := editable(n, edit)
+	m.slots[i] = hamtSlot{child: pairNode(shift+hamtBits, c, sl, edit)}
+	return m, nil, true
+}
+
+// Returns the root of a version of the trie rooted at n, at the given shift,
+// without the given key, modifying in place only nodes the given edit owns.
+// Also returns the value that was associated with the key, if any, and true
+// if it was present. If it was not, returns n itself.
+//
+// Nodes left holding a single entry are folded into their parents, and empty
+// nodes are returned as nil.
+func removeHAMT(n *hamtNode, shift uint, hash uint64, key interface{}, edit *int) (root *hamtNode, old interface{}, removed bool) {
+	if n == nil {
+		return nil, nil, false
+	}
+
+	if shift >= 64 {
+		for i, c := range n.slots {
+			if c.key == key {
+				m := editable(n, edit)
+				removeSlot(m, i)
+				return m, c.value, true
+			}
+		}
+		return n, nil, false
+	}
+
+	bit := bitpos(hash, shift)
+	if n.bitmap&bit == 0 {
+		return n, nil, false
+	}
+	i := slotIndex(n, bit)
+	c := n.slots[i]
+
+	if c.child != nil {
+		var child *hamtNode
+		child, old, removed = removeHAMT(c.child, shift+hamtBits, hash, key, edit)
+		if !removed {
+			return n, nil, false
+		}
+
+		m := editable(n, edit)
+		switch {
+		case child == nil:
+			removeSlot(m, i)
+			m.bitmap &^= bit
+		case len(child.slots) == 1 && child.slots[0].child == nil:
+			m.slots[i] = child.slots[0]
+		default:
+			m.slots[i].child = child
+		}
+		if len(m.slots) == 0 {
+			return nil, old, true
+		}
+		return m, old, true
+	}
+
+	if c.key != key {
+		return n, nil, false
+	}
+	if len(n.slots) == 1 {
+		return nil, c.value, true
+	}
+	m := editable(n, edit)
+	removeSlot(m, i)
+	m.bitmap &^= bit
+	return m, c.value, true
+}
+
+// Applies the given function to every entry in the trie rooted at n. Stops
+// once the function returns false, and returns false if it did.
+func mapHAMT(n *hamtNode, f func(key interface{}, value interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	for _, sl := range n.slots {
+		if !mapSlot(sl, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Applies the given function to every entry held in the given slot.
+func mapSlot(sl hamtSlot, f func(key interface{}, value interface{}) bool) bool {
+	if sl.child != nil {
+		return mapHAMT(sl.child, f)
+	}
+	return f(sl.key, sl.value)
+}
+
+// Applies the given function to every key whose value differs between the
+// tries rooted at a and b, both at the given shift, with its value in a and
+// in b, either nil if it is absent. Skips subtries that a and b share. Stops
+// once the function returns false, and returns false if it did.
+func diffHAMT(a *hamtNode, b *hamtNode, shift uint, f func(key, before, after interface{}) bool) bool {
+	if a == b {
+		return true
+	}
+	if a == nil {
+		return mapHAMT(b, func(k, v interface{}) bool { return f(k, nil, v) })
+	}
+	if b == nil {
+		return mapHAMT(a, func(k, v interface{}) bool { return f(k, v, nil) })
+	}
+
+	if shift >= 64 {
+		return diffEntries(a, b, f)
+	}
+
+	for bitmap := a.bitmap | b.bitmap; bitmap != 0; bitmap &= bitmap - 1 {
+		bit := bitmap & -bitmap
+		var sa, sb *hamtSlot
+		if a.bitmap&bit != 0 {
+			sa = &a.slots[slotIndex(a, bit)]
+		}
+		if b.bitmap&bit != 0 {
+			sb = &b.slots[slotIndex(b, bit)]
+		}
+
+		var ok bool
+		switch {
+		case sb == nil:
+			ok = mapSlot(*sa, func(k, v interface{}) bool { return f(k, v, nil) })
+		case sa == nil:
+			ok = mapSlot(*sb, func(k, v interface{}) bool { return f(k, nil, v) })
+		case sa.child != nil && sb.child != nil:
+			ok = diffHAMT(sa.child, sb.child, shift+hamtBits, f)
+		case sa.child == nil && sb.child == nil && sa.key == sb.key:
+			ok = valuesEqual(sa.value, sb.value) || f(sa.key, sa.value, sb.value)
+		default:
+			ok = diffEntries(&hamtNode{0, []hamtSlot{*sa}, nil}, &hamtNode{0, []hamtSlot{*sb}, nil}, f)
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Diffs the entries held in the given nodes' slots through maps. Only used
+// where the tries can share nothing, and hold few entries.
+func diffEntries(a *hamtNode, b *hamtNode, f func(key, before, after interface{}) bool) bool {
+	as, bs := entriesOf(a), entriesOf(b)
+
+	for k, v := range as {
+		if w, ok := bs[k]; !ok || !valuesEqual(v, w) {
+			if !f(k, v, w) {
+				return false
+			}
+		}
+	}
+	for k, w := range bs {
+		if _, ok := as[k]; !ok && !f(k, nil, w) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a map of all the entries in the trie rooted at n.
+func entriesOf(n *hamtNode) map[interface{}]interface{} {
+	entries := make(map[interface{}]interface{})
+	mapHAMT(n, func(k, v interface{}) bool {
+		entries[k] = v
+		return true
+	})
+	return entries
+}
+
+// Returns true if the given values are deeply equal.
+func valuesEqual(a interface{}, b interface{}) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// end node stuff
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// A PersistentHashMap is an immutable map, backed by a hash array mapped
+// trie. Keys must be comparable, as for a HashMap.
+//
+// Insert() and Remove() leave the PersistentHashMap they are called on
+// untouched, and return a new version that shares all but O(log32 n) nodes
+// with it. Every version stays readable forever, and may be read, or
+// updated, from any number of goroutines at once without locks.
+//
+// To make many updates at once, use Transient().
+//
+// Since it is never modified, a PersistentHashMap is not a Collection, and
+// needs no Unsafe variant. The zero value is an empty PersistentHashMap.
+//
+type PersistentHashMap struct {
+	root *hamtNode
+	size int
+}
+
+// Returns a pointer to a new, empty PersistentHashMap.
+func NewPersistentHashMap() *PersistentHashMap {
+	return &PersistentHashMap{}
+}
+
+// Returns a PersistentHashMap with the given value associated with the given
+// key, and the previous value associated with that key, or nil, if none
+// existed.
+//
+// Panics if the given key or value are nil.
+func (s *PersistentHashMap) Insert(key interface{}, value interface{}) (*PersistentHashMap, interface{}) {
+	if value == nil {
+		log.Panic("Nil value.")
+	}
+
+	root, old, added := insertHAMT(s.root, 0, hamtSlot{hash: hashKey(key), key: key, value: value}, nil)
+	if added {
+		return &PersistentHashMap{root, s.size + 1}, old
+	}
+	return &PersistentHashMap{root, s.size}, old
+}
+
+// Returns a PersistentHashMap without the given key, and the value that was
+// associated with it, or nil, if none was. If the key is absent, returns this
+// PersistentHashMap.
+//
+// Panics if the given key is nil.
+func (s *PersistentHashMap) Remove(key interface{}) (*PersistentHashMap, interface{}) {
+	root, old, removed := removeHAMT(s.root, 0, hashKey(key), key, nil)
+	if !removed {
+		return s, nil
+	}
+	return &PersistentHashMap{root, s.size - 1}, old
+}
+
+// Returns the value associated with the given key, or nil, if none is.
+//
+// Panics if the given key is nil.
+func (s *PersistentHashMap) Locate(key interface{}) interface{} {
+	return lookupHAMT(s.root, hashKey(key), key)
+}
+
+// Returns true if all the given keys have entries in this PersistentHashMap,
+// false otherwise.
+func (s *PersistentHashMap) Contains(keys ...interface{}) bool {
+	if len(keys) == 0 {
+		return false
+	}
+
+	for _, k := range keys {
+		if s.Locate(k) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the number of KeyValues in this PersistentHashMap.
+func (s *PersistentHashMap) Size() int {
+	return s.size
+}
+
+// Returns true if this PersistentHashMap has no KeyValues, false otherwise.
+func (s *PersistentHashMap) Empty() bool {
+	return s.size == 0
+}
+
+// Returns true if this PersistentHashMap and the given one hold the same keys,
+// with deeply equal values, false otherwise. Skips subtries both share, so
+// comparing a version with one derived from it takes time proportional to
+// the changes between them.
+func (s *PersistentHashMap) Equal(o *PersistentHashMap) bool {
+	return s.size == o.size && s.Diff(o, func(key, before, after interface{}) bool {
+		return false
+	})
+}
+
+// Attempts to apply the given function to every key whose value differs
+// between this PersistentHashMap and the given one, in no particular order.
+// before is its value in this one, and after its value in the given one,
+// either nil if it is absent there. Skips subtries both share. Stops once all
+// differences have been processed, or once the function returns false,
+// whichever occurs first.
+func (s *PersistentHashMap) Diff(o *PersistentHashMap, f func(key, before, after interface{}) bool) bool {
+	return diffHAMT(s.root, o.root, 0, f)
+}
+
+// Returns a TransientHashMap holding the same KeyValues as this
+// PersistentHashMap, which is left untouched.
+func (s *PersistentHashMap) Transient() *TransientHashMap {
+	return &TransientHashMap{s.root, s.size, new(int)}
+}
+
+// Attempts to apply the given function to a pointer to a KeyValue for every
+// entry in this PersistentHashMap, in no particular order. Stops once all
+// entries have been processed, or once the function returns false,
+// whichever occurs first.
+func (s *PersistentHashMap) Map(f func(item interface{}) bool) bool {
+	return mapHAMT(s.root, func(k, v interface{}) bool {
+		return f(&KeyValue{k, v})
+	})
+}
+
+// Returns a slice of pointers to KeyValue structs, in no particular order.
+func (s *PersistentHashMap) Slice() *[]interface{} {
+	slice := make([]interface{}, 0, s.size)
+	s.Map(func(kv interface{}) bool {
+		slice = append(slice, kv)
+		return true
+	})
+	return &slice
+}
+
+func (s *PersistentHashMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// A TransientHashMap is a mutable view of a PersistentHashMap, for making
+// many updates at once. It modifies in place the nodes it has already
+// copied, instead of copying them again on every update.
+//
+// A TransientHashMap is not thread-safe, and may not be used after
+// Persistent() is called on it.
+//
+type TransientHashMap struct {
+	root *hamtNode
+	size int
+	edit *int
+}
+
+// Returns a pointer to a new, empty TransientHashMap.
+func NewTransientHashMap() *TransientHashMap {
+	return NewPersistentHashMap().Transient()
+}
+
+// Inserts the given value associated with the given key into this
+// TransientHashMap. Returns the previous value associated with that key,
+// or nil, if none existed.
+//
+// Panics if the given key or value are nil.
+func (s *TransientHashMap) Insert(key interface{}, value interface{}) interface{} {
+	s.checkEdit()
+	if value == nil {
+		log.Panic("Nil value.")
+	}
+
+	root, old, added := insertHAMT(s.root, 0, hamtSlot{hash: hashKey(key), key: key, value: value}, s.edit)
+	s.root = root
+	if added {
+		s.size += 1
+	}
+	return old
+}
+
+// Removes and returns the value associated with the given key from this
+// TransientHashMap, or nil, if there is none.
+//
+// Panics if the given key is nil.
+func (s *TransientHashMap) Remove(key interface{}) interface{} {
+	s.checkEdit()
+
+	root, old, removed := removeHAMT(s.root, 0, hashKey(key), key, s.edit)
+	s.root = root
+	if removed {
+		s.size -= 1
+	}
+	return old
+}
+
+// Returns the value associated with the given key, or nil, if none is.
+//
+// Panics if the given key is nil.
+func (s *TransientHashMap) Locate(key interface{}) interface{} {
+	s.checkEdit()
+
+	return lookupHAMT(s.root, hashKey(key), key)
+}
+
+// Returns the number of KeyValues in this TransientHashMap.
+func (s *TransientHashMap) Size() int {
+	s.checkEdit()
+
+	return s.size
+}
+
+// Returns a PersistentHashMap holding the KeyValues of this TransientHashMap,
+// which may not be used afterwards.
+func (s *TransientHashMap) Persistent() *PersistentHashMap {
+	s.checkEdit()
+
+	s.edit = nil
+	return &PersistentHashMap{s.root, s.size}
+}
+
+// Panics if Persistent() has been called on this TransientHashMap.
+func (s *TransientHashMap) checkEdit() {
+	if s.edit == nil {
+		log.Panic("TransientHashMap used after Persistent().")
+	}
+}
@@ -0,0 +1,258 @@
+// This module contains tests for persistenthashmap.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Returns the entries of the given PersistentHashMap as a map.
+func entriesOfPersistent(s *PersistentHashMap) map[interface{}]interface{} {
+	entries := make(map[interface{}]interface{})
+	s.Map(func(kv interface{}) bool {
+		kvc := kv.(*KeyValue)
+		entries[kvc.Key] = kvc.Value
+		return true
+	})
+	return entries
+}
+
+// Checks that the given PersistentHashMap holds exactly the given entries.
+func checkPersistentHashMap(t *testing.T, s *PersistentHashMap, expected map[int]int) {
+	if s.Size() != len(expected) || len(*s.Slice()) != len(expected) {
+		t.Fatalf("Expected %d entries, got %d.", len(expected), s.Size())
+	}
+	for k, v := range expected {
+		if s.Locate(k) != v {
+			t.Fatalf("Wrong value for %d: expected %d, got %v.", k, v, s.Locate(k))
+		}
+	}
+}
+
+func TestNewEmptyPersistentHashMap(t *testing.T) {
+	s := NewPersistentHashMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertNil(t, s.Locate("a"), "New dictionary has an entry.")
+	test.AssertFalse(t, s.Contains("a"), "New dictionary has an entry.")
+
+	var zero PersistentHashMap
+	v, _ := zero.Insert("a", 1)
+	test.AssertEqual(t, v.Locate("a"), 1, "Zero value is not usable.")
+}
+
+func TestVersionsPersistentHashMap(t *testing.T) {
+	v0 := NewPersistentHashMap()
+	v1, old := v0.Insert("a", 1)
+	test.AssertNil(t, old, "Inserting a new key returned a value.")
+	v2, _ := v1.Insert(1, "one")
+	v3, old := v2.Insert("a", 2)
+	test.AssertEqual(t, old, 1, "Replacing a key returned the wrong value.")
+	v4, old := v3.Remove(1)
+	test.AssertEqual(t, old, "one", "Remove returned the wrong value.")
+	v5, old := v4.Remove("z")
+	test.AssertNil(t, old, "Removing a missing key returned a value.")
+	test.AssertTrue(t, v5 == v4, "Removing a missing key made a new version.")
+
+	test.AssertEqual(t, v0.Size(), 0, "Version 0 changed.")
+	test.AssertEqual(t, v1.Locate("a"), 1, "Version 1 changed.")
+	test.AssertFalse(t, v1.Contains(1), "Version 1 changed.")
+	test.AssertTrue(t, v2.Contains("a", 1), "Version 2 changed.")
+	test.AssertFalse(t, v2.Contains("1"), "int(1) and \"1\" are the same key.")
+	test.AssertEqual(t, v3.Locate("a"), 2, "Version 3 is wrong.")
+	test.AssertEqual(t, v4.Size(), 1, "Version 4 is wrong.")
+}
+
+func TestRandomVersionsPersistentHashMap(t *testing.T) {
+	r := rand.New(rand.NewSource(45))
+	versions := []*PersistentHashMap{NewPersistentHashMap()}
+	expected := []map[int]int{{}}
+
+	for i := 0; i < 3000; i++ {
+		j := r.Intn(len(versions))
+		kvs := make(map[int]int)
+		for k, v := range expected[j] {
+			kvs[k] = v
+		}
+
+		k := r.Intn(2000)
+		var next *PersistentHashMap
+		if r.Intn(3) == 0 {
+			next, _ = versions[j].Remove(k)
+			delete(kvs, k)
+		} else {
+			next, _ = versions[j].Insert(k, i)
+			kvs[k] = i
+		}
+		versions = append(versions, next)
+		expected = append(expected, kvs)
+	}
+
+	for i, v := range versions {
+		checkPersistentHashMap(t, v, expected[i])
+	}
+}
+
+func TestTransientPersistentHashMap(t *testing.T) {
+	r := rand.New(rand.NewSource(45))
+	base := NewPersistentHashMap()
+	for i := 0; i < 500; i++ {
+		base, _ = base.Insert(i, i)
+	}
+
+	tr := base.Transient()
+	expected := make(map[int]int)
+	for i := 0; i < 500; i++ {
+		expected[i] = i
+	}
+	for i := 0; i < 5000; i++ {
+		k := r.Intn(1500)
+		if r.Intn(3) == 0 {
+			_, present := expected[k]
+			if old := tr.Remove(k); (old != nil) != present {
+				t.Fatalf("Remove(%d) returned %v.", k, old)
+			}
+			delete(expected, k)
+		} else {
+			tr.Insert(k, -i)
+			expected[k] = -i
+		}
+	}
+	test.AssertEqual(t, tr.Size(), len(expected), "Transient has the wrong size.")
+
+	built := tr.Persistent()
+	checkPersistentHashMap(t, built, expected)
+	for i := 0; i < 500; i++ {
+		test.AssertEqual(t, base.Locate(i), i, "Transient modified the version it came from.")
+	}
+	test.AssertEqual(t, base.Size(), 500, "Transient modified the version it came from.")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Using a TransientHashMap after Persistent() did not panic.")
+		}
+	}()
+	tr.Insert(0, 0)
+}
+
+func TestDiffEqualPersistentHashMap(t *testing.T) {
+	r := rand.New(rand.NewSource(45))
+	a := NewPersistentHashMap()
+	for i := 0; i < 1000; i++ {
+		a, _ = a.Insert(i, i)
+	}
+
+	b := a
+	changes := make(map[interface{}][2]interface{})
+	for i := 0; i < 30; i++ {
+		k := r.Intn(1200)
+		before := a.Locate(k)
+		switch r.Intn(3) {
+		case 0:
+			b, _ = b.Remove(k)
+		default:
+			b, _ = b.Insert(k, -k-1)
+		}
+		if after := b.Locate(k); after != before {
+			changes[k] = [2]interface{}{before, after}
+		} else {
+			delete(changes, k)
+		}
+	}
+
+	seen := make(map[interface{}][2]interface{})
+	a.Diff(b, func(k, before, after interface{}) bool {
+		if _, ok := seen[k]; ok {
+			t.Errorf("Diff visited %v twice.", k)
+		}
+		seen[k] = [2]interface{}{before, after}
+		return true
+	})
+	test.AssertEqual(t, len(seen), len(changes), "Diff found the wrong number of changes.")
+	for k, c := range changes {
+		test.AssertEqual(t, seen[k], c, "Diff reported the wrong change.")
+	}
+
+	test.AssertTrue(t, a.Equal(a), "A version does not equal itself.")
+	test.AssertEqual(t, a.Equal(b), len(changes) == 0, "Equal disagrees with Diff.")
+
+	// A map built independently, sharing nothing, is still Equal.
+	c := NewTransientHashMap()
+	for i := 999; i >= 0; i-- {
+		c.Insert(i, i)
+	}
+	test.AssertTrue(t, a.Equal(c.Persistent()), "Equal maps built separately are not Equal.")
+
+	d, _ := a.Insert(5, []int{5})
+	e, _ := a.Insert(5, []int{5})
+	test.AssertTrue(t, d.Equal(e), "Values are not compared deeply.")
+	test.AssertFalse(t, d.Equal(a), "Changed values are Equal.")
+
+	stops := 0
+	test.AssertFalse(t, a.Diff(NewPersistentHashMap(), func(k, before, after interface{}) bool {
+		stops++
+		return false
+	}), "Diff did not report stopping.")
+	test.AssertEqual(t, stops, 1, "Diff did not stop.")
+}
+
+func TestCollisionsPersistentHashMap(t *testing.T) {
+	// Every key gets the same hash, so all end up in one collision node.
+	var root *hamtNode
+	for i := 0; i < 10; i++ {
+		root, _, _ = insertHAMT(root, 0, hamtSlot{hash: 42, key: i, value: i}, nil)
+	}
+	root, old, added := insertHAMT(root, 0, hamtSlot{hash: 42, key: 3, value: 30}, nil)
+	test.AssertTrue(t, old == 3 && !added, "Replacing a colliding key is wrong.")
+
+	for i := 0; i < 10; i++ {
+		if v := lookupHAMT(root, 42, i); (i == 3 && v != 30) || (i != 3 && v != i) {
+			t.Fatalf("Wrong value for colliding key %d: %v", i, v)
+		}
+	}
+	test.AssertNil(t, lookupHAMT(root, 42, 10), "Found a missing colliding key.")
+
+	before := root
+	for i := 0; i < 10; i++ {
+		var removed bool
+		root, _, removed = removeHAMT(root, 0, 42, i, nil)
+		test.AssertTrue(t, removed, "Failed to remove a colliding key.")
+		test.AssertEqual(t, len(entriesOf(root)), 9-i, "Removing a colliding key lost entries.")
+	}
+	test.AssertTrue(t, root == nil, "Removing every key did not empty the trie.")
+	test.AssertEqual(t, len(entriesOf(before)), 10, "Removing modified an older version.")
+}
+
+func TestFloatKeysPersistentHashMap(t *testing.T) {
+	s, _ := NewPersistentHashMap().Insert(0.0, "zero")
+
+	test.AssertEqual(t, s.Locate(math.Copysign(0, -1)), "zero", "-0 and 0 are different keys.")
+	test.AssertNil(t, s.Locate(float32(0)), "float32 and float64 keys are the same.")
+	test.AssertEqual(t, len(entriesOfPersistent(s)), 1, "Map is wrong.")
+}
+
+func TestPointerAndStructKeysPersistentHashMap(t *testing.T) {
+	type box struct{ N int }
+	type point struct {
+		X, Y float64
+		b    *box
+	}
+	a, b := &box{1}, &box{1}
+	s, _ := NewPersistentHashMap().Insert(a, "a")
+	s, _ = s.Insert(point{0, 1, a}, "point")
+
+	a.N = 2
+	test.AssertEqual(t, s.Locate(a), "a", "Changing a pointer key's target lost it.")
+	test.AssertNil(t, s.Locate(b), "Pointers to equal values are the same key.")
+	test.AssertEqual(t, s.Locate(point{math.Copysign(0, -1), 1, a}), "point", "-0 and 0 in a struct are different keys.")
+	test.AssertNil(t, s.Locate(point{0, 1, b}), "Structs with different pointer fields are the same key.")
+	test.AssertEqual(t, len(entriesOfPersistent(s)), 2, "Map is wrong.")
+}
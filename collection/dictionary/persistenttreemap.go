@@ -0,0 +1,243 @@
+// This module implements a PersistentTreeMap, an immutable AVL Tree backed
+// map whose updates return new versions sharing structure with the old.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+)
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// Path copying versions of the node helpers in treemap.go. These never
+// modify a node reachable from an existing version, only fresh copies.
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// Returns a pointer to a new node with the same key, value, height, size and
+// children as the given node.
+func cloneNode(n *node) *node {
+	return &node{K: n.K, V: n.V, H: n.H, S: n.S, C: []*node{n.C[0], n.C[1]}}
+}
+
+// Rebalances the given fresh node as rebalance() does, first copying the
+// children that the rotation will modify.
+func rebalanceCopy(n *node) *node {
+	updateHeight(n)
+	if balanced(n) {
+		return n
+	}
+
+	dir1 := tallestDir(n)
+	c := cloneNode(child(n, dir1))
+	n.C[dir1] = c
+	if height(child(c, 1-dir1)) > height(child(c, dir1)) {
+		c.C[1-dir1] = cloneNode(child(c, 1-dir1))
+	}
+	return rebalance(n)
+}
+
+// Returns the root of a copy of the subtree rooted at n with the given key
+// associated with the given value, and true if the key was not already
+// present.
+func insertCopy(n *node, k collection.Comparer, v interface{}) (root *node, added bool) {
+	if n == nil {
+		return newNode(k, v, 0), true
+	}
+
+	m := cloneNode(n)
+	if k.Compare(n.K) == 0 {
+		m.V = v
+		return m, false
+	}
+
+	dir := direction(k, n.K)
+	m.C[dir], added = insertCopy(child(n, dir), k, v)
+	return rebalanceCopy(m), added
+}
+
+// Returns the root of a copy of the subtree rooted at n without its leftmost
+// node, and a fresh copy of that node, whose children are left unset.
+func removeMinCopy(n *node) (*node, *node) {
+	if child(n, 0) == nil {
+		return child(n, 1), cloneNode(n)
+	}
+
+	var min *node
+	m := cloneNode(n)
+	m.C[0], min = removeMinCopy(child(n, 0))
+	return rebalanceCopy(m), min
+}
+
+// Returns the root of a copy of the subtree rooted at n without the given
+// key, and true if the key was present. If it was not, returns n itself.
+func removeCopy(n *node, k collection.Comparer) (root *node, removed bool) {
+	if n == nil {
+		return nil, false
+	}
+
+	if k.Compare(n.K) != 0 {
+		dir := direction(k, n.K)
+		c, removed := removeCopy(child(n, dir), k)
+		if !removed {
+			return n, false
+		}
+		m := cloneNode(n)
+		m.C[dir] = c
+		return rebalanceCopy(m), true
+	}
+
+	if child(n, 0) == nil {
+		return child(n, 1), true
+	}
+	if child(n, 1) == nil {
+		return child(n, 0), true
+	}
+
+	// Replace n with a copy of its in-order successor.
+	right, succ := removeMinCopy(child(n, 1))
+	succ.C[0] = child(n, 0)
+	succ.C[1] = right
+	return rebalanceCopy(succ), true
+}
+
+// Applies the given function to the KeyValue of every node in the subtree
+// rooted at n, in order. Stops once the function returns false, and returns
+// false if it did.
+func mapInOrder(n *node, f func(item interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	return mapInOrder(child(n, 0), f) && f(&KeyValue{n.K, n.V}) && mapInOrder(child(n, 1), f)
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// end node stuff
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// A PersistentTreeMap is an immutable map, storing its KeyValues in sorted
+// order, as defined by the Key's Compare() method (Keys should implement
+// collection.Comparer).
+//
+// Insert() and Remove() leave the PersistentTreeMap they are called on
+// untouched, and return a new version that shares all but O(log n) nodes
+// with it. Every version stays readable forever, and may be read, or
+// updated, from any number of goroutines at once without locks.
+//
+// Since it is never modified, a PersistentTreeMap is not a Collection, and
+// needs no Unsafe variant. The zero value is an empty PersistentTreeMap.
+//
+type PersistentTreeMap struct {
+	root *node
+	size int
+}
+
+// Returns a pointer to a new, empty PersistentTreeMap.
+func NewPersistentTreeMap() *PersistentTreeMap {
+	return &PersistentTreeMap{}
+}
+
+// Returns a PersistentTreeMap with the given value associated with the given
+// key, and the previous value associated with that key, or nil, if none
+// existed.
+//
+// Panics if the given key or value are nil, or the key doesn't implement
+// collection.Comparer.
+func (s *PersistentTreeMap) Insert(key interface{}, value interface{}) (*PersistentTreeMap, interface{}) {
+	if value == nil {
+		log.Panic("Nil value.")
+	}
+	kc := comparerKey(key)
+
+	old := s.Locate(kc)
+	root, added := insertCopy(s.root, kc, value)
+	if added {
+		return &PersistentTreeMap{root, s.size + 1}, old
+	}
+	return &PersistentTreeMap{root, s.size}, old
+}
+
+// Returns a PersistentTreeMap without the given key, and the value that was
+// associated with it, or nil, if none was. If the key is absent, returns this
+// PersistentTreeMap.
+//
+// Panics if the given key is nil, or doesn't implement collection.Comparer.
+func (s *PersistentTreeMap) Remove(key interface{}) (*PersistentTreeMap, interface{}) {
+	kc := comparerKey(key)
+
+	old := s.Locate(kc)
+	if old == nil {
+		return s, nil
+	}
+	root, _ := removeCopy(s.root, kc)
+	return &PersistentTreeMap{root, s.size - 1}, old
+}
+
+// Returns the value associated with the given key, or nil, if none is.
+//
+// Panics if the given key is nil, or doesn't implement collection.Comparer.
+func (s *PersistentTreeMap) Locate(key interface{}) interface{} {
+	kc := comparerKey(key)
+
+	current := s.root
+	for current != nil {
+		c := kc.Compare(current.K)
+		if c == 0 {
+			return current.V
+		}
+		current = child(current, direction(kc, current.K))
+	}
+	return nil
+}
+
+// Returns true if all the given keys have entries in this PersistentTreeMap,
+// false otherwise.
+func (s *PersistentTreeMap) Contains(keys ...interface{}) bool {
+	if len(keys) == 0 {
+		return false
+	}
+
+	for _, k := range keys {
+		if s.Locate(k) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the number of KeyValues in this PersistentTreeMap.
+func (s *PersistentTreeMap) Size() int {
+	return s.size
+}
+
+// Returns true if this PersistentTreeMap has no KeyValues, false otherwise.
+func (s *PersistentTreeMap) Empty() bool {
+	return s.size == 0
+}
+
+// Attempts to apply the given function to a pointer to a KeyValue for every
+// entry in this PersistentTreeMap, in ascending order of their keys. Stops
+// once all entries have been processed, or once the function returns false,
+// whichever occurs first.
+func (s *PersistentTreeMap) Map(f func(item interface{}) bool) bool {
+	return mapInOrder(s.root, f)
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of
+// their keys.
+func (s *PersistentTreeMap) Slice() *[]interface{} {
+	slice := make([]interface{}, 0, s.size)
+	s.Map(func(kv interface{}) bool {
+		slice = append(slice, kv)
+		return true
+	})
+	return &slice
+}
+
+func (s *PersistentTreeMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
@@ -0,0 +1,137 @@
+// This module contains tests for persistenttreemap.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"sync"
+	"testing"
+)
+
+// Returns the keys of the given PersistentTreeMap, in order.
+func persistentKeysOf(s *PersistentTreeMap) []int {
+	keys := make([]int, 0, s.Size())
+	s.Map(func(kv interface{}) bool {
+		keys = append(keys, kv.(*KeyValue).Key.(compInt).i)
+		return true
+	})
+	return keys
+}
+
+func TestNewEmptyPersistentTreeMap(t *testing.T) {
+	s := NewPersistentTreeMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertNil(t, s.Locate(compInt{1}), "New dictionary has an entry.")
+
+	var zero PersistentTreeMap
+	v, _ := zero.Insert(compInt{1}, 1)
+	test.AssertEqual(t, v.Locate(compInt{1}), 1, "Zero value is not usable.")
+}
+
+func TestVersionsPersistentTreeMap(t *testing.T) {
+	v0 := NewPersistentTreeMap()
+	v1, old := v0.Insert(compInt{1}, "a")
+	test.AssertNil(t, old, "Inserting a new key returned a value.")
+	v2, _ := v1.Insert(compInt{2}, "b")
+	v3, old := v2.Insert(compInt{1}, "c")
+	test.AssertEqual(t, old, "a", "Replacing a key returned the wrong value.")
+	v4, old := v3.Remove(compInt{2})
+	test.AssertEqual(t, old, "b", "Remove returned the wrong value.")
+	v5, old := v4.Remove(compInt{7})
+	test.AssertNil(t, old, "Removing a missing key returned a value.")
+	test.AssertTrue(t, v5 == v4, "Removing a missing key made a new version.")
+
+	test.AssertEqual(t, v0.Size(), 0, "Version 0 changed.")
+	test.AssertEqual(t, v1.Size(), 1, "Version 1 changed.")
+	test.AssertEqual(t, v1.Locate(compInt{1}), "a", "Version 1 changed.")
+	test.AssertFalse(t, v1.Contains(compInt{2}), "Version 1 changed.")
+	test.AssertTrue(t, v2.Contains(compInt{1}, compInt{2}), "Version 2 changed.")
+	test.AssertEqual(t, v2.Locate(compInt{1}), "a", "Version 2 changed.")
+	test.AssertEqual(t, v3.Locate(compInt{1}), "c", "Version 3 is wrong.")
+	test.AssertEqual(t, v3.Size(), 2, "Version 3 is wrong.")
+	test.AssertEqual(t, v4.Size(), 1, "Version 4 is wrong.")
+	test.AssertFalse(t, v4.Contains(compInt{2}), "Version 4 is wrong.")
+}
+
+func TestRandomVersionsPersistentTreeMap(t *testing.T) {
+	r := rand.New(rand.NewSource(44))
+	versions := []*PersistentTreeMap{NewPersistentTreeMap()}
+	expected := []map[int]int{{}}
+
+	for i := 0; i < 2000; i++ {
+		prev := versions[r.Intn(len(versions))]
+		kvs := make(map[int]int)
+		for _, kv := range *prev.Slice() {
+			kvc := kv.(*KeyValue)
+			kvs[kvc.Key.(compInt).i] = kvc.Value.(int)
+		}
+
+		k := r.Intn(300)
+		var next *PersistentTreeMap
+		if r.Intn(3) == 0 {
+			next, _ = prev.Remove(compInt{k})
+			delete(kvs, k)
+		} else {
+			next, _ = prev.Insert(compInt{k}, i)
+			kvs[k] = i
+		}
+		versions = append(versions, next)
+		expected = append(expected, kvs)
+	}
+
+	for i, v := range versions {
+		test.AssertEqual(t, v.Size(), len(expected[i]), "A version has the wrong size.")
+		test.AssertEqual(t, checkAVL(t, v.root), len(expected[i]), "A version lost nodes.")
+		for k, val := range expected[i] {
+			if v.Locate(compInt{k}) != val {
+				t.Fatalf("Version %d holds the wrong value for %d.", i, k)
+			}
+		}
+		keys := persistentKeysOf(v)
+		for j := 1; j < len(keys); j++ {
+			if keys[j-1] >= keys[j] {
+				t.Fatalf("Version %d is not in order: %v", i, keys)
+			}
+		}
+	}
+}
+
+func TestSharedReadersPersistentTreeMap(t *testing.T) {
+	s := NewPersistentTreeMap()
+	for _, k := range rand.Perm(1000) {
+		s, _ = s.Insert(compInt{k}, k)
+	}
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			v := s
+			for i := 0; i < 200; i++ {
+				v, _ = v.Remove(compInt{(g*200 + i) % 1000})
+			}
+			test.AssertEqual(t, v.Size(), 800, "A writer's version has the wrong size.")
+			test.AssertEqual(t, len(persistentKeysOf(s)), 1000, "A reader saw another version's writes.")
+		}(g)
+	}
+	wg.Wait()
+
+	test.AssertEqual(t, checkAVL(t, s.root), 1000, "The shared version changed.")
+}
+
+func TestInvalidKeyPersistentTreeMap(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Inserting a key that isn't a Comparer did not panic.")
+		}
+	}()
+	NewPersistentTreeMap().Insert("a", 1)
+}
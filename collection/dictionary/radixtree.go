@@ -0,0 +1,503 @@
+// This module implements a RadixTree, a Dictionary keyed by strings that
+// answers prefix queries.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"sort"
+	"strings"
+)
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// rnode definition, and associated helper functions.
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// The node struct for the RadixTree. P is the label on the edge from this
+// node's parent, and C holds its children, in order of their labels' first
+// bytes. Leaf is true if the path down to this node is a key, with value V.
+type rnode struct {
+	P    string
+	Leaf bool
+	V    interface{}
+	C    []*rnode
+}
+
+// Returns the index of the child whose label starts with the given byte,
+// and the child, or the index it would be inserted at, and nil.
+func (n *rnode) child(b byte) (int, *rnode) {
+	i := sort.Search(len(n.C), func(i int) bool {
+		return n.C[i].P[0] >= b
+	})
+	if i < len(n.C) && n.C[i].P[0] == b {
+		return i, n.C[i]
+	}
+	return i, nil
+}
+
+// Removes child i if it no longer holds any keys, or replaces it with its
+// only child if it isn't one.
+func (n *rnode) prune(i int) {
+	c := n.C[i]
+	switch {
+	case c.Leaf:
+	case len(c.C) == 0:
+		n.C = append(n.C[:i], n.C[i+1:]...)
+	case len(c.C) == 1:
+		g := c.C[0]
+		g.P = c.P + g.P
+		n.C[i] = g
+	}
+}
+
+// Returns the number of keys in the subtree rooted at n.
+func (n *rnode) count() int {
+	count := 0
+	if n.Leaf {
+		count++
+	}
+	for _, c := range n.C {
+		count += c.count()
+	}
+	return count
+}
+
+// Returns a copy of the subtree rooted at n.
+func (n *rnode) copy() *rnode {
+	c := &rnode{P: n.P, Leaf: n.Leaf, V: n.V, C: make([]*rnode, len(n.C))}
+	for i, child := range n.C {
+		c.C[i] = child.copy()
+	}
+	return c
+}
+
+// Applies the given function to a KeyValue for every key in the subtree
+// rooted at n, in order, given the key leading to n. Stops once the function
+// returns false, and returns false if it did.
+func (n *rnode) walk(key string, f func(item interface{}) bool) bool {
+	if n.Leaf && !f(&KeyValue{key, n.V}) {
+		return false
+	}
+	for _, c := range n.C {
+		if !c.walk(key+c.P, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// end node stuff
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// A RadixTree implements Dictionary for string and []byte keys, with the
+// additional guarantee of storing its keys in ascending byte order. It is a
+// trie whose chains of single children are merged into one edge, so it holds
+// at most two nodes per key, and finds every key with a given prefix, or the
+// longest key that is a prefix of a given string, in time proportional to
+// the prefix's length.
+//
+// []byte keys are stored, and reported, as strings, so "ab" and []byte("ab")
+// are the same key.
+//
+// Behavior unspecified if a RadixTree is not created using NewRadixTree(),
+// NewRadixTreeUnsafe() or if RadixTree.Init() / RadixTree.InitUnsafe(), is
+// not first called on a new &RadixTree{}.
+//
+type RadixTree struct {
+	collection.Base
+	root *rnode
+}
+
+// Returns a pointer to a new RadixTree.
+func NewRadixTree() *RadixTree {
+	s := &RadixTree{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe RadixTree.
+func NewRadixTreeUnsafe() *RadixTree {
+	s := &RadixTree{}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *RadixTree) Init() {
+	s.InitBase()
+
+	s.root = &rnode{}
+}
+
+func (s *RadixTree) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.root = &rnode{}
+}
+
+// Associates the given value with the given key. Returns the previous value
+// associated with it, or nil, if none existed.
+//
+// Panics if the given key is not a string or []byte.
+func (s *RadixTree) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	k := radixKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	n := s.root
+	for k != "" {
+		i, c := n.child(k[0])
+		if c == nil {
+			n.C = append(n.C, nil)
+			copy(n.C[i+1:], n.C[i:])
+			n.C[i] = &rnode{P: k}
+			n = n.C[i]
+			break
+		}
+
+		l := commonPrefix(k, c.P)
+		if l < len(c.P) {
+			// Split c's label where k leaves it.
+			mid := &rnode{P: c.P[:l], C: []*rnode{c}}
+			c.P = c.P[l:]
+			n.C[i] = mid
+			c = mid
+		}
+		k = k[l:]
+		n = c
+	}
+
+	old := n.V
+	if !n.Leaf {
+		n.Leaf = true
+		s.Sizeb += 1
+	}
+	n.V = value
+	return old
+}
+
+// Returns the value associated with the given key, or nil, if none is.
+//
+// Panics if the given key is not a string or []byte.
+func (s *RadixTree) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	k := radixKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := s.locate(k); n != nil {
+		return n.V
+	}
+	return nil
+}
+
+// Removes and returns the value associated with the given key, or nil, if
+// none is.
+//
+// Panics if the given key is not a string or []byte.
+func (s *RadixTree) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	k := radixKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	v, ok := s.remove(s.root, k)
+	if ok {
+		s.Sizeb -= 1
+	}
+	return v
+}
+
+// Panics if any of the given keys is not a string or []byte.
+func (s *RadixTree) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+	if len(keys) == 0 {
+		return false
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, key := range keys {
+		if s.locate(radixKey(key)) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a pointer to a KeyValue for the longest key in this RadixTree that
+// is a prefix of the given key, or nil, if none is.
+//
+// Panics if the given key is not a string or []byte.
+func (s *RadixTree) LongestPrefixMatch(key interface{}) *KeyValue {
+	s.CheckInit()
+	k := radixKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	var match *KeyValue
+	n, depth := s.root, 0
+	for {
+		if n.Leaf {
+			match = &KeyValue{k[:depth], n.V}
+		}
+		if depth == len(k) {
+			return match
+		}
+
+		_, c := n.child(k[depth])
+		if c == nil || !strings.HasPrefix(k[depth:], c.P) {
+			return match
+		}
+		n, depth = c, depth+len(c.P)
+	}
+}
+
+// Attempts to apply the given function to a pointer to a KeyValue for every
+// key in this RadixTree starting with the given prefix, in ascending order.
+// Stops once all such keys have been processed, or once the function returns
+// false, whichever occurs first.
+//
+// Panics if the given prefix is not a string or []byte.
+func (s *RadixTree) WalkPrefix(prefix interface{}, f func(item interface{}) bool) bool {
+	s.CheckInit()
+	p := radixKey(prefix)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	n, key := s.under(p)
+	if n == nil {
+		return true
+	}
+	return n.walk(key, f)
+}
+
+// Removes every key in this RadixTree starting with the given prefix.
+// Returns the number of keys removed.
+//
+// Panics if the given prefix is not a string or []byte.
+func (s *RadixTree) DeletePrefix(prefix interface{}) int {
+	s.CheckInit()
+	p := radixKey(prefix)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	var removed int
+	if p == "" {
+		removed = s.Sizeb
+		s.root = &rnode{}
+	} else {
+		removed = s.deletePrefix(s.root, p)
+	}
+	s.Sizeb -= removed
+	return removed
+}
+
+func (s *RadixTree) Copy() Dictionary {
+	s.CheckInit()
+
+	c := &RadixTree{}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.root = s.root.copy()
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to a pointer to a KeyValue for every
+// key in this RadixTree, in ascending order. Stops once all keys have been
+// processed, or once the function returns false, whichever occurs first.
+func (s *RadixTree) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.root.walk("", f)
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of
+// their keys.
+func (s *RadixTree) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(kv interface{}) bool {
+		slice = append(slice, kv)
+		return true
+	})
+	return &slice
+}
+
+func (s *RadixTree) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.root = &rnode{}
+	s.Sizeb = 0
+}
+
+func (s *RadixTree) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Returns the given key as a string.
+//
+// Panics if the given key is not a string or []byte.
+func radixKey(key interface{}) string {
+	switch k := key.(type) {
+	case string:
+		return k
+	case []byte:
+		return string(k)
+	}
+	log.Panic("Key is not a string or []byte.")
+	return ""
+}
+
+// Returns the length of the longest common prefix of a and b.
+func commonPrefix(a string, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// Returns the node for the given key, or nil, if it isn't in this RadixTree.
+func (s *RadixTree) locate(k string) *rnode {
+	n := s.root
+	for k != "" {
+		_, c := n.child(k[0])
+		if c == nil || !strings.HasPrefix(k, c.P) {
+			return nil
+		}
+		k = k[len(c.P):]
+		n = c
+	}
+
+	if !n.Leaf {
+		return nil
+	}
+	return n
+}
+
+// Returns the highest node whose keys all start with the given prefix, and
+// the key leading to it, or nil, if no key does.
+func (s *RadixTree) under(p string) (*rnode, string) {
+	n, depth := s.root, 0
+	for depth < len(p) {
+		_, c := n.child(p[depth])
+		if c == nil {
+			return nil, ""
+		}
+
+		rest := p[depth:]
+		if len(rest) <= len(c.P) {
+			// The prefix ends partway along c's label.
+			if !strings.HasPrefix(c.P, rest) {
+				return nil, ""
+			}
+			return c, p[:depth] + c.P
+		}
+		if !strings.HasPrefix(rest, c.P) {
+			return nil, ""
+		}
+		n, depth = c, depth+len(c.P)
+	}
+	return n, p
+}
+
+// Removes the given key from below n. Returns its value, and true if it was
+// present.
+func (s *RadixTree) remove(n *rnode, k string) (interface{}, bool) {
+	if k == "" {
+		if !n.Leaf {
+			return nil, false
+		}
+		v := n.V
+		n.Leaf, n.V = false, nil
+		return v, true
+	}
+
+	i, c := n.child(k[0])
+	if c == nil || !strings.HasPrefix(k, c.P) {
+		return nil, false
+	}
+
+	v, ok := s.remove(c, k[len(c.P):])
+	if ok {
+		n.prune(i)
+	}
+	return v, ok
+}
+
+// Removes every key below n starting with the given non-empty prefix.
+// Returns the number of keys removed.
+func (s *RadixTree) deletePrefix(n *rnode, p string) int {
+	i, c := n.child(p[0])
+	if c == nil {
+		return 0
+	}
+
+	if len(p) <= len(c.P) {
+		// Every key below c starts with p, or none does.
+		if !strings.HasPrefix(c.P, p) {
+			return 0
+		}
+		n.C = append(n.C[:i], n.C[i+1:]...)
+		return c.count()
+	}
+	if !strings.HasPrefix(p, c.P) {
+		return 0
+	}
+
+	removed := s.deletePrefix(c, p[len(c.P):])
+	if removed > 0 {
+		n.prune(i)
+	}
+	return removed
+}
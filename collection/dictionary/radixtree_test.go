@@ -0,0 +1,209 @@
+// This module contains tests for radixtree.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// Checks that every node below n, other than the root, has a non-empty label,
+// that children are in order, and that no non-key node has fewer than two
+// children. Returns the number of keys below n.
+func checkRadix(t *testing.T, n *rnode, root bool) int {
+	if !root {
+		if n.P == "" {
+			t.Fatal("Node has an empty label.")
+		}
+		if !n.Leaf && len(n.C) < 2 {
+			t.Fatalf("Node %q should have been pruned or merged.", n.P)
+		}
+	}
+
+	count := 0
+	if n.Leaf {
+		count++
+	}
+	for i, c := range n.C {
+		if i > 0 && n.C[i-1].P[0] >= c.P[0] {
+			t.Fatalf("Children %q and %q are out of order.", n.C[i-1].P, c.P)
+		}
+		count += checkRadix(t, c, false)
+	}
+	return count
+}
+
+// Returns the keys given to the function by WalkPrefix(), or Map().
+func radixKeys(walk func(f func(item interface{}) bool) bool) []string {
+	var keys []string
+	walk(func(item interface{}) bool {
+		keys = append(keys, item.(*KeyValue).Key.(string))
+		return true
+	})
+	return keys
+}
+
+func TestNewEmptyRadixTree(t *testing.T) {
+	s := NewRadixTree()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertNil(t, s.Locate(""), "Empty dictionary has an entry.")
+	test.AssertTrue(t, s.LongestPrefixMatch("abc") == nil, "Empty dictionary has a prefix match.")
+}
+
+func TestInsertRemoveRadixTree(t *testing.T) {
+	s := NewRadixTree()
+
+	test.AssertNil(t, s.Insert("romane", 1), "New key had a value.")
+	test.AssertNil(t, s.Insert("romanus", 2), "New key had a value.")
+	test.AssertNil(t, s.Insert([]byte("rom"), 3), "New key had a value.")
+	test.AssertNil(t, s.Insert("", 4), "New key had a value.")
+	test.AssertEqual(t, s.Insert("rom", 5), 3, "[]byte and string keys differ.")
+	test.AssertEqual(t, s.Size(), 4, "Dictionary has the wrong size.")
+	test.AssertEqual(t, checkRadix(t, s.root, true), 4, "Tree holds the wrong number of keys.")
+
+	test.AssertEqual(t, s.Locate([]byte("romanus")), 2, "Located the wrong value.")
+	test.AssertNil(t, s.Locate("roman"), "Located a split point.")
+	test.AssertNil(t, s.Locate("ro"), "Located part of a label.")
+	test.AssertTrue(t, s.Contains("", "rom", "romane"), "Missing an inserted key.")
+	test.AssertFalse(t, s.Contains("rom", "roma"), "Contains a missing key.")
+
+	test.AssertNil(t, s.Remove("roman"), "Removed a split point.")
+	test.AssertEqual(t, s.Remove("romane"), 1, "Removed the wrong value.")
+	test.AssertEqual(t, s.Remove(""), 4, "Removed the wrong value.")
+	test.AssertEqual(t, s.Size(), 2, "Removing didn't shrink the dictionary.")
+	test.AssertEqual(t, checkRadix(t, s.root, true), 2, "Removing didn't prune the tree.")
+	test.AssertEqual(t, strings.Join(radixKeys(s.Map), " "), "rom romanus", "Wrong keys left after removing.")
+}
+
+func TestBadKeyPanicsRadixTree(t *testing.T) {
+	s := NewRadixTree()
+	defer func() {
+		if recover() == nil {
+			t.Error("Inserting an int key did not panic.")
+		}
+	}()
+	s.Insert(1, 1)
+}
+
+func TestPrefixQueriesRadixTree(t *testing.T) {
+	s := NewRadixTreeUnsafe()
+	for i, k := range []string{"/", "/api/", "/api/v1/", "/api/v1/users", "/api/v1/users/me", "/api/v2/users", "/static"} {
+		s.Insert(k, i)
+	}
+
+	kv := s.LongestPrefixMatch("/api/v1/users/42")
+	test.AssertEqual(t, kv.Key, "/api/v1/users", "Wrong longest prefix match.")
+	test.AssertEqual(t, kv.Value, 3, "Wrong longest prefix match value.")
+	test.AssertEqual(t, s.LongestPrefixMatch("/api/v3").Key, "/api/", "Wrong longest prefix match.")
+	test.AssertEqual(t, s.LongestPrefixMatch("/api/v1/").Key, "/api/v1/", "Exact key is not its own match.")
+	test.AssertTrue(t, s.LongestPrefixMatch("api") == nil, "Matched a key that isn't a prefix.")
+
+	walk := func(p string) string {
+		return strings.Join(radixKeys(func(f func(item interface{}) bool) bool {
+			return s.WalkPrefix(p, f)
+		}), " ")
+	}
+	test.AssertEqual(t, walk("/api/v1/"), "/api/v1/ /api/v1/users /api/v1/users/me", "Wrong keys under a key.")
+	test.AssertEqual(t, walk("/api/v"), "/api/v1/ /api/v1/users /api/v1/users/me /api/v2/users", "Wrong keys under part of a label.")
+	test.AssertEqual(t, walk("/st"), "/static", "Wrong keys under part of a leaf label.")
+	test.AssertEqual(t, walk("/api/v3"), "", "Found keys under a missing prefix.")
+	test.AssertEqual(t, walk("/statics"), "", "Found keys under a prefix past a key.")
+
+	test.AssertEqual(t, s.DeletePrefix("/api/v1"), 3, "Deleted the wrong number of keys.")
+	test.AssertEqual(t, s.DeletePrefix("/api/v1"), 0, "Deleted keys twice.")
+	test.AssertEqual(t, s.Size(), 4, "Deleting didn't shrink the dictionary.")
+	test.AssertEqual(t, checkRadix(t, s.root, true), 4, "Deleting didn't prune the tree.")
+	test.AssertEqual(t, walk(""), "/ /api/ /api/v2/users /static", "Wrong keys left after deleting.")
+
+	test.AssertEqual(t, s.DeletePrefix(""), 4, "Deleting everything missed keys.")
+	test.AssertTrue(t, s.Empty(), "Deleting everything left keys.")
+}
+
+func TestCopyRadixTree(t *testing.T) {
+	s := NewRadixTree()
+	s.Insert("abc", 1)
+	s.Insert("abd", 2)
+
+	c := s.Copy().(*RadixTree)
+	c.Remove("abc")
+	c.Insert("abd", 3)
+
+	test.AssertEqual(t, s.Size(), 2, "Changing a copy changed the original.")
+	test.AssertEqual(t, s.Locate("abd"), 2, "Changing a copy changed the original.")
+	test.AssertEqual(t, checkRadix(t, s.root, true), 2, "Changing a copy changed the original.")
+	test.AssertEqual(t, checkRadix(t, c.root, true), 1, "Copy has the wrong keys.")
+}
+
+func TestLargeRandomRadixTree(t *testing.T) {
+	r := rand.New(rand.NewSource(49))
+	s := NewRadixTree()
+	kvs := make(map[string]int)
+
+	// Short keys from a small alphabet share many prefixes.
+	randomKey := func() string {
+		b := make([]byte, r.Intn(8))
+		for i := range b {
+			b[i] = "abc"[r.Intn(3)]
+		}
+		return string(b)
+	}
+
+	for j := 0; j < 5000; j++ {
+		k := randomKey()
+		switch r.Intn(10) {
+		case 0:
+			p := k[:len(k)/2]
+			removed := 0
+			for key := range kvs {
+				if strings.HasPrefix(key, p) {
+					delete(kvs, key)
+					removed++
+				}
+			}
+			test.AssertEqual(t, s.DeletePrefix(p), removed, "Deleted the wrong number of keys.")
+		case 1, 2, 3:
+			s.Remove(k)
+			delete(kvs, k)
+		default:
+			s.Insert(k, j)
+			kvs[k] = j
+		}
+	}
+	test.AssertEqual(t, checkRadix(t, s.root, true), len(kvs), "Tree holds the wrong number of keys.")
+	test.AssertEqual(t, s.Size(), len(kvs), "Size doesn't match the entries.")
+
+	keys := make([]string, 0, len(kvs))
+	for k := range kvs {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	test.AssertEqual(t, strings.Join(radixKeys(s.Map), " "), strings.Join(keys, " "), "Keys are out of order.")
+
+	for j := 0; j < 500; j++ {
+		k := randomKey()
+		best := ""
+		found := false
+		for key := range kvs {
+			if strings.HasPrefix(k, key) && (!found || len(key) > len(best)) {
+				best, found = key, true
+			}
+		}
+
+		kv := s.LongestPrefixMatch(k)
+		if !found {
+			test.AssertTrue(t, kv == nil, "Matched a missing prefix.")
+			continue
+		}
+		test.AssertEqual(t, kv.Key, best, "Wrong longest prefix match.")
+		test.AssertEqual(t, kv.Value, kvs[best], "Wrong longest prefix match value.")
+	}
+}
@@ -0,0 +1,505 @@
+// This module implements a red-black tree backed Dictionary, conforming to
+// Navigable, with the additional stipulation that all Keys must implement
+// collection.Comparer.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+)
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// rbnode definition, and associated helper functions.
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// The node struct for the red-black tree. C[0] and C[1] are the left and
+// right children, and P the parent. Missing children, and the root's parent,
+// are the tree's black sentinel rather than nil.
+type rbnode struct {
+	K   collection.Comparer
+	V   interface{}
+	Red bool
+	C   [2]*rbnode
+	P   *rbnode
+}
+
+// Returns 0 if the given node is its parent's left child, 1 otherwise.
+func side(n *rbnode) int {
+	if n == n.P.C[0] {
+		return 0
+	}
+	return 1
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// end node stuff
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// A RedBlackTreeMap implements Navigable. It offers the same ordered behavior
+// as TreeMap, but keeps its tree less strictly balanced, so does at most two
+// rotations per Insert() and three per Remove(), which suits write-heavy
+// workloads. Lookups may be a little slower.
+//
+// Behavior unspecified if a RedBlackTreeMap is not created using
+// NewRedBlackTreeMap(), NewRedBlackTreeMapUnsafe() or if
+// RedBlackTreeMap.Init() / RedBlackTreeMap.InitUnsafe(), is not first called
+// on a new &RedBlackTreeMap{}.
+//
+type RedBlackTreeMap struct {
+	collection.Base
+	root *rbnode
+	leaf *rbnode // The black sentinel.
+}
+
+// Returns a pointer to a new RedBlackTreeMap.
+func NewRedBlackTreeMap() *RedBlackTreeMap {
+	s := &RedBlackTreeMap{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe RedBlackTreeMap.
+func NewRedBlackTreeMapUnsafe() *RedBlackTreeMap {
+	s := &RedBlackTreeMap{}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *RedBlackTreeMap) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *RedBlackTreeMap) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *RedBlackTreeMap) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	p, n := s.leaf, s.root
+	dir := 0
+	for n != s.leaf {
+		c := kc.Compare(n.K)
+		if c == 0 {
+			old := n.V
+			n.V = value
+			return old
+		}
+		p, dir = n, direction(kc, n.K)
+		n = n.C[dir]
+	}
+
+	z := &rbnode{K: kc, V: value, Red: true, C: [2]*rbnode{s.leaf, s.leaf}, P: p}
+	if p == s.leaf {
+		s.root = z
+	} else {
+		p.C[dir] = z
+	}
+	s.insertFixup(z)
+
+	s.Sizeb += 1
+	return nil
+}
+
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *RedBlackTreeMap) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := s.find(kc); n != s.leaf {
+		return n.V
+	}
+	return nil
+}
+
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *RedBlackTreeMap) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	z := s.find(kc)
+	if z == s.leaf {
+		return nil
+	}
+	s.delete(z)
+
+	s.Sizeb -= 1
+	return z.V
+}
+
+func (s *RedBlackTreeMap) Contains(keys ...interface{}) bool {
+	if len(keys) == 0 {
+		return false
+	}
+
+	for _, k := range keys {
+		if s.Locate(k) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a pointer to the KeyValue with the smallest key, or nil if this
+// RedBlackTreeMap is empty.
+func (s *RedBlackTreeMap) First() *KeyValue {
+	return s.end(0)
+}
+
+// Returns a pointer to the KeyValue with the largest key, or nil if this
+// RedBlackTreeMap is empty.
+func (s *RedBlackTreeMap) Last() *KeyValue {
+	return s.end(1)
+}
+
+// Returns a pointer to the KeyValue with the largest key less than or equal
+// to the given key, or nil if there is none.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *RedBlackTreeMap) Floor(key interface{}) *KeyValue {
+	return s.nearest(key, 0)
+}
+
+// Returns a pointer to the KeyValue with the smallest key greater than or
+// equal to the given key, or nil if there is none.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *RedBlackTreeMap) Ceiling(key interface{}) *KeyValue {
+	return s.nearest(key, 1)
+}
+
+// Attempts to apply the given function to a pointer to the KeyValue of
+// every key from from, inclusive, to to, exclusive, in ascending order.
+// Stops once all those KeyValues have been processed, or once the function
+// returns false, whichever occurs first.
+//
+// Panics if either key is nil or doesn't implement collection.Comparer.
+func (s *RedBlackTreeMap) Range(from interface{}, to interface{}, f func(item interface{}) bool) bool {
+	s.CheckInit()
+	fc, tc := comparerKey(from), comparerKey(to)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for n := s.nearestNode(fc, 1); n != s.leaf && tc.Compare(n.K) > 0; n = s.next(n) {
+		if !f(&KeyValue{n.K, n.V}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *RedBlackTreeMap) Copy() Dictionary {
+	s.CheckInit()
+
+	c := &RedBlackTreeMap{}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.root = s.copyNodes(s.root, c.leaf, c.leaf)
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to a pointer to a KeyValue for every
+// entry in this RedBlackTreeMap, in ascending order of their keys. Stops
+// once all entries have been processed, or once the function returns false,
+// whichever occurs first.
+func (s *RedBlackTreeMap) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	ok := true
+	for n := s.extreme(s.root, 0); n != s.leaf && ok; n = s.next(n) {
+		ok = f(&KeyValue{n.K, n.V})
+	}
+	return ok
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of keys.
+func (s *RedBlackTreeMap) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(kv interface{}) bool {
+		slice = append(slice, kv)
+		return true
+	})
+	return &slice
+}
+
+func (s *RedBlackTreeMap) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.root = s.leaf
+	s.Sizeb = 0
+}
+
+func (s *RedBlackTreeMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *RedBlackTreeMap) setup() {
+	s.leaf = &rbnode{}
+	s.root = s.leaf
+}
+
+// Returns the node with the given key, or the sentinel if there is none.
+func (s *RedBlackTreeMap) find(k collection.Comparer) *rbnode {
+	n := s.root
+	for n != s.leaf {
+		c := k.Compare(n.K)
+		if c == 0 {
+			return n
+		}
+		n = n.C[direction(k, n.K)]
+	}
+	return n
+}
+
+// Returns the leftmost (dir 0) or rightmost (dir 1) node in the subtree
+// rooted at n, or the sentinel if it is empty.
+func (s *RedBlackTreeMap) extreme(n *rbnode, dir int) *rbnode {
+	if n == s.leaf {
+		return n
+	}
+	for n.C[dir] != s.leaf {
+		n = n.C[dir]
+	}
+	return n
+}
+
+// Returns the in-order successor of the given node, or the sentinel if there
+// is none.
+func (s *RedBlackTreeMap) next(n *rbnode) *rbnode {
+	if n.C[1] != s.leaf {
+		return s.extreme(n.C[1], 0)
+	}
+	for n.P != s.leaf && n == n.P.C[1] {
+		n = n.P
+	}
+	return n.P
+}
+
+// Returns the node with the largest key less than or equal to (dir 0), or the
+// smallest key greater than or equal to (dir 1), the given key, or the
+// sentinel if there is none.
+func (s *RedBlackTreeMap) nearestNode(k collection.Comparer, dir int) *rbnode {
+	best, n := s.leaf, s.root
+	for n != s.leaf {
+		c := k.Compare(n.K)
+		if c == 0 {
+			return n
+		}
+		if (c > 0) == (dir == 0) {
+			best = n
+		}
+		n = n.C[direction(k, n.K)]
+	}
+	return best
+}
+
+// Returns a pointer to the KeyValue with the smallest (dir 0) or largest
+// (dir 1) key, or nil if this RedBlackTreeMap is empty.
+func (s *RedBlackTreeMap) end(dir int) *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := s.extreme(s.root, dir); n != s.leaf {
+		return &KeyValue{n.K, n.V}
+	}
+	return nil
+}
+
+// Returns a pointer to the KeyValue with the largest key less than or equal
+// to (dir 0), or the smallest key greater than or equal to (dir 1), the given
+// key, or nil if there is none.
+func (s *RedBlackTreeMap) nearest(key interface{}, dir int) *KeyValue {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := s.nearestNode(kc, dir); n != s.leaf {
+		return &KeyValue{n.K, n.V}
+	}
+	return nil
+}
+
+// Rotates the given node down towards dir, bringing its other child up in
+// its place.
+func (s *RedBlackTreeMap) rotate(x *rbnode, dir int) {
+	y := x.C[1-dir]
+	x.C[1-dir] = y.C[dir]
+	if y.C[dir] != s.leaf {
+		y.C[dir].P = x
+	}
+	s.replace(x, y)
+	y.C[dir] = x
+	x.P = y
+}
+
+// Puts v in u's place under u's parent.
+func (s *RedBlackTreeMap) replace(u *rbnode, v *rbnode) {
+	if u.P == s.leaf {
+		s.root = v
+	} else {
+		u.P.C[side(u)] = v
+	}
+	v.P = u.P
+}
+
+// Restores the red-black properties after inserting the red node z.
+func (s *RedBlackTreeMap) insertFixup(z *rbnode) {
+	for z.P.Red {
+		p := z.P
+		g := p.P
+		dir := side(p)
+
+		if u := g.C[1-dir]; u.Red {
+			p.Red, u.Red, g.Red = false, false, true
+			z = g
+			continue
+		}
+
+		if z == p.C[1-dir] {
+			z = p
+			s.rotate(z, dir)
+		}
+		z.P.Red, g.Red = false, true
+		s.rotate(g, 1-dir)
+	}
+	s.root.Red = false
+}
+
+// Unlinks the given node from the tree, and restores the red-black
+// properties.
+func (s *RedBlackTreeMap) delete(z *rbnode) {
+	var x *rbnode
+	y, red := z, z.Red
+
+	switch {
+	case z.C[0] == s.leaf:
+		x = z.C[1]
+		s.replace(z, x)
+	case z.C[1] == s.leaf:
+		x = z.C[0]
+		s.replace(z, x)
+	default:
+		// Replace z with its in-order successor y.
+		y = s.extreme(z.C[1], 0)
+		red = y.Red
+		x = y.C[1]
+		if y.P == z {
+			x.P = y
+		} else {
+			s.replace(y, x)
+			y.C[1] = z.C[1]
+			y.C[1].P = y
+		}
+		s.replace(z, y)
+		y.C[0] = z.C[0]
+		y.C[0].P = y
+		y.Red = z.Red
+	}
+
+	if !red {
+		s.deleteFixup(x)
+	}
+}
+
+// Restores the red-black properties after removing a black node from above
+// x, which carries an extra black.
+func (s *RedBlackTreeMap) deleteFixup(x *rbnode) {
+	for x != s.root && !x.Red {
+		dir := side(x)
+		w := x.P.C[1-dir]
+
+		if w.Red {
+			w.Red, x.P.Red = false, true
+			s.rotate(x.P, dir)
+			w = x.P.C[1-dir]
+		}
+
+		if !w.C[0].Red && !w.C[1].Red {
+			w.Red = true
+			x = x.P
+			continue
+		}
+
+		if !w.C[1-dir].Red {
+			w.C[dir].Red, w.Red = false, true
+			s.rotate(w, 1-dir)
+			w = x.P.C[1-dir]
+		}
+		w.Red, x.P.Red, w.C[1-dir].Red = x.P.Red, false, false
+		s.rotate(x.P, dir)
+		x = s.root
+	}
+	x.Red = false
+}
+
+// Returns a copy of the subtree rooted at n, whose nodes are on this tree,
+// hung from the given parent and linked with the given sentinel.
+func (s *RedBlackTreeMap) copyNodes(n *rbnode, p *rbnode, leaf *rbnode) *rbnode {
+	if n == s.leaf {
+		return leaf
+	}
+
+	c := &rbnode{K: n.K, V: n.V, Red: n.Red, P: p}
+	c.C[0] = s.copyNodes(n.C[0], c, leaf)
+	c.C[1] = s.copyNodes(n.C[1], c, leaf)
+	return c
+}
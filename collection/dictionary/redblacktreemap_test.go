@@ -0,0 +1,109 @@
+// This module contains tests for redblacktreemap.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"testing"
+)
+
+// Checks that the subtree rooted at n is ordered, has consistent parents, no
+// red node with a red child, and the same number of black nodes on every
+// path down. Returns its size and black height.
+func checkRB(t *testing.T, s *RedBlackTreeMap, n *rbnode) (size int, black int) {
+	if n == s.leaf {
+		return 0, 1
+	}
+	for dir, c := range n.C {
+		if c == s.leaf {
+			continue
+		}
+		if c.P != n {
+			t.Fatalf("Node %v has the wrong parent.", c.K)
+		}
+		if direction(c.K, n.K) != dir {
+			t.Fatalf("Node %v is on the wrong side of %v.", c.K, n.K)
+		}
+		if n.Red && c.Red {
+			t.Fatalf("Red node %v has a red child.", n.K)
+		}
+	}
+
+	ls, lb := checkRB(t, s, n.C[0])
+	rs, rb := checkRB(t, s, n.C[1])
+	if lb != rb {
+		t.Fatalf("Node %v has unequal black heights.", n.K)
+	}
+	if !n.Red {
+		lb += 1
+	}
+	return ls + rs + 1, lb
+}
+
+func TestNewEmptyRedBlackTreeMap(t *testing.T) {
+	s := NewRedBlackTreeMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertTrue(t, s.First() == nil && s.Last() == nil, "Empty dictionary has ends.")
+	test.AssertNil(t, s.Locate(compInt{1}), "Empty dictionary has an entry.")
+}
+
+func TestLargeRandomRedBlackTreeMap(t *testing.T) {
+	r := rand.New(rand.NewSource(46))
+	s := NewRedBlackTreeMap()
+	kvs := make(map[int]int)
+
+	for i := 0; i < 20000; i++ {
+		k := r.Intn(2000)
+		if r.Intn(2) == 0 {
+			s.Insert(compInt{k}, i)
+			kvs[k] = i
+		} else {
+			removed := s.Remove(compInt{k})
+			if v, ok := kvs[k]; ok {
+				test.AssertEqual(t, removed, v, "Removed wrong value.")
+			} else {
+				test.AssertNil(t, removed, "Removed a missing key.")
+			}
+			delete(kvs, k)
+		}
+
+		if i%1000 == 0 {
+			size, _ := checkRB(t, s, s.root)
+			test.AssertEqual(t, size, len(kvs), "Tree lost nodes.")
+			test.AssertFalse(t, s.root.Red, "Root is red.")
+		}
+	}
+
+	test.AssertEqual(t, s.Size(), len(kvs), "Wrong size.")
+	for k, v := range kvs {
+		test.AssertEqual(t, s.Locate(compInt{k}), v, "Retrieved wrong value.")
+	}
+	test.AssertFalse(t, s.leaf.Red, "The sentinel turned red.")
+}
+
+func TestCopyClearRedBlackTreeMap(t *testing.T) {
+	s := NewRedBlackTreeMap()
+	for _, k := range rand.Perm(500) {
+		s.Insert(compInt{k}, k)
+	}
+	c := s.Copy().(*RedBlackTreeMap)
+
+	size, _ := checkRB(t, c, c.root)
+	test.AssertEqual(t, size, 500, "Copy lost nodes.")
+	c.Remove(compInt{1})
+	s.Insert(compInt{1000}, 1000)
+	test.AssertEqual(t, s.Locate(compInt{1}), 1, "Copy shares nodes with the original.")
+	test.AssertNil(t, c.Locate(compInt{1000}), "Copy shares nodes with the original.")
+
+	s.Clear()
+	test.AssertTrue(t, s.Empty() && s.First() == nil, "Clear did not empty the dictionary.")
+	s.Insert(compInt{1}, 1)
+	test.AssertEqual(t, s.Locate(compInt{1}), 1, "A cleared dictionary is not usable.")
+}
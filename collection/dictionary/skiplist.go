@@ -0,0 +1,428 @@
+// This module implements a skip list backed Dictionary, conforming to
+// Dictionary, with the additional stipulation that all Keys must implement
+// collection.Comparer.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"math/rand"
+)
+
+// The tallest a skip list node may be. With p = 0.5, enough for 2^32 keys.
+const maxLevel = 32
+
+// The default probability of a skip list node reaching each next level.
+const DefaultProbability = 0.5
+
+// The node struct for the skip list. next[i] is the next node on level i.
+type snode struct {
+	K    collection.Comparer
+	V    interface{}
+	next []*snode
+}
+
+// A SkipListMap implements Dictionary, with the additional guarantee of
+// storing its KeyValues in sorted order, as defined by the Key's Compare()
+// method (Keys should implement collection.Comparer).
+//
+// It offers the same ordered behavior as TreeMap, with O(log n) expected
+// time operations, and a configurable probability p of each node being
+// promoted to the next level: a lower p uses less memory, at the cost of
+// longer searches.
+//
+// Behavior unspecified if a SkipListMap is not created using NewSkipListMap(),
+// NewSkipListMapUnsafe() or if SkipListMap.Init() / SkipListMap.InitUnsafe(),
+// is not first called on a new &SkipListMap{}.
+//
+type SkipListMap struct {
+	collection.Base
+	head  *snode
+	level int // The number of levels in use.
+	p     float64
+	rand  *rand.Rand
+}
+
+// Returns a pointer to a new SkipListMap with DefaultProbability.
+func NewSkipListMap() *SkipListMap {
+	s := &SkipListMap{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe SkipListMap with DefaultProbability.
+func NewSkipListMapUnsafe() *SkipListMap {
+	s := &SkipListMap{}
+	s.InitUnsafe()
+	return s
+}
+
+// Returns a pointer to a new SkipListMap with the given level probability.
+//
+// Panics if p is not strictly between 0 and 1.
+func NewSkipListMapWithProbability(p float64) *SkipListMap {
+	s := &SkipListMap{p: p}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe SkipListMap with the given level
+// probability.
+//
+// Panics if p is not strictly between 0 and 1.
+func NewSkipListMapWithProbabilityUnsafe(p float64) *SkipListMap {
+	s := &SkipListMap{p: p}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *SkipListMap) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *SkipListMap) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Returns the probability of a node reaching each next level.
+func (s *SkipListMap) Probability() float64 {
+	s.CheckInit()
+	return s.p
+}
+
+// Panic if key doesn't implement collection.Comparer
+func (s *SkipListMap) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	var update [maxLevel]*snode
+	n := s.findGreaterOrEqual(kc, &update)
+	if n != nil && kc.Compare(n.K) == 0 {
+		old := n.V
+		n.V = value
+		return old
+	}
+
+	level := s.randomLevel()
+	for i := s.level; i < level; i++ {
+		update[i] = s.head
+	}
+	if level > s.level {
+		s.level = level
+	}
+
+	n = &snode{K: kc, V: value, next: make([]*snode, level)}
+	for i := 0; i < level; i++ {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+	}
+	s.Sizeb += 1
+	return nil
+}
+
+func (s *SkipListMap) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	n := s.findGreaterOrEqual(kc, nil)
+	if n != nil && kc.Compare(n.K) == 0 {
+		return n.V
+	}
+	return nil
+}
+
+func (s *SkipListMap) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	var update [maxLevel]*snode
+	n := s.findGreaterOrEqual(kc, &update)
+	if n == nil || kc.Compare(n.K) != 0 {
+		return nil
+	}
+
+	for i := range n.next {
+		update[i].next[i] = n.next[i]
+	}
+	for s.level > 1 && s.head.next[s.level-1] == nil {
+		s.level -= 1
+	}
+	s.Sizeb -= 1
+	return n.V
+}
+
+// Will also Panic if any key doesn't implement collection.Comparer.
+func (s *SkipListMap) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, key := range keys {
+		kc := comparerKey(key)
+		n := s.findGreaterOrEqual(kc, nil)
+		if n == nil || kc.Compare(n.K) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a pointer to the KeyValue with the smallest key, or nil if this
+// SkipListMap is empty.
+func (s *SkipListMap) First() *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := s.head.next[0]; n != nil {
+		return &KeyValue{n.K, n.V}
+	}
+	return nil
+}
+
+// Returns a pointer to the KeyValue with the largest key, or nil if this
+// SkipListMap is empty.
+func (s *SkipListMap) Last() *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	n := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for n.next[i] != nil {
+			n = n.next[i]
+		}
+	}
+	if n == s.head {
+		return nil
+	}
+	return &KeyValue{n.K, n.V}
+}
+
+// Returns a pointer to the KeyValue with the smallest key greater than or
+// equal to the given key, or nil if there is none.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *SkipListMap) Ceiling(key interface{}) *KeyValue {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := s.findGreaterOrEqual(kc, nil); n != nil {
+		return &KeyValue{n.K, n.V}
+	}
+	return nil
+}
+
+// Returns a pointer to the KeyValue with the largest key less than or
+// equal to the given key, or nil if there is none.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *SkipListMap) Floor(key interface{}) *KeyValue {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	var update [maxLevel]*snode
+	if n := s.findGreaterOrEqual(kc, &update); n != nil && kc.Compare(n.K) == 0 {
+		return &KeyValue{n.K, n.V}
+	}
+	if update[0] == s.head {
+		return nil
+	}
+	return &KeyValue{update[0].K, update[0].V}
+}
+
+// Attempts to apply the given function to a pointer to the KeyValue of
+// every key from from, inclusive, to to, exclusive, in ascending order.
+// Stops once all those KeyValues have been processed, or once the function
+// returns false, whichever occurs first.
+//
+// Panics if either key is nil or doesn't implement collection.Comparer.
+func (s *SkipListMap) Range(from interface{}, to interface{}, f func(item interface{}) bool) bool {
+	s.CheckInit()
+	fc, tc := comparerKey(from), comparerKey(to)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for n := s.findGreaterOrEqual(fc, nil); n != nil && tc.Compare(n.K) > 0; n = n.next[0] {
+		if !f(&KeyValue{n.K, n.V}) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *SkipListMap) Copy() Dictionary {
+	s.CheckInit()
+
+	c := &SkipListMap{p: s.p}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	// Keys arrive in order, so every node is appended after the last.
+	var last [maxLevel]*snode
+	for i := range last {
+		last[i] = c.head
+	}
+	for n := s.head.next[0]; n != nil; n = n.next[0] {
+		cn := &snode{K: n.K, V: n.V, next: make([]*snode, len(n.next))}
+		for i := range cn.next {
+			last[i].next[i] = cn
+			last[i] = cn
+		}
+	}
+	c.level = s.level
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Maps over KeyValues, in ascending order of keys.
+func (s *SkipListMap) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	ok := true
+	for n := s.head.next[0]; n != nil && ok; n = n.next[0] {
+		ok = f(&KeyValue{n.K, n.V})
+	}
+	return ok
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of keys.
+func (s *SkipListMap) Slice() *[]interface{} {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	for n := s.head.next[0]; n != nil; n = n.next[0] {
+		slice = append(slice, &KeyValue{n.K, n.V})
+	}
+	return &slice
+}
+
+func (s *SkipListMap) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.head = &snode{next: make([]*snode, maxLevel)}
+	s.level = 1
+	s.Sizeb = 0
+}
+
+func (s *SkipListMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *SkipListMap) setup() {
+	if s.p == 0 {
+		s.p = DefaultProbability
+	}
+	if s.p <= 0 || s.p >= 1 {
+		log.Panic("Level probability must be between 0 and 1.")
+	}
+	s.head = &snode{next: make([]*snode, maxLevel)}
+	s.level = 1
+	s.rand = rand.New(rand.NewSource(rand.Int63()))
+}
+
+// Returns the first node with a key greater than or equal to the given key,
+// or nil if there is none. If update is non-nil, fills it with the last node
+// before that one on each level in use.
+func (s *SkipListMap) findGreaterOrEqual(k collection.Comparer, update *[maxLevel]*snode) *snode {
+	n := s.head
+	for i := s.level - 1; i >= 0; i-- {
+		for n.next[i] != nil && k.Compare(n.next[i].K) > 0 {
+			n = n.next[i]
+		}
+		if update != nil {
+			update[i] = n
+		}
+	}
+	return n.next[0]
+}
+
+// Returns a random level for a new node: 1, plus one more with probability p,
+// and so on, up to maxLevel.
+func (s *SkipListMap) randomLevel() int {
+	level := 1
+	for level < maxLevel && s.rand.Float64() < s.p {
+		level += 1
+	}
+	return level
+}
+
+// Returns the given key as a collection.Comparer.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func comparerKey(key interface{}) collection.Comparer {
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	kc, ok := key.(collection.Comparer)
+	if !ok {
+		log.Panic("Key doesn't implement collection.Comparer.")
+	}
+	return kc
+}
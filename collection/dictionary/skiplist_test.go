@@ -0,0 +1,140 @@
+// This module contains tests for skiplist.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"testing"
+)
+
+func TestNewEmptySkipListMap(t *testing.T) {
+	s := NewSkipListMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertEqual(t, s.Probability(), DefaultProbability, "Wrong probability.")
+	test.AssertTrue(t, s.First() == nil && s.Last() == nil, "Empty dictionary has ends.")
+}
+
+func TestOrderingSkipListMap(t *testing.T) {
+	s := NewSkipListMap()
+
+	for _, k := range []int{1, 3, 7, 2, 5} {
+		s.Insert(compInt{k}, k)
+	}
+
+	keys := []int{1, 2, 3, 5, 7}
+	for i, kv := range *s.Slice() {
+		test.AssertEqual(t, kv.(*KeyValue).Key.(compInt).i, keys[i], "Items returned out of order!")
+	}
+
+	test.AssertEqual(t, s.First().Key, compInt{1}, "Wrong first key.")
+	test.AssertEqual(t, s.Last().Key, compInt{7}, "Wrong last key.")
+	test.AssertEqual(t, s.Ceiling(compInt{4}).Key, compInt{5}, "Wrong ceiling.")
+	test.AssertEqual(t, s.Ceiling(compInt{5}).Key, compInt{5}, "Wrong ceiling.")
+	test.AssertTrue(t, s.Ceiling(compInt{8}) == nil, "Ceiling past the end.")
+	test.AssertEqual(t, s.Floor(compInt{4}).Key, compInt{3}, "Wrong floor.")
+	test.AssertEqual(t, s.Floor(compInt{3}).Key, compInt{3}, "Wrong floor.")
+	test.AssertTrue(t, s.Floor(compInt{0}) == nil, "Floor before the start.")
+}
+
+func TestInsertRemoveSkipListMap(t *testing.T) {
+	s := NewSkipListMap()
+
+	x := compInt{5}
+	y := compInt{10}
+
+	test.AssertNil(t, s.Insert(x, "hello"), "")
+	test.AssertNil(t, s.Insert(y, "world"), "")
+	test.AssertEqual(t, s.Insert(x, "goodbye"), "hello", "Wrong previous value.")
+	test.AssertEqual(t, s.Size(), 2, "Wrong size after inserting.")
+
+	test.AssertEqual(t, s.Remove(x), "goodbye", "Wrong removed value.")
+	test.AssertNil(t, s.Remove(x), "Removed a missing key.")
+	test.AssertFalse(t, s.Contains(x), "Removed element present.")
+	test.AssertTrue(t, s.Contains(y), "Remaining element missing.")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after removing.")
+}
+
+func TestProbabilitySkipListMap(t *testing.T) {
+	for _, p := range []float64{0.25, 0.75} {
+		s := NewSkipListMapWithProbability(p)
+		for i := 0; i < 1000; i++ {
+			s.Insert(compInt{i}, i)
+		}
+		for i := 0; i < 1000; i++ {
+			test.AssertEqual(t, s.Locate(compInt{i}), i, "Retrieved wrong value.")
+		}
+	}
+
+	defer func() {
+		test.AssertNonNil(t, recover(), "Invalid probability did not panic.")
+	}()
+	NewSkipListMapWithProbability(1)
+}
+
+func TestLargeRandomLoadSkipListMap(t *testing.T) {
+	s := NewSkipListMap()
+
+	kvs := make(map[int]int)
+
+	for i := 0; i < 10000; i++ {
+		k := rand.Intn(1000)
+		if rand.Intn(3) == 0 {
+			s.Remove(compInt{k})
+			delete(kvs, k)
+		} else {
+			s.Insert(compInt{k}, i)
+			kvs[k] = i
+		}
+	}
+
+	test.AssertEqual(t, s.Size(), len(kvs), "Wrong size.")
+	test.AssertEqual(t, len(*s.Slice()), len(kvs), "Wrong slice length.")
+	for k, v := range kvs {
+		test.AssertEqual(t, s.Locate(compInt{k}), v, "Retrieved wrong value.")
+	}
+}
+
+func TestCopySkipListMap(t *testing.T) {
+	s := NewSkipListMapWithProbability(0.25)
+
+	for i := 0; i < 100; i++ {
+		s.Insert(compInt{i}, i)
+	}
+	c := s.Copy().(*SkipListMap)
+	c.Remove(compInt{50})
+	c.Insert(compInt{100}, 100)
+
+	test.AssertEqual(t, c.Probability(), 0.25, "Copy has a different probability.")
+	test.AssertTrue(t, s.Contains(compInt{50}), "Copy shares state with original.")
+	test.AssertFalse(t, s.Contains(compInt{100}), "Copy shares state with original.")
+	test.AssertEqual(t, c.Size(), 100, "Wrong size of copy.")
+	for i := 0; i <= 100; i++ {
+		test.AssertEqual(t, c.Contains(compInt{i}), i != 50, "Copy has wrong entries.")
+	}
+}
+
+func TestClearSkipListMap(t *testing.T) {
+	s := NewSkipListMap()
+
+	s.Insert(compInt{1}, 1)
+	s.Clear()
+
+	test.AssertEqual(t, s.Size(), 0, "Wrong size after clearing.")
+	test.AssertFalse(t, s.Contains(compInt{1}), "Cleared element present.")
+}
+
+func BenchmarkLargeRandomLoadSkipListMap(t *testing.B) {
+	s := NewSkipListMap()
+
+	for i := 0; i < 100000; i++ {
+		k := rand.Intn(100000)
+		s.Insert(compInt{k}, i)
+	}
+}
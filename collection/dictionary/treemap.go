@@ -13,6 +13,7 @@ import (
 	"github.com/michalpiszczek/nonstdlib/util/math"
     "github.com/michalpiszczek/nonstdlib/collection/worklist"
 	"log"
+	"unsafe" // To order Join()'s locks.
 )
 
 // * * * * * * * * * * * * * * * * * * * * * * * * * *
@@ -27,13 +28,14 @@ type node struct {
 	K collection.Comparer
 	V interface{}
 	H int
+	S int // The number of nodes in the subtree rooted here.
 	C []*node
 }
 
 // Returns a pointer to a new node with the given key, value
 // and height.
 func newNode(k collection.Comparer, v interface{}, h int) *node {
-	n := &node{K: k, V: v, H: h, C: make([]*node, 2)}
+	n := &node{K: k, V: v, H: h, S: 1, C: make([]*node, 2)}
 	return n
 }
 
@@ -48,10 +50,28 @@ func height(n *node) int {
 	}
 }
 
+// Returns the number of nodes in the subtree rooted at the given node.
+func size(n *node) int {
+	if n == nil {
+		return 0
+	}
+	return n.S
+}
+
 // Sets the height of the given node to one greater than the max
-// height of its children.
+// height of its children, and its size to one more than theirs.
 func updateHeight(n *node) {
-	n.H = math.Max(height(child(n, 0)), height(child(n, 1))+1)
+	n.H = math.Max(height(child(n, 0)), height(child(n, 1))) + 1
+	n.S = size(child(n, 0)) + size(child(n, 1)) + 1
+}
+
+// Returns the leftmost (dir 0) or rightmost (dir 1) node in the subtree
+// rooted at the given node, which must not be nil.
+func extremeNode(n *node, dir int) *node {
+	for child(n, dir) != nil {
+		n = child(n, dir)
+	}
+	return n
 }
 
 // Returns a pointer to the 0th or 1st child of the given node.
@@ -61,10 +81,7 @@ func child(n *node, c int) *node {
 	return n.C[c]
 }
 
-// Returns 0 if k1 <= k2, 1 if k1 > k2.
-//
-// TODO: a better version of this could be elegantly reused in Insert()
-// to help clean up some of the garbage there.
+// Returns 0 if k1 < k2, 1 if k1 >= k2.
 func direction(k1 collection.Comparer, k2 interface{}) int {
 	return math.Signum(math.Signum(k1.Compare(k2) + 1))
 }
@@ -88,43 +105,223 @@ func rotate(p *node, dir1 int, dir2 int) *node {
 	return p
 }
 
-// Returns either the left most (if dir == 0), or right most
-// if (dir == 1) successor of n, and removes it from its parent
-// node. Don't pass in a leaf as n.
-func successor(n *node) *node {
-	if child(n, 0) == nil && child(n, 1) == nil {
+// Removes the left most node from the subtree rooted at n, rebalancing
+// on the way back up. Returns the new root of the subtree, and the removed
+// node, whose children are left unset.
+func removeMin(n *node) (*node, *node) {
+	if child(n, 0) == nil {
+		return child(n, 1), n
+	}
+
+	var min *node
+	n.C[0], min = removeMin(child(n, 0))
+	return rebalance(n), min
+}
+
+// Returns 0 of height(child(n, 0)) > height(child(n, 1)), or 1 otherwise.
+func tallestDir(n *node) int {
+	return math.Signum(math.Signum(height(child(n, 1))-height(child(n, 0))) + 1)
+}
+
+// Returns true if the difference in height between the given node's children
+// is no greater than 1. False otherwise.
+func balanced(n *node) bool {
+	return math.Abs(height(child(n, 0))-height(child(n, 1))) <= 1
+}
+
+// Updates the height of the given node and, if its children's heights
+// differ by more than 1, rotates it back into balance. Returns a pointer to
+// the new parent node of the resulting subtree.
+func rebalance(n *node) *node {
+	updateHeight(n)
+	if balanced(n) {
+		return n
+	}
+
+	dir1 := tallestDir(n)
+	c := child(n, dir1)
+	dir2 := dir1
+	if height(child(c, 1-dir1)) > height(child(c, dir1)) {
+		dir2 = 1 - dir1
+	}
+	return rotate(n, dir1, dir2)
+}
+
+// Inserts the given key and value into the subtree rooted at n. Returns the
+// new root of the subtree, and the value previously associated with the
+// given key, if any. added is true if the key was not already present.
+func insertNode(n *node, k collection.Comparer, v interface{}) (root *node, old interface{}, added bool) {
+	if n == nil {
+		return newNode(k, v, 0), nil, true
+	}
+
+	c := k.Compare(n.K)
+	if c == 0 {
+		old = n.V
+		n.V = v
+		return n, old, false
+	}
+
+	dir := direction(k, n.K)
+	n.C[dir], old, added = insertNode(child(n, dir), k, v)
+	return rebalance(n), old, added
+}
+
+// Removes the given key from the subtree rooted at n. Returns the new root
+// of the subtree, and the removed node, or nil if the key was not present.
+func removeNode(n *node, k collection.Comparer) (root *node, removed *node) {
+	if n == nil {
+		return nil, nil
+	}
+
+	c := k.Compare(n.K)
+	if c != 0 {
+		dir := direction(k, n.K)
+		n.C[dir], removed = removeNode(child(n, dir), k)
+		if removed == nil {
+			return n, nil
+		}
+		return rebalance(n), removed
+	}
+
+	if child(n, 0) == nil {
+		return child(n, 1), n
+	}
+	if child(n, 1) == nil {
+		return child(n, 0), n
+	}
+
+	// Replace n with its in-order successor.
+	right, succ := removeMin(child(n, 1))
+	succ.C[0] = child(n, 0)
+	succ.C[1] = right
+	return rebalance(succ), n
+}
+
+// Appends the nodes of the subtree rooted at n to the given slice, in order.
+func appendNodes(n *node, ns []*node) []*node {
+	if n == nil {
+		return ns
+	}
+	ns = appendNodes(child(n, 0), ns)
+	ns = append(ns, n)
+	return appendNodes(child(n, 1), ns)
+}
+
+// Links the given nodes, which must be in order, into a balanced subtree,
+// and returns its root.
+func buildBalanced(ns []*node) *node {
+	if len(ns) == 0 {
 		return nil
 	}
 
-	dir := tallestDir(n)
+	mid := len(ns) / 2
+	n := ns[mid]
+	n.C[0] = buildBalanced(ns[:mid])
+	n.C[1] = buildBalanced(ns[mid+1:])
+	updateHeight(n)
+	return n
+}
 
-	parent := n
-	current := child(n, dir)
+// Returns the node with the largest key less than or equal to (dir 0), or
+// the smallest key greater than or equal to (dir 1), the given key, in the
+// subtree rooted at n, or nil if there is none.
+func nearestNode(n *node, k collection.Comparer, dir int) *node {
+	var best *node
+	for n != nil {
+		c := k.Compare(n.K)
+		if c == 0 {
+			return n
+		}
+		if (c > 0) == (dir == 0) {
+			best = n
+		}
+		n = child(n, direction(k, n.K))
+	}
+	return best
+}
 
-	if current == nil {
-		parent.C[dir] = nil
-		return current
+// Applies the given function to the KeyValue of every node in the subtree
+// rooted at n with a key from from, inclusive, to to, exclusive, in order.
+// Skips subtrees wholly outside that range. Stops once the function returns
+// false, and returns false if it did.
+func rangeNodes(n *node, from collection.Comparer, to collection.Comparer, f func(item interface{}) bool) bool {
+	if n == nil {
+		return true
 	}
 
-	dir = math.Abs(dir - 1)
+	above, below := from.Compare(n.K) <= 0, to.Compare(n.K) > 0
+	if above && !rangeNodes(child(n, 0), from, to, f) {
+		return false
+	}
+	if above && below && !f(&KeyValue{n.K, n.V}) {
+		return false
+	}
+	if below {
+		return rangeNodes(child(n, 1), from, to, f)
+	}
+	return true
+}
 
-	for child(current, dir) != nil {
-		parent = current
-		current = child(current, dir)
+// Joins the subtrees rooted at l and r around the detached node k, where
+// every key in l is less than k's, and every key in r greater. Returns the
+// root of the resulting balanced subtree, in O(|height(l) - height(r)|).
+func joinNodes(l *node, k *node, r *node) *node {
+	if height(l) > height(r) {
+		return joinTall(l, k, r, 1)
 	}
-	parent.C[dir] = nil
-	return current
+	return joinTall(r, k, l, 0)
 }
 
-// Returns 0 of height(child(n, 0)) > height(child(n, 1)), or 1 otherwise.
-func tallestDir(n *node) int {
-	return math.Signum(math.Signum(height(child(n, 1))-height(child(n, 0))) + 1)
+// Joins the subtree rooted at t with the shorter subtree rooted at o, which
+// goes on t's dir side, around the detached node k. Walks down t's dir spine
+// to a node no more than one taller than o, hangs it and o off of k, and
+// rebalances on the way back up.
+func joinTall(t *node, k *node, o *node, dir int) *node {
+	if height(t) <= height(o)+1 {
+		k.C[1-dir] = t
+		k.C[dir] = o
+		updateHeight(k)
+		return k
+	}
+
+	t.C[dir] = joinTall(child(t, dir), k, o, dir)
+	return rebalance(t)
 }
 
-// Returns true if the difference in height between the given node's children
-// is no greater than 1. False otherwise.
-func balanced(n *node) bool {
-	return math.Abs(height(child(n, 0))-height(child(n, 1))) > 1
+// Joins the subtrees rooted at l and r, where every key in l is less than
+// every key in r. Returns the root of the resulting balanced subtree.
+func join2Nodes(l *node, r *node) *node {
+	if r == nil {
+		return l
+	}
+
+	r, min := removeMin(r)
+	min.C[0], min.C[1] = nil, nil
+	return joinNodes(l, min, r)
+}
+
+// Splits the subtree rooted at n into one holding the keys less than k, and
+// one holding the keys greater than or equal to k. Returns the roots of both
+// balanced subtrees, in O(height(n)).
+func splitNode(n *node, k collection.Comparer) (l *node, r *node) {
+	if n == nil {
+		return nil, nil
+	}
+
+	left, right := child(n, 0), child(n, 1)
+	n.C[0], n.C[1] = nil, nil
+
+	c := k.Compare(n.K)
+	if c == 0 {
+		return left, joinNodes(nil, n, right)
+	}
+	if c < 0 {
+		l, r = splitNode(left, k)
+		return l, joinNodes(r, n, right)
+	}
+	l, r = splitNode(right, k)
+	return joinNodes(left, n, l), r
 }
 
 // * * * * * * * * * * * * * * * * * * * * * * * * * *
@@ -159,6 +356,26 @@ func NewTreeMapUnsafe() *TreeMap {
 	return s
 }
 
+// Returns a pointer to a new TreeMap holding the given KeyValues, which must
+// be in strictly ascending order of their keys. Builds a balanced tree
+// directly, in O(n) time.
+//
+// Panics if the keys aren't in strictly ascending order, or any doesn't
+// implement collection.Comparer.
+func NewTreeMapFromSorted(kvs []*KeyValue) *TreeMap {
+	s := NewTreeMap()
+	s.load(kvs)
+	return s
+}
+
+// Returns a pointer to a new unsafe TreeMap holding the given KeyValues, as
+// NewTreeMapFromSorted() does.
+func NewTreeMapFromSortedUnsafe(kvs []*KeyValue) *TreeMap {
+	s := NewTreeMapUnsafe()
+	s.load(kvs)
+	return s
+}
+
 func (s *TreeMap) Init() {
 	s.InitBase()
 }
@@ -184,36 +401,13 @@ func (s *TreeMap) Insert(key interface{}, value interface{}) interface{} {
 		defer s.Lockb.Unlock()
 	}
 
-	current := s.root
-	visited := worklist.NewStackUnsafe()
-
-	for current != nil && kc.Compare(current.K) != 0 {
-		visited.Push(current)
-		direction := direction(kc, current.K)
-		current = child(current, direction)
-	}
-
-	if current == nil {
-		current = newNode(kc, value, 0)
-		for !visited.Empty() {
-			parent, _ := visited.Pop().(*node)
-			dir1 := direction(kc, parent.K)
-			parent.C[dir1] = current
-			if balanced(current) {
-				dir2 := tallestDir(current)
-				parent = rotate(parent, dir1, dir2)
-			}
-			updateHeight(parent)
-			current = parent
-		}
+	var old interface{}
+	var added bool
+	s.root, old, added = insertNode(s.root, kc, value)
+	if added {
 		s.Sizeb += 1
-		s.root = current
-	} else if kc.Compare(current.K) == 0 {
-		old := current.V
-		current.V = value
-		return old
 	}
-	return nil
+	return old
 }
 
 func (s *TreeMap) Locate(key interface{}) interface{} {
@@ -263,38 +457,14 @@ func (s *TreeMap) Remove(key interface{}) interface{} {
 		defer s.Lockb.Unlock()
 	}
 
-	current := s.root
-	visited := worklist.NewStackUnsafe()
-
-	for current != nil && kc.Compare(current.K) != 0 {
-		visited.Push(current)
-		direction := direction(kc, current.K)
-		current = child(current, direction)
-	}
-
-	if current == nil {
+	var removed *node
+	s.root, removed = removeNode(s.root, kc)
+	if removed == nil {
 		return nil
-	} else if kc.Compare(current.K) == 0 {
-		oldV := current.V
-		current = successor(current)
-		for !visited.Empty() {
-			parent, _ := visited.Pop().(*node)
-			dir1 := direction(kc, parent.K)
-			parent.C[dir1] = current
-			if current != nil && balanced(current) {
-				dir2 := tallestDir(current)
-				parent = rotate(parent, dir1, dir2)
-			}
-			updateHeight(parent)
-			current = parent
-		}
-
-		s.Sizeb -= 1
-		s.root = current
-
-		return oldV
 	}
-	return nil
+
+	s.Sizeb -= 1
+	return removed.V
 }
 
 // Will also Fatal() if any key doesn't implement collection.Comparer.
@@ -316,28 +486,20 @@ func (s *TreeMap) Contains(keys ...interface{}) bool {
 func (s *TreeMap) Copy() Dictionary {
     s.CheckInit()
 
-    var c *TreeMap
-    if s.Threadsafe() {
-        c = NewTreeMap()
-    } else {
-        c = NewTreeMapUnsafe()
-    }
-
-    // maybe?
-    if s.Size() == 0 {
-        return c
-    }
+    c := s.empty()
 
     if s.Threadsafe() {
         s.Lockb.RLock()
         defer s.Lockb.RUnlock()
     }
 
-    s.Map(func(kv interface{}) bool {
-        kvc, _ := kv.(*KeyValue)
-        c.Insert(kvc.Key, kvc.Value)
-        return true
-    })
+    // The nodes are already in order, so build the copy directly in O(n).
+    ns := appendNodes(s.root, make([]*node, 0, s.Sizeb))
+    for i, n := range ns {
+        ns[i] = newNode(n.K, n.V, 0)
+    }
+    c.root = buildBalanced(ns)
+    c.Sizeb = len(ns)
 
     return c
 }
@@ -384,13 +546,12 @@ func (s *TreeMap) Slice() *[]interface{} {
 		defer s.Lockb.RUnlock()
 	}
 
-	slice := make([]interface{}, 0, s.Size())
+	slice := make([]interface{}, 0, s.Sizeb)
 
-    s.Map(func(kv interface{}) bool {
-        kvc, _ := kv.(*KeyValue)
-        slice = append(slice, kvc)
-        return true
-    })
+	// Walks the nodes directly, as Map() would take the lock again.
+	for _, n := range appendNodes(s.root, make([]*node, 0, s.Sizeb)) {
+		slice = append(slice, &KeyValue{n.K, n.V})
+	}
 	return &slice
 }
 
@@ -406,6 +567,180 @@ func (s *TreeMap) Clear() {
 	s.Sizeb = 0
 }
 
+// Returns a pointer to the KeyValue with the smallest key, or nil if this
+// TreeMap is empty.
+func (s *TreeMap) First() *KeyValue {
+	return s.end(0)
+}
+
+// Returns a pointer to the KeyValue with the largest key, or nil if this
+// TreeMap is empty.
+func (s *TreeMap) Last() *KeyValue {
+	return s.end(1)
+}
+
+// Returns a pointer to the KeyValue with the largest key less than or equal
+// to the given key, or nil if there is none.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *TreeMap) Floor(key interface{}) *KeyValue {
+	return s.nearest(key, 0)
+}
+
+// Returns a pointer to the KeyValue with the smallest key greater than or
+// equal to the given key, or nil if there is none.
+//
+// Panics if the given key is nil or doesn't implement collection.Comparer.
+func (s *TreeMap) Ceiling(key interface{}) *KeyValue {
+	return s.nearest(key, 1)
+}
+
+// Attempts to apply the given function to a pointer to the KeyValue of
+// every key from from, inclusive, to to, exclusive, in ascending order.
+// Stops once all those KeyValues have been processed, or once the function
+// returns false, whichever occurs first.
+//
+// Panics if either key is nil or doesn't implement collection.Comparer.
+func (s *TreeMap) Range(from interface{}, to interface{}, f func(item interface{}) bool) bool {
+	s.CheckInit()
+	fc, tc := comparerKey(from), comparerKey(to)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return rangeNodes(s.root, fc, tc, f)
+}
+
+// Splits this TreeMap into two: one holding the keys less than the given key,
+// and one holding the keys greater than or equal to it. Both are as
+// thread-safe as this TreeMap, which is left empty. Runs in O(log n).
+func (s *TreeMap) Split(key interface{}) (*TreeMap, *TreeMap) {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	kc, ok := key.(collection.Comparer)
+	if !ok {
+		log.Panic("Key doesn't implement collection.Comparer.")
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	l, r := s.empty(), s.empty()
+	l.root, r.root = splitNode(s.root, kc)
+	l.Sizeb, r.Sizeb = size(l.root), size(r.root)
+
+	s.root = nil
+	s.Sizeb = 0
+	return l, r
+}
+
+// Returns a pointer to a new TreeMap, as thread-safe as a, holding the
+// KeyValues of both given TreeMaps, which are left empty. Every key in a must
+// be less than every key in b. Runs in O(log n).
+//
+// Panics if the key ranges of the given TreeMaps overlap.
+func Join(a *TreeMap, b *TreeMap) *TreeMap {
+	a.CheckInit()
+	b.CheckInit()
+	if a == b {
+		log.Panic("Cannot Join a TreeMap with itself.")
+	}
+
+	// Lock in address order, so Join(x, y) and Join(y, x) can't deadlock.
+	first, second := a, b
+	if uintptr(unsafe.Pointer(b)) < uintptr(unsafe.Pointer(a)) {
+		first, second = b, a
+	}
+	if first.Threadsafe() {
+		first.Lockb.Lock()
+		defer first.Lockb.Unlock()
+	}
+	if second.Threadsafe() {
+		second.Lockb.Lock()
+		defer second.Lockb.Unlock()
+	}
+
+	if a.root != nil && b.root != nil && extremeNode(a.root, 1).K.Compare(extremeNode(b.root, 0).K) >= 0 {
+		log.Panic("Cannot Join TreeMaps whose key ranges overlap.")
+	}
+
+	j := a.empty()
+	j.root = join2Nodes(a.root, b.root)
+	j.Sizeb = a.Sizeb + b.Sizeb
+
+	a.root, b.root = nil, nil
+	a.Sizeb, b.Sizeb = 0, 0
+	return j
+}
+
 func (s *TreeMap) String() string {
 	return fmt.Sprintf("%v", s.Slice())
 }
+
+// Replaces the contents of this TreeMap with a balanced tree of the given
+// KeyValues, which must be in strictly ascending order of their keys.
+func (s *TreeMap) load(kvs []*KeyValue) {
+	ns := make([]*node, len(kvs))
+	for i, kv := range kvs {
+		kc, ok := kv.Key.(collection.Comparer)
+		if !ok {
+			log.Panic("Key doesn't implement collection.Comparer.")
+		}
+		if i > 0 && kc.Compare(ns[i-1].K) <= 0 {
+			log.Panic("Keys are not in strictly ascending order.")
+		}
+		ns[i] = newNode(kc, kv.Value, 0)
+	}
+
+	s.root = buildBalanced(ns)
+	s.Sizeb = len(ns)
+}
+
+// Returns a new TreeMap, as thread-safe as this one, with no KeyValues.
+func (s *TreeMap) empty() *TreeMap {
+	if s.Threadsafe() {
+		return NewTreeMap()
+	}
+	return NewTreeMapUnsafe()
+}
+
+// Returns a pointer to the KeyValue with the smallest (dir 0) or largest
+// (dir 1) key, or nil if this TreeMap is empty.
+func (s *TreeMap) end(dir int) *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.root == nil {
+		return nil
+	}
+	n := extremeNode(s.root, dir)
+	return &KeyValue{n.K, n.V}
+}
+
+// Returns a pointer to the KeyValue with the largest key less than or equal
+// to (dir 0), or the smallest key greater than or equal to (dir 1), the given
+// key, or nil if there is none.
+func (s *TreeMap) nearest(key interface{}, dir int) *KeyValue {
+	s.CheckInit()
+	kc := comparerKey(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := nearestNode(s.root, kc, dir); n != nil {
+		return &KeyValue{n.K, n.V}
+	}
+	return nil
+}
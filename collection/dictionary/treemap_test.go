@@ -8,9 +8,13 @@
 package dictionary
 
 import (
+    "github.com/michalpiszczek/nonstdlib/util/math"
     "github.com/michalpiszczek/nonstdlib/util/test"
     "math/rand"
+    "runtime"
+    "sync"
     "testing"
+    "time"
 )
 
 // A wrapper for int that will implement Comparer.
@@ -178,6 +182,59 @@ func TestLargeRandomLoadTreeMap(t *testing.T) {
     }
 }
 
+// Checks that the subtree rooted at n is ordered, has correct heights and
+// sizes, and is balanced. Returns its size.
+func checkAVL(t *testing.T, n *node) int {
+    if n == nil {
+        return 0
+    }
+    for dir, c := range n.C {
+        if c != nil && direction(c.K, n.K) != dir {
+            t.Fatalf("Node %v is on the wrong side of %v.", c.K, n.K)
+        }
+    }
+    size := checkAVL(t, child(n, 0)) + checkAVL(t, child(n, 1)) + 1
+    if n.H != math.Max(height(child(n, 0)), height(child(n, 1)))+1 {
+        t.Fatalf("Node %v has the wrong height.", n.K)
+    }
+    if !balanced(n) {
+        t.Fatalf("Node %v is unbalanced.", n.K)
+    }
+    if n.S != size {
+        t.Fatalf("Node %v has the wrong size.", n.K)
+    }
+    return size
+}
+
+func TestLargeRandomRemoveTreeMap(t *testing.T) {
+    s := NewTreeMap()
+
+    kvs := make(map[int]int)
+
+    for i := 0; i < 10000; i++ {
+        k := rand.Intn(1000)
+        if rand.Intn(2) == 0 {
+            s.Insert(compInt{k}, i)
+            kvs[k] = i
+        } else {
+            r := s.Remove(compInt{k})
+            if v, ok := kvs[k]; ok {
+                test.AssertEqual(t, r, v, "Removed wrong value.")
+            } else {
+                test.AssertNil(t, r, "Removed a missing key.")
+            }
+            delete(kvs, k)
+        }
+    }
+
+    test.AssertEqual(t, s.Size(), len(kvs), "Wrong size after removing.")
+    test.AssertEqual(t, checkAVL(t, s.root), len(kvs), "Tree lost nodes.")
+
+    for k, v := range kvs {
+        test.AssertEqual(t, s.Locate(compInt{k}), v, "Retrieved wrong value.")
+    }
+}
+
 func BenchmarkLargeRandomLoadTreeMap(t *testing.B) {
     s := NewTreeMap()
 
@@ -198,3 +255,184 @@ func BenchmarkLargeRandomLoadTreeMap(t *testing.B) {
         }
     }
 }
+
+func TestNewTreeMapFromSorted(t *testing.T) {
+    for _, n := range []int{0, 1, 2, 7, 1000} {
+        kvs := make([]*KeyValue, n)
+        for i := range kvs {
+            kvs[i] = &KeyValue{compInt{2 * i}, i}
+        }
+        s := NewTreeMapFromSorted(kvs)
+
+        test.AssertEqual(t, s.Size(), n, "Wrong size after a bulk load.")
+        test.AssertEqual(t, checkAVL(t, s.root), n, "Bulk load lost nodes.")
+        for i := 0; i < n; i++ {
+            test.AssertEqual(t, s.Locate(compInt{2 * i}), i, "Retrieved wrong value.")
+        }
+
+        s.Insert(compInt{1}, -1)
+        test.AssertEqual(t, checkAVL(t, s.root), n+1, "Insert after a bulk load lost nodes.")
+    }
+}
+
+func TestNewTreeMapFromUnsortedPanics(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Error("Loading keys out of order did not panic.")
+        }
+    }()
+    NewTreeMapFromSortedUnsafe([]*KeyValue{{compInt{2}, 0}, {compInt{1}, 1}})
+}
+
+func TestCopyTreeMap(t *testing.T) {
+    s := NewTreeMap()
+    for i := 0; i < 1000; i++ {
+        s.Insert(compInt{rand.Intn(500)}, i)
+    }
+    c := s.Copy().(*TreeMap)
+
+    test.AssertEqual(t, c.Size(), s.Size(), "Copy has the wrong size.")
+    test.AssertEqual(t, checkAVL(t, c.root), s.Size(), "Copy lost nodes.")
+    s.Map(func(kv interface{}) bool {
+        kvc := kv.(*KeyValue)
+        test.AssertEqual(t, c.Locate(kvc.Key), kvc.Value, "Copy holds the wrong value.")
+        return true
+    })
+
+    c.Insert(compInt{1000}, -1)
+    s.Remove(compInt{0})
+    test.AssertNil(t, s.Locate(compInt{1000}), "Copy shares nodes with the original.")
+    test.AssertEqual(t, checkAVL(t, s.root), s.Size(), "Original lost nodes.")
+}
+
+// Returns a new TreeMap holding each of the given keys, mapped to itself.
+func treeMapOf(keys ...int) *TreeMap {
+    s := NewTreeMap()
+    for _, k := range keys {
+        s.Insert(compInt{k}, k)
+    }
+    return s
+}
+
+// Returns the keys of the given TreeMap, in order.
+func keysOf(s *TreeMap) []int {
+    keys := make([]int, 0, s.Size())
+    s.Map(func(kv interface{}) bool {
+        keys = append(keys, kv.(*KeyValue).Key.(compInt).i)
+        return true
+    })
+    return keys
+}
+
+func TestSplitTreeMap(t *testing.T) {
+    for i := 0; i < 100; i++ {
+        keys := rand.Perm(200)[:rand.Intn(200)]
+        s := treeMapOf(keys...)
+        at := rand.Intn(220) - 10
+
+        below, above := 0, 0
+        for _, k := range keys {
+            if k < at {
+                below++
+            } else {
+                above++
+            }
+        }
+
+        l, r := s.Split(compInt{at})
+        test.AssertEqual(t, s.Size(), 0, "Split did not empty the original.")
+        test.AssertEqual(t, l.Size(), below, "Lower half has the wrong size.")
+        test.AssertEqual(t, r.Size(), above, "Upper half has the wrong size.")
+        test.AssertEqual(t, checkAVL(t, l.root), below, "Lower half lost nodes.")
+        test.AssertEqual(t, checkAVL(t, r.root), above, "Upper half lost nodes.")
+
+        for _, k := range keysOf(l) {
+            if k >= at {
+                t.Fatalf("Key %d is in the lower half of a split at %d.", k, at)
+            }
+        }
+        for _, k := range keysOf(r) {
+            if k < at {
+                t.Fatalf("Key %d is in the upper half of a split at %d.", k, at)
+            }
+        }
+    }
+}
+
+func TestJoinTreeMap(t *testing.T) {
+    for i := 0; i < 100; i++ {
+        // Join trees of very different heights, in both directions.
+        n, m := rand.Intn(300), rand.Intn(10)
+        if i%2 == 0 {
+            n, m = m, n
+        }
+        a, b := NewTreeMap(), NewTreeMap()
+        for k := 0; k < n; k++ {
+            a.Insert(compInt{k}, k)
+        }
+        for k := n; k < n+m; k++ {
+            b.Insert(compInt{k}, k)
+        }
+
+        j := Join(a, b)
+        test.AssertEqual(t, a.Size()+b.Size(), 0, "Join did not empty its operands.")
+        test.AssertEqual(t, j.Size(), n+m, "Join has the wrong size.")
+        test.AssertEqual(t, checkAVL(t, j.root), n+m, "Join lost nodes.")
+        for k := 0; k < n+m; k++ {
+            test.AssertEqual(t, j.Locate(compInt{k}), k, "Retrieved wrong value.")
+        }
+    }
+}
+
+func TestSplitJoinRoundTripTreeMap(t *testing.T) {
+    s := treeMapOf(rand.Perm(500)...)
+
+    l, r := s.Split(compInt{250})
+    test.AssertEqual(t, r.Locate(compInt{250}), 250, "The split key is not in the upper half.")
+
+    j := Join(l, r)
+    test.AssertEqual(t, checkAVL(t, j.root), 500, "Rejoined tree lost nodes.")
+    keys := keysOf(j)
+    for i, k := range keys {
+        if k != i {
+            t.Fatalf("Rejoined tree holds %d at position %d.", k, i)
+        }
+    }
+}
+
+func TestJoinOverlappingTreeMapPanics(t *testing.T) {
+    defer func() {
+        if recover() == nil {
+            t.Error("Joining overlapping TreeMaps did not panic.")
+        }
+    }()
+    Join(treeMapOf(1, 5), treeMapOf(3, 7))
+}
+
+func TestJoinBothWaysConcurrentlyTreeMap(t *testing.T) {
+    // The two goroutines must really run at once to interleave their locks.
+    defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+    x, y := NewTreeMap(), NewTreeMap()
+
+    done := make(chan bool)
+    go func() {
+        var wg sync.WaitGroup
+        for _, ab := range [][2]*TreeMap{{x, y}, {y, x}} {
+            wg.Add(1)
+            go func(a *TreeMap, b *TreeMap) {
+                defer wg.Done()
+                for i := 0; i < 100000; i++ {
+                    Join(a, b)
+                }
+            }(ab[0], ab[1])
+        }
+        wg.Wait()
+        close(done)
+    }()
+
+    select {
+    case <-done:
+    case <-time.After(10 * time.Second):
+        t.Fatal("Join(x, y) and Join(y, x) deadlocked.")
+    }
+}
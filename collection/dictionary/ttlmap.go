@@ -0,0 +1,547 @@
+// This module implements a TTLMap, conforming to the Dictionary interface,
+// with the additional ability to associate a time-to-live with each entry.
+//
+// Expired entries are evicted lazily, whenever they are accessed, and
+// optionally by a background sweeper driven by a min-heap of deadlines.
+
+package dictionary
+
+import (
+	"container/heap"
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"time"
+)
+
+// A Clock tells a TTLMap what time it is. Tests may inject their own.
+type Clock interface {
+
+	// Returns the current time.
+	Now() time.Time
+}
+
+// The Clock used by TTLMaps unless told otherwise.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// An entry in a TTLMap. Entries without a deadline never expire, and are
+// not kept in the deadline heap (index == -1).
+type ttlEntry struct {
+	key      interface{}
+	value    interface{}
+	deadline time.Time
+	expires  bool
+	index    int
+}
+
+// A min-heap of entries, ordered by deadline. Implements heap.Interface.
+type deadlineHeap []*ttlEntry
+
+func (h deadlineHeap) Len() int {
+	return len(h)
+}
+
+func (h deadlineHeap) Less(i, j int) bool {
+	return h[i].deadline.Before(h[j].deadline)
+}
+
+func (h deadlineHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *deadlineHeap) Push(x interface{}) {
+	e, _ := x.(*ttlEntry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+func (h *deadlineHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	e := old[n-1]
+	old[n-1] = nil
+	e.index = -1
+	*h = old[:n-1]
+	return e
+}
+
+// A TTLMap implements Dictionary, with the additional ability to Insert
+// entries that expire after a given time-to-live.
+//
+// Entries inserted through Insert() never expire. Entries inserted through
+// InsertWithTTL() are evicted once their deadline has passed, either when
+// they are next accessed, when Expire() is called, or by the background
+// sweeper started with StartSweeper().
+//
+// A TTLMap created by NewTTLMap() locks on every call, so it may be used
+// alongside its own sweeper, and from any number of goroutines.
+//
+// Behavior unspecified if a TTLMap is not created using NewTTLMap(), NewTTLMapUnsafe()
+// or if TTLMap.Init() / TTLMap.InitUnsafe(), is not first called on a new &TTLMap{}.
+//
+type TTLMap struct {
+	collection.Base
+	m        map[interface{}]*ttlEntry
+	h        deadlineHeap
+	clock    Clock
+	onExpire func(key interface{}, value interface{})
+	stop     chan struct{}
+	done     chan struct{}
+}
+
+// Returns a pointer to a new TTLMap.
+func NewTTLMap() *TTLMap {
+	s := &TTLMap{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe TTLMap.
+func NewTTLMapUnsafe() *TTLMap {
+	s := &TTLMap{}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *TTLMap) Init() {
+	s.InitBase()
+
+	s.m = make(map[interface{}]*ttlEntry)
+	s.h = make(deadlineHeap, 0)
+	s.clock = systemClock{}
+}
+
+func (s *TTLMap) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.m = make(map[interface{}]*ttlEntry)
+	s.h = make(deadlineHeap, 0)
+	s.clock = systemClock{}
+}
+
+// Replaces the Clock this TTLMap uses to decide whether entries have expired.
+//
+// Panics if the given Clock is nil.
+func (s *TTLMap) SetClock(c Clock) {
+	s.CheckInit()
+	if c == nil {
+		log.Panic("Nil clock.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.clock = c
+}
+
+// Registers a function to be called with the key and value of every entry
+// evicted because it expired. Entries removed through Remove() or Clear()
+// are not reported. Pass nil to stop receiving callbacks.
+//
+// The function is called without this TTLMap's lock held, so it may safely
+// call back into this TTLMap.
+func (s *TTLMap) OnExpire(f func(key interface{}, value interface{})) {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.onExpire = f
+}
+
+func (s *TTLMap) Insert(key interface{}, value interface{}) interface{} {
+	return s.insert(key, value, 0, false)
+}
+
+// Inserts the given value associated with the given key into this TTLMap,
+// to be evicted once the given ttl has elapsed. Returns the previous value
+// associated with that key, or nil, if none existed.
+//
+// Panics if the given key or value are nil, or if the given ttl is not positive.
+func (s *TTLMap) InsertWithTTL(key interface{}, value interface{}, ttl time.Duration) interface{} {
+	if ttl <= 0 {
+		log.Panic("Non-positive TTL.")
+	}
+	return s.insert(key, value, ttl, true)
+}
+
+func (s *TTLMap) insert(key interface{}, value interface{}, ttl time.Duration, expires bool) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+	if value == nil {
+		log.Panic("Nil value.")
+	}
+
+	fire := s.lockAndCollect(key)
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	var old interface{}
+	if e, ok := s.m[key]; ok {
+		old = e.value
+		s.unlink(e)
+	}
+
+	e := &ttlEntry{key: key, value: value, expires: expires, index: -1}
+	if expires {
+		e.deadline = s.clock.Now().Add(ttl)
+		heap.Push(&s.h, e)
+	}
+	s.m[key] = e
+	s.Sizeb += 1
+
+	return old
+}
+
+func (s *TTLMap) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+
+	fire := s.lockAndCollect(key)
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	e, ok := s.m[key]
+	if !ok {
+		return nil
+	}
+	return e.value
+}
+
+// Returns the time remaining before the entry for the given key expires.
+// The bool returned is false if there is no entry for the given key. An entry
+// that never expires reports a negative duration and true.
+//
+// Panics if the given key is nil.
+func (s *TTLMap) TTL(key interface{}) (time.Duration, bool) {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+
+	fire := s.lockAndCollect(key)
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	e, ok := s.m[key]
+	if !ok {
+		return 0, false
+	}
+	if !e.expires {
+		return -1, true
+	}
+	return e.deadline.Sub(s.clock.Now()), true
+}
+
+func (s *TTLMap) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	if key == nil {
+		log.Panic("Nil key.")
+	}
+
+	fire := s.lockAndCollect(key)
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	e, ok := s.m[key]
+	if !ok {
+		return nil
+	}
+
+	s.unlink(e)
+	return e.value
+}
+
+func (s *TTLMap) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+	for _, key := range keys {
+		if key == nil {
+			log.Panic("Nil key.")
+		}
+	}
+
+	fire := s.lockAndCollect(keys...)
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	for _, key := range keys {
+		if _, ok := s.m[key]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Evicts every expired entry from this TTLMap, and returns how many
+// entries were evicted.
+func (s *TTLMap) Expire() int {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+	}
+	expired := s.collect()
+	fire := s.notifier(expired)
+	if s.Threadsafe() {
+		s.Lockb.Unlock()
+	}
+	fire()
+
+	return len(expired)
+}
+
+// Starts a goroutine that calls Expire() every given interval, until
+// StopSweeper() is called. The sweeper always takes this TTLMap's lock,
+// so an unsafe TTLMap, from NewTTLMapUnsafe(), should be accessed under
+// Lock() while it runs.
+//
+// Panics if the given interval is not positive, or if a sweeper is
+// already running.
+func (s *TTLMap) StartSweeper(interval time.Duration) {
+	s.CheckInit()
+	if interval <= 0 {
+		log.Panic("Non-positive sweep interval.")
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if s.stop != nil {
+		log.Panic("Sweeper already running.")
+	}
+
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	s.stop = stop
+	s.done = done
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				s.sweep()
+			}
+		}
+	}()
+}
+
+// Stops the sweeper started by StartSweeper(), blocking until it has
+// exited. Does nothing if no sweeper is running.
+func (s *TTLMap) StopSweeper() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+	}
+	stop, done := s.stop, s.done
+	s.stop = nil
+	s.done = nil
+	if s.Threadsafe() {
+		s.Lockb.Unlock()
+	}
+
+	if stop == nil {
+		return
+	}
+	close(stop)
+	<-done
+}
+
+// The body of the sweeper. Unlike Expire(), always locks, as the sweeper
+// runs concurrently with the owner of an unsafe TTLMap.
+func (s *TTLMap) sweep() {
+	s.Lockb.Lock()
+	fire := s.notifier(s.collect())
+	s.Lockb.Unlock()
+	fire()
+}
+
+// Size, not counting any entries that have expired.
+func (s *TTLMap) Size() int {
+	s.CheckInit()
+
+	fire := s.lockAndCollect()
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	return s.Sizeb
+}
+
+// Returns true if this TTLMap holds no unexpired entries.
+func (s *TTLMap) Empty() bool {
+	return s.Size() == 0
+}
+
+func (s *TTLMap) Copy() Dictionary {
+	s.CheckInit()
+
+	var c *TTLMap
+	if s.Threadsafe() {
+		c = NewTTLMap()
+	} else {
+		c = NewTTLMapUnsafe()
+	}
+
+	fire := s.lockAndCollect()
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	c.clock = s.clock
+	c.onExpire = s.onExpire
+	for k, e := range s.m {
+		ce := &ttlEntry{key: k, value: e.value, deadline: e.deadline, expires: e.expires, index: -1}
+		if ce.expires {
+			heap.Push(&c.h, ce)
+		}
+		c.m[k] = ce
+	}
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Maps over KeyValues of unexpired entries.
+func (s *TTLMap) Map(f func(interface{}) bool) bool {
+	s.CheckInit()
+
+	fire := s.lockAndCollect()
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	ok := true
+	for k, e := range s.m {
+		if ok = f(&KeyValue{k, e.value}); !ok {
+			break
+		}
+	}
+	return ok
+}
+
+// Returns a slice of pointers to KeyValue structs, for unexpired entries.
+func (s *TTLMap) Slice() *[]interface{} {
+	s.CheckInit()
+
+	fire := s.lockAndCollect()
+	defer fire()
+	if s.Threadsafe() {
+		defer s.Lockb.Unlock()
+	}
+
+	slice := make([]interface{}, 0, len(s.m))
+	for k, e := range s.m {
+		slice = append(slice, &KeyValue{k, e.value})
+	}
+	return &slice
+}
+
+func (s *TTLMap) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.m = make(map[interface{}]*ttlEntry)
+	s.h = make(deadlineHeap, 0)
+	s.Sizeb = 0
+}
+
+func (s *TTLMap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Takes the write lock if this TTLMap is thread-safe, then evicts expired
+// entries: all of them if no keys are given, otherwise only those for the
+// given keys. Returns a function reporting the evicted entries, to be called
+// once the lock has been released.
+//
+// Eviction mutates, so even lookups take the write lock.
+func (s *TTLMap) lockAndCollect(keys ...interface{}) func() {
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+	}
+
+	if len(keys) == 0 {
+		return s.notifier(s.collect())
+	}
+
+	now := s.clock.Now()
+	var expired []*ttlEntry
+	for _, key := range keys {
+		if e, ok := s.m[key]; ok && e.expires && !now.Before(e.deadline) {
+			s.unlink(e)
+			expired = append(expired, e)
+		}
+	}
+	return s.notifier(expired)
+}
+
+// Pops every expired entry off the deadline heap, and removes it.
+func (s *TTLMap) collect() []*ttlEntry {
+	now := s.clock.Now()
+	var expired []*ttlEntry
+	for len(s.h) > 0 && !now.Before(s.h[0].deadline) {
+		e := s.h[0]
+		s.unlink(e)
+		expired = append(expired, e)
+	}
+	return expired
+}
+
+// Removes the given entry from the map and, if present, the deadline heap.
+func (s *TTLMap) unlink(e *ttlEntry) {
+	if e.index >= 0 {
+		heap.Remove(&s.h, e.index)
+	}
+	delete(s.m, e.key)
+	s.Sizeb -= 1
+}
+
+// Returns a function reporting the given expired entries to the OnExpire()
+// callback, if any. Must be called with the lock held; the returned function
+// must be called without it.
+func (s *TTLMap) notifier(expired []*ttlEntry) func() {
+	f := s.onExpire
+	return func() {
+		if f == nil {
+			return
+		}
+		for _, e := range expired {
+			f(e.key, e.value)
+		}
+	}
+}
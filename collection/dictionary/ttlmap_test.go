@@ -0,0 +1,264 @@
+// This module contains tests for ttlmap.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"sync"
+	"testing"
+	"time"
+)
+
+// A Clock that only moves when told to.
+type fakeClock struct {
+	lock sync.Mutex
+	now  time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Unix(0, 0)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newFakeTTLMap() (*TTLMap, *fakeClock) {
+	s := NewTTLMap()
+	c := newFakeClock()
+	s.SetClock(c)
+	return s, c
+}
+
+func TestNewEmptyTTLMap(t *testing.T) {
+	s := NewTTLMap()
+
+	test.AssertEqual(t, s.Size(), 0, "New dictionary has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+}
+
+func TestInsertNoTTLTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	old := s.Insert("a", 1)
+	test.AssertNil(t, old, "")
+
+	c.Advance(time.Hour)
+
+	test.AssertEqual(t, s.Locate("a"), 1, "Entry without TTL expired.")
+	ttl, ok := s.TTL("a")
+	test.AssertTrue(t, ok, "Entry without TTL missing.")
+	test.AssertTrue(t, ttl < 0, "Entry without TTL reports a deadline.")
+}
+
+func TestInsertWithTTLExpiresLazilyTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	s.InsertWithTTL("a", 1, time.Second)
+	s.InsertWithTTL("b", 2, 2*time.Second)
+
+	test.AssertEqual(t, s.Locate("a"), 1, "Entry expired early.")
+
+	c.Advance(time.Second)
+
+	test.AssertNil(t, s.Locate("a"), "Entry did not expire.")
+	test.AssertFalse(t, s.Contains("a"), "Expired entry present.")
+	test.AssertTrue(t, s.Contains("b"), "Entry expired early.")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after expiry.")
+
+	c.Advance(time.Second)
+
+	test.AssertEqual(t, s.Size(), 0, "Wrong size after expiry.")
+	test.AssertTrue(t, s.Empty(), "Dictionary not empty after expiry.")
+}
+
+func TestTTLTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	s.InsertWithTTL("a", 1, 10*time.Second)
+	c.Advance(4 * time.Second)
+
+	ttl, ok := s.TTL("a")
+	test.AssertTrue(t, ok, "Entry missing.")
+	test.AssertEqual(t, ttl, 6*time.Second, "Wrong TTL.")
+
+	_, ok = s.TTL("b")
+	test.AssertFalse(t, ok, "Missing entry reports a TTL.")
+}
+
+func TestReinsertResetsTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	s.InsertWithTTL("a", 1, time.Second)
+	c.Advance(500 * time.Millisecond)
+	old := s.InsertWithTTL("a", 2, time.Second)
+	c.Advance(700 * time.Millisecond)
+
+	test.AssertEqual(t, old, 1, "Wrong previous value.")
+	test.AssertEqual(t, s.Locate("a"), 2, "Reinserted entry expired early.")
+	test.AssertEqual(t, s.Size(), 1, "Wrong size after reinserting.")
+
+	s.Insert("a", 3)
+	c.Advance(time.Hour)
+
+	test.AssertEqual(t, s.Locate("a"), 3, "Entry without TTL expired.")
+}
+
+func TestRemoveTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	expired := 0
+	s.OnExpire(func(k interface{}, v interface{}) {
+		expired += 1
+	})
+
+	s.InsertWithTTL("a", 1, time.Second)
+	old := s.Remove("a")
+	c.Advance(time.Second)
+
+	test.AssertEqual(t, old, 1, "Wrong removed value.")
+	test.AssertEqual(t, s.Expire(), 0, "Removed entry expired.")
+	test.AssertEqual(t, expired, 0, "Removed entry reported as expired.")
+}
+
+func TestOnExpireTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	expired := make(map[interface{}]interface{})
+	s.OnExpire(func(k interface{}, v interface{}) {
+		expired[k] = v
+		// Calling back in must not deadlock.
+		s.Contains(k)
+	})
+
+	s.InsertWithTTL("a", 1, time.Second)
+	s.InsertWithTTL("b", 2, 3*time.Second)
+	s.InsertWithTTL("c", 3, 2*time.Second)
+
+	c.Advance(2 * time.Second)
+
+	test.AssertEqual(t, s.Expire(), 2, "Wrong number of entries expired.")
+	test.AssertEqual(t, len(expired), 2, "Wrong number of entries reported.")
+	test.AssertEqual(t, expired["a"], 1, "Expired entry not reported.")
+	test.AssertEqual(t, expired["c"], 3, "Expired entry not reported.")
+	test.AssertTrue(t, s.Contains("b"), "Entry expired early.")
+}
+
+func TestMapSkipsExpiredTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	s.InsertWithTTL("a", 1, time.Second)
+	s.Insert("b", 2)
+	c.Advance(time.Second)
+
+	test.AssertEqual(t, len(*s.Slice()), 1, "Slice includes expired entries.")
+	s.Map(func(kv interface{}) bool {
+		test.AssertEqual(t, kv.(*KeyValue).Key, "b", "Map visited an expired entry.")
+		return true
+	})
+}
+
+func TestCopyTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	s.InsertWithTTL("a", 1, time.Second)
+	s.Insert("b", 2)
+
+	cpy := s.Copy()
+	c.Advance(time.Second)
+
+	test.AssertEqual(t, cpy.Size(), 1, "Copy lost its deadlines.")
+	test.AssertFalse(t, cpy.Contains("a"), "Copied entry did not expire.")
+	test.AssertTrue(t, s.Contains("b"), "Copied entry missing.")
+}
+
+func TestClearTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	s.InsertWithTTL("a", 1, time.Second)
+	s.Insert("b", 2)
+	s.Clear()
+	c.Advance(time.Second)
+
+	test.AssertEqual(t, s.Size(), 0, "Wrong size after clearing.")
+	test.AssertEqual(t, s.Expire(), 0, "Cleared entry expired.")
+}
+
+func TestSweeperTTLMap(t *testing.T) {
+	s, c := newFakeTTLMap()
+
+	evicted := make(chan interface{}, 1)
+	s.OnExpire(func(k interface{}, v interface{}) {
+		evicted <- k
+	})
+
+	s.InsertWithTTL("a", 1, time.Second)
+	s.StartSweeper(time.Millisecond)
+	defer s.StopSweeper()
+
+	c.Advance(time.Second)
+
+	select {
+	case k := <-evicted:
+		test.AssertEqual(t, k, "a", "Sweeper evicted the wrong entry.")
+	case <-time.After(5 * time.Second):
+		t.Fatal("Sweeper did not evict the expired entry.")
+	}
+}
+
+func TestStopSweeperTwiceTTLMap(t *testing.T) {
+	s := NewTTLMap()
+
+	s.StartSweeper(time.Millisecond)
+	s.StopSweeper()
+	s.StopSweeper()
+	s.StartSweeper(time.Millisecond)
+	s.StopSweeper()
+}
+
+func TestSweeperWithInsertsTTLMap(t *testing.T) {
+	s := NewTTLMap()
+	test.AssertTrue(t, s.Threadsafe(), "NewTTLMap() is not thread-safe.")
+
+	s.StartSweeper(time.Millisecond)
+	defer s.StopSweeper()
+
+	// Run with -race: the sweeper must not touch the map while these do.
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 2000; i++ {
+				s.InsertWithTTL(g*2000+i, i, time.Microsecond)
+				s.Locate(g*2000 + i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	time.Sleep(10 * time.Millisecond)
+	test.AssertEqual(t, s.Size(), 0, "Sweeper left expired entries.")
+}
+
+func TestLockThreadsafeTTLMapPanics(t *testing.T) {
+	s := NewTTLMap()
+	defer func() {
+		if recover() == nil {
+			t.Error("Locking a thread-safe TTLMap did not panic.")
+		}
+	}()
+	s.Lock()
+}
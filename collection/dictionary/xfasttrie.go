@@ -0,0 +1,437 @@
+// This module implements an x-fast trie backed Dictionary, conforming to
+// Dictionary, with the additional stipulation that all Keys must be uint64s.
+//
+// See Willard, "Log-logarithmic worst-case range queries are possible in
+// space Θ(N)", 1983.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+)
+
+// The number of bits in a key, and so the depth of an x-fast trie.
+const keyBits = 64
+
+// The node struct for the x-fast trie.
+//
+// Internal nodes use children, and desc: if a node is missing its left
+// child, desc is the leaf with the smallest key under its right child, and if
+// it is missing its right child, desc is the leaf with the largest key under
+// its left child.
+//
+// Leaves use key, value, and prev and next, which link all the leaves in
+// order.
+type xnode struct {
+	children [2]*xnode
+	desc     *xnode
+
+	key        uint64
+	value      interface{}
+	prev, next *xnode
+}
+
+// An XFastTrie implements Dictionary, with the additional guarantee of
+// storing its KeyValues in ascending order of their keys, which must be
+// uint64s.
+//
+// Locate() and Contains() take O(1) time, Predecessor() and Successor()
+// O(log log U) time, where U is 2^64, and Insert() and Remove() O(log U)
+// time. An XFastTrie uses O(n log U) space. See YFastTrie for one that uses
+// O(n) space, and inserts and removes in O(log log U) amortized time.
+//
+// Behavior unspecified if an XFastTrie is not created using NewXFastTrie(),
+// NewXFastTrieUnsafe() or if XFastTrie.Init() / XFastTrie.InitUnsafe(),
+// is not first called on a new &XFastTrie{}.
+//
+type XFastTrie struct {
+	collection.Base
+	levels     [keyBits + 1]map[uint64]*xnode // prefix length -> prefix -> node
+	head, tail *xnode
+}
+
+// Returns a pointer to a new XFastTrie.
+func NewXFastTrie() *XFastTrie {
+	s := &XFastTrie{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe XFastTrie.
+func NewXFastTrieUnsafe() *XFastTrie {
+	s := &XFastTrie{}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *XFastTrie) Init() {
+	s.InitBase()
+
+	s.reset()
+}
+
+func (s *XFastTrie) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.reset()
+}
+
+// Panics if key isn't a uint64.
+func (s *XFastTrie) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	k := uint64Key(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if leaf := s.leaf(k); leaf != nil {
+		old := leaf.value
+		leaf.value = value
+		return old
+	}
+
+	s.insert(k, value)
+	return nil
+}
+
+func (s *XFastTrie) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	k := uint64Key(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if leaf := s.leaf(k); leaf != nil {
+		return leaf.value
+	}
+	return nil
+}
+
+func (s *XFastTrie) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	k := uint64Key(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if leaf := s.leaf(k); leaf != nil {
+		s.remove(leaf)
+		return leaf.value
+	}
+	return nil
+}
+
+// Will also Panic if any key isn't a uint64.
+func (s *XFastTrie) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, key := range keys {
+		if s.leaf(uint64Key(key)) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a pointer to the KeyValue with the smallest key, or nil if this
+// XFastTrie is empty.
+func (s *XFastTrie) First() *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.head.keyValue()
+}
+
+// Returns a pointer to the KeyValue with the largest key, or nil if this
+// XFastTrie is empty.
+func (s *XFastTrie) Last() *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.tail.keyValue()
+}
+
+// Returns a pointer to the KeyValue with the largest key strictly less than
+// the given key, or nil if there is none.
+func (s *XFastTrie) Predecessor(key uint64) *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if leaf := s.leaf(key); leaf != nil {
+		return leaf.prev.keyValue()
+	}
+	pred, _ := s.neighbors(key)
+	return pred.keyValue()
+}
+
+// Returns a pointer to the KeyValue with the smallest key strictly greater
+// than the given key, or nil if there is none.
+func (s *XFastTrie) Successor(key uint64) *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if leaf := s.leaf(key); leaf != nil {
+		return leaf.next.keyValue()
+	}
+	_, succ := s.neighbors(key)
+	return succ.keyValue()
+}
+
+func (s *XFastTrie) Copy() Dictionary {
+	s.CheckInit()
+
+	c := &XFastTrie{}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for leaf := s.head; leaf != nil; leaf = leaf.next {
+		c.insert(leaf.key, leaf.value)
+	}
+	return c
+}
+
+// Maps over KeyValues, in ascending order of keys.
+func (s *XFastTrie) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for leaf := s.head; leaf != nil; leaf = leaf.next {
+		if !f(leaf.keyValue()) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of keys.
+func (s *XFastTrie) Slice() *[]interface{} {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	for leaf := s.head; leaf != nil; leaf = leaf.next {
+		slice = append(slice, leaf.keyValue())
+	}
+	return &slice
+}
+
+func (s *XFastTrie) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.reset()
+}
+
+func (s *XFastTrie) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *XFastTrie) reset() {
+	for l := range s.levels {
+		s.levels[l] = make(map[uint64]*xnode)
+	}
+	s.head, s.tail = nil, nil
+	s.Sizeb = 0
+}
+
+// Returns the leaf with the given key, or nil.
+func (s *XFastTrie) leaf(k uint64) *xnode {
+	return s.levels[keyBits][k]
+}
+
+// Returns the leaves with the largest key less than, and the smallest key
+// greater than, the given key, which must not be in this XFastTrie.
+//
+// Binary searches for the longest prefix of the key in the trie. The node
+// with that prefix is missing the child the key would be under, so its
+// desc is one of the two.
+func (s *XFastTrie) neighbors(k uint64) (pred *xnode, succ *xnode) {
+	if s.Sizeb == 0 {
+		return nil, nil
+	}
+
+	lo, hi := 0, keyBits
+	for hi-lo > 1 {
+		mid := (lo + hi) / 2
+		if _, ok := s.levels[mid][prefix(k, mid)]; ok {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+
+	n := s.levels[lo][prefix(k, lo)]
+	if bit(k, lo) == 0 {
+		return n.desc.prev, n.desc
+	}
+	return n.desc, n.desc.next
+}
+
+// Inserts a leaf with the given key, which must not be in this XFastTrie,
+// and value.
+func (s *XFastTrie) insert(k uint64, v interface{}) {
+	leaf := &xnode{key: k, value: v}
+	leaf.prev, leaf.next = s.neighbors(k)
+	if leaf.prev != nil {
+		leaf.prev.next = leaf
+	} else {
+		s.head = leaf
+	}
+	if leaf.next != nil {
+		leaf.next.prev = leaf
+	} else {
+		s.tail = leaf
+	}
+	s.levels[keyBits][k] = leaf
+
+	// Add the missing prefixes, bottom up, and fix the desc pointers on the
+	// way, of which only those on the leaf's path can change.
+	c := leaf
+	for l := keyBits - 1; l >= 0; l-- {
+		p := prefix(k, l)
+		n := s.levels[l][p]
+		if n == nil {
+			n = &xnode{}
+			s.levels[l][p] = n
+		}
+
+		b := bit(k, l)
+		n.children[b] = c
+		switch {
+		case n.children[1-b] != nil:
+			n.desc = nil
+		case b == 0 && (n.desc == nil || k > n.desc.key):
+			n.desc = leaf
+		case b == 1 && (n.desc == nil || k < n.desc.key):
+			n.desc = leaf
+		}
+		c = n
+	}
+	s.Sizeb += 1
+}
+
+// Removes the given leaf from this XFastTrie.
+func (s *XFastTrie) remove(leaf *xnode) {
+	k := leaf.key
+	pred, succ := leaf.prev, leaf.next
+	if pred != nil {
+		pred.next = succ
+	} else {
+		s.head = succ
+	}
+	if succ != nil {
+		succ.prev = pred
+	} else {
+		s.tail = pred
+	}
+	delete(s.levels[keyBits], k)
+
+	// Remove the prefixes left without children, bottom up, and fix the
+	// desc pointers that were, or now should be, the leaf's neighbors.
+	removed := true
+	for l := keyBits - 1; l >= 0; l-- {
+		p := prefix(k, l)
+		n := s.levels[l][p]
+		b := bit(k, l)
+
+		if removed {
+			n.children[b] = nil
+			if n.children[1-b] == nil {
+				delete(s.levels[l], p)
+				continue
+			}
+			removed = false
+		}
+
+		switch {
+		case n.children[0] != nil && n.children[1] != nil:
+			n.desc = nil
+		case n.children[0] == nil && (n.desc == nil || n.desc == leaf):
+			n.desc = succ
+		case n.children[1] == nil && (n.desc == nil || n.desc == leaf):
+			n.desc = pred
+		}
+	}
+	s.Sizeb -= 1
+}
+
+// Returns a pointer to a KeyValue for this leaf, or nil if it is nil.
+func (n *xnode) keyValue() *KeyValue {
+	if n == nil {
+		return nil
+	}
+	return &KeyValue{n.key, n.value}
+}
+
+// Returns the first l bits of the given key.
+func prefix(k uint64, l int) uint64 {
+	if l == 0 {
+		return 0
+	}
+	return k >> (keyBits - l)
+}
+
+// Returns the bit of the given key after its first l bits.
+func bit(k uint64, l int) int {
+	return int(k>>(keyBits-1-l)) & 1
+}
+
+// Returns the given key as a uint64.
+//
+// Panics if the given key isn't a uint64.
+func uint64Key(key interface{}) uint64 {
+	k, ok := key.(uint64)
+	if !ok {
+		log.Panicf("Key %v is not a uint64.", key)
+	}
+	return k
+}
@@ -0,0 +1,240 @@
+// This module contains tests for xfasttrie.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// An ordered dictionary over uint64 keys, like XFastTrie and YFastTrie.
+type uint64Dictionary interface {
+	Dictionary
+	First() *KeyValue
+	Last() *KeyValue
+	Predecessor(key uint64) *KeyValue
+	Successor(key uint64) *KeyValue
+}
+
+// Returns n random keys, mixing full range keys, keys clustered in a small
+// range (sharing long prefixes), and the extremes.
+func randomUint64Keys(r *rand.Rand, n int) []uint64 {
+	keys := []uint64{0, 1, math.MaxUint64, math.MaxUint64 - 1}
+	for len(keys) < n {
+		if r.Intn(2) == 0 {
+			keys = append(keys, r.Uint64())
+		} else {
+			keys = append(keys, 1<<40+uint64(r.Intn(4*n)))
+		}
+	}
+	return keys
+}
+
+// Checks the given dictionary against the given reference, including the
+// neighbors of every key, and of points next to them.
+func checkUint64Dictionary(t *testing.T, s uint64Dictionary, ref map[uint64]int) {
+	keys := make([]uint64, 0, len(ref))
+	for k := range ref {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+
+	test.AssertEqual(t, s.Size(), len(keys), "Wrong size.")
+	slice := *s.Slice()
+	test.AssertEqual(t, len(slice), len(keys), "Wrong slice length.")
+	for i, kv := range slice {
+		test.AssertEqual(t, kv.(*KeyValue).Key, keys[i], "Items returned out of order!")
+		test.AssertEqual(t, kv.(*KeyValue).Value, ref[keys[i]], "Wrong value.")
+	}
+
+	if len(keys) == 0 {
+		test.AssertTrue(t, s.First() == nil && s.Last() == nil, "Empty dictionary has ends.")
+		test.AssertTrue(t, s.Predecessor(5) == nil && s.Successor(5) == nil, "Empty dictionary has neighbors.")
+		return
+	}
+	test.AssertEqual(t, s.First().Key, keys[0], "Wrong first key.")
+	test.AssertEqual(t, s.Last().Key, keys[len(keys)-1], "Wrong last key.")
+
+	for i, k := range keys {
+		test.AssertEqual(t, s.Locate(k), ref[k], "Retrieved wrong value.")
+		for _, q := range []uint64{k - 1, k, k + 1} {
+			j := sort.Search(len(keys), func(j int) bool { return keys[j] >= q })
+			pred, succ := s.Predecessor(q), s.Successor(q)
+			if j == 0 {
+				test.AssertTrue(t, pred == nil, "Unexpected predecessor.")
+			} else {
+				test.AssertTrue(t, pred != nil && pred.Key == keys[j-1], "Wrong predecessor.")
+			}
+			if j < len(keys) && keys[j] == q {
+				j += 1
+			}
+			if j == len(keys) {
+				test.AssertTrue(t, succ == nil, "Unexpected successor.")
+			} else {
+				test.AssertTrue(t, succ != nil && succ.Key == keys[j], "Wrong successor.")
+			}
+		}
+		if i > 0 && keys[i-1] < k-1 {
+			test.AssertFalse(t, s.Contains(k-1), "Contains a missing key.")
+		}
+	}
+}
+
+// Randomly inserts and removes keys, checking against a map as it goes.
+func randomLoadUint64Dictionary(t *testing.T, s uint64Dictionary, n int, check func()) {
+	r := rand.New(rand.NewSource(1))
+	keys := randomUint64Keys(r, n)
+	ref := make(map[uint64]int)
+
+	for i := 0; i < 4*n; i++ {
+		k := keys[r.Intn(len(keys))]
+		if r.Intn(3) == 0 {
+			_, ok := ref[k]
+			removed := s.Remove(k)
+			test.AssertEqual(t, removed != nil, ok, "Remove disagrees with reference.")
+			delete(ref, k)
+		} else {
+			s.Insert(k, i)
+			ref[k] = i
+		}
+		if i%(n/2) == 0 {
+			checkUint64Dictionary(t, s, ref)
+			check()
+		}
+	}
+	checkUint64Dictionary(t, s, ref)
+	check()
+
+	for k := range ref {
+		s.Remove(k)
+	}
+	checkUint64Dictionary(t, s, map[uint64]int{})
+	check()
+}
+
+// Checks the structure of the given XFastTrie: every prefix of every key is
+// present, no others are, and every desc pointer is right.
+func checkXFastTrie(t *testing.T, s *XFastTrie) {
+	count := 0
+	for l := 0; l < keyBits; l++ {
+		count += len(s.levels[l])
+		for p, n := range s.levels[l] {
+			for b := 0; b < 2; b++ {
+				c := s.levels[l+1][p<<1|uint64(b)]
+				test.AssertTrue(t, n.children[b] == c, "Child doesn't match prefix.")
+			}
+			test.AssertTrue(t, n.children[0] != nil || n.children[1] != nil, "Prefix without children.")
+
+			var want *xnode
+			if n.children[0] == nil {
+				want = s.head
+				for want != nil && prefix(want.key, l+1) != p<<1|1 {
+					want = want.next
+				}
+			} else if n.children[1] == nil {
+				want = s.tail
+				for want != nil && prefix(want.key, l+1) != p<<1 {
+					want = want.prev
+				}
+			}
+			test.AssertTrue(t, n.desc == want, "Wrong descendant pointer.")
+		}
+	}
+
+	for leaf := s.head; leaf != nil; leaf = leaf.next {
+		for l := 0; l < keyBits; l++ {
+			test.AssertTrue(t, s.levels[l][prefix(leaf.key, l)] != nil, "Missing prefix.")
+		}
+	}
+	if s.Size() == 0 {
+		test.AssertEqual(t, count, 0, "Empty trie has prefixes.")
+	}
+}
+
+func TestNewEmptyXFastTrie(t *testing.T) {
+	s := NewXFastTrie()
+
+	checkUint64Dictionary(t, s, map[uint64]int{})
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+}
+
+func TestNonUint64KeyXFastTrie(t *testing.T) {
+	s := NewXFastTrie()
+
+	defer func() {
+		test.AssertNonNil(t, recover(), "Inserting an int key did not panic.")
+	}()
+	s.Insert(5, "five")
+}
+
+func TestInsertRemoveXFastTrie(t *testing.T) {
+	s := NewXFastTrie()
+
+	test.AssertNil(t, s.Insert(uint64(5), "five"), "")
+	test.AssertNil(t, s.Insert(uint64(3), "three"), "")
+	test.AssertEqual(t, s.Insert(uint64(5), "FIVE"), "five", "Wrong previous value.")
+	test.AssertEqual(t, s.Size(), 2, "Wrong size.")
+	test.AssertEqual(t, s.Predecessor(5).Key, uint64(3), "Wrong predecessor.")
+	test.AssertEqual(t, s.Successor(3).Value, "FIVE", "Wrong successor.")
+	test.AssertTrue(t, s.Successor(5) == nil, "Unexpected successor.")
+
+	test.AssertEqual(t, s.Remove(uint64(3)), "three", "Wrong removed value.")
+	test.AssertNil(t, s.Remove(uint64(3)), "Removed a missing key.")
+	test.AssertTrue(t, s.Predecessor(5) == nil, "Unexpected predecessor.")
+	checkXFastTrie(t, s)
+}
+
+func TestRandomLoadXFastTrie(t *testing.T) {
+	s := NewXFastTrie()
+
+	randomLoadUint64Dictionary(t, s, 300, func() { checkXFastTrie(t, s) })
+}
+
+func TestCopyClearXFastTrie(t *testing.T) {
+	s := NewXFastTrie()
+
+	for i := uint64(0); i < 100; i++ {
+		s.Insert(i*i, i)
+	}
+	c := s.Copy().(*XFastTrie)
+	s.Clear()
+
+	test.AssertTrue(t, s.Empty(), "Dictionary not empty after clearing.")
+	checkXFastTrie(t, s)
+	test.AssertEqual(t, c.Size(), 100, "Wrong size of copy.")
+	test.AssertEqual(t, c.Locate(uint64(81)), uint64(9), "Copy has wrong entries.")
+	checkXFastTrie(t, c)
+}
+
+// Keys for the benchmarks, shared by those of YFastTrie and TreeMap.
+var benchmarkKeys = randomUint64Keys(rand.New(rand.NewSource(1)), 100000)
+
+func BenchmarkInsertXFastTrie(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := NewXFastTrieUnsafe()
+		for _, k := range benchmarkKeys {
+			s.Insert(k, k)
+		}
+	}
+}
+
+func BenchmarkSuccessorXFastTrie(b *testing.B) {
+	s := NewXFastTrieUnsafe()
+	for _, k := range benchmarkKeys {
+		s.Insert(k, k)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, k := range benchmarkKeys {
+			s.Successor(k + 1)
+		}
+	}
+}
@@ -0,0 +1,453 @@
+// This module implements a y-fast trie backed Dictionary, conforming to
+// Dictionary, with the additional stipulation that all Keys must be uint64s.
+//
+// See Willard, "Log-logarithmic worst-case range queries are possible in
+// space Θ(N)", 1983.
+
+package dictionary
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"math"
+)
+
+// Buckets are split once they hold more than maxBucket keys, and merged with
+// a neighbor once they hold fewer than minBucket, so they hold Θ(log U).
+const (
+	maxBucket = 2 * keyBits
+	minBucket = keyBits / 4
+)
+
+// A uint64 implementing collection.Comparer, so buckets can reuse the AVL
+// Tree nodes of TreeMap.
+type ukey uint64
+
+func (k ukey) Compare(o interface{}) int {
+	oc := o.(ukey)
+	if k < oc {
+		return -1
+	} else if k > oc {
+		return 1
+	}
+	return 0
+}
+
+// A bucket of a y-fast trie: an AVL Tree of its keys.
+type ybucket struct {
+	root *node
+	size int
+}
+
+// A YFastTrie implements Dictionary, with the additional guarantee of
+// storing its KeyValues in ascending order of their keys, which must be
+// uint64s.
+//
+// Its keys are split into buckets of Θ(log U) consecutive keys, each an AVL
+// Tree, where U is 2^64. An XFastTrie indexes the buckets by representatives,
+// such that the bucket with representative r holds the keys between the
+// previous representative, exclusive, and r, inclusive. The last bucket's
+// representative is always the largest uint64.
+//
+// Locate(), Contains(), Predecessor() and Successor() take O(log log U)
+// time, and Insert() and Remove() O(log log U) amortized time. A YFastTrie
+// uses O(n) space.
+//
+// Behavior unspecified if a YFastTrie is not created using NewYFastTrie(),
+// NewYFastTrieUnsafe() or if YFastTrie.Init() / YFastTrie.InitUnsafe(),
+// is not first called on a new &YFastTrie{}.
+//
+type YFastTrie struct {
+	collection.Base
+	reps *XFastTrie // representative -> *ybucket, always unsafe.
+}
+
+// Returns a pointer to a new YFastTrie.
+func NewYFastTrie() *YFastTrie {
+	s := &YFastTrie{}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe YFastTrie.
+func NewYFastTrieUnsafe() *YFastTrie {
+	s := &YFastTrie{}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *YFastTrie) Init() {
+	s.InitBase()
+
+	s.reset()
+}
+
+func (s *YFastTrie) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.reset()
+}
+
+// Panics if key isn't a uint64.
+func (s *YFastTrie) Insert(key interface{}, value interface{}) interface{} {
+	s.CheckInit()
+	k := uint64Key(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if s.Sizeb == 0 {
+		s.reps.insert(math.MaxUint64, &ybucket{})
+	}
+
+	rep := s.bucket(k)
+	b := rep.value.(*ybucket)
+
+	var old interface{}
+	var added bool
+	b.root, old, added = insertNode(b.root, ukey(k), value)
+	if !added {
+		return old
+	}
+
+	b.size += 1
+	s.Sizeb += 1
+	if b.size > maxBucket {
+		s.split(rep)
+	}
+	return nil
+}
+
+func (s *YFastTrie) Locate(key interface{}) interface{} {
+	s.CheckInit()
+	k := uint64Key(key)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := s.locate(k); n != nil {
+		return n.V
+	}
+	return nil
+}
+
+func (s *YFastTrie) Remove(key interface{}) interface{} {
+	s.CheckInit()
+	k := uint64Key(key)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if s.Sizeb == 0 {
+		return nil
+	}
+
+	rep := s.bucket(k)
+	b := rep.value.(*ybucket)
+
+	var removed *node
+	b.root, removed = removeNode(b.root, ukey(k))
+	if removed == nil {
+		return nil
+	}
+
+	b.size -= 1
+	s.Sizeb -= 1
+	if b.size < minBucket {
+		s.merge(rep)
+	}
+	return removed.V
+}
+
+// Will also Panic if any key isn't a uint64.
+func (s *YFastTrie) Contains(keys ...interface{}) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, key := range keys {
+		if s.locate(uint64Key(key)) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a pointer to the KeyValue with the smallest key, or nil if this
+// YFastTrie is empty.
+func (s *YFastTrie) First() *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.Sizeb == 0 {
+		return nil
+	}
+	return extreme(s.reps.head.value.(*ybucket).root, 0)
+}
+
+// Returns a pointer to the KeyValue with the largest key, or nil if this
+// YFastTrie is empty.
+func (s *YFastTrie) Last() *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.Sizeb == 0 {
+		return nil
+	}
+	return extreme(s.reps.tail.value.(*ybucket).root, 1)
+}
+
+// Returns a pointer to the KeyValue with the largest key strictly less than
+// the given key, or nil if there is none.
+func (s *YFastTrie) Predecessor(key uint64) *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.Sizeb == 0 {
+		return nil
+	}
+
+	// Every key in the buckets before key's is less than it.
+	rep := s.bucket(key)
+	if kv := neighbor(rep.value.(*ybucket).root, key, 0); kv != nil {
+		return kv
+	}
+	if rep.prev == nil {
+		return nil
+	}
+	return extreme(rep.prev.value.(*ybucket).root, 1)
+}
+
+// Returns a pointer to the KeyValue with the smallest key strictly greater
+// than the given key, or nil if there is none.
+func (s *YFastTrie) Successor(key uint64) *KeyValue {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.Sizeb == 0 {
+		return nil
+	}
+
+	// Every key in the buckets after key's is greater than it.
+	rep := s.bucket(key)
+	if kv := neighbor(rep.value.(*ybucket).root, key, 1); kv != nil {
+		return kv
+	}
+	if rep.next == nil {
+		return nil
+	}
+	return extreme(rep.next.value.(*ybucket).root, 0)
+}
+
+func (s *YFastTrie) Copy() Dictionary {
+	s.CheckInit()
+
+	c := &YFastTrie{}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for rep := s.reps.head; rep != nil; rep = rep.next {
+		ns := appendNodes(rep.value.(*ybucket).root, nil)
+		for i, n := range ns {
+			ns[i] = newNode(n.K, n.V, 0)
+		}
+		c.reps.insert(rep.key, &ybucket{buildBalanced(ns), len(ns)})
+	}
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Maps over KeyValues, in ascending order of keys.
+func (s *YFastTrie) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for rep := s.reps.head; rep != nil; rep = rep.next {
+		if !mapNodes(rep.value.(*ybucket).root, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a slice of pointers to KeyValue structs, in ascending order of keys.
+func (s *YFastTrie) Slice() *[]interface{} {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	for rep := s.reps.head; rep != nil; rep = rep.next {
+		mapNodes(rep.value.(*ybucket).root, func(kv interface{}) bool {
+			slice = append(slice, kv)
+			return true
+		})
+	}
+	return &slice
+}
+
+func (s *YFastTrie) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.reset()
+}
+
+func (s *YFastTrie) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *YFastTrie) reset() {
+	s.reps = NewXFastTrieUnsafe()
+	s.Sizeb = 0
+}
+
+// Returns the leaf of reps for the bucket the given key belongs in: the one
+// with the smallest representative greater than or equal to it. This
+// YFastTrie must not be empty.
+func (s *YFastTrie) bucket(k uint64) *xnode {
+	if rep := s.reps.leaf(k); rep != nil {
+		return rep
+	}
+	_, rep := s.reps.neighbors(k)
+	return rep
+}
+
+// Returns the node with the given key, or nil.
+func (s *YFastTrie) locate(k uint64) *node {
+	if s.Sizeb == 0 {
+		return nil
+	}
+
+	n := s.bucket(k).value.(*ybucket).root
+	for n != nil && ukey(k) != n.K {
+		n = child(n, direction(ukey(k), n.K))
+	}
+	return n
+}
+
+// Splits the given representative's bucket in two, giving the lower half a
+// new representative, its largest key.
+func (s *YFastTrie) split(rep *xnode) {
+	b := rep.value.(*ybucket)
+	ns := appendNodes(b.root, nil)
+	lower, upper := ns[:len(ns)/2], ns[len(ns)/2:]
+
+	b.root, b.size = buildBalanced(upper), len(upper)
+	s.reps.insert(uint64(lower[len(lower)-1].K.(ukey)), &ybucket{buildBalanced(lower), len(lower)})
+}
+
+// Merges the given representative's bucket into a neighbor, splitting the
+// result if it is too large. Removes the bucket if it is the only one, and
+// empty.
+func (s *YFastTrie) merge(rep *xnode) {
+	lower, upper := rep.prev, rep
+	if rep.next != nil {
+		lower, upper = rep, rep.next
+	}
+	if lower == nil {
+		if rep.value.(*ybucket).size == 0 {
+			s.reps.remove(rep)
+		}
+		return
+	}
+
+	// The upper representative covers both buckets' keys.
+	lb, ub := lower.value.(*ybucket), upper.value.(*ybucket)
+	ns := appendNodes(lb.root, make([]*node, 0, lb.size+ub.size))
+	ns = appendNodes(ub.root, ns)
+	ub.root, ub.size = buildBalanced(ns), len(ns)
+	s.reps.remove(lower)
+
+	if ub.size > maxBucket {
+		s.split(upper)
+	}
+}
+
+// Returns a pointer to the KeyValue with the smallest (dir 0) or largest
+// (dir 1) key in the subtree rooted at n, or nil if it is empty.
+func extreme(n *node, dir int) *KeyValue {
+	if n == nil {
+		return nil
+	}
+	for child(n, dir) != nil {
+		n = child(n, dir)
+	}
+	return &KeyValue{uint64(n.K.(ukey)), n.V}
+}
+
+// Returns a pointer to the KeyValue with the largest key less than (dir 0),
+// or the smallest key greater than (dir 1), the given key, in the subtree
+// rooted at n, or nil if there is none.
+func neighbor(n *node, k uint64, dir int) *KeyValue {
+	var best *node
+	for n != nil {
+		nk := uint64(n.K.(ukey))
+		if (dir == 0 && nk < k) || (dir == 1 && nk > k) {
+			best = n
+			n = child(n, 1-dir)
+		} else {
+			n = child(n, dir)
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return &KeyValue{uint64(best.K.(ukey)), best.V}
+}
+
+// Applies the given function to a KeyValue for each node in the subtree
+// rooted at n, in order, stopping if it returns false. Returns false if it
+// did.
+func mapNodes(n *node, f func(item interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	return mapNodes(child(n, 0), f) &&
+		f(&KeyValue{uint64(n.K.(ukey)), n.V}) &&
+		mapNodes(child(n, 1), f)
+}
@@ -0,0 +1,176 @@
+// This module contains tests for yfasttrie.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package dictionary
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"testing"
+)
+
+// Checks the structure of the given YFastTrie: every bucket is an AVL Tree
+// of the right size, between its representative and the previous one, and
+// only a lone bucket is small.
+func checkYFastTrie(t *testing.T, s *YFastTrie) {
+	total := 0
+	var prev *xnode
+	for rep := s.reps.head; rep != nil; rep = rep.next {
+		b := rep.value.(*ybucket)
+		checkAVL(t, b.root)
+		ns := appendNodes(b.root, nil)
+		test.AssertEqual(t, len(ns), b.size, "Wrong bucket size.")
+		test.AssertTrue(t, b.size <= maxBucket, "Bucket too large.")
+		if s.reps.Size() > 1 {
+			test.AssertTrue(t, b.size >= minBucket, "Bucket too small.")
+		}
+		for _, n := range ns {
+			k := uint64(n.K.(ukey))
+			test.AssertTrue(t, k <= rep.key, "Key above its representative.")
+			test.AssertTrue(t, prev == nil || k > prev.key, "Key below its bucket.")
+		}
+		total += b.size
+		prev = rep
+	}
+	test.AssertEqual(t, total, s.Size(), "Buckets don't add up to size.")
+	if s.Size() > 0 {
+		test.AssertEqual(t, s.reps.Last().Key, uint64(1<<64-1), "Last representative isn't the largest key.")
+	} else {
+		test.AssertTrue(t, s.reps.Empty(), "Empty trie has buckets.")
+	}
+}
+
+func TestNewEmptyYFastTrie(t *testing.T) {
+	s := NewYFastTrie()
+
+	checkUint64Dictionary(t, s, map[uint64]int{})
+	test.AssertTrue(t, s.Empty(), "New dictionary is not empty.")
+	test.AssertNil(t, s.Remove(uint64(1)), "Removed from an empty dictionary.")
+	test.AssertFalse(t, s.Contains(uint64(1)), "Empty dictionary contains a key.")
+}
+
+func TestInsertRemoveYFastTrie(t *testing.T) {
+	s := NewYFastTrie()
+
+	test.AssertNil(t, s.Insert(uint64(5), "five"), "")
+	test.AssertNil(t, s.Insert(uint64(3), "three"), "")
+	test.AssertEqual(t, s.Insert(uint64(5), "FIVE"), "five", "Wrong previous value.")
+	test.AssertEqual(t, s.Size(), 2, "Wrong size.")
+	test.AssertEqual(t, s.Predecessor(5).Key, uint64(3), "Wrong predecessor.")
+	test.AssertEqual(t, s.Successor(3).Value, "FIVE", "Wrong successor.")
+
+	test.AssertEqual(t, s.Remove(uint64(3)), "three", "Wrong removed value.")
+	test.AssertNil(t, s.Remove(uint64(3)), "Removed a missing key.")
+	checkYFastTrie(t, s)
+}
+
+func TestSplitMergeYFastTrie(t *testing.T) {
+	s := NewYFastTrie()
+
+	for i := uint64(0); i < 10*maxBucket; i++ {
+		s.Insert(i, i)
+	}
+	checkYFastTrie(t, s)
+	test.AssertTrue(t, s.reps.Size() > 5, "Buckets were not split.")
+
+	for i := uint64(0); i < 10*maxBucket; i += 2 {
+		s.Remove(i)
+	}
+	checkYFastTrie(t, s)
+
+	for i := uint64(1); i < 10*maxBucket-2; i += 2 {
+		s.Remove(i)
+	}
+	checkYFastTrie(t, s)
+	test.AssertEqual(t, s.reps.Size(), 1, "Buckets were not merged.")
+
+	s.Remove(uint64(10*maxBucket - 1))
+	checkYFastTrie(t, s)
+}
+
+func TestRandomLoadYFastTrie(t *testing.T) {
+	s := NewYFastTrie()
+
+	randomLoadUint64Dictionary(t, s, 2000, func() { checkYFastTrie(t, s) })
+}
+
+func TestCopyClearYFastTrie(t *testing.T) {
+	s := NewYFastTrie()
+
+	for i := uint64(0); i < 1000; i++ {
+		s.Insert(i*i, i)
+	}
+	c := s.Copy().(*YFastTrie)
+	s.Clear()
+
+	test.AssertTrue(t, s.Empty(), "Dictionary not empty after clearing.")
+	checkYFastTrie(t, s)
+	test.AssertEqual(t, c.Size(), 1000, "Wrong size of copy.")
+	test.AssertEqual(t, c.Locate(uint64(81)), uint64(9), "Copy has wrong entries.")
+	checkYFastTrie(t, c)
+
+	c.Insert(uint64(2), 2)
+	test.AssertFalse(t, s.Contains(uint64(2)), "Copy shares state with original.")
+}
+
+func BenchmarkInsertYFastTrie(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := NewYFastTrieUnsafe()
+		for _, k := range benchmarkKeys {
+			s.Insert(k, k)
+		}
+	}
+}
+
+func BenchmarkInsertTreeMapUint64(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		s := NewTreeMapUnsafe()
+		for _, k := range benchmarkKeys {
+			s.Insert(compInt{int(k)}, k)
+		}
+	}
+}
+
+func BenchmarkSuccessorYFastTrie(b *testing.B) {
+	s := NewYFastTrieUnsafe()
+	for _, k := range benchmarkKeys {
+		s.Insert(k, k)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, k := range benchmarkKeys {
+			s.Successor(k + 1)
+		}
+	}
+}
+
+func BenchmarkLocateYFastTrie(b *testing.B) {
+	s := NewYFastTrieUnsafe()
+	for _, k := range benchmarkKeys {
+		s.Insert(k, k)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, k := range benchmarkKeys {
+			s.Locate(k)
+		}
+	}
+}
+
+func BenchmarkLocateTreeMapUint64(b *testing.B) {
+	s := NewTreeMapUnsafe()
+	for _, k := range benchmarkKeys {
+		s.Insert(compInt{int(k)}, k)
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, k := range benchmarkKeys {
+			s.Locate(compInt{int(k)})
+		}
+	}
+}
@@ -0,0 +1,232 @@
+// This module implements a FenwickTree, for prefix sums with point updates.
+
+package rangequery
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+)
+
+// A FenwickTree, or binary indexed tree, holds a fixed number of float64s,
+// until it is Clear()ed, and sums any range of them, or changes any one, in
+// O(log n) time.
+//
+// Element i of the backing slice holds the sum of values i & (i + 1) through
+// i, inclusive.
+//
+// Behavior unspecified if a FenwickTree is not created using
+// NewFenwickTree(), NewFenwickTreeUnsafe(), or if FenwickTree.Init() /
+// FenwickTree.InitUnsafe(), is not first called on a new &FenwickTree{}.
+//
+type FenwickTree struct {
+	collection.Base
+	tree []float64
+}
+
+// Returns a pointer to a new FenwickTree holding the given values.
+func NewFenwickTree(values ...float64) *FenwickTree {
+	s := &FenwickTree{tree: append([]float64(nil), values...)}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe FenwickTree holding the given values.
+func NewFenwickTreeUnsafe(values ...float64) *FenwickTree {
+	s := &FenwickTree{tree: append([]float64(nil), values...)}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *FenwickTree) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *FenwickTree) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Adds delta to the value at index i.
+//
+// Panics if i is out of bounds.
+func (s *FenwickTree) Add(i int, delta float64) {
+	s.CheckInit()
+	checkIndex(i, s.Sizeb)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.add(i, delta)
+}
+
+// Sets the value at index i.
+//
+// Panics if i is out of bounds.
+func (s *FenwickTree) Set(i int, value float64) {
+	s.CheckInit()
+	checkIndex(i, s.Sizeb)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.add(i, value-s.sum(i, i+1))
+}
+
+// Returns the value at index i.
+//
+// Panics if i is out of bounds.
+func (s *FenwickTree) Get(i int) float64 {
+	s.CheckInit()
+	checkIndex(i, s.Sizeb)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.sum(i, i+1)
+}
+
+// Returns the sum of the values before index to.
+//
+// Panics if to is out of bounds.
+func (s *FenwickTree) PrefixSum(to int) float64 {
+	return s.Sum(0, to)
+}
+
+// Returns the sum of the values from index from, up to but not including
+// index to.
+//
+// Panics if [from, to) is out of bounds.
+func (s *FenwickTree) Sum(from int, to int) float64 {
+	s.CheckInit()
+	checkRange(from, to, s.Sizeb)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.sum(from, to)
+}
+
+// Returns a pointer to a new FenwickTree holding the same values.
+func (s *FenwickTree) Copy() *FenwickTree {
+	s.CheckInit()
+
+	c := &FenwickTree{}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.tree = append([]float64(nil), s.tree...)
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to every value in this FenwickTree,
+// in index order. Stops once all values have been processed, or once the
+// function returns false, whichever occurs first.
+func (s *FenwickTree) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for i := 0; i < s.Sizeb; i++ {
+		if !f(s.sum(i, i+1)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a slice of the values in this FenwickTree, in index order.
+func (s *FenwickTree) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(item interface{}) bool {
+		slice = append(slice, item)
+		return true
+	})
+	return &slice
+}
+
+// Removes every value from this FenwickTree, leaving it with a Size() of 0.
+// See Reset() to keep the values, but set them to 0.
+func (s *FenwickTree) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.tree = []float64{}
+	s.Sizeb = 0
+}
+
+// Sets every value in this FenwickTree to 0, keeping its Size().
+func (s *FenwickTree) Reset() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for i := range s.tree {
+		s.tree[i] = 0
+	}
+}
+
+func (s *FenwickTree) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Turns the values in tree into sums, in O(n) time, by adding each element
+// into the next one covering it.
+func (s *FenwickTree) setup() {
+	s.Sizeb = len(s.tree)
+	for i := range s.tree {
+		if j := i | (i + 1); j < len(s.tree) {
+			s.tree[j] += s.tree[i]
+		}
+	}
+}
+
+func (s *FenwickTree) add(i int, delta float64) {
+	for ; i < len(s.tree); i |= i + 1 {
+		s.tree[i] += delta
+	}
+}
+
+// Returns the sum of the values before index to.
+func (s *FenwickTree) prefix(to int) float64 {
+	sum := 0.0
+	for i := to - 1; i >= 0; i = i&(i+1) - 1 {
+		sum += s.tree[i]
+	}
+	return sum
+}
+
+func (s *FenwickTree) sum(from int, to int) float64 {
+	return s.prefix(to) - s.prefix(from)
+}
@@ -0,0 +1,86 @@
+// This module contains tests for fenwicktree.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package rangequery
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math/rand"
+	"testing"
+)
+
+func TestNewEmptyFenwickTree(t *testing.T) {
+	s := NewFenwickTree()
+
+	test.AssertEqual(t, s.Size(), 0, "New tree has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New tree is not empty.")
+	test.AssertEqual(t, s.PrefixSum(0), 0.0, "Empty tree has a non-zero sum.")
+}
+
+func TestSimpleFenwickTree(t *testing.T) {
+	s := NewFenwickTree(3, 1, 4, 1, 5)
+
+	test.AssertEqual(t, s.Size(), 5, "Tree has the wrong size.")
+	test.AssertEqual(t, s.PrefixSum(5), 14.0, "Wrong total.")
+	test.AssertEqual(t, s.Sum(1, 4), 6.0, "Wrong range sum.")
+	test.AssertEqual(t, s.Get(2), 4.0, "Wrong value.")
+
+	s.Add(2, 2)
+	s.Set(4, -1)
+	test.AssertEqual(t, s.String(), "&[3 1 6 1 -1]", "Wrong values after updates.")
+	test.AssertEqual(t, s.Sum(2, 5), 6.0, "Wrong range sum after updates.")
+
+	c := s.Copy()
+	s.Reset()
+	test.AssertEqual(t, s.Size(), 5, "Reset changed the size.")
+	test.AssertEqual(t, s.PrefixSum(5), 0.0, "Reset left a non-zero sum.")
+	test.AssertEqual(t, c.PrefixSum(5), 10.0, "Resetting the original changed the copy.")
+
+	c.Clear()
+	test.AssertEqual(t, c.Size(), 0, "Cleared tree has size != 0.")
+	test.AssertTrue(t, c.Empty(), "Cleared tree is not empty.")
+	test.AssertEqual(t, c.String(), "&[]", "Cleared tree has values.")
+	test.AssertEqual(t, c.PrefixSum(0), 0.0, "Cleared tree has a non-zero sum.")
+}
+
+func TestOutOfBoundsFenwickTree(t *testing.T) {
+	s := NewFenwickTree(1, 2)
+	defer func() {
+		if recover() == nil {
+			t.Error("Adding out of bounds did not panic.")
+		}
+	}()
+	s.Add(2, 1)
+}
+
+func TestLargeRandomFenwickTree(t *testing.T) {
+	r := rand.New(rand.NewSource(48))
+	values := make([]float64, 1000)
+	for i := range values {
+		values[i] = float64(r.Intn(100))
+	}
+	s := NewFenwickTree(values...)
+
+	for j := 0; j < 2000; j++ {
+		i := r.Intn(len(values))
+		if r.Intn(2) == 0 {
+			d := float64(r.Intn(21) - 10)
+			values[i] += d
+			s.Add(i, d)
+		} else {
+			values[i] = float64(r.Intn(100))
+			s.Set(i, values[i])
+		}
+
+		from := r.Intn(len(values) + 1)
+		to := from + r.Intn(len(values)-from+1)
+		sum := 0.0
+		for _, v := range values[from:to] {
+			sum += v
+		}
+		test.AssertEqual(t, s.Sum(from, to), sum, "Wrong range sum.")
+	}
+}
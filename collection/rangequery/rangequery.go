@@ -0,0 +1,112 @@
+// Package rangequery defines array-backed Collections that answer aggregate
+// queries, like sums and minimums, over ranges of their values.
+//
+package rangequery
+
+import (
+	"log"
+	"math"
+)
+
+// A Monoid describes how a SegmentTree aggregates its values. Combine must be
+// associative, and combining any value with Identity must leave it unchanged.
+//
+type Monoid struct {
+	Identity interface{}
+	Combine  func(a interface{}, b interface{}) interface{}
+}
+
+// An Updater describes how a SegmentTree updates ranges of its values.
+//
+// Apply returns the aggregate of n values, whose aggregate was a, after
+// applying the update u to each of them. Compose returns the single update
+// equivalent to applying first, and then second. Updates may not be nil.
+//
+type Updater struct {
+	Apply   func(u interface{}, a interface{}, n int) interface{}
+	Compose func(first interface{}, second interface{}) interface{}
+}
+
+// ****************************************************************************
+//
+//	Monoids and Updaters over float64s, for the common cases.
+//
+// ****************************************************************************
+
+// Sums float64s.
+var Sum = Monoid{
+	Identity: 0.0,
+	Combine: func(a interface{}, b interface{}) interface{} {
+		return a.(float64) + b.(float64)
+	},
+}
+
+// Takes the minimum of float64s, or +Inf for no values.
+var Min = Monoid{
+	Identity: math.Inf(1),
+	Combine: func(a interface{}, b interface{}) interface{} {
+		return math.Min(a.(float64), b.(float64))
+	},
+}
+
+// Takes the maximum of float64s, or -Inf for no values.
+var Max = Monoid{
+	Identity: math.Inf(-1),
+	Combine: func(a interface{}, b interface{}) interface{} {
+		return math.Max(a.(float64), b.(float64))
+	},
+}
+
+// Adds a float64 to every value in a range, aggregated by Sum.
+var AddToSum = Updater{
+	Apply: func(u interface{}, a interface{}, n int) interface{} {
+		return a.(float64) + u.(float64)*float64(n)
+	},
+	Compose: addUpdates,
+}
+
+// Adds a float64 to every value in a range, aggregated by Min or Max.
+var AddToExtremum = Updater{
+	Apply: func(u interface{}, a interface{}, n int) interface{} {
+		return a.(float64) + u.(float64)
+	},
+	Compose: addUpdates,
+}
+
+// Sets every value in a range to a float64, aggregated by Sum.
+var AssignToSum = Updater{
+	Apply: func(u interface{}, a interface{}, n int) interface{} {
+		return u.(float64) * float64(n)
+	},
+	Compose: lastUpdate,
+}
+
+// Sets every value in a range to a float64, aggregated by Min or Max.
+var AssignToExtremum = Updater{
+	Apply: func(u interface{}, a interface{}, n int) interface{} {
+		return u
+	},
+	Compose: lastUpdate,
+}
+
+func addUpdates(first interface{}, second interface{}) interface{} {
+	return first.(float64) + second.(float64)
+}
+
+func lastUpdate(first interface{}, second interface{}) interface{} {
+	return second
+}
+
+// Panics if [from, to) is not a range of indices below n.
+func checkRange(from int, to int, n int) {
+	if from < 0 || to > n || from > to {
+		log.Panic("Range out of bounds.")
+	}
+}
+
+// Panics if i is not an index below n.
+func checkIndex(i int, n int) {
+	if i < 0 || i >= n {
+		log.Panic("Index out of bounds.")
+	}
+}
@@ -0,0 +1,48 @@
+// This module contains tests for rangequery.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+
+package rangequery
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math"
+	"testing"
+)
+
+func TestMonoidIdentities(t *testing.T) {
+	for _, m := range []Monoid{Sum, Min, Max} {
+		for _, v := range []float64{-2.5, 0, 7} {
+			test.AssertEqual(t, m.Combine(m.Identity, v), v, "Identity changed a value.")
+			test.AssertEqual(t, m.Combine(v, m.Identity), v, "Identity changed a value.")
+		}
+	}
+	test.AssertEqual(t, Min.Identity, math.Inf(1), "Min of nothing is not +Inf.")
+	test.AssertEqual(t, Max.Identity, math.Inf(-1), "Max of nothing is not -Inf.")
+}
+
+func TestUpdaters(t *testing.T) {
+	// Three values, 1, 2 and 3.
+	test.AssertEqual(t, AddToSum.Apply(2.0, 6.0, 3), 12.0, "Adding to a sum is wrong.")
+	test.AssertEqual(t, AddToExtremum.Apply(2.0, 3.0, 3), 5.0, "Adding to a maximum is wrong.")
+	test.AssertEqual(t, AssignToSum.Apply(2.0, 6.0, 3), 6.0, "Assigning to a sum is wrong.")
+	test.AssertEqual(t, AssignToExtremum.Apply(2.0, 3.0, 3), 2.0, "Assigning to a maximum is wrong.")
+
+	test.AssertEqual(t, AddToSum.Compose(1.0, 2.0), 3.0, "Additions don't compose.")
+	test.AssertEqual(t, AssignToSum.Compose(1.0, 2.0), 2.0, "Assignments don't keep the last.")
+}
+
+func TestBadRangesPanic(t *testing.T) {
+	for _, r := range [][2]int{{-1, 2}, {2, 1}, {0, 6}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Range %v did not panic.", r)
+				}
+			}()
+			checkRange(r[0], r[1], 5)
+		}()
+	}
+	checkRange(5, 5, 5)
+}
@@ -0,0 +1,373 @@
+// This module implements a SegmentTree, for aggregate queries and updates
+// over ranges of values.
+
+package rangequery
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"github.com/michalpiszczek/nonstdlib/util/math"
+	"log"
+)
+
+// A SegmentTree holds a fixed number of values, until it is Clear()ed, and
+// aggregates any range of them with a Monoid, or changes any one, in O(log n)
+// time. A SegmentTree with an Updater also updates any range of them in
+// O(log n) time, by leaving updates pending on the largest nodes they cover
+// until a later update needs to pass through them.
+//
+// Node k covers a range of values, and its children, 2k and 2k + 1, each
+// cover half of it. The root, node 1, covers them all.
+//
+// Behavior unspecified if a SegmentTree is not created using
+// NewSegmentTree(), NewLazySegmentTree(), or one of their Unsafe variants.
+//
+type SegmentTree struct {
+	collection.Base
+	monoid  Monoid
+	updater *Updater
+	values  []interface{} // The initial values, until setup().
+	agg     []interface{} // The aggregate of each node's range.
+	lazy    []interface{} // The update pending on each node's children, or nil.
+}
+
+// Returns a pointer to a new SegmentTree holding the given values,
+// aggregated by the given Monoid.
+func NewSegmentTree(m Monoid, values ...interface{}) *SegmentTree {
+	s := &SegmentTree{monoid: m, values: values}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe SegmentTree holding the given values,
+// aggregated by the given Monoid.
+func NewSegmentTreeUnsafe(m Monoid, values ...interface{}) *SegmentTree {
+	s := &SegmentTree{monoid: m, values: values}
+	s.InitUnsafe()
+	return s
+}
+
+// Returns a pointer to a new SegmentTree holding the given values,
+// aggregated by the given Monoid, and supporting Update() with the given
+// Updater.
+func NewLazySegmentTree(m Monoid, u Updater, values ...interface{}) *SegmentTree {
+	s := &SegmentTree{monoid: m, updater: &u, values: values}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe SegmentTree holding the given values,
+// aggregated by the given Monoid, and supporting Update() with the given
+// Updater.
+func NewLazySegmentTreeUnsafe(m Monoid, u Updater, values ...interface{}) *SegmentTree {
+	s := &SegmentTree{monoid: m, updater: &u, values: values}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *SegmentTree) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *SegmentTree) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Returns the value at index i.
+//
+// Panics if i is out of bounds.
+func (s *SegmentTree) Get(i int) interface{} {
+	return s.Query(i, i+1)
+}
+
+// Sets the value at index i.
+//
+// Panics if i is out of bounds.
+func (s *SegmentTree) Set(i int, value interface{}) {
+	s.CheckInit()
+	checkIndex(i, s.Sizeb)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.set(1, 0, s.Sizeb, i, value)
+}
+
+// Returns the aggregate of the values from index from, up to but not
+// including index to, or the Monoid's Identity if the range is empty.
+//
+// Panics if [from, to) is out of bounds.
+func (s *SegmentTree) Query(from int, to int) interface{} {
+	s.CheckInit()
+	checkRange(from, to, s.Sizeb)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if from == to {
+		return s.monoid.Identity
+	}
+	return s.query(1, 0, s.Sizeb, from, to)
+}
+
+// Applies the given update to every value from index from, up to but not
+// including index to.
+//
+// Panics if this SegmentTree has no Updater, the given update is nil, or
+// [from, to) is out of bounds.
+func (s *SegmentTree) Update(from int, to int, u interface{}) {
+	s.CheckInit()
+	if s.updater == nil {
+		log.Panic("SegmentTree has no Updater.")
+	}
+	if u == nil {
+		log.Panic("Update is nil.")
+	}
+	checkRange(from, to, s.Sizeb)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if from < to {
+		s.update(1, 0, s.Sizeb, from, to, u)
+	}
+}
+
+// Returns a pointer to a new SegmentTree holding the same values, with the
+// same Monoid and Updater.
+func (s *SegmentTree) Copy() *SegmentTree {
+	s.CheckInit()
+
+	c := &SegmentTree{monoid: s.monoid, updater: s.updater}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.agg = append([]interface{}(nil), s.agg...)
+	c.lazy = append([]interface{}(nil), s.lazy...)
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to every value in this SegmentTree,
+// in index order. Stops once all values have been processed, or once the
+// function returns false, whichever occurs first.
+func (s *SegmentTree) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.Sizeb == 0 {
+		return true
+	}
+	return s.walk(1, 0, s.Sizeb, nil, f)
+}
+
+// Returns a slice of the values in this SegmentTree, in index order.
+func (s *SegmentTree) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(item interface{}) bool {
+		slice = append(slice, item)
+		return true
+	})
+	return &slice
+}
+
+// Removes every value from this SegmentTree, leaving it with a Size() of 0.
+// See Reset() to keep the values, but set them to the Monoid's Identity.
+func (s *SegmentTree) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.agg, s.lazy = []interface{}{}, []interface{}{}
+	s.Sizeb = 0
+}
+
+// Sets every value in this SegmentTree to the Monoid's Identity, keeping its
+// Size().
+func (s *SegmentTree) Reset() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for k := range s.agg {
+		s.agg[k] = s.monoid.Identity
+		s.lazy[k] = nil
+	}
+}
+
+func (s *SegmentTree) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Builds the tree from the initial values, in O(n) time.
+//
+// Panics if the Monoid, or a given Updater, is missing a function.
+func (s *SegmentTree) setup() {
+	if s.monoid.Combine == nil {
+		log.Panic("Monoid has no Combine function.")
+	}
+	if s.updater != nil && (s.updater.Apply == nil || s.updater.Compose == nil) {
+		log.Panic("Updater is missing a function.")
+	}
+
+	s.Sizeb = len(s.values)
+	s.agg = make([]interface{}, 4*s.Sizeb)
+	s.lazy = make([]interface{}, 4*s.Sizeb)
+	if s.Sizeb > 0 {
+		s.build(1, 0, s.Sizeb)
+	}
+	s.values = nil
+}
+
+func (s *SegmentTree) build(k int, l int, r int) {
+	if r-l == 1 {
+		s.agg[k] = s.values[l]
+		return
+	}
+
+	m := (l + r) / 2
+	s.build(2*k, l, m)
+	s.build(2*k+1, m, r)
+	s.pull(k)
+}
+
+// Recomputes node k's aggregate from its children's.
+func (s *SegmentTree) pull(k int) {
+	s.agg[k] = s.monoid.Combine(s.agg[2*k], s.agg[2*k+1])
+}
+
+// Applies the update u to every value in node k's range [l, r).
+func (s *SegmentTree) apply(k int, l int, r int, u interface{}) {
+	s.agg[k] = s.updater.Apply(u, s.agg[k], r-l)
+	if r-l > 1 {
+		if s.lazy[k] == nil {
+			s.lazy[k] = u
+		} else {
+			s.lazy[k] = s.updater.Compose(s.lazy[k], u)
+		}
+	}
+}
+
+// Passes node k's pending update, if any, down to its children.
+func (s *SegmentTree) push(k int, l int, m int, r int) {
+	if s.lazy[k] == nil {
+		return
+	}
+	s.apply(2*k, l, m, s.lazy[k])
+	s.apply(2*k+1, m, r, s.lazy[k])
+	s.lazy[k] = nil
+}
+
+func (s *SegmentTree) set(k int, l int, r int, i int, value interface{}) {
+	if r-l == 1 {
+		s.agg[k] = value
+		return
+	}
+
+	m := (l + r) / 2
+	s.push(k, l, m, r)
+	if i < m {
+		s.set(2*k, l, m, i, value)
+	} else {
+		s.set(2*k+1, m, r, i, value)
+	}
+	s.pull(k)
+}
+
+// Applies the update u to [from, to), which is non-empty and within node k's
+// range [l, r).
+func (s *SegmentTree) update(k int, l int, r int, from int, to int, u interface{}) {
+	if from == l && to == r {
+		s.apply(k, l, r, u)
+		return
+	}
+
+	m := (l + r) / 2
+	s.push(k, l, m, r)
+	if from < m {
+		s.update(2*k, l, m, from, math.Min(to, m), u)
+	}
+	if to > m {
+		s.update(2*k+1, m, r, math.Max(from, m), to, u)
+	}
+	s.pull(k)
+}
+
+// Returns the aggregate of [from, to), which is non-empty and within node k's
+// range [l, r). Rather than pushing pending updates down, which would need
+// the write lock, applies them to the partial aggregates on the way up.
+func (s *SegmentTree) query(k int, l int, r int, from int, to int) interface{} {
+	if from == l && to == r {
+		return s.agg[k]
+	}
+
+	m := (l + r) / 2
+	var a interface{}
+	switch {
+	case to <= m:
+		a = s.query(2*k, l, m, from, to)
+	case from >= m:
+		a = s.query(2*k+1, m, r, from, to)
+	default:
+		a = s.monoid.Combine(s.query(2*k, l, m, from, m), s.query(2*k+1, m, r, m, to))
+	}
+
+	if s.lazy[k] != nil {
+		a = s.updater.Apply(s.lazy[k], a, to-from)
+	}
+	return a
+}
+
+// Applies the given function to every value in node k's range [l, r), in
+// order, after the update pending from its ancestors, if any. Stops once the
+// function returns false, and returns false if it did.
+func (s *SegmentTree) walk(k int, l int, r int, pending interface{}, f func(item interface{}) bool) bool {
+	if r-l == 1 {
+		if pending == nil {
+			return f(s.agg[k])
+		}
+		return f(s.updater.Apply(pending, s.agg[k], 1))
+	}
+
+	// Updates pending here are older than those pending above.
+	if s.lazy[k] != nil {
+		if pending == nil {
+			pending = s.lazy[k]
+		} else {
+			pending = s.updater.Compose(s.lazy[k], pending)
+		}
+	}
+
+	m := (l + r) / 2
+	return s.walk(2*k, l, m, pending, f) && s.walk(2*k+1, m, r, pending, f)
+}
@@ -0,0 +1,142 @@
+// This module contains tests for segmenttree.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package rangequery
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// Returns the given float64s as a slice of interface{}.
+func floats(values ...float64) []interface{} {
+	items := make([]interface{}, len(values))
+	for i, v := range values {
+		items[i] = v
+	}
+	return items
+}
+
+func TestNewEmptySegmentTree(t *testing.T) {
+	s := NewSegmentTree(Sum)
+
+	test.AssertEqual(t, s.Size(), 0, "New tree has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New tree is not empty.")
+	test.AssertEqual(t, s.Query(0, 0), 0.0, "Empty range is not the identity.")
+}
+
+func TestSimpleSegmentTree(t *testing.T) {
+	s := NewSegmentTree(Min, floats(3, 1, 4, 1, 5)...)
+
+	test.AssertEqual(t, s.Query(0, 5), 1.0, "Wrong minimum.")
+	test.AssertEqual(t, s.Query(2, 3), 4.0, "Wrong single value minimum.")
+	test.AssertEqual(t, s.Query(4, 4), math.Inf(1), "Empty range is not the identity.")
+
+	s.Set(1, 9.0)
+	s.Set(3, 2.0)
+	test.AssertEqual(t, s.Query(1, 5), 2.0, "Wrong minimum after Set().")
+	test.AssertEqual(t, s.String(), "&[3 9 4 2 5]", "Wrong values after Set().")
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Update() without an Updater did not panic.")
+		}
+	}()
+	s.Update(0, 1, 1.0)
+}
+
+func TestCustomMonoidSegmentTree(t *testing.T) {
+	// Concatenation is associative, but not commutative.
+	concat := Monoid{"", func(a interface{}, b interface{}) interface{} {
+		return a.(string) + b.(string)
+	}}
+	s := NewSegmentTreeUnsafe(concat, "a", "b", "c", "d", "e")
+
+	test.AssertEqual(t, s.Query(1, 4), "bcd", "Combined out of order.")
+	s.Set(2, "X")
+	test.AssertEqual(t, s.Query(0, 5), "abXde", "Combined out of order after Set().")
+}
+
+func TestLazySegmentTree(t *testing.T) {
+	s := NewLazySegmentTree(Sum, AddToSum, floats(1, 2, 3, 4, 5, 6)...)
+
+	s.Update(1, 5, 10.0)
+	test.AssertEqual(t, s.Query(0, 6), 61.0, "Wrong total after Update().")
+	test.AssertEqual(t, s.Query(2, 3), 13.0, "Wrong value after Update().")
+	s.Update(0, 3, 1.0)
+	s.Set(4, 0.0)
+	test.AssertEqual(t, s.String(), "&[2 13 14 14 0 6]", "Wrong values after updates.")
+
+	c := s.Copy()
+	s.Reset()
+	test.AssertEqual(t, s.Size(), 6, "Reset changed the size.")
+	test.AssertEqual(t, s.Query(0, 6), 0.0, "Reset left a non-zero sum.")
+	s.Update(0, 6, 1.0)
+	test.AssertEqual(t, s.Query(0, 6), 6.0, "Update() after Reset() is wrong.")
+	test.AssertEqual(t, c.Query(0, 6), 49.0, "Resetting the original changed the copy.")
+
+	c.Clear()
+	test.AssertEqual(t, c.Size(), 0, "Cleared tree has size != 0.")
+	test.AssertTrue(t, c.Empty(), "Cleared tree is not empty.")
+	test.AssertEqual(t, c.String(), "&[]", "Cleared tree has values.")
+	test.AssertEqual(t, c.Query(0, 0), 0.0, "Empty range is not the identity.")
+}
+
+func TestLargeRandomLazySegmentTree(t *testing.T) {
+	r := rand.New(rand.NewSource(48))
+	for _, tc := range []struct {
+		m       Monoid
+		u       Updater
+		combine func(a float64, b float64) float64
+		update  func(v float64, u float64) float64
+	}{
+		{Sum, AddToSum, func(a, b float64) float64 { return a + b }, func(v, u float64) float64 { return v + u }},
+		{Sum, AssignToSum, func(a, b float64) float64 { return a + b }, func(v, u float64) float64 { return u }},
+		{Max, AddToExtremum, math.Max, func(v, u float64) float64 { return v + u }},
+		{Min, AssignToExtremum, math.Min, func(v, u float64) float64 { return u }},
+	} {
+		values := make([]float64, 300)
+		for i := range values {
+			values[i] = float64(r.Intn(100))
+		}
+		s := NewLazySegmentTree(tc.m, tc.u, floats(values...)...)
+
+		for j := 0; j < 1000; j++ {
+			from := r.Intn(len(values) + 1)
+			to := from + r.Intn(len(values)-from+1)
+
+			switch r.Intn(3) {
+			case 0:
+				u := float64(r.Intn(21) - 10)
+				s.Update(from, to, u)
+				for i := from; i < to; i++ {
+					values[i] = tc.update(values[i], u)
+				}
+			case 1:
+				if from < len(values) {
+					values[from] = float64(r.Intn(100))
+					s.Set(from, values[from])
+				}
+			}
+
+			agg := tc.m.Identity.(float64)
+			for _, v := range values[from:to] {
+				agg = tc.combine(agg, v)
+			}
+			test.AssertEqual(t, s.Query(from, to), agg, "Wrong range aggregate.")
+		}
+
+		i := 0
+		s.Map(func(item interface{}) bool {
+			test.AssertEqual(t, item, values[i], "Map() gave the wrong value.")
+			i++
+			return true
+		})
+		test.AssertEqual(t, i, len(values), "Map() missed values.")
+	}
+}
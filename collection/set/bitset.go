@@ -0,0 +1,638 @@
+// This module implements a BitSet, conforming to set.Interface.
+
+package set
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"math/bits"
+)
+
+// A BitSet implements set.Interface, for non-negative ints, with the
+// additional guarantee of storing its items in ascending order.
+//
+// It stores one bit per int, up to the largest it has held, so it is much
+// smaller than a HashSet of small, dense ints. Operations with another
+// BitSet work a word of 64 items at a time. Its Size() counts the set bits.
+//
+// Behavior unspecified if a BitSet is not created using NewBitSet(),
+// NewBitSetUnsafe() or if BitSet.Init() / BitSet.InitUnsafe(), is not first
+// called on a new &BitSet{}.
+//
+type BitSet struct {
+	collection.Base
+	words []uint64
+}
+
+// Returns a pointer to a new BitSet containing the given ints.
+//
+// Panics if any of the given items is not a non-negative int.
+func NewBitSet(items ...interface{}) *BitSet {
+	s := &BitSet{}
+	s.Init()
+	s.Insert(items...)
+	return s
+}
+
+// Returns a pointer to a new unsafe BitSet containing the given ints.
+//
+// Panics if any of the given items is not a non-negative int.
+func NewBitSetUnsafe(items ...interface{}) *BitSet {
+	s := &BitSet{}
+	s.InitUnsafe()
+	s.Insert(items...)
+	return s
+}
+
+func (s *BitSet) Init() {
+	s.InitBase()
+
+	s.words = nil
+}
+
+func (s *BitSet) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.words = nil
+}
+
+// Panics if any of the given items is not a non-negative int.
+func (s *BitSet) Insert(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		s.set(bitIndex(item))
+	}
+}
+
+// Panics if any of the given items is not a non-negative int.
+func (s *BitSet) Remove(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		s.clear(bitIndex(item))
+	}
+}
+
+// Returns false if any of the given items is not a non-negative int.
+func (s *BitSet) Contains(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		i, ok := item.(int)
+		if !ok || i < 0 || !s.test(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Adds i to this BitSet.
+//
+// Panics if i is negative.
+func (s *BitSet) SetBit(i int) {
+	s.Insert(i)
+}
+
+// Removes i from this BitSet.
+//
+// Panics if i is negative.
+func (s *BitSet) ClearBit(i int) {
+	s.Remove(i)
+}
+
+// Returns true if i is in this BitSet.
+func (s *BitSet) Test(i int) bool {
+	return s.Contains(i)
+}
+
+// Returns the number of items in this BitSet.
+func (s *BitSet) Size() int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return popcount(s.words)
+}
+
+func (s *BitSet) Empty() bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, w := range s.words {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the smallest item in this BitSet greater than or equal to from, or
+// -1 if there is none.
+func (s *BitSet) NextSetBit(from int) int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.next(from)
+}
+
+// Returns the largest item in this BitSet less than or equal to from, or -1
+// if there is none.
+func (s *BitSet) PrevSetBit(from int) int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if from < 0 {
+		return -1
+	}
+	w := from / 64
+	if w >= len(s.words) {
+		w, from = len(s.words)-1, 64*len(s.words)-1
+	}
+	for ; w >= 0; w-- {
+		word := s.words[w]
+		if w == from/64 {
+			word &= ^uint64(0) >> (63 - from%64)
+		}
+		if word != 0 {
+			return 64*w + 63 - bits.LeadingZeros64(word)
+		}
+	}
+	return -1
+}
+
+// Returns a pointer to a new Set containing all the items in either this
+// Set or the given Set. The result is a BitSet.
+//
+// Panics if the given Set holds an item that is not a non-negative int.
+func (s *BitSet) Union(o Set) Set {
+	s.CheckInit()
+
+	result := s.copyBitSet()
+	result.UnionWith(o)
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in both this Set
+// and the given Set. The result is a BitSet.
+func (s *BitSet) Intersection(o Set) Set {
+	s.CheckInit()
+
+	result := s.copyBitSet()
+	result.IntersectWith(o)
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in this Set that
+// are not in the given Set. The result is a BitSet.
+func (s *BitSet) Difference(o Set) Set {
+	s.CheckInit()
+
+	result := s.copyBitSet()
+	result.DifferenceWith(o)
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in exactly one of
+// this Set and the given Set. The result is a BitSet.
+//
+// Panics if the given Set holds an item that is not a non-negative int.
+func (s *BitSet) SymmetricDifference(o Set) Set {
+	s.CheckInit()
+
+	result := s.copyBitSet()
+	result.SymmetricDifferenceWith(o)
+	return result
+}
+
+// Returns true if this Set and the given Set have no items in common, false
+// otherwise.
+func (s *BitSet) IsDisjoint(o Set) bool {
+	s.CheckInit()
+
+	ow := s.wordsIn(o)
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for i := 0; i < len(s.words) && i < len(ow); i++ {
+		if s.words[i]&ow[i] != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Adds all the items in the given Set to this BitSet.
+//
+// Panics if the given Set holds an item that is not a non-negative int.
+func (s *BitSet) UnionWith(o Set) {
+	s.CheckInit()
+
+	ow := wordsOf(o)
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.grow(len(ow))
+	for i, w := range ow {
+		s.words[i] |= w
+	}
+}
+
+// Removes all the items not in the given Set from this BitSet.
+func (s *BitSet) IntersectWith(o Set) {
+	s.CheckInit()
+
+	ow := s.wordsIn(o)
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for i := range s.words {
+		if i < len(ow) {
+			s.words[i] &= ow[i]
+		} else {
+			s.words[i] = 0
+		}
+	}
+	s.trim()
+}
+
+// Removes all the items in the given Set from this BitSet.
+func (s *BitSet) DifferenceWith(o Set) {
+	s.CheckInit()
+
+	ow := s.wordsIn(o)
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for i := 0; i < len(s.words) && i < len(ow); i++ {
+		s.words[i] &^= ow[i]
+	}
+	s.trim()
+}
+
+// Removes the items in the given Set from this BitSet, and adds those in the
+// given Set that were not in it.
+//
+// Panics if the given Set holds an item that is not a non-negative int.
+func (s *BitSet) SymmetricDifferenceWith(o Set) {
+	s.CheckInit()
+
+	ow := wordsOf(o)
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.grow(len(ow))
+	for i, w := range ow {
+		s.words[i] ^= w
+	}
+	s.trim()
+}
+
+// Returns true if this Set and the given Set contain exactly the same items,
+// false otherwise.
+func (s *BitSet) Equal(o Set) bool {
+	s.CheckInit()
+
+	if _, ok := o.(*BitSet); !ok && s.Size() != o.Size() {
+		return false
+	}
+
+	ow, ok := s.wordsInExactly(o)
+	if !ok {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for i := 0; i < len(s.words) || i < len(ow); i++ {
+		if word(s.words, i) != word(ow, i) {
+			return false
+		}
+	}
+	return true
+}
+
+// The first bool returned is true if this Set is a subset of the given Set,
+// false otherwise. If the first returned bool is true, then the second bool
+// will be false if these two sets are equal, true otherwise.
+//
+// true, true -> s is a proper subset of o
+// true, false -> s is equal to o
+// false, true -> s is not a subset of o
+// false, false -> s is not a subset of o
+func (s *BitSet) Subset(o Set) (subset bool, proper bool) {
+	s.CheckInit()
+
+	proper = s.Size() != o.Size()
+
+	ow := s.wordsIn(o)
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for i, w := range s.words {
+		if w&^word(ow, i) != 0 {
+			return false, proper
+		}
+	}
+	return true, proper
+}
+
+// The first bool returned is true if this Set is a superset of the given
+// Set, false otherwise. If the first returned bool is true, then the
+// second bool will be false if these two sets are equal, true otherwise.
+//
+// true, true -> s is a proper superset of o
+// true, false -> s is equal to o
+// false, true -> s is not a superset of o
+// false, false -> s is not a superset of o
+func (s *BitSet) Superset(o Set) (superset bool, proper bool) {
+	s.CheckInit()
+
+	proper = s.Size() != o.Size()
+
+	ow, ok := s.wordsInExactly(o)
+	if !ok {
+		return false, proper
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for i, w := range ow {
+		if w&^word(s.words, i) != 0 {
+			return false, proper
+		}
+	}
+	return true, proper
+}
+
+// Returns a pointer to a new Set that is a copy of this Set.
+func (s *BitSet) Copy() Set {
+	return s.copyBitSet()
+}
+
+// Attempts to apply the given function to every item in this Set, in
+// ascending order. Stops once all elements have been processed, or once
+// the function returns false, whichever occurs first.
+func (s *BitSet) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for i := s.next(0); i >= 0; i = s.next(i + 1) {
+		if !f(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a slice of all the items in this Set, in ascending order.
+func (s *BitSet) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, popcount(s.words))
+	for i := s.next(0); i >= 0; i = s.next(i + 1) {
+		slice = append(slice, i)
+	}
+	return &slice
+}
+
+// Removes all items from this Set.
+func (s *BitSet) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.words = nil
+}
+
+func (s *BitSet) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *BitSet) set(i int) {
+	s.grow(i/64 + 1)
+	s.words[i/64] |= 1 << (i % 64)
+}
+
+func (s *BitSet) clear(i int) {
+	if i/64 < len(s.words) {
+		s.words[i/64] &^= 1 << (i % 64)
+		s.trim()
+	}
+}
+
+func (s *BitSet) test(i int) bool {
+	return word(s.words, i/64)&(1<<(i%64)) != 0
+}
+
+// Returns the smallest item greater than or equal to from, or -1.
+func (s *BitSet) next(from int) int {
+	if from < 0 {
+		from = 0
+	}
+	w := from / 64
+	if w >= len(s.words) {
+		return -1
+	}
+	word := s.words[w] >> (from % 64) << (from % 64)
+	for {
+		if word != 0 {
+			return 64*w + bits.TrailingZeros64(word)
+		}
+		w += 1
+		if w == len(s.words) {
+			return -1
+		}
+		word = s.words[w]
+	}
+}
+
+// Extends words to at least n words.
+func (s *BitSet) grow(n int) {
+	if n <= len(s.words) {
+		return
+	}
+	if n <= cap(s.words) {
+		s.words = s.words[:n]
+		return
+	}
+	words := make([]uint64, n, 2*n)
+	copy(words, s.words)
+	s.words = words
+}
+
+// Drops trailing zero words, so that a BitSet shrinks as its largest items
+// are removed.
+func (s *BitSet) trim() {
+	n := len(s.words)
+	for n > 0 && s.words[n-1] == 0 {
+		n -= 1
+	}
+	s.words = s.words[:n]
+}
+
+// Returns a new BitSet, as thread-safe as this one, with the same items.
+func (s *BitSet) copyBitSet() *BitSet {
+	s.CheckInit()
+
+	c := &BitSet{}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.words = make([]uint64, len(s.words))
+	copy(c.words, s.words)
+	return c
+}
+
+// Returns the words of a BitSet holding the items of the given Set that are
+// non-negative ints, and so might be in this BitSet. Taken before locking
+// this BitSet, so that the two are never locked at once.
+func (s *BitSet) wordsIn(o Set) []uint64 {
+	if ob, ok := o.(*BitSet); ok {
+		return ob.copyBitSet().words
+	}
+
+	// Only items that might be in this BitSet matter.
+	t := &BitSet{}
+	t.InitUnsafe()
+	o.Map(func(item interface{}) bool {
+		if i, ok := item.(int); ok && i >= 0 {
+			t.set(i)
+		}
+		return true
+	})
+	return t.words
+}
+
+// Like wordsIn(), but returns false if the given Set holds anything that is
+// not a non-negative int, and so can't be in this BitSet.
+func (s *BitSet) wordsInExactly(o Set) ([]uint64, bool) {
+	if ob, ok := o.(*BitSet); ok {
+		return ob.copyBitSet().words, true
+	}
+
+	t := &BitSet{}
+	t.InitUnsafe()
+	ok := o.Map(func(item interface{}) bool {
+		i, ok := item.(int)
+		if ok && i >= 0 {
+			t.set(i)
+		}
+		return ok && i >= 0
+	})
+	return t.words, ok
+}
+
+// Returns the words of a BitSet holding the items of the given Set.
+//
+// Panics if the given Set holds an item that is not a non-negative int.
+func wordsOf(o Set) []uint64 {
+	if ob, ok := o.(*BitSet); ok {
+		return ob.copyBitSet().words
+	}
+
+	t := &BitSet{}
+	t.InitUnsafe()
+	o.Map(func(item interface{}) bool {
+		t.set(bitIndex(item))
+		return true
+	})
+	return t.words
+}
+
+// Returns the i-th word of the given words, or 0 past their end.
+func word(words []uint64, i int) uint64 {
+	if i < len(words) {
+		return words[i]
+	}
+	return 0
+}
+
+// Returns the number of set bits in the given words.
+func popcount(words []uint64) int {
+	count := 0
+	for _, w := range words {
+		count += bits.OnesCount64(w)
+	}
+	return count
+}
+
+// Returns the given item as an index into a BitSet.
+//
+// Panics if the given item is not a non-negative int.
+func bitIndex(item interface{}) int {
+	i, ok := item.(int)
+	if !ok || i < 0 {
+		log.Panicf("Item %v is not a non-negative int.", item)
+	}
+	return i
+}
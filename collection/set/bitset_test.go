@@ -0,0 +1,230 @@
+// This module contains tests for bitset.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestNewEmptyBitSet(t *testing.T) {
+	s := NewBitSet()
+
+	if s.Size() != 0 || !s.Empty() {
+		t.Error("NewBitSet with 0 args does not create an empty set!")
+	}
+	if s.NextSetBit(0) != -1 || s.PrevSetBit(100) != -1 {
+		t.Error("Empty set has set bits!")
+	}
+}
+
+func TestSetClearTestBitSet(t *testing.T) {
+	s := NewBitSet(0, 63, 64, 1000)
+
+	s.SetBit(5)
+	s.ClearBit(1000)
+	s.ClearBit(2000)
+
+	if fmt.Sprint(*s.Slice()) != "[0 5 63 64]" || s.Size() != 4 {
+		t.Errorf("Wrong items: %v", *s.Slice())
+	}
+	if !s.Test(63) || s.Test(62) || s.Test(1000) || s.Test(-1) {
+		t.Error("Test is wrong!")
+	}
+	if s.Contains("a") || s.Contains(5, 6) || !s.Contains(5, 64) {
+		t.Error("Contains is wrong!")
+	}
+	if len(s.words) != 2 {
+		t.Errorf("Removing the largest item did not shrink the set: %d words", len(s.words))
+	}
+}
+
+func TestInvalidItemBitSet(t *testing.T) {
+	for _, item := range []interface{}{-1, "a", uint(1)} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Inserting %v did not panic!", item)
+				}
+			}()
+			NewBitSet(item)
+		}()
+	}
+}
+
+func TestNextPrevSetBitBitSet(t *testing.T) {
+	s := NewBitSet(3, 64, 130)
+
+	cases := []struct{ from, next, prev int }{
+		{-5, 3, -1},
+		{0, 3, -1},
+		{3, 3, 3},
+		{4, 64, 3},
+		{64, 64, 64},
+		{65, 130, 64},
+		{130, 130, 130},
+		{131, -1, 130},
+		{1000, -1, 130},
+	}
+	for _, c := range cases {
+		if next := s.NextSetBit(c.from); next != c.next {
+			t.Errorf("NextSetBit(%d) = %d, expected %d", c.from, next, c.next)
+		}
+		if prev := s.PrevSetBit(c.from); prev != c.prev {
+			t.Errorf("PrevSetBit(%d) = %d, expected %d", c.from, prev, c.prev)
+		}
+	}
+}
+
+func TestSetOperationsBitSet(t *testing.T) {
+	a := NewBitSet(1, 2, 3, 100)
+	b := NewBitSet(2, 3, 4, 200)
+
+	if u := a.Union(b); fmt.Sprint(*u.Slice()) != "[1 2 3 4 100 200]" {
+		t.Errorf("Wrong union: %v", *u.Slice())
+	}
+	if i := a.Intersection(b); fmt.Sprint(*i.Slice()) != "[2 3]" {
+		t.Errorf("Wrong intersection: %v", *i.Slice())
+	}
+	if d := a.Difference(b); fmt.Sprint(*d.Slice()) != "[1 100]" {
+		t.Errorf("Wrong difference: %v", *d.Slice())
+	}
+	if x := a.SymmetricDifference(b); fmt.Sprint(*x.Slice()) != "[1 4 100 200]" {
+		t.Errorf("Wrong symmetric difference: %v", *x.Slice())
+	}
+	if fmt.Sprint(*a.Slice()) != "[1 2 3 100]" || fmt.Sprint(*b.Slice()) != "[2 3 4 200]" {
+		t.Error("Operations changed their operands!")
+	}
+}
+
+func TestInPlaceOperationsBitSet(t *testing.T) {
+	a := NewBitSet(1, 2, 3, 100)
+	a.UnionWith(NewBitSet(4, 300))
+	if fmt.Sprint(*a.Slice()) != "[1 2 3 4 100 300]" {
+		t.Errorf("Wrong UnionWith: %v", *a.Slice())
+	}
+	a.IntersectWith(NewBitSet(1, 2, 3, 4))
+	if fmt.Sprint(*a.Slice()) != "[1 2 3 4]" || len(a.words) != 1 {
+		t.Errorf("Wrong IntersectWith: %v", *a.Slice())
+	}
+	a.DifferenceWith(NewBitSet(1))
+	if fmt.Sprint(*a.Slice()) != "[2 3 4]" {
+		t.Errorf("Wrong DifferenceWith: %v", *a.Slice())
+	}
+	a.SymmetricDifferenceWith(NewBitSet(4, 5))
+	if fmt.Sprint(*a.Slice()) != "[2 3 5]" {
+		t.Errorf("Wrong SymmetricDifferenceWith: %v", *a.Slice())
+	}
+	a.UnionWith(a)
+	a.SymmetricDifferenceWith(a)
+	if !a.Empty() {
+		t.Error("Symmetric difference with itself is not empty!")
+	}
+}
+
+func TestOtherSetsBitSet(t *testing.T) {
+	a := NewBitSet(1, 2, 3)
+	h := NewHashSet(2, 3, 4, "a")
+
+	if u := a.Union(NewHashSet(4)); fmt.Sprint(*u.Slice()) != "[1 2 3 4]" {
+		t.Errorf("Wrong union with a HashSet: %v", *u.Slice())
+	}
+	if i := a.Intersection(h); fmt.Sprint(*i.Slice()) != "[2 3]" {
+		t.Errorf("Wrong intersection with a HashSet: %v", *i.Slice())
+	}
+	if d := a.Difference(h); fmt.Sprint(*d.Slice()) != "[1]" {
+		t.Errorf("Wrong difference with a HashSet: %v", *d.Slice())
+	}
+	if !a.Equal(NewHashSet(3, 2, 1)) || a.Equal(h) || a.Equal(NewHashSet(1, 2, "a")) {
+		t.Error("Wrong equality with a HashSet!")
+	}
+	if sub, proper := a.Subset(NewHashSet(1, 2, 3, "a")); !sub || !proper {
+		t.Error("Wrong subset of a HashSet!")
+	}
+	if sup, _ := a.Superset(NewHashSet(1, "a")); sup {
+		t.Error("Superset of a set with non-ints!")
+	}
+	if sup, proper := a.Superset(NewHashSet(1, 2)); !sup || !proper {
+		t.Error("Wrong superset of a HashSet!")
+	}
+}
+
+func TestRandomOperationsBitSet(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	for trial := 0; trial < 20; trial++ {
+		a, b := NewBitSet(), NewBitSet()
+		ha, hb := NewHashSet(), NewHashSet()
+		for i := 0; i < 200; i++ {
+			x, y := r.Intn(1000), r.Intn(500)
+			a.Insert(x)
+			ha.Insert(x)
+			b.Insert(y)
+			hb.Insert(y)
+		}
+
+		if !a.Union(b).Equal(ha.Union(hb)) || !a.Intersection(b).Equal(ha.Intersection(hb)) ||
+			!a.Difference(b).Equal(ha.Difference(hb)) || !b.Difference(a).Equal(hb.Difference(ha)) {
+			t.Fatal("Word-parallel operations disagree with HashSet!")
+		}
+		if a.Size() != ha.Size() {
+			t.Fatalf("Size %d, expected %d", a.Size(), ha.Size())
+		}
+		sub, _ := a.Intersection(b).Subset(a)
+		sup, _ := a.Union(b).Superset(b)
+		if !sub || !sup {
+			t.Fatal("Wrong subset or superset!")
+		}
+	}
+}
+
+func TestCopyClearBitSet(t *testing.T) {
+	s := NewBitSetUnsafe(1, 2)
+	c := s.Copy()
+	s.Clear()
+
+	if !s.Empty() || s.Contains(1) {
+		t.Error("Clear did not empty the set!")
+	}
+	if fmt.Sprint(c) != "&[1 2]" {
+		t.Errorf("Clear changed a copy: %v", c)
+	}
+}
+
+func BenchmarkUnionBitSet(b *testing.B) {
+	x, y := NewBitSetUnsafe(), NewBitSetUnsafe()
+	for i := 0; i < 100000; i++ {
+		x.Insert(rand.Intn(1000000))
+		y.Insert(rand.Intn(1000000))
+	}
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		x.Union(y)
+	}
+}
+
+func TestIsDisjointBitSet(t *testing.T) {
+	s := NewBitSet(1, 64, 200)
+
+	cases := []struct {
+		o        Set
+		disjoint bool
+	}{
+		{NewBitSet(), true},
+		{NewBitSet(0, 2, 65, 199, 1000), true},
+		{NewBitSet(200), false},
+		{NewHashSet(64, "a"), false},
+		{NewHashSet(-1, "a", 2), true},
+	}
+	for i, c := range cases {
+		if s.IsDisjoint(c.o) != c.disjoint {
+			t.Errorf("Case %d: IsDisjoint should be %v", i, c.disjoint)
+		}
+	}
+}
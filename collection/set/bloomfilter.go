@@ -0,0 +1,360 @@
+// This module implements a BloomFilter, a probabilistic set.
+
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"math"
+	"math/bits"
+)
+
+// Returned when combining probabilistic sets with different parameters.
+var ErrIncompatibleFilters = errors.New("filters have different sizes or hash counts")
+
+// Returned when unmarshaling bytes that don't hold a valid filter.
+var ErrInvalidEncoding = errors.New("invalid filter encoding")
+
+// Identifies, and versions, the binary encoding of a BloomFilter.
+var bloomMagic = [4]byte{'B', 'L', 'M', 1}
+
+// magic, hashes, bits, count, expected items, false positive rate.
+const bloomHeaderSize = 4 + 4 + 8 + 8 + 8 + 8
+
+// A BloomFilter is a probabilistic set. It may report that it contains an
+// item that was never added to it, but never that it doesn't contain an item
+// that was, and uses a small, fixed amount of memory however many items are
+// added.
+//
+// It is sized from the number of items it is expected to hold, and the false
+// positive rate wanted once it holds them. Adding more items raises the rate.
+// Items are hashed with double hashing, see hashItem(), so a filter may be
+// marshaled in one process and unmarshaled in another.
+//
+// Items can't be removed from, or listed by, a BloomFilter, so it is not a
+// Set. Its Size() is the number of Add()ed items that changed it, which is
+// at most the number of distinct items added.
+//
+// Behavior unspecified if a BloomFilter is not created using NewBloomFilter(),
+// NewBloomFilterUnsafe(), or unmarshaled into a new &BloomFilter{}.
+//
+type BloomFilter struct {
+	collection.Base
+	bits     []uint64
+	m        uint64 // The number of bits, a multiple of 64.
+	k        int    // The number of hashes.
+	expected int
+	rate     float64
+}
+
+// Returns a pointer to a new BloomFilter, sized to hold the given number of
+// items with the given false positive rate.
+//
+// Panics if expectedItems is not positive, or falsePositiveRate is not
+// strictly between 0 and 1.
+func NewBloomFilter(expectedItems int, falsePositiveRate float64) *BloomFilter {
+	s := &BloomFilter{expected: expectedItems, rate: falsePositiveRate}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe BloomFilter, sized to hold the given
+// number of items with the given false positive rate.
+//
+// Panics if expectedItems is not positive, or falsePositiveRate is not
+// strictly between 0 and 1.
+func NewBloomFilterUnsafe(expectedItems int, falsePositiveRate float64) *BloomFilter {
+	s := &BloomFilter{expected: expectedItems, rate: falsePositiveRate}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *BloomFilter) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *BloomFilter) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Adds the given items to this BloomFilter.
+//
+// Panics if any of the given items are nil.
+func (s *BloomFilter) Add(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		h1, h2 := hashItem(item)
+		changed := false
+		for i := 0; i < s.k; i++ {
+			bit := (h1 + uint64(i)*h2) % s.m
+			if s.bits[bit/64]&(1<<(bit%64)) == 0 {
+				s.bits[bit/64] |= 1 << (bit % 64)
+				changed = true
+			}
+		}
+		if changed {
+			s.Sizeb += 1
+		}
+	}
+}
+
+// Returns true if all the given items might have been added to this
+// BloomFilter, false if any definitely were not.
+//
+// Panics if any of the given items are nil.
+func (s *BloomFilter) MightContain(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		h1, h2 := hashItem(item)
+		for i := 0; i < s.k; i++ {
+			bit := (h1 + uint64(i)*h2) % s.m
+			if s.bits[bit/64]&(1<<(bit%64)) == 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Returns a pointer to a new BloomFilter which might contain every item that
+// might be in either this BloomFilter or the given BloomFilter. Its Size() is
+// its ApproximateCount().
+//
+// Returns ErrIncompatibleFilters if the two were not created with the same
+// expected items and false positive rate.
+func (s *BloomFilter) Union(o *BloomFilter) (*BloomFilter, error) {
+	s.CheckInit()
+
+	// Snapshot o first, so the two are never locked at once.
+	result := o.Copy()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.m != result.m || s.k != result.k {
+		return nil, ErrIncompatibleFilters
+	}
+
+	for i, w := range s.bits {
+		result.bits[i] |= w
+	}
+	result.Sizeb = int(math.Min(math.Round(result.approximateCount()), float64(result.m)))
+	return result, nil
+}
+
+// Returns the probability that MightContain() returns true for an item that
+// was never added, given the items added so far.
+func (s *BloomFilter) EstimatedFalsePositiveRate() float64 {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return math.Pow(float64(s.ones())/float64(s.m), float64(s.k))
+}
+
+// Returns an estimate of the number of distinct items added to this
+// BloomFilter, from the number of its bits that are set.
+//
+// See Swamidass and Baldi, "Mathematical correction for fingerprint
+// similarity measures to improve chemical retrieval", 2007.
+func (s *BloomFilter) ApproximateCount() float64 {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.approximateCount()
+}
+
+// Returns the number of bits in this BloomFilter.
+func (s *BloomFilter) Bits() int {
+	s.CheckInit()
+	return int(s.m)
+}
+
+// Returns the number of hashes this BloomFilter sets, and checks, per item.
+func (s *BloomFilter) Hashes() int {
+	s.CheckInit()
+	return s.k
+}
+
+// Returns a pointer to a new BloomFilter, that is a copy of this BloomFilter.
+func (s *BloomFilter) Copy() *BloomFilter {
+	s.CheckInit()
+
+	c := &BloomFilter{expected: s.expected, rate: s.rate}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	copy(c.bits, s.bits)
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Removes all items from this BloomFilter.
+func (s *BloomFilter) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for i := range s.bits {
+		s.bits[i] = 0
+	}
+	s.Sizeb = 0
+}
+
+func (s *BloomFilter) String() string {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return fmt.Sprintf("BloomFilter{bits: %d, hashes: %d, size: %d}", s.m, s.k, s.Sizeb)
+}
+
+// Implements encoding.BinaryMarshaler. The encoding is the same on every
+// platform.
+func (s *BloomFilter) MarshalBinary() ([]byte, error) {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	data := make([]byte, bloomHeaderSize+8*len(s.bits))
+	copy(data, bloomMagic[:])
+	binary.BigEndian.PutUint32(data[4:], uint32(s.k))
+	binary.BigEndian.PutUint64(data[8:], s.m)
+	binary.BigEndian.PutUint64(data[16:], uint64(s.Sizeb))
+	binary.BigEndian.PutUint64(data[24:], uint64(s.expected))
+	binary.BigEndian.PutUint64(data[32:], math.Float64bits(s.rate))
+	for i, w := range s.bits {
+		binary.BigEndian.PutUint64(data[bloomHeaderSize+8*i:], w)
+	}
+	return data, nil
+}
+
+// Implements encoding.BinaryUnmarshaler, replacing the contents of this
+// BloomFilter with those encoded in the given bytes. A new &BloomFilter{}
+// is initialized by this, and will be thread-safe.
+//
+// Returns ErrInvalidEncoding if the given bytes weren't produced by
+// MarshalBinary(), in which case this BloomFilter is left unchanged.
+func (s *BloomFilter) UnmarshalBinary(data []byte) error {
+	if len(data) < bloomHeaderSize || !bytes.Equal(data[:4], bloomMagic[:]) {
+		return ErrInvalidEncoding
+	}
+
+	k := int(binary.BigEndian.Uint32(data[4:]))
+	m := binary.BigEndian.Uint64(data[8:])
+	size := binary.BigEndian.Uint64(data[16:])
+	expected := binary.BigEndian.Uint64(data[24:])
+	rate := math.Float64frombits(binary.BigEndian.Uint64(data[32:]))
+
+	if k < 1 || m == 0 || m%64 != 0 || uint64(len(data)-bloomHeaderSize) != m/8 ||
+		size > m || expected == 0 || expected > math.MaxInt32 || !(rate > 0 && rate < 1) {
+		return ErrInvalidEncoding
+	}
+
+	words := make([]uint64, m/64)
+	for i := range words {
+		words[i] = binary.BigEndian.Uint64(data[bloomHeaderSize+8*i:])
+	}
+
+	// A BloomFilter can't have been initialized without setting m.
+	if s.m == 0 {
+		s.InitBase()
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.bits, s.m, s.k = words, m, k
+	s.expected, s.rate = int(expected), rate
+	s.Sizeb = int(size)
+	return nil
+}
+
+func (s *BloomFilter) setup() {
+	s.m, s.k = bloomSize(s.expected, s.rate)
+	s.m = (s.m + 63) / 64 * 64
+	s.bits = make([]uint64, s.m/64)
+}
+
+// Returns the number of bits m, and hashes k, for a Bloom filter holding n
+// items with false positive rate p: m = -n ln(p) / ln(2)^2, k = (m / n) ln(2).
+//
+// Panics if n is not positive, or p is not strictly between 0 and 1.
+func bloomSize(n int, p float64) (m uint64, k int) {
+	if n <= 0 {
+		log.Panic("Expected items must be positive.")
+	}
+	if !(p > 0 && p < 1) {
+		log.Panic("False positive rate must be between 0 and 1.")
+	}
+
+	m = uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	k = int(math.Max(1, math.Round(float64(m)/float64(n)*math.Ln2)))
+	return
+}
+
+// Returns the number of set bits.
+func (s *BloomFilter) ones() int {
+	ones := 0
+	for _, w := range s.bits {
+		ones += bits.OnesCount64(w)
+	}
+	return ones
+}
+
+// Returns -(m / k) ln(1 - X / m), where X is the number of set bits.
+func (s *BloomFilter) approximateCount() float64 {
+	ones := s.ones()
+	if uint64(ones) == s.m {
+		return math.Inf(1)
+	}
+	return -float64(s.m) / float64(s.k) * math.Log(1-float64(ones)/float64(s.m))
+}
@@ -0,0 +1,201 @@
+// This module contains tests for bloomfilter.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestNewEmptyBloomFilter(t *testing.T) {
+	s := NewBloomFilter(1000, 0.01)
+
+	if s.Size() != 0 || !s.Empty() {
+		t.Error("NewBloomFilter does not create an empty filter!")
+	}
+	if s.MightContain("a") || s.MightContain() {
+		t.Error("Empty filter might contain an item!")
+	}
+	if s.ApproximateCount() != 0 || s.EstimatedFalsePositiveRate() != 0 {
+		t.Error("Empty filter has a non-zero count or false positive rate!")
+	}
+}
+
+func TestSizingBloomFilter(t *testing.T) {
+	s := NewBloomFilter(1000, 0.01)
+
+	// 9585 bits, rounded up to 9600, and 7 hashes.
+	if s.Bits() != 9600 || s.Hashes() != 7 {
+		t.Errorf("Wrong sizing: %d bits, %d hashes", s.Bits(), s.Hashes())
+	}
+}
+
+func TestInvalidBloomFilter(t *testing.T) {
+	for _, args := range [][2]float64{{0, 0.1}, {10, 0}, {10, 1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewBloomFilter%v did not panic!", args)
+				}
+			}()
+			NewBloomFilter(int(args[0]), args[1])
+		}()
+	}
+}
+
+func TestFalsePositivesBloomFilter(t *testing.T) {
+	n := 10000
+	s := NewBloomFilter(n, 0.01)
+
+	for i := 0; i < n; i++ {
+		s.Add(i)
+	}
+	for i := 0; i < n; i++ {
+		if !s.MightContain(i) {
+			t.Fatalf("False negative for %d!", i)
+		}
+	}
+
+	falsePositives := 0
+	for i := n; i < 11*n; i++ {
+		if s.MightContain(i) {
+			falsePositives += 1
+		}
+	}
+	rate := float64(falsePositives) / float64(10*n)
+	if rate > 0.015 {
+		t.Errorf("False positive rate %v, expected about 0.01", rate)
+	}
+	if estimate := s.EstimatedFalsePositiveRate(); math.Abs(estimate-rate) > 0.005 {
+		t.Errorf("Estimated false positive rate %v, measured %v", estimate, rate)
+	}
+	if count := s.ApproximateCount(); math.Abs(count-float64(n)) > 0.03*float64(n) {
+		t.Errorf("Approximate count %v, expected about %d", count, n)
+	}
+	if s.Size() > n || s.Size() < n*99/100 {
+		t.Errorf("Wrong size %d", s.Size())
+	}
+}
+
+func TestItemKindsBloomFilter(t *testing.T) {
+	s := NewBloomFilter(100, 0.001)
+
+	s.Add("1", []byte("b"), true, 2.5, uint8(3), S{"x"})
+
+	if !s.MightContain("1", []byte("b"), true, 2.5, uint8(3), S{"x"}) {
+		t.Error("False negative!")
+	}
+	if s.MightContain(1) || s.MightContain("b") || s.MightContain(false) || s.MightContain(S{"y"}) {
+		t.Error("Items of different kinds or values collide!")
+	}
+}
+
+func TestUnionBloomFilter(t *testing.T) {
+	a := NewBloomFilter(1000, 0.01)
+	b := NewBloomFilter(1000, 0.01)
+
+	for i := 0; i < 500; i++ {
+		a.Add(i)
+		b.Add(i + 500)
+	}
+
+	u, err := a.Union(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 1000; i++ {
+		if !u.MightContain(i) {
+			t.Fatalf("False negative for %d in union!", i)
+		}
+	}
+	if math.Abs(float64(u.Size())-1000) > 30 {
+		t.Errorf("Union size %d, expected about 1000", u.Size())
+	}
+	if a.MightContain(999) && a.MightContain(998) && a.MightContain(997) {
+		t.Error("Union changed its receiver!")
+	}
+
+	if _, err := a.Union(NewBloomFilter(1000, 0.02)); err != ErrIncompatibleFilters {
+		t.Errorf("Expected ErrIncompatibleFilters, got %v", err)
+	}
+}
+
+func TestMarshalBloomFilter(t *testing.T) {
+	s := NewBloomFilter(100, 0.05)
+	s.Add("hello", "world")
+
+	data, err := s.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &BloomFilter{}
+	if err := c.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if !c.MightContain("hello", "world") || c.Size() != 2 {
+		t.Error("Unmarshaled filter lost items!")
+	}
+	if c.Bits() != s.Bits() || c.Hashes() != s.Hashes() {
+		t.Error("Unmarshaled filter has different parameters!")
+	}
+	if fmt.Sprint(c) != fmt.Sprint(s) {
+		t.Errorf("%v != %v", c, s)
+	}
+	if _, err := c.Union(s); err != nil {
+		t.Error("Unmarshaled filter is incompatible with the original!")
+	}
+
+	// An initialized filter may be overwritten.
+	o := NewBloomFilter(5, 0.5)
+	if err := o.UnmarshalBinary(data); err != nil || !o.MightContain("hello") {
+		t.Error("Failed to unmarshal into an initialized filter!")
+	}
+}
+
+func TestMarshalInvalidBloomFilter(t *testing.T) {
+	data, _ := NewBloomFilter(100, 0.05).MarshalBinary()
+
+	bad := [][]byte{
+		nil,
+		data[:10],
+		data[:len(data)-1],
+		append([]byte{'X'}, data[1:]...),
+	}
+	for i, b := range bad {
+		c := NewBloomFilter(5, 0.5)
+		if err := c.UnmarshalBinary(b); err != ErrInvalidEncoding {
+			t.Errorf("Case %d: expected ErrInvalidEncoding, got %v", i, err)
+		}
+		if c.Bits() != 64 {
+			t.Errorf("Case %d: failed unmarshal changed the filter!", i)
+		}
+	}
+}
+
+func TestClearBloomFilter(t *testing.T) {
+	s := NewBloomFilterUnsafe(100, 0.01)
+	s.Add("a")
+	c := s.Copy()
+	s.Clear()
+
+	if !s.Empty() || s.MightContain("a") {
+		t.Error("Clear did not empty the filter!")
+	}
+	if !c.MightContain("a") {
+		t.Error("Clear changed a copy!")
+	}
+}
+
+func BenchmarkAddBloomFilter(b *testing.B) {
+	s := NewBloomFilterUnsafe(b.N+1, 0.01)
+
+	for i := 0; i < b.N; i++ {
+		s.Add(i)
+	}
+}
@@ -0,0 +1,235 @@
+// This module implements a CountingBloomFilter, a probabilistic set
+// supporting removal.
+
+package set
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"math"
+)
+
+// The value at which a CountingBloomFilter's counters stick.
+const maxCount = math.MaxUint8
+
+// A CountingBloomFilter is a BloomFilter with a counter in place of each
+// bit, so that items may be removed as well as added. It uses eight times
+// the memory of a BloomFilter with the same parameters.
+//
+// Only remove items that were added. Removing an item that was never added,
+// but MightContain() it anyway, can cause false negatives for other items.
+// A counter that reaches 255 sticks there, and is never decremented.
+//
+// Its Size() is the number of items added, less those removed.
+//
+// Behavior unspecified if a CountingBloomFilter is not created using
+// NewCountingBloomFilter() or NewCountingBloomFilterUnsafe().
+//
+type CountingBloomFilter struct {
+	collection.Base
+	counts   []uint8
+	k        int // The number of hashes.
+	expected int
+	rate     float64
+}
+
+// Returns a pointer to a new CountingBloomFilter, sized to hold the given
+// number of items with the given false positive rate.
+//
+// Panics if expectedItems is not positive, or falsePositiveRate is not
+// strictly between 0 and 1.
+func NewCountingBloomFilter(expectedItems int, falsePositiveRate float64) *CountingBloomFilter {
+	s := &CountingBloomFilter{expected: expectedItems, rate: falsePositiveRate}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe CountingBloomFilter, sized to hold the
+// given number of items with the given false positive rate.
+//
+// Panics if expectedItems is not positive, or falsePositiveRate is not
+// strictly between 0 and 1.
+func NewCountingBloomFilterUnsafe(expectedItems int, falsePositiveRate float64) *CountingBloomFilter {
+	s := &CountingBloomFilter{expected: expectedItems, rate: falsePositiveRate}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *CountingBloomFilter) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *CountingBloomFilter) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Adds the given items to this CountingBloomFilter. An item added twice
+// must be removed twice.
+//
+// Panics if any of the given items are nil.
+func (s *CountingBloomFilter) Add(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		h1, h2 := hashItem(item)
+		for i := 0; i < s.k; i++ {
+			if c := &s.counts[s.index(h1, h2, i)]; *c < maxCount {
+				*c += 1
+			}
+		}
+		s.Sizeb += 1
+	}
+}
+
+// Removes one occurrence of the given item from this CountingBloomFilter.
+// Returns false, and does nothing, if the item was definitely not in it,
+// and true otherwise.
+//
+// Panics if the given item is nil.
+func (s *CountingBloomFilter) Remove(item interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	h1, h2 := hashItem(item)
+	if !s.contains(h1, h2) {
+		return false
+	}
+
+	for i := 0; i < s.k; i++ {
+		if c := &s.counts[s.index(h1, h2, i)]; *c < maxCount {
+			*c -= 1
+		}
+	}
+	s.Sizeb -= 1
+	return true
+}
+
+// Returns true if all the given items might be in this CountingBloomFilter,
+// false if any definitely are not.
+//
+// Panics if any of the given items are nil.
+func (s *CountingBloomFilter) MightContain(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		if !s.contains(hashItem(item)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the number of items this CountingBloomFilter was sized to hold.
+func (s *CountingBloomFilter) Capacity() int {
+	s.CheckInit()
+	return s.expected
+}
+
+// Returns the probability that MightContain() returns true for an item that
+// is not in this CountingBloomFilter, given the items in it now.
+func (s *CountingBloomFilter) EstimatedFalsePositiveRate() float64 {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	nonzero := 0
+	for _, c := range s.counts {
+		if c != 0 {
+			nonzero += 1
+		}
+	}
+	return math.Pow(float64(nonzero)/float64(len(s.counts)), float64(s.k))
+}
+
+// Returns a pointer to a new CountingBloomFilter, that is a copy of this
+// CountingBloomFilter.
+func (s *CountingBloomFilter) Copy() *CountingBloomFilter {
+	s.CheckInit()
+
+	c := &CountingBloomFilter{expected: s.expected, rate: s.rate}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	copy(c.counts, s.counts)
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Removes all items from this CountingBloomFilter.
+func (s *CountingBloomFilter) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for i := range s.counts {
+		s.counts[i] = 0
+	}
+	s.Sizeb = 0
+}
+
+func (s *CountingBloomFilter) String() string {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return fmt.Sprintf("CountingBloomFilter{counters: %d, hashes: %d, size: %d}", len(s.counts), s.k, s.Sizeb)
+}
+
+func (s *CountingBloomFilter) setup() {
+	m, k := bloomSize(s.expected, s.rate)
+	s.counts = make([]uint8, m)
+	s.k = k
+}
+
+// Returns the index of the i-th counter for an item with the given hashes.
+func (s *CountingBloomFilter) index(h1 uint64, h2 uint64, i int) uint64 {
+	return (h1 + uint64(i)*h2) % uint64(len(s.counts))
+}
+
+// Returns true if all the counters for an item with the given hashes are
+// non-zero.
+func (s *CountingBloomFilter) contains(h1 uint64, h2 uint64) bool {
+	for i := 0; i < s.k; i++ {
+		if s.counts[s.index(h1, h2, i)] == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+// This module contains tests for countingbloomfilter.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"testing"
+)
+
+func TestNewEmptyCountingBloomFilter(t *testing.T) {
+	s := NewCountingBloomFilter(1000, 0.01)
+
+	if s.Size() != 0 || !s.Empty() || s.Capacity() != 1000 {
+		t.Error("NewCountingBloomFilter does not create an empty filter!")
+	}
+	if s.MightContain("a") || s.Remove("a") {
+		t.Error("Empty filter might contain an item!")
+	}
+}
+
+func TestAddRemoveCountingBloomFilter(t *testing.T) {
+	n := 5000
+	s := NewCountingBloomFilter(n, 0.01)
+
+	for i := 0; i < n; i++ {
+		s.Add(i)
+	}
+	if s.Size() != n {
+		t.Errorf("Wrong size %d", s.Size())
+	}
+	for i := 0; i < n; i += 2 {
+		if !s.Remove(i) {
+			t.Fatalf("Failed to remove %d!", i)
+		}
+	}
+	if s.Size() != n/2 {
+		t.Errorf("Wrong size %d after removing", s.Size())
+	}
+
+	falsePositives := 0
+	for i := 0; i < n; i++ {
+		if i%2 == 1 && !s.MightContain(i) {
+			t.Fatalf("False negative for %d!", i)
+		}
+		if i%2 == 0 && s.MightContain(i) {
+			falsePositives += 1
+		}
+	}
+	// Half full, so well below the 1% rate at capacity.
+	if rate := float64(falsePositives) / float64(n/2); rate > 0.01 {
+		t.Errorf("False positive rate %v after removing", rate)
+	}
+	if estimate := s.EstimatedFalsePositiveRate(); estimate > 0.01 {
+		t.Errorf("Estimated false positive rate %v after removing", estimate)
+	}
+}
+
+func TestDuplicatesCountingBloomFilter(t *testing.T) {
+	s := NewCountingBloomFilter(100, 0.01)
+
+	s.Add("a", "a")
+	s.Remove("a")
+	if !s.MightContain("a") {
+		t.Error("Item added twice was removed once!")
+	}
+	s.Remove("a")
+	if s.MightContain("a") || !s.Empty() {
+		t.Error("Item added twice was not removed twice!")
+	}
+}
+
+func TestSaturationCountingBloomFilter(t *testing.T) {
+	s := NewCountingBloomFilterUnsafe(10, 0.1)
+
+	for i := 0; i < 300; i++ {
+		s.Add("a")
+	}
+	for i := 0; i < 300; i++ {
+		s.Remove("a")
+	}
+	if !s.MightContain("a") {
+		t.Error("Saturated counters were decremented!")
+	}
+}
+
+func TestCopyClearCountingBloomFilter(t *testing.T) {
+	s := NewCountingBloomFilter(100, 0.01)
+	s.Add("a")
+	c := s.Copy()
+	s.Clear()
+
+	if !s.Empty() || s.MightContain("a") {
+		t.Error("Clear did not empty the filter!")
+	}
+	if !c.MightContain("a") || c.Size() != 1 {
+		t.Error("Clear changed a copy!")
+	}
+}
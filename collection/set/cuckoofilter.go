@@ -0,0 +1,359 @@
+// This module implements a CuckooFilter, a probabilistic set supporting
+// removal.
+//
+// See Fan, Andersen, Kaminsky and Mitzenmacher, "Cuckoo Filter: Practically
+// Better Than Bloom", 2014.
+
+package set
+
+import (
+	"errors"
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"math"
+	"math/rand"
+)
+
+// Returned when adding to a CuckooFilter with no room left.
+var ErrFilterFull = errors.New("filter is full")
+
+const (
+	// The number of fingerprints each bucket holds.
+	bucketSize = 4
+
+	// The most fingerprints moved to make room for a new one.
+	maxKicks = 500
+
+	// The load factor a CuckooFilter is sized for. Past about 95%, adding
+	// to a filter with buckets of four is likely to fail.
+	targetLoad = 0.95
+)
+
+// A bucket of fingerprints, of which 0 marks an empty slot.
+type bucket [bucketSize]uint16
+
+// A CuckooFilter is a probabilistic set. Like a BloomFilter, it may report
+// that it contains an item that is not in it, but never that it doesn't
+// contain an item that is. Unlike a BloomFilter, it supports removal.
+//
+// It stores a short fingerprint of each item in one of two buckets, moving
+// fingerprints between their buckets to make room. Adding an item fails with
+// ErrFilterFull once no room can be made, or the filter holds Capacity()
+// fingerprints. A full filter does not lose items: one fingerprint that
+// could not be placed is stashed, and the filter is full until it can be.
+//
+// An item added twice must be removed twice, and an item may only be added
+// 2 * 4 times before its buckets are full. Only remove items that were
+// added. Removing an item that was never added, but MightContain() it
+// anyway, can cause a false negative for another item.
+//
+// Its Size() is the number of fingerprints it holds.
+//
+// Behavior unspecified if a CuckooFilter is not created using
+// NewCuckooFilter() or NewCuckooFilterUnsafe().
+//
+type CuckooFilter struct {
+	collection.Base
+	buckets  []bucket // A power of two of them.
+	fpBits   uint     // The length of a fingerprint.
+	victim   uint16   // A stashed fingerprint, or 0.
+	victimAt uint64   // One of the stashed fingerprint's buckets.
+	capacity int
+	rate     float64
+	rand     *rand.Rand
+}
+
+// Returns a pointer to a new CuckooFilter, sized to hold the given number of
+// items with at most the given false positive rate.
+//
+// Panics if capacity is not positive, or falsePositiveRate is not strictly
+// between 0 and 1.
+func NewCuckooFilter(capacity int, falsePositiveRate float64) *CuckooFilter {
+	s := &CuckooFilter{capacity: capacity, rate: falsePositiveRate}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe CuckooFilter, sized to hold the given
+// number of items with at most the given false positive rate.
+//
+// Panics if capacity is not positive, or falsePositiveRate is not strictly
+// between 0 and 1.
+func NewCuckooFilterUnsafe(capacity int, falsePositiveRate float64) *CuckooFilter {
+	s := &CuckooFilter{capacity: capacity, rate: falsePositiveRate}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *CuckooFilter) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *CuckooFilter) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Adds the given items to this CuckooFilter, in order. Returns
+// ErrFilterFull, and adds none of the remaining items, if an item doesn't
+// fit.
+//
+// Panics if any of the given items are nil.
+func (s *CuckooFilter) Add(items ...interface{}) error {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return nil
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		if s.victim != 0 || s.Sizeb >= s.capacity {
+			return ErrFilterFull
+		}
+
+		fp, i1, i2 := s.locate(item)
+		if s.buckets[i1].insert(fp) || s.buckets[i2].insert(fp) {
+			s.Sizeb += 1
+			continue
+		}
+
+		// Evict a random fingerprint, and move it to its other bucket,
+		// until one fits. The last one evicted is stashed.
+		i := i1
+		if s.rand.Intn(2) == 0 {
+			i = i2
+		}
+		for kick := 0; kick < maxKicks; kick++ {
+			slot := s.rand.Intn(bucketSize)
+			fp, s.buckets[i][slot] = s.buckets[i][slot], fp
+			i = s.alternate(i, fp)
+			if s.buckets[i].insert(fp) {
+				break
+			}
+			if kick == maxKicks-1 {
+				s.victim, s.victimAt = fp, i
+			}
+		}
+		s.Sizeb += 1
+	}
+	return nil
+}
+
+// Removes one occurrence of the given item from this CuckooFilter. Returns
+// false, and does nothing, if the item was definitely not in it, and true
+// otherwise.
+//
+// Panics if the given item is nil.
+func (s *CuckooFilter) Remove(item interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	fp, i1, i2 := s.locate(item)
+	switch {
+	case s.buckets[i1].remove(fp), s.buckets[i2].remove(fp):
+	case s.victim == fp && (s.victimAt == i1 || s.victimAt == i2):
+		s.victim = 0
+	default:
+		return false
+	}
+	s.Sizeb -= 1
+
+	// Room was made, so the stashed fingerprint may now fit.
+	if s.victim != 0 {
+		if s.buckets[s.victimAt].insert(s.victim) || s.buckets[s.alternate(s.victimAt, s.victim)].insert(s.victim) {
+			s.victim = 0
+		}
+	}
+	return true
+}
+
+// Returns true if all the given items might be in this CuckooFilter, false
+// if any definitely are not.
+//
+// Panics if any of the given items are nil.
+func (s *CuckooFilter) MightContain(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		fp, i1, i2 := s.locate(item)
+		if !s.buckets[i1].contains(fp) && !s.buckets[i2].contains(fp) &&
+			!(s.victim == fp && (s.victimAt == i1 || s.victimAt == i2)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the number of items this CuckooFilter can hold.
+func (s *CuckooFilter) Capacity() int {
+	s.CheckInit()
+	return s.capacity
+}
+
+// Returns the fraction of this CuckooFilter's slots that are in use.
+func (s *CuckooFilter) LoadFactor() float64 {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return float64(s.Sizeb) / float64(len(s.buckets)*bucketSize)
+}
+
+// Returns the probability that MightContain() returns true for an item that
+// is not in this CuckooFilter, given the items in it now: the chance that
+// any of the fingerprints in its two buckets match.
+func (s *CuckooFilter) EstimatedFalsePositiveRate() float64 {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	load := float64(s.Sizeb) / float64(len(s.buckets)*bucketSize)
+	match := 1 / float64(uint64(1)<<s.fpBits-1)
+	return 1 - math.Pow(1-match, 2*bucketSize*load)
+}
+
+// Returns a pointer to a new CuckooFilter, that is a copy of this
+// CuckooFilter.
+func (s *CuckooFilter) Copy() *CuckooFilter {
+	s.CheckInit()
+
+	c := &CuckooFilter{capacity: s.capacity, rate: s.rate}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	copy(c.buckets, s.buckets)
+	c.victim, c.victimAt = s.victim, s.victimAt
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Removes all items from this CuckooFilter.
+func (s *CuckooFilter) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for i := range s.buckets {
+		s.buckets[i] = bucket{}
+	}
+	s.victim = 0
+	s.Sizeb = 0
+}
+
+func (s *CuckooFilter) String() string {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return fmt.Sprintf("CuckooFilter{buckets: %d, fingerprint bits: %d, size: %d}", len(s.buckets), s.fpBits, s.Sizeb)
+}
+
+// Sizes this CuckooFilter: enough buckets for capacity fingerprints at the
+// target load, and fingerprints of f bits, where 2 * 4 / 2^f <= rate.
+func (s *CuckooFilter) setup() {
+	if s.capacity <= 0 {
+		log.Panic("Capacity must be positive.")
+	}
+	if !(s.rate > 0 && s.rate < 1) {
+		log.Panic("False positive rate must be between 0 and 1.")
+	}
+
+	f := math.Ceil(math.Log2(2 * bucketSize / s.rate))
+	if f > 16 {
+		log.Panic("False positive rate too low for 16 bit fingerprints.")
+	}
+	s.fpBits = uint(math.Max(f, 4))
+
+	n := uint64(math.Ceil(float64(s.capacity) / bucketSize / targetLoad))
+	size := uint64(1)
+	for size < n {
+		size <<= 1
+	}
+	s.buckets = make([]bucket, size)
+	s.rand = rand.New(rand.NewSource(rand.Int63()))
+}
+
+// Returns the given item's fingerprint, and its two buckets.
+func (s *CuckooFilter) locate(item interface{}) (fp uint16, i1 uint64, i2 uint64) {
+	h1, h2 := hashItem(item)
+
+	// Fingerprints come from bits of h2 unused for i1, and are never 0.
+	fp = uint16(h2>>32) & (1<<s.fpBits - 1)
+	if fp == 0 {
+		fp = 1
+	}
+	i1 = h1 & uint64(len(s.buckets)-1)
+	return fp, i1, s.alternate(i1, fp)
+}
+
+// Returns the other bucket of a fingerprint in the given bucket. Depends only
+// on the fingerprint, so a fingerprint can be moved without its item.
+func (s *CuckooFilter) alternate(i uint64, fp uint16) uint64 {
+	return (i ^ mix64(uint64(fp))) & uint64(len(s.buckets)-1)
+}
+
+// Puts the given fingerprint in an empty slot. Returns false if there is none.
+func (b *bucket) insert(fp uint16) bool {
+	for i, x := range b {
+		if x == 0 {
+			b[i] = fp
+			return true
+		}
+	}
+	return false
+}
+
+// Empties a slot holding the given fingerprint. Returns false if there is none.
+func (b *bucket) remove(fp uint16) bool {
+	for i, x := range b {
+		if x == fp {
+			b[i] = 0
+			return true
+		}
+	}
+	return false
+}
+
+func (b *bucket) contains(fp uint16) bool {
+	for _, x := range b {
+		if x == fp {
+			return true
+		}
+	}
+	return false
+}
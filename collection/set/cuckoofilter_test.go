@@ -0,0 +1,170 @@
+// This module contains tests for cuckoofilter.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"testing"
+)
+
+func TestNewEmptyCuckooFilter(t *testing.T) {
+	s := NewCuckooFilter(1000, 0.01)
+
+	if s.Size() != 0 || !s.Empty() || s.Capacity() != 1000 || s.LoadFactor() != 0 {
+		t.Error("NewCuckooFilter does not create an empty filter!")
+	}
+	if s.MightContain("a") || s.Remove("a") {
+		t.Error("Empty filter might contain an item!")
+	}
+	if s.EstimatedFalsePositiveRate() != 0 {
+		t.Error("Empty filter has a non-zero false positive rate!")
+	}
+}
+
+func TestInvalidCuckooFilter(t *testing.T) {
+	for _, rate := range []float64{0, 1, 1e-6} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewCuckooFilter(10, %v) did not panic!", rate)
+				}
+			}()
+			NewCuckooFilter(10, rate)
+		}()
+	}
+}
+
+func TestAddRemoveCuckooFilter(t *testing.T) {
+	n := 10000
+	s := NewCuckooFilter(n, 0.01)
+
+	for i := 0; i < n; i++ {
+		if err := s.Add(i); err != nil {
+			t.Fatalf("Failed to add %d: %v", i, err)
+		}
+	}
+	if s.Size() != n {
+		t.Errorf("Wrong size %d", s.Size())
+	}
+
+	falsePositives := 0
+	for i := n; i < 11*n; i++ {
+		if s.MightContain(i) {
+			falsePositives += 1
+		}
+	}
+	rate := float64(falsePositives) / float64(10*n)
+	if rate > 0.01 {
+		t.Errorf("False positive rate %v, expected at most 0.01", rate)
+	}
+	if estimate := s.EstimatedFalsePositiveRate(); estimate > 0.01 || estimate < rate/2 {
+		t.Errorf("Estimated false positive rate %v, measured %v", estimate, rate)
+	}
+
+	for i := 0; i < n; i += 2 {
+		if !s.Remove(i) {
+			t.Fatalf("Failed to remove %d!", i)
+		}
+	}
+	for i := 1; i < n; i += 2 {
+		if !s.MightContain(i) {
+			t.Fatalf("False negative for %d!", i)
+		}
+	}
+	if s.Size() != n/2 {
+		t.Errorf("Wrong size %d after removing", s.Size())
+	}
+}
+
+func TestFullCuckooFilter(t *testing.T) {
+	s := NewCuckooFilter(100, 0.01)
+
+	for i := 0; i < 100; i++ {
+		if err := s.Add(i); err != nil {
+			t.Fatalf("Failed to add %d: %v", i, err)
+		}
+	}
+	if err := s.Add(100, 101); err != ErrFilterFull {
+		t.Errorf("Expected ErrFilterFull, got %v", err)
+	}
+	if s.Size() != 100 {
+		t.Errorf("Full filter changed size to %d", s.Size())
+	}
+
+	s.Remove(0)
+	if err := s.Add(100); err != nil {
+		t.Errorf("Failed to add after removing: %v", err)
+	}
+}
+
+func TestVictimCuckooFilter(t *testing.T) {
+	// Any one item may be added 8 times, filling its two buckets. The 9th
+	// is stashed, after which the filter is full.
+	s := NewCuckooFilter(1000, 0.01)
+
+	for i := 0; i < 2*bucketSize+1; i++ {
+		if err := s.Add("a"); err != nil {
+			t.Fatalf("Failed to add copy %d: %v", i, err)
+		}
+	}
+	if err := s.Add("b"); err != ErrFilterFull {
+		t.Errorf("Expected ErrFilterFull with a stashed item, got %v", err)
+	}
+	for i := 0; i < 2*bucketSize+1; i++ {
+		if !s.Remove("a") {
+			t.Fatalf("Failed to remove copy %d!", i)
+		}
+	}
+	if s.MightContain("a") || !s.Empty() {
+		t.Error("Removing every copy left the item!")
+	}
+	if err := s.Add("b"); err != nil {
+		t.Errorf("Failed to add after emptying: %v", err)
+	}
+}
+
+func TestRollingWindowCuckooFilter(t *testing.T) {
+	window := 1000
+	s := NewCuckooFilterUnsafe(window, 0.001)
+
+	for i := 0; i < 20*window; i++ {
+		if i >= window {
+			if !s.Remove(i - window) {
+				t.Fatalf("Failed to expire %d!", i-window)
+			}
+		}
+		if err := s.Add(i); err != nil {
+			t.Fatalf("Failed to add %d: %v", i, err)
+		}
+	}
+	for i := 19 * window; i < 20*window; i++ {
+		if !s.MightContain(i) {
+			t.Fatalf("False negative for %d!", i)
+		}
+	}
+}
+
+func TestCopyClearCuckooFilter(t *testing.T) {
+	s := NewCuckooFilter(100, 0.01)
+	s.Add("a")
+	c := s.Copy()
+	s.Clear()
+
+	if !s.Empty() || s.MightContain("a") {
+		t.Error("Clear did not empty the filter!")
+	}
+	if !c.MightContain("a") || c.Size() != 1 {
+		t.Error("Clear changed a copy!")
+	}
+}
+
+func BenchmarkAddCuckooFilter(b *testing.B) {
+	s := NewCuckooFilterUnsafe(b.N+1, 0.01)
+
+	for i := 0; i < b.N; i++ {
+		s.Add(i)
+	}
+}
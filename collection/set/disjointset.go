@@ -0,0 +1,326 @@
+// This module implements a DisjointSet, conforming to collection.Collection.
+//
+// See Tarjan, "Efficiency of a Good But Not Linear Set Union Algorithm",
+// 1975.
+
+package set
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+)
+
+// A DisjointSet, or union-find, implements collection.Collection, keeping
+// its items in disjoint sets, which can be merged but not split.
+//
+// Items may be of any comparable type. Each set is identified by one of its
+// items, its representative, as returned by Find(). Using union by rank and
+// path compression, MakeSet(), Find(), Union() and Connected() take
+// effectively O(1) amortized time.
+//
+// Since Find() compresses paths, even queries modify a DisjointSet, so a
+// thread-safe DisjointSet serializes them.
+//
+// Behavior unspecified if a DisjointSet is not created using
+// NewDisjointSet(), NewDisjointSetUnsafe() or if DisjointSet.Init() /
+// DisjointSet.InitUnsafe(), is not first called on a new &DisjointSet{}.
+//
+type DisjointSet struct {
+	collection.Base
+	index  map[interface{}]int // item -> its index in the slices below
+	items  []interface{}
+	parent []int
+	rank   []uint8
+	size   []int // Only meaningful for representatives.
+	sets   int
+}
+
+// Returns a pointer to a new DisjointSet containing each given item in a
+// set of its own.
+func NewDisjointSet(items ...interface{}) *DisjointSet {
+	s := &DisjointSet{}
+	s.Init()
+	s.MakeSet(items...)
+	return s
+}
+
+// Returns a pointer to a new unsafe DisjointSet containing each given item
+// in a set of its own.
+func NewDisjointSetUnsafe(items ...interface{}) *DisjointSet {
+	s := &DisjointSet{}
+	s.InitUnsafe()
+	s.MakeSet(items...)
+	return s
+}
+
+func (s *DisjointSet) Init() {
+	s.InitBase()
+
+	s.reset()
+}
+
+func (s *DisjointSet) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.reset()
+}
+
+// Adds each given item not already in this DisjointSet, in a set of its own.
+func (s *DisjointSet) MakeSet(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		s.makeSet(item)
+	}
+}
+
+// Returns the representative of the set containing the given item, or nil
+// if it is not in this DisjointSet.
+func (s *DisjointSet) Find(item interface{}) interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	i, ok := s.index[item]
+	if !ok {
+		return nil
+	}
+	return s.items[s.find(i)]
+}
+
+// Merges the sets containing the given items, first adding either item not
+// already in this DisjointSet. Returns false if they were already in the
+// same set, true otherwise.
+func (s *DisjointSet) Union(a interface{}, b interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	i, j := s.find(s.makeSet(a)), s.find(s.makeSet(b))
+	if i == j {
+		return false
+	}
+
+	if s.rank[i] < s.rank[j] {
+		i, j = j, i
+	}
+	s.parent[j] = i
+	s.size[i] += s.size[j]
+	if s.rank[i] == s.rank[j] {
+		s.rank[i] += 1
+	}
+	s.sets -= 1
+	return true
+}
+
+// Returns true if the given items are in the same set, false otherwise, or
+// if either is not in this DisjointSet.
+func (s *DisjointSet) Connected(a interface{}, b interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	i, ok := s.index[a]
+	j, ok2 := s.index[b]
+	return ok && ok2 && s.find(i) == s.find(j)
+}
+
+// Returns the number of items in the set containing the given item, or 0 if
+// it is not in this DisjointSet.
+func (s *DisjointSet) SetSize(item interface{}) int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	i, ok := s.index[item]
+	if !ok {
+		return 0
+	}
+	return s.size[s.find(i)]
+}
+
+// Returns the number of disjoint sets in this DisjointSet.
+func (s *DisjointSet) NumSets() int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.sets
+}
+
+// Returns true if all the given items are in this DisjointSet, false
+// otherwise.
+func (s *DisjointSet) Contains(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		if _, ok := s.index[item]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the disjoint sets of this DisjointSet, each as a slice of its
+// items. Sets are ordered by, and items within them are in, the order their
+// items were first added.
+func (s *DisjointSet) Sets() [][]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	sets := make([][]interface{}, 0, s.sets)
+	position := make(map[int]int, s.sets) // representative -> index in sets
+	for i, item := range s.items {
+		r := s.find(i)
+		p, ok := position[r]
+		if !ok {
+			p = len(sets)
+			position[r] = p
+			sets = append(sets, make([]interface{}, 0, s.size[r]))
+		}
+		sets[p] = append(sets[p], item)
+	}
+	return sets
+}
+
+// Returns a pointer to a new DisjointSet, as thread-safe as this one, with
+// the same items in the same sets.
+func (s *DisjointSet) Copy() *DisjointSet {
+	s.CheckInit()
+
+	c := &DisjointSet{}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for item, i := range s.index {
+		c.index[item] = i
+	}
+	c.items = append(c.items, s.items...)
+	c.parent = append(c.parent, s.parent...)
+	c.rank = append(c.rank, s.rank...)
+	c.size = append(c.size, s.size...)
+	c.sets = s.sets
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to every item in this DisjointSet,
+// in the order they were added. Stops once all items have been processed,
+// or once the function returns false, whichever occurs first.
+func (s *DisjointSet) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range s.items {
+		if !f(item) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a slice of all the items in this DisjointSet, in the order they
+// were added.
+func (s *DisjointSet) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := append(make([]interface{}, 0, len(s.items)), s.items...)
+	return &slice
+}
+
+// Removes all items from this DisjointSet.
+func (s *DisjointSet) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.reset()
+}
+
+func (s *DisjointSet) String() string {
+	return fmt.Sprintf("%v", s.Sets())
+}
+
+func (s *DisjointSet) reset() {
+	s.index = make(map[interface{}]int)
+	s.items, s.parent, s.rank, s.size = nil, nil, nil, nil
+	s.sets = 0
+	s.Sizeb = 0
+}
+
+// Returns the index of the given item, first adding it in a set of its own
+// if it is not in this DisjointSet.
+func (s *DisjointSet) makeSet(item interface{}) int {
+	if i, ok := s.index[item]; ok {
+		return i
+	}
+
+	i := len(s.items)
+	s.index[item] = i
+	s.items = append(s.items, item)
+	s.parent = append(s.parent, i)
+	s.rank = append(s.rank, 0)
+	s.size = append(s.size, 1)
+	s.sets += 1
+	s.Sizeb += 1
+	return i
+}
+
+// Returns the index of the representative of the set containing the item at
+// the given index, pointing every item on the way directly at it.
+func (s *DisjointSet) find(i int) int {
+	r := i
+	for s.parent[r] != r {
+		r = s.parent[r]
+	}
+	for s.parent[i] != r {
+		s.parent[i], i = r, s.parent[i]
+	}
+	return r
+}
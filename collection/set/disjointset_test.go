@@ -0,0 +1,174 @@
+// This module contains tests for disjointset.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"fmt"
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestDisjointSet(t *testing.T) {
+	s := NewDisjointSet()
+	var _ collection.Collection = s
+}
+
+func TestNewEmptyDisjointSet(t *testing.T) {
+	s := NewDisjointSet()
+
+	if s.Size() != 0 || !s.Empty() || s.NumSets() != 0 || len(s.Sets()) != 0 {
+		t.Error("NewDisjointSet with 0 args does not create an empty set!")
+	}
+	if s.Find("a") != nil || s.SetSize("a") != 0 || s.Connected("a", "a") || s.Contains("a") {
+		t.Error("Empty set contains an item!")
+	}
+}
+
+func TestMakeSetDisjointSet(t *testing.T) {
+	s := NewDisjointSet("a", "b", "a")
+	s.MakeSet("c", 1)
+
+	if s.Size() != 4 || s.NumSets() != 4 {
+		t.Errorf("Expected 4 items in 4 sets, got %d in %d", s.Size(), s.NumSets())
+	}
+	if s.Find("a") != "a" || s.Find(1) != 1 || s.SetSize("b") != 1 {
+		t.Error("A new item is not alone in its set!")
+	}
+	if !s.Contains("a", 1) || s.Contains("a", 2) {
+		t.Error("Contains is wrong!")
+	}
+	if fmt.Sprint(*s.Slice()) != "[a b c 1]" {
+		t.Errorf("Items not in the order they were added: %v", *s.Slice())
+	}
+}
+
+func TestUnionDisjointSet(t *testing.T) {
+	s := NewDisjointSet("a", "b", "c", "d")
+
+	if !s.Union("a", "b") || !s.Union("c", "d") || !s.Union("b", "d") {
+		t.Error("Union of disjoint sets returned false!")
+	}
+	if s.Union("a", "c") {
+		t.Error("Union of connected items returned true!")
+	}
+	if !s.Union("e", "f") || s.Size() != 6 {
+		t.Error("Union did not add missing items!")
+	}
+
+	if !s.Connected("a", "d") || s.Connected("a", "e") || s.Connected("a", "z") {
+		t.Error("Connected is wrong!")
+	}
+	if s.Find("a") != s.Find("d") || s.Find("a") == s.Find("e") {
+		t.Error("Find is wrong!")
+	}
+	if s.SetSize("c") != 4 || s.SetSize("f") != 2 || s.NumSets() != 2 {
+		t.Errorf("Wrong sizes: %d %d %d", s.SetSize("c"), s.SetSize("f"), s.NumSets())
+	}
+	if fmt.Sprint(s.Sets()) != "[[a b c d] [e f]]" {
+		t.Errorf("Wrong sets: %v", s.Sets())
+	}
+}
+
+func TestRandomUnionDisjointSet(t *testing.T) {
+	r := rand.New(rand.NewSource(39))
+	n := 1000
+	s := NewDisjointSet()
+	label := make([]int, n) // A naive O(n) per union reference.
+	for i := range label {
+		label[i] = i
+		s.MakeSet(i)
+	}
+
+	for k := 0; k < 800; k++ {
+		a, b := r.Intn(n), r.Intn(n)
+		merged := s.Union(a, b)
+		if merged != (label[a] != label[b]) {
+			t.Fatalf("Union(%d, %d) returned %v", a, b, merged)
+		}
+		if old := label[b]; merged {
+			for i := range label {
+				if label[i] == old {
+					label[i] = label[a]
+				}
+			}
+		}
+	}
+
+	sizes := make(map[int]int)
+	for _, l := range label {
+		sizes[l] += 1
+	}
+	if s.NumSets() != len(sizes) || len(s.Sets()) != len(sizes) {
+		t.Errorf("Expected %d sets, got %d", len(sizes), s.NumSets())
+	}
+	for i := 0; i < 200; i++ {
+		a, b := r.Intn(n), r.Intn(n)
+		if s.Connected(a, b) != (label[a] == label[b]) || s.SetSize(a) != sizes[label[a]] {
+			t.Fatalf("Wrong answer for %d and %d", a, b)
+		}
+	}
+}
+
+func TestKruskalDisjointSet(t *testing.T) {
+	type edge struct {
+		a, b   string
+		weight int
+	}
+	edges := []edge{
+		{"a", "b", 7}, {"a", "d", 5}, {"b", "c", 8}, {"b", "d", 9}, {"b", "e", 7},
+		{"c", "e", 5}, {"d", "e", 15}, {"d", "f", 6}, {"e", "f", 8}, {"e", "g", 9},
+		{"f", "g", 11},
+	}
+	sort.Slice(edges, func(i, j int) bool { return edges[i].weight < edges[j].weight })
+
+	s := NewDisjointSet()
+	total := 0
+	for _, e := range edges {
+		if s.Union(e.a, e.b) {
+			total += e.weight
+		}
+	}
+	if total != 39 || s.NumSets() != 1 || s.SetSize("a") != 7 {
+		t.Errorf("Minimum spanning tree weighs %d, expected 39", total)
+	}
+}
+
+func TestCopyClearDisjointSet(t *testing.T) {
+	s := NewDisjointSet("a", "b", "c")
+	s.Union("a", "b")
+	c := s.Copy()
+
+	s.Union("b", "c")
+	c.MakeSet("d")
+
+	if c.Connected("a", "c") || !s.Connected("a", "c") || s.Contains("d") || c.SetSize("a") != 2 {
+		t.Error("Copy shares state with the original!")
+	}
+
+	s.Clear()
+	if !s.Empty() || s.NumSets() != 0 || s.Find("a") != nil || c.Empty() {
+		t.Error("Clear is wrong!")
+	}
+	if s.Union("x", "y"); s.Size() != 2 || s.NumSets() != 1 {
+		t.Error("A cleared DisjointSet is not usable!")
+	}
+}
+
+func BenchmarkUnionDisjointSet(b *testing.B) {
+	r := rand.New(rand.NewSource(39))
+	s := NewDisjointSetUnsafe()
+	for i := 0; i < 1<<16; i++ {
+		s.MakeSet(i)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.Union(r.Intn(1<<16), r.Intn(1<<16))
+	}
+}
@@ -0,0 +1,128 @@
+// This module provides the item hashing shared by the probabilistic sets.
+
+package set
+
+import (
+	"encoding"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"math"
+)
+
+// Tags distinguishing the encodings of items of different kinds, so that,
+// as in a HashSet, int(1) and "1" are different items.
+const (
+	tagString byte = iota
+	tagBytes
+	tagBool
+	tagInt
+	tagUint
+	tagFloat
+	tagMarshaler
+	tagOther
+)
+
+// FNV-1a constants.
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// Returns a stable byte encoding of the given item, the same in every
+// process, so that probabilistic sets built in one may be loaded by another.
+//
+// Strings, []bytes, bools, and numbers are encoded by value, as are items
+// implementing encoding.BinaryMarshaler. Anything else is encoded by its
+// %#v formatting, which is only stable if that is (it isn't for pointers).
+//
+// Panics if the given item is nil, or fails to marshal itself.
+func itemBytes(item interface{}) []byte {
+	var buf [9]byte
+
+	switch v := item.(type) {
+	case nil:
+		log.Panic("Nil item.")
+		return nil
+	case string:
+		return append([]byte{tagString}, v...)
+	case []byte:
+		return append([]byte{tagBytes}, v...)
+	case bool:
+		buf[0], buf[1] = tagBool, 0
+		if v {
+			buf[1] = 1
+		}
+		return buf[:2]
+	case int:
+		return putInt(buf[:], int64(v))
+	case int8:
+		return putInt(buf[:], int64(v))
+	case int16:
+		return putInt(buf[:], int64(v))
+	case int32:
+		return putInt(buf[:], int64(v))
+	case int64:
+		return putInt(buf[:], v)
+	case uint:
+		return putUint(buf[:], uint64(v))
+	case uint8:
+		return putUint(buf[:], uint64(v))
+	case uint16:
+		return putUint(buf[:], uint64(v))
+	case uint32:
+		return putUint(buf[:], uint64(v))
+	case uint64:
+		return putUint(buf[:], v)
+	case float32:
+		buf[0] = tagFloat
+		binary.BigEndian.PutUint64(buf[1:], math.Float64bits(float64(v)))
+		return buf[:]
+	case float64:
+		buf[0] = tagFloat
+		binary.BigEndian.PutUint64(buf[1:], math.Float64bits(v))
+		return buf[:]
+	case encoding.BinaryMarshaler:
+		b, err := v.MarshalBinary()
+		if err != nil {
+			log.Panicf("Item failed to marshal: %v", err)
+		}
+		return append([]byte{tagMarshaler}, b...)
+	default:
+		return append([]byte{tagOther}, fmt.Sprintf("%#v", v)...)
+	}
+}
+
+func putInt(buf []byte, v int64) []byte {
+	buf[0] = tagInt
+	binary.BigEndian.PutUint64(buf[1:], uint64(v))
+	return buf[:9]
+}
+
+func putUint(buf []byte, v uint64) []byte {
+	buf[0] = tagUint
+	binary.BigEndian.PutUint64(buf[1:], v)
+	return buf[:9]
+}
+
+// Returns two independent 64 bit hashes of the given item. h2 is always
+// odd, so h1 + i*h2 visits every slot of a power of two sized table.
+//
+// Panics if the given item is nil, see itemBytes().
+func hashItem(item interface{}) (h1 uint64, h2 uint64) {
+	h := uint64(fnvOffset)
+	for _, b := range itemBytes(item) {
+		h ^= uint64(b)
+		h *= fnvPrime
+	}
+
+	// FNV mixes its high bits poorly, so finish both hashes with SplitMix64.
+	return mix64(h), mix64(h^0x9e3779b97f4a7c15) | 1
+}
+
+// The SplitMix64 finalizer.
+func mix64(h uint64) uint64 {
+	h = (h ^ (h >> 30)) * 0xbf58476d1ce4e5b9
+	h = (h ^ (h >> 27)) * 0x94d049bb133111eb
+	return h ^ (h >> 31)
+}
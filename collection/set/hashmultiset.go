@@ -0,0 +1,347 @@
+// This module implements a HashMultiSet, conforming to set.MultiSet.
+
+package set
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+)
+
+// A HashMultiSet implements set.MultiSet, counting its items in a map.
+//
+// Behavior unspecified if a HashMultiSet is not created using
+// NewHashMultiSet(), NewHashMultiSetUnsafe() or if HashMultiSet.Init() /
+// HashMultiSet.InitUnsafe(), is not first called on a new &HashMultiSet{}.
+//
+type HashMultiSet struct {
+	collection.Base
+	m map[interface{}]int
+}
+
+// Returns a pointer to a new HashMultiSet containing the given items, each
+// as many times as it is given.
+func NewHashMultiSet(items ...interface{}) *HashMultiSet {
+	s := &HashMultiSet{}
+	s.Init()
+	s.Insert(items...)
+	return s
+}
+
+// Returns a pointer to a new unsafe HashMultiSet containing the given items,
+// each as many times as it is given.
+func NewHashMultiSetUnsafe(items ...interface{}) *HashMultiSet {
+	s := &HashMultiSet{}
+	s.InitUnsafe()
+	s.Insert(items...)
+	return s
+}
+
+func (s *HashMultiSet) Init() {
+	s.InitBase()
+
+	s.m = make(map[interface{}]int)
+}
+
+func (s *HashMultiSet) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.m = make(map[interface{}]int)
+}
+
+// Adds one to the count of each given item.
+func (s *HashMultiSet) Insert(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		s.m[item] += 1
+		s.Sizeb += 1
+	}
+}
+
+// Takes one off the count of each given item, if it is in this MultiSet.
+func (s *HashMultiSet) Remove(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		count, ok := s.m[item]
+		if !ok {
+			continue
+		}
+		if count == 1 {
+			delete(s.m, item)
+		} else {
+			s.m[item] = count - 1
+		}
+		s.Sizeb -= 1
+	}
+}
+
+func (s *HashMultiSet) Contains(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		if _, ok := s.m[item]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *HashMultiSet) Count(item interface{}) int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.m[item]
+}
+
+func (s *HashMultiSet) SetCount(item interface{}, count int) int {
+	s.CheckInit()
+
+	if count < 0 {
+		log.Panicf("Cannot set a negative count: %d.", count)
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	old := s.m[item]
+	if count == 0 {
+		delete(s.m, item)
+	} else {
+		s.m[item] = count
+	}
+	s.Sizeb += count - old
+	return old
+}
+
+func (s *HashMultiSet) DistinctSize() int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return len(s.m)
+}
+
+// Returns a pointer to a new HashMultiSet with each item's count the larger
+// of its counts in this MultiSet and the given Set.
+func (s *HashMultiSet) Union(o Set) Set {
+	return multiUnion(s.copyMulti(), o)
+}
+
+// Returns a pointer to a new HashMultiSet with each item's count the smaller
+// of its counts in this MultiSet and the given Set.
+func (s *HashMultiSet) Intersection(o Set) Set {
+	return multiIntersection(s.copyMulti(), o)
+}
+
+// Returns a pointer to a new HashMultiSet with each item's count in this
+// MultiSet reduced by its count in the given Set, down to zero.
+func (s *HashMultiSet) Difference(o Set) Set {
+	return multiDifference(s.copyMulti(), o)
+}
+
+// Returns a pointer to a new HashMultiSet with each item's count the sum of
+// its counts in this MultiSet and the given Set.
+func (s *HashMultiSet) Sum(o Set) MultiSet {
+	return multiSum(s.copyMulti(), o)
+}
+
+// Returns a pointer to a new HashMultiSet with each item's count the
+// difference between its counts in this MultiSet and the given Set.
+func (s *HashMultiSet) SymmetricDifference(o Set) Set {
+	s.CheckInit()
+
+	return symmetricDifference(s, o)
+}
+
+// Returns true if this MultiSet and the given Set have no items in common,
+// false otherwise.
+func (s *HashMultiSet) IsDisjoint(o Set) bool {
+	s.CheckInit()
+
+	return isDisjoint(s, o)
+}
+
+// Sets each item's count to the larger of its counts in this MultiSet and
+// the given Set.
+func (s *HashMultiSet) UnionWith(o Set) {
+	s.CheckInit()
+
+	multiUnion(s, o)
+}
+
+// Sets each item's count to the smaller of its counts in this MultiSet and
+// the given Set.
+func (s *HashMultiSet) IntersectWith(o Set) {
+	s.CheckInit()
+
+	multiIntersection(s, o)
+}
+
+// Reduces each item's count by its count in the given Set, down to zero.
+func (s *HashMultiSet) DifferenceWith(o Set) {
+	s.CheckInit()
+
+	multiDifference(s, o)
+}
+
+// Returns true if this MultiSet and the given Set hold each item the same
+// number of times, false otherwise.
+func (s *HashMultiSet) Equal(o Set) bool {
+	s.CheckInit()
+
+	return multiEqual(s, o)
+}
+
+// The first bool returned is true if this MultiSet holds no item more times
+// than the given Set, false otherwise. If the first returned bool is true,
+// then the second bool will be false if these two sets are equal, true
+// otherwise.
+//
+// true, true -> s is a proper subset of o
+// true, false -> s is equal to o
+// false, true -> s is not a subset of o
+// false, false -> s is not a subset of o
+func (s *HashMultiSet) Subset(o Set) (subset bool, proper bool) {
+	s.CheckInit()
+
+	return multiSubset(s, o)
+}
+
+// The first bool returned is true if this MultiSet holds every item at least
+// as many times as the given Set, false otherwise. If the first returned
+// bool is true, then the second bool will be false if these two sets are
+// equal, true otherwise.
+//
+// true, true -> s is a proper superset of o
+// true, false -> s is equal to o
+// false, true -> s is not a superset of o
+// false, false -> s is not a superset of o
+func (s *HashMultiSet) Superset(o Set) (superset bool, proper bool) {
+	s.CheckInit()
+
+	return multiSuperset(s, o)
+}
+
+// Returns a pointer to a new HashMultiSet that is a copy of this MultiSet.
+func (s *HashMultiSet) Copy() Set {
+	return s.copyMulti()
+}
+
+// Attempts to apply the given function to every item in this MultiSet, as
+// many times as its count, in no particular order. Stops once all items have
+// been processed, or once the function returns false, whichever occurs
+// first.
+func (s *HashMultiSet) Map(f func(item interface{}) bool) bool {
+	return s.MapCounts(func(item interface{}, count int) bool {
+		for i := 0; i < count; i++ {
+			if !f(item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Maps over distinct items in no particular order.
+func (s *HashMultiSet) MapCounts(f func(item interface{}, count int) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for item, count := range s.m {
+		if !f(item, count) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a slice of all the items in this MultiSet, each as many times as
+// its count, in no particular order.
+func (s *HashMultiSet) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(item interface{}) bool {
+		slice = append(slice, item)
+		return true
+	})
+	return &slice
+}
+
+// Removes all items from this MultiSet.
+func (s *HashMultiSet) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.m = make(map[interface{}]int)
+	s.Sizeb = 0
+}
+
+func (s *HashMultiSet) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Returns a new HashMultiSet, as thread-safe as this one, with no items.
+func (s *HashMultiSet) empty() *HashMultiSet {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		return NewHashMultiSet()
+	}
+	return NewHashMultiSetUnsafe()
+}
+
+// Returns a new HashMultiSet, as thread-safe as this one, with the same
+// counts.
+func (s *HashMultiSet) copyMulti() *HashMultiSet {
+	c := s.empty()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for item, count := range s.m {
+		c.m[item] = count
+	}
+	c.Sizeb = s.Sizeb
+	return c
+}
@@ -0,0 +1,234 @@
+// This module contains tests for hashmultiset.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+)
+
+// Returns the items of the given Set, sorted by their printed form.
+func sortedItems(s interface {
+	Size() int
+	Map(f func(item interface{}) bool) bool
+}) string {
+	items := make([]string, 0, s.Size())
+	s.Map(func(item interface{}) bool {
+		items = append(items, fmt.Sprint(item))
+		return true
+	})
+	sort.Strings(items)
+	return fmt.Sprint(items)
+}
+
+func TestNewEmptyHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet()
+
+	if s.Size() != 0 || !s.Empty() || s.DistinctSize() != 0 {
+		t.Error("NewHashMultiSet with 0 args does not create an empty set!")
+	}
+	if s.Count("a") != 0 || s.Contains("a") {
+		t.Error("Empty set contains an item!")
+	}
+}
+
+func TestInsertRemoveHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet("a", "b", "a", "c", "a")
+
+	if s.Size() != 5 || s.DistinctSize() != 3 || s.Count("a") != 3 || s.Count("b") != 1 {
+		t.Errorf("Wrong counts: %v", s)
+	}
+
+	s.Remove("a", "b", "d")
+	if s.Size() != 3 || s.DistinctSize() != 2 || s.Count("a") != 2 || s.Contains("b") {
+		t.Errorf("Wrong counts after Remove: %v", s)
+	}
+	if !s.Contains("a", "c") || s.Contains("a", "b") || s.Contains() {
+		t.Error("Contains is wrong!")
+	}
+	if sortedItems(s) != "[a a c]" {
+		t.Errorf("Map does not visit each item as many times as its count: %v", sortedItems(s))
+	}
+}
+
+func TestSetCountHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet("a")
+
+	if old := s.SetCount("a", 4); old != 1 || s.Count("a") != 4 || s.Size() != 4 {
+		t.Errorf("SetCount(a, 4) returned %d, left %v", old, s)
+	}
+	if old := s.SetCount("b", 2); old != 0 || s.Size() != 6 || s.DistinctSize() != 2 {
+		t.Errorf("SetCount(b, 2) returned %d, left %v", old, s)
+	}
+	if old := s.SetCount("a", 0); old != 4 || s.Contains("a") || s.Size() != 2 || s.DistinctSize() != 1 {
+		t.Errorf("SetCount(a, 0) returned %d, left %v", old, s)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("SetCount with a negative count did not panic!")
+		}
+	}()
+	s.SetCount("a", -1)
+}
+
+func TestAlgebraHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet("a", "a", "a", "b", "c")
+	o := NewHashMultiSet("a", "b", "b", "d")
+
+	cases := []struct {
+		name     string
+		got      Set
+		expected string
+	}{
+		{"Union", s.Union(o), "[a a a b b c d]"},
+		{"Sum", s.Sum(o), "[a a a a b b b c d]"},
+		{"Intersection", s.Intersection(o), "[a b]"},
+		{"Difference", s.Difference(o), "[a a c]"},
+		{"Difference", o.Difference(s), "[b d]"},
+	}
+	for _, c := range cases {
+		if got := sortedItems(c.got); got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, got)
+		}
+		if _, ok := c.got.(*HashMultiSet); !ok {
+			t.Errorf("%s did not return a HashMultiSet!", c.name)
+		}
+	}
+	if sortedItems(s) != "[a a a b c]" || sortedItems(o) != "[a b b d]" {
+		t.Error("Operations changed their operands!")
+	}
+}
+
+func TestAlgebraWithSetHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet("a", "a", "b")
+	o := NewHashSet("a", "c")
+
+	if got := sortedItems(s.Union(o)); got != "[a a b c]" {
+		t.Errorf("Union with a HashSet: %s", got)
+	}
+	if got := sortedItems(s.Sum(o)); got != "[a a a b c]" {
+		t.Errorf("Sum with a HashSet: %s", got)
+	}
+	if got := sortedItems(s.Intersection(o)); got != "[a]" {
+		t.Errorf("Intersection with a HashSet: %s", got)
+	}
+	if got := sortedItems(s.Difference(o)); got != "[a b]" {
+		t.Errorf("Difference with a HashSet: %s", got)
+	}
+}
+
+func TestEqualSubsetSupersetHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet("a", "a", "b")
+
+	if !s.Equal(NewHashMultiSet("b", "a", "a")) || s.Equal(NewHashMultiSet("a", "b", "b")) {
+		t.Error("Equal is wrong!")
+	}
+	if s.Equal(NewHashSet("a", "b")) || !NewHashMultiSet("a", "b").Equal(NewHashSet("a", "b")) {
+		t.Error("Equal with a HashSet is wrong!")
+	}
+
+	if sub, proper := s.Subset(NewHashMultiSet("a", "a", "b", "c")); !sub || !proper {
+		t.Error("Subset of a larger MultiSet is wrong!")
+	}
+	if sub, _ := s.Subset(NewHashMultiSet("a", "b", "c")); sub {
+		t.Error("A MultiSet holding an item more times is a Subset!")
+	}
+	if sub, proper := s.Subset(s.Copy()); !sub || proper {
+		t.Error("Subset of an equal MultiSet is wrong!")
+	}
+
+	if super, proper := s.Superset(NewHashSet("a", "b")); !super || !proper {
+		t.Error("Superset of a HashSet is wrong!")
+	}
+	if super, _ := s.Superset(NewHashMultiSet("b", "b")); super {
+		t.Error("A MultiSet holding an item fewer times is a Superset!")
+	}
+}
+
+func TestEqualSupersetWithTreeSetHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet(compInt{1}, compInt{2})
+	o := NewTreeSet(compInt{1}, compInt{3})
+
+	if s.Equal(o) {
+		t.Error("Equal to a different TreeSet of the same size!")
+	}
+	if super, _ := s.Superset(o); super {
+		t.Error("Superset of a TreeSet holding another item!")
+	}
+	if !s.Equal(NewTreeSet(compInt{2}, compInt{1})) {
+		t.Error("Not Equal to a TreeSet of the same items!")
+	}
+}
+
+func TestCopyClearHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet("a", "a", "b")
+	c := s.Copy().(*HashMultiSet)
+
+	s.Insert("a")
+	c.Remove("b")
+
+	if s.Count("a") != 3 || c.Count("a") != 2 || !s.Contains("b") || c.Contains("b") {
+		t.Error("Copy shares state with the original!")
+	}
+
+	s.Clear()
+	if !s.Empty() || s.DistinctSize() != 0 || c.Empty() {
+		t.Error("Clear is wrong!")
+	}
+}
+
+func TestMapCountsHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet("a", "a", "b")
+
+	counts := make(map[interface{}]int)
+	s.MapCounts(func(item interface{}, count int) bool {
+		counts[item] = count
+		return true
+	})
+	if len(counts) != 2 || counts["a"] != 2 || counts["b"] != 1 {
+		t.Errorf("MapCounts is wrong: %v", counts)
+	}
+
+	visited := 0
+	if s.Map(func(item interface{}) bool {
+		visited += 1
+		return visited < 2
+	}) || visited != 2 {
+		t.Error("Map does not stop when the function returns false!")
+	}
+}
+
+func TestInPlaceAlgebraHashMultiSet(t *testing.T) {
+	s := NewHashMultiSet("a", "a", "a", "b", "c")
+	o := NewHashMultiSet("a", "b", "b", "d")
+
+	if got := sortedItems(s.SymmetricDifference(o)); got != "[a a b c d]" {
+		t.Errorf("SymmetricDifference: expected [a a b c d], got %s", got)
+	}
+	if s.IsDisjoint(o) || !s.IsDisjoint(NewHashSet("d", "e")) {
+		t.Error("IsDisjoint is wrong!")
+	}
+
+	s.UnionWith(o)
+	if got := sortedItems(s); got != "[a a a b b c d]" {
+		t.Errorf("UnionWith: expected [a a a b b c d], got %s", got)
+	}
+	s.IntersectWith(NewHashMultiSet("a", "a", "b", "b", "b", "d"))
+	if got := sortedItems(s); got != "[a a b b d]" {
+		t.Errorf("IntersectWith: expected [a a b b d], got %s", got)
+	}
+	s.DifferenceWith(NewHashSet("a", "d"))
+	if got := sortedItems(s); got != "[a b b]" || s.DistinctSize() != 2 {
+		t.Errorf("DifferenceWith: expected [a b b], got %s", got)
+	}
+	s.UnionWith(s)
+	if got := sortedItems(s); got != "[a b b]" {
+		t.Errorf("UnionWith itself: expected [a b b], got %s", got)
+	}
+}
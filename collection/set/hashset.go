@@ -57,8 +57,8 @@ func (s *HashSet) Insert(items ...interface{}) {
 		return
 	}
 	if s.Threadsafe() {
-		s.Lock()
-		defer s.Unlock()
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
 	}
 
 	for _, item := range items {
@@ -76,8 +76,10 @@ func (s *HashSet) Remove(items ...interface{}) {
 		return
 	}
 
-	s.Lockb.Lock()
-	defer s.Lockb.Unlock()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
 
 	for _, item := range items {
         if _, ok := s.m[item]; ok {
@@ -94,8 +96,10 @@ func (s *HashSet) Contains(items ...interface{}) bool {
 		return false
 	}
 
-	s.Lockb.RLock()
-	defer s.RUnlock()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
 
 	for _, item := range items {
 		if _, ok := s.m[item]; !ok {
@@ -159,6 +163,43 @@ func (s *HashSet) Difference(o Set) Set {
 	return result
 }
 
+// Returns a pointer to a new Set containing all the items in exactly one of
+// this Set and the given Set.
+func (s *HashSet) SymmetricDifference(o Set) Set {
+	s.CheckInit()
+
+	return symmetricDifference(s, o)
+}
+
+// Returns true if this Set and the given Set have no items in common, false
+// otherwise.
+func (s *HashSet) IsDisjoint(o Set) bool {
+	s.CheckInit()
+
+	return isDisjoint(s, o)
+}
+
+// Adds all the items in the given Set to this Set.
+func (s *HashSet) UnionWith(o Set) {
+	s.CheckInit()
+
+	s.Insert(*o.Slice()...)
+}
+
+// Removes all the items not in the given Set from this Set.
+func (s *HashSet) IntersectWith(o Set) {
+	s.CheckInit()
+
+	intersectWith(s, o)
+}
+
+// Removes all the items in the given Set from this Set.
+func (s *HashSet) DifferenceWith(o Set) {
+	s.CheckInit()
+
+	s.Remove(*o.Slice()...)
+}
+
 // Returns true if this Set and the given Set contain exactly the same items,
 // false otherwise.
 func (s *HashSet) Equal(o Set) bool {
@@ -169,8 +210,10 @@ func (s *HashSet) Equal(o Set) bool {
 		return false
 	}
 
-	s.RLock()
-	defer s.RUnlock()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
 	equal := true
 	o.Map(func(item interface{}) bool {
 		_, equal = s.m[item]
@@ -249,8 +292,10 @@ func (s *HashSet) Copy() Set {
 func (s *HashSet) Map(f func(item interface{}) bool) bool {
 	s.CheckInit()
 
-	s.RLock()
-	defer s.RUnlock()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
 
 	ok := true
 	for item := range s.m {
@@ -265,8 +310,10 @@ func (s *HashSet) Map(f func(item interface{}) bool) bool {
 func (s *HashSet) Slice() *[]interface{} {
 	s.CheckInit()
 
-	s.RLock()
-	defer s.RUnlock()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
 
 	slice := make([]interface{}, 0, len(s.m))
 
@@ -281,8 +328,10 @@ func (s *HashSet) Slice() *[]interface{} {
 func (s *HashSet) Clear() {
 	s.CheckInit()
 
-	s.Lock()
-	defer s.Unlock()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
 
 	s.m = make(map[interface{}]struct{})
     s.Sizeb = 0
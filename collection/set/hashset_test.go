@@ -376,6 +376,30 @@ func TestEqualSubsetHashSet(t *testing.T) {
 	}
 }
 
+func TestThreadsafeHashSet(t *testing.T) {
+	if !NewHashSet().Threadsafe() || NewHashSetUnsafe().Threadsafe() {
+		t.Fatal("Threadsafe() doesn't match the constructor!")
+	}
+
+	s := NewHashSet()
+	var wg sync.WaitGroup
+	wg.Add(8)
+	for g := 0; g < 8; g++ {
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				s.Insert(g*1000 + i)
+				s.Contains(i)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	if s.Size() != 8000 {
+		t.Error("Concurrent inserts were lost: ", s.Size())
+	}
+}
+
 // Intended to reveal potential deadlock issues...
 func TestEqualConcurrencyHashSet(t *testing.T) {
 	s1 := NewHashSet(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
@@ -670,3 +694,47 @@ func TestClearToEmptyHashSet(t *testing.T) {
 		t.Error("{1, 2, 3}.Clear() should yield {}")
 	}
 }
+
+func TestSymmetricDifferenceHashSet(t *testing.T) {
+	s := NewHashSet(1, 2, 3)
+	o := NewHashSet(2, 3, 4)
+
+	if !s.SymmetricDifference(o).Equal(NewHashSet(1, 4)) {
+		t.Error("SymmetricDifference of {1, 2, 3} and {2, 3, 4} should be {1, 4}, not: ", s.SymmetricDifference(o))
+	}
+	if !s.SymmetricDifference(s).Empty() {
+		t.Error("SymmetricDifference with itself should be empty!")
+	}
+}
+
+func TestIsDisjointHashSet(t *testing.T) {
+	s := NewHashSet(1, 2, 3)
+
+	if s.IsDisjoint(NewHashSet(3, 4)) || !s.IsDisjoint(NewHashSet(4, 5, 6, 7)) || !s.IsDisjoint(NewHashSet()) {
+		t.Error("IsDisjoint is wrong!")
+	}
+}
+
+func TestInPlaceOperationsHashSet(t *testing.T) {
+	s := NewHashSet(1, 2, 3)
+
+	s.UnionWith(NewHashSet(3, 4))
+	if !s.Equal(NewHashSet(1, 2, 3, 4)) {
+		t.Error("UnionWith {3, 4} should give {1, 2, 3, 4}, not: ", s)
+	}
+
+	s.IntersectWith(NewHashSet(2, 3, 4, 5))
+	if !s.Equal(NewHashSet(2, 3, 4)) {
+		t.Error("IntersectWith {2, 3, 4, 5} should give {2, 3, 4}, not: ", s)
+	}
+
+	s.DifferenceWith(NewHashSet(4, 6))
+	if !s.Equal(NewHashSet(2, 3)) {
+		t.Error("DifferenceWith {4, 6} should give {2, 3}, not: ", s)
+	}
+
+	s.UnionWith(s)
+	if !s.Equal(NewHashSet(2, 3)) {
+		t.Error("UnionWith itself should change nothing, not give: ", s)
+	}
+}
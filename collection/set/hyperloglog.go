@@ -0,0 +1,493 @@
+// This module implements a HyperLogLog, a cardinality estimator.
+//
+// See Heule, Nunkesser and Hall, "HyperLogLog in Practice: Algorithmic
+// Engineering of a State of The Art Cardinality Estimation Algorithm", 2013,
+// for the sparse representation, and Ertl, "New cardinality estimation
+// algorithms for HyperLogLog sketches", 2017, for the estimator.
+
+package set
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"math"
+	"math/bits"
+	"sort"
+)
+
+// Returned when merging HyperLogLogs with different precisions.
+var ErrPrecisionMismatch = errors.New("sketches have different precisions")
+
+const (
+	// The range of precisions a HyperLogLog may have.
+	MinPrecision = 4
+	MaxPrecision = 18
+
+	// The precision of the sparse representation.
+	sparsePrecision = 25
+)
+
+// Identifies, and versions, the binary encoding of a HyperLogLog.
+var hllMagic = [4]byte{'H', 'L', 'L', 1}
+
+// The formats of the binary encoding.
+const (
+	hllSparse byte = iota
+	hllDense
+)
+
+// magic, precision, format.
+const hllHeaderSize = 4 + 1 + 1
+
+// A HyperLogLog estimates the number of distinct items added to it, using
+// 2^precision bytes at most, with a relative standard error of about
+// 1.04 / sqrt(2^precision), see StandardError().
+//
+// It starts out sparse, storing a register only for each distinct hash
+// prefix seen, with a precision of 25, and so counts small numbers of items
+// almost exactly. Once that would take more memory than 2^precision
+// registers, it switches to the dense representation.
+//
+// Items can't be removed from, or listed by, a HyperLogLog. Its Size() is
+// its Count().
+//
+// Behavior unspecified if a HyperLogLog is not created using NewHyperLogLog(),
+// NewHyperLogLogUnsafe(), or unmarshaled into a new &HyperLogLog{}.
+//
+type HyperLogLog struct {
+	collection.Base
+	p      uint
+	sparse map[uint32]uint8 // sparse index -> register, nil once dense.
+	dense  []uint8          // index -> register, nil while sparse.
+}
+
+// Returns a pointer to a new HyperLogLog with 2^precision registers.
+//
+// Panics if precision is not between MinPrecision and MaxPrecision.
+func NewHyperLogLog(precision int) *HyperLogLog {
+	s := &HyperLogLog{p: uint(precision)}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe HyperLogLog with 2^precision registers.
+//
+// Panics if precision is not between MinPrecision and MaxPrecision.
+func NewHyperLogLogUnsafe(precision int) *HyperLogLog {
+	s := &HyperLogLog{p: uint(precision)}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *HyperLogLog) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *HyperLogLog) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Adds the given items to this HyperLogLog.
+//
+// Panics if any of the given items are nil.
+func (s *HyperLogLog) Add(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		h, _ := hashItem(item)
+		if s.sparse != nil {
+			i, r := hllRegister(h, sparsePrecision)
+			if r > s.sparse[i] {
+				s.sparse[i] = r
+				if len(s.sparse) > s.sparseLimit() {
+					s.toDense()
+				}
+			}
+		} else {
+			i, r := hllRegister(h, s.p)
+			if r > s.dense[i] {
+				s.dense[i] = r
+			}
+		}
+	}
+}
+
+// Returns an estimate of the number of distinct items added to this
+// HyperLogLog.
+func (s *HyperLogLog) Count() uint64 {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.count()
+}
+
+// Same as Count().
+func (s *HyperLogLog) Size() int {
+	return int(s.Count())
+}
+
+// Returns true if no items have been added to this HyperLogLog.
+func (s *HyperLogLog) Empty() bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.sparse != nil {
+		return len(s.sparse) == 0
+	}
+	for _, r := range s.dense {
+		if r != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the precision of this HyperLogLog.
+func (s *HyperLogLog) Precision() int {
+	s.CheckInit()
+	return int(s.p)
+}
+
+// Returns the relative standard error of Count() once this HyperLogLog is
+// dense, 1.04 / sqrt(2^precision). Counts are within twice that of the true
+// count about 95% of the time.
+func (s *HyperLogLog) StandardError() float64 {
+	s.CheckInit()
+	return 1.04 / math.Sqrt(float64(uint64(1)<<s.p))
+}
+
+// Returns true if this HyperLogLog uses the sparse representation.
+func (s *HyperLogLog) Sparse() bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.sparse != nil
+}
+
+// Adds every item added to the given HyperLogLog to this HyperLogLog, so
+// that its Count() estimates the size of the union of the two.
+//
+// Returns ErrPrecisionMismatch if the two have different precisions.
+func (s *HyperLogLog) Merge(o *HyperLogLog) error {
+	s.CheckInit()
+
+	// Snapshot o first, so the two are never locked at once.
+	o = o.Copy()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	if s.p != o.p {
+		return ErrPrecisionMismatch
+	}
+
+	if s.sparse != nil && o.sparse != nil {
+		for i, r := range o.sparse {
+			if r > s.sparse[i] {
+				s.sparse[i] = r
+			}
+		}
+		if len(s.sparse) > s.sparseLimit() {
+			s.toDense()
+		}
+		return nil
+	}
+
+	if s.sparse != nil {
+		s.toDense()
+	}
+	if o.sparse != nil {
+		o.toDense()
+	}
+	for i, r := range o.dense {
+		if r > s.dense[i] {
+			s.dense[i] = r
+		}
+	}
+	return nil
+}
+
+// Returns a pointer to a new HyperLogLog, that is a copy of this HyperLogLog.
+func (s *HyperLogLog) Copy() *HyperLogLog {
+	s.CheckInit()
+
+	c := &HyperLogLog{p: s.p}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.sparse != nil {
+		for i, r := range s.sparse {
+			c.sparse[i] = r
+		}
+	} else {
+		c.sparse = nil
+		c.dense = make([]uint8, len(s.dense))
+		copy(c.dense, s.dense)
+	}
+	return c
+}
+
+// Removes all items from this HyperLogLog, making it sparse again.
+func (s *HyperLogLog) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.sparse = make(map[uint32]uint8)
+	s.dense = nil
+}
+
+func (s *HyperLogLog) String() string {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return fmt.Sprintf("HyperLogLog{precision: %d, sparse: %v, count: %d}", s.p, s.sparse != nil, s.count())
+}
+
+// Implements encoding.BinaryMarshaler. The encoding is the same on every
+// platform, and is small while this HyperLogLog is sparse.
+func (s *HyperLogLog) MarshalBinary() ([]byte, error) {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if s.dense != nil {
+		data := s.header(hllDense, len(s.dense))
+		copy(data[hllHeaderSize:], s.dense)
+		return data, nil
+	}
+
+	// Each sparse register as its index, and its value in the low 6 bits,
+	// in order of index.
+	entries := make([]uint32, 0, len(s.sparse))
+	for i, r := range s.sparse {
+		entries = append(entries, i<<6|uint32(r))
+	}
+	sort.Slice(entries, func(a, b int) bool { return entries[a] < entries[b] })
+
+	data := s.header(hllSparse, 4+4*len(entries))
+	binary.BigEndian.PutUint32(data[hllHeaderSize:], uint32(len(entries)))
+	for i, e := range entries {
+		binary.BigEndian.PutUint32(data[hllHeaderSize+4+4*i:], e)
+	}
+	return data, nil
+}
+
+// Returns a new encoding of this HyperLogLog, with the header filled in for
+// the given representation, and room for a body of the given size.
+func (s *HyperLogLog) header(representation byte, body int) []byte {
+	data := make([]byte, hllHeaderSize+body)
+	copy(data, hllMagic[:])
+	data[4] = byte(s.p)
+	data[5] = representation
+	return data
+}
+
+// Implements encoding.BinaryUnmarshaler, replacing the contents of this
+// HyperLogLog with those encoded in the given bytes. A new &HyperLogLog{}
+// is initialized by this, and will be thread-safe.
+//
+// Returns ErrInvalidEncoding if the given bytes weren't produced by
+// MarshalBinary(), in which case this HyperLogLog is left unchanged.
+func (s *HyperLogLog) UnmarshalBinary(data []byte) error {
+	if len(data) < hllHeaderSize || !bytes.Equal(data[:4], hllMagic[:]) {
+		return ErrInvalidEncoding
+	}
+
+	p := uint(data[4])
+	if p < MinPrecision || p > MaxPrecision {
+		return ErrInvalidEncoding
+	}
+
+	var sparse map[uint32]uint8
+	var dense []uint8
+	body := data[hllHeaderSize:]
+
+	switch data[5] {
+	case hllDense:
+		if len(body) != 1<<p {
+			return ErrInvalidEncoding
+		}
+		dense = make([]uint8, len(body))
+		for i, r := range body {
+			if r > 64-uint8(p)+1 {
+				return ErrInvalidEncoding
+			}
+			dense[i] = r
+		}
+	case hllSparse:
+		if len(body) < 4 || uint64(len(body)) != 4+4*uint64(binary.BigEndian.Uint32(body)) {
+			return ErrInvalidEncoding
+		}
+		sparse = make(map[uint32]uint8)
+		for off := 4; off < len(body); off += 4 {
+			e := binary.BigEndian.Uint32(body[off:])
+			i, r := e>>6, uint8(e&63)
+			if r == 0 || r > 64-sparsePrecision+1 || i >= 1<<sparsePrecision {
+				return ErrInvalidEncoding
+			}
+			if _, ok := sparse[i]; ok {
+				return ErrInvalidEncoding
+			}
+			sparse[i] = r
+		}
+	default:
+		return ErrInvalidEncoding
+	}
+
+	// A HyperLogLog can't have been initialized without setting p.
+	if s.p == 0 {
+		s.InitBase()
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.p, s.sparse, s.dense = p, sparse, dense
+	return nil
+}
+
+func (s *HyperLogLog) setup() {
+	if s.p < MinPrecision || s.p > MaxPrecision {
+		log.Panicf("Precision must be between %d and %d.", MinPrecision, MaxPrecision)
+	}
+
+	s.sparse = make(map[uint32]uint8)
+}
+
+// Returns the most sparse registers to keep, before switching to dense:
+// those at which the sparse encoding, 4 bytes per register, would take more
+// memory than the dense one, 1 byte per register.
+func (s *HyperLogLog) sparseLimit() int {
+	return 1 << s.p / 4
+}
+
+// Switches to the dense representation.
+func (s *HyperLogLog) toDense() {
+	s.dense = make([]uint8, 1<<s.p)
+
+	// The bits of a sparse index past the first p come first in the rest of
+	// the hash, after which the sparse register counts.
+	extra := sparsePrecision - s.p
+	for i, r := range s.sparse {
+		low := i & (1<<extra - 1)
+		if low != 0 {
+			r = uint8(bits.LeadingZeros32(low)-(32-int(extra))) + 1
+		} else {
+			r += uint8(extra)
+		}
+		if r > s.dense[i>>extra] {
+			s.dense[i>>extra] = r
+		}
+	}
+	s.sparse = nil
+}
+
+func (s *HyperLogLog) count() uint64 {
+	if s.sparse != nil {
+		// Linear counting over the 2^25 sparse registers.
+		m := float64(uint64(1) << sparsePrecision)
+		return uint64(math.Round(m * math.Log(m/(m-float64(len(s.sparse))))))
+	}
+
+	q := 64 - int(s.p)
+	m := float64(len(s.dense))
+	histogram := make([]float64, q+2)
+	for _, r := range s.dense {
+		histogram[r] += 1
+	}
+
+	z := m * hllTau(1-histogram[q+1]/m)
+	for k := q; k >= 1; k-- {
+		z = 0.5 * (z + histogram[k])
+	}
+	z += m * hllSigma(histogram[0]/m)
+	return uint64(math.Round(m * m / (2 * math.Ln2) / z))
+}
+
+// Returns the given hash's register index, from its first p bits, and value,
+// one more than the number of leading zeros in the rest.
+func hllRegister(h uint64, p uint) (uint32, uint8) {
+	r := bits.LeadingZeros64(h<<p) + 1
+	if r > 64-int(p)+1 {
+		r = 64 - int(p) + 1
+	}
+	return uint32(h >> (64 - p)), uint8(r)
+}
+
+// The sigma function of Ertl, x + sum x^(2^k) 2^(k-1), for the registers still 0.
+func hllSigma(x float64) float64 {
+	if x == 1 {
+		return math.Inf(1)
+	}
+	y, z := 1.0, x
+	for {
+		x *= x
+		prev := z
+		z += x * y
+		y += y
+		if z == prev {
+			return z
+		}
+	}
+}
+
+// The tau function of Ertl, for the registers at their maximum value.
+func hllTau(x float64) float64 {
+	if x == 0 || x == 1 {
+		return 0
+	}
+	y, z := 1.0, 1-x
+	for {
+		x = math.Sqrt(x)
+		prev := z
+		y *= 0.5
+		z -= (1 - x) * (1 - x) * y
+		if z == prev {
+			return z / 3
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// This module contains tests for hyperloglog.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+// Returns the relative error of the given estimate of the given count.
+func relativeError(estimate uint64, count int) float64 {
+	return math.Abs(float64(estimate)-float64(count)) / float64(count)
+}
+
+func TestNewEmptyHyperLogLog(t *testing.T) {
+	s := NewHyperLogLog(14)
+
+	if s.Count() != 0 || s.Size() != 0 || !s.Empty() || !s.Sparse() {
+		t.Error("NewHyperLogLog does not create an empty, sparse sketch!")
+	}
+	if s.Precision() != 14 || math.Abs(s.StandardError()-0.008125) > 1e-9 {
+		t.Error("Wrong precision or standard error!")
+	}
+}
+
+func TestInvalidHyperLogLog(t *testing.T) {
+	for _, p := range []int{MinPrecision - 1, MaxPrecision + 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewHyperLogLog(%d) did not panic!", p)
+				}
+			}()
+			NewHyperLogLog(p)
+		}()
+	}
+}
+
+func TestSparseHyperLogLog(t *testing.T) {
+	s := NewHyperLogLog(14)
+
+	// Duplicates don't count.
+	for i := 0; i < 3000; i++ {
+		s.Add(i % 1000)
+	}
+	if !s.Sparse() {
+		t.Fatal("Sketch of 1000 items is not sparse!")
+	}
+	if e := relativeError(s.Count(), 1000); e > 0.001 {
+		t.Errorf("Sparse count %d of 1000", s.Count())
+	}
+}
+
+func TestDenseHyperLogLog(t *testing.T) {
+	s := NewHyperLogLog(10)
+
+	for i := 0; i < 1000; i++ {
+		s.Add(i)
+	}
+	if s.Sparse() {
+		t.Fatal("Sketch past its sparse limit is still sparse!")
+	}
+	if e := relativeError(s.Count(), 1000); e > 3*s.StandardError() {
+		t.Errorf("Dense count %d of 1000", s.Count())
+	}
+}
+
+func TestErrorBoundsHyperLogLog(t *testing.T) {
+	// Within 3 standard errors, 99.7% of the time, across cardinalities
+	// from the sparse range up, and over several disjoint runs, the mean
+	// error is within 1 standard error.
+	for _, p := range []int{8, 12, 14} {
+		for _, n := range []int{10, 100, 1000, 10000, 100000} {
+			total := 0.0
+			runs := 5
+			for run := 0; run < runs; run++ {
+				s := NewHyperLogLogUnsafe(p)
+				for i := 0; i < n; i++ {
+					s.Add(run*n + i)
+				}
+				e := relativeError(s.Count(), n)
+				if e > 3*s.StandardError() {
+					t.Errorf("p = %d, n = %d: count %d, error %v > %v", p, n, s.Count(), e, 3*s.StandardError())
+				}
+				total += e
+			}
+			if bound := 1.04 / math.Sqrt(float64(int(1)<<p)); total/float64(runs) > bound {
+				t.Errorf("p = %d, n = %d: mean error %v > %v", p, n, total/float64(runs), bound)
+			}
+		}
+	}
+}
+
+func TestMergeHyperLogLog(t *testing.T) {
+	sparse := NewHyperLogLog(14)
+	dense := NewHyperLogLog(14)
+	other := NewHyperLogLog(14)
+
+	for i := 0; i < 500; i++ {
+		sparse.Add(i)
+	}
+	for i := 500; i < 50000; i++ {
+		dense.Add(i)
+	}
+	for i := 0; i < 1000; i++ {
+		other.Add(i)
+	}
+
+	u := sparse.Copy()
+	if err := u.Merge(other); err != nil {
+		t.Fatal(err)
+	}
+	if !u.Sparse() || relativeError(u.Count(), 1000) > 0.001 {
+		t.Errorf("Sparse merge counted %d of 1000", u.Count())
+	}
+
+	if err := u.Merge(dense); err != nil {
+		t.Fatal(err)
+	}
+	if u.Sparse() || relativeError(u.Count(), 50000) > 3*u.StandardError() {
+		t.Errorf("Dense merge counted %d of 50000", u.Count())
+	}
+
+	// Merging is the same as adding everything to one sketch.
+	all := NewHyperLogLog(14)
+	for i := 0; i < 50000; i++ {
+		all.Add(i)
+	}
+	if all.Count() != u.Count() {
+		t.Errorf("Merge counted %d, adding counted %d", u.Count(), all.Count())
+	}
+
+	if sparse.Count() != 500 || relativeError(dense.Count(), 49500) > 3*dense.StandardError() {
+		t.Error("Merge changed its argument or a copy's original!")
+	}
+	if err := u.Merge(NewHyperLogLog(12)); err != ErrPrecisionMismatch {
+		t.Errorf("Expected ErrPrecisionMismatch, got %v", err)
+	}
+}
+
+func TestMarshalHyperLogLog(t *testing.T) {
+	for _, n := range []int{0, 100, 100000} {
+		s := NewHyperLogLog(12)
+		for i := 0; i < n; i++ {
+			s.Add(i)
+		}
+
+		data, err := s.MarshalBinary()
+		if err != nil {
+			t.Fatal(err)
+		}
+		c := &HyperLogLog{}
+		if err := c.UnmarshalBinary(data); err != nil {
+			t.Fatal(err)
+		}
+		if c.Count() != s.Count() || c.Sparse() != s.Sparse() || c.Precision() != 12 {
+			t.Errorf("n = %d: unmarshaled %v from %v", n, c, s)
+		}
+		if n == 100 && len(data) > 1000 {
+			t.Errorf("Sparse encoding of 100 items takes %d bytes", len(data))
+		}
+	}
+}
+
+// Returns a sparse encoding, at the given precision, of the given entries.
+func sparseEncoding(p byte, entries ...uint32) []byte {
+	data := make([]byte, hllHeaderSize+4+4*len(entries))
+	copy(data, hllMagic[:])
+	data[4], data[5] = p, hllSparse
+	binary.BigEndian.PutUint32(data[hllHeaderSize:], uint32(len(entries)))
+	for i, e := range entries {
+		binary.BigEndian.PutUint32(data[hllHeaderSize+4+4*i:], e)
+	}
+	return data
+}
+
+func TestMarshalInvalidHyperLogLog(t *testing.T) {
+	s := NewHyperLogLog(4)
+	s.Add("a")
+	data, _ := s.MarshalBinary()
+
+	bad := [][]byte{
+		nil,
+		data[:5],
+		data[:len(data)-1],
+		{'H', 'L', 'L', 1, 30, hllDense},
+		{'H', 'L', 'L', 1, 4, 7},
+		append([]byte{'H', 'L', 'L', 1, 4, hllDense}, make([]byte, 15)...),
+		sparseEncoding(4, (1<<sparsePrecision)<<6|1),
+		sparseEncoding(4, (1<<26-1)<<6|1),
+		sparseEncoding(4, 7<<6|1, 7<<6|2),
+	}
+	for i, b := range bad {
+		c := NewHyperLogLog(4)
+		if err := c.UnmarshalBinary(b); err != ErrInvalidEncoding {
+			t.Errorf("Case %d: expected ErrInvalidEncoding, got %v", i, err)
+		}
+		if !c.Empty() {
+			t.Errorf("Case %d: failed unmarshal changed the sketch!", i)
+		}
+	}
+
+	c := NewHyperLogLog(4)
+	if err := c.UnmarshalBinary(sparseEncoding(4, 7<<6|1, (1<<sparsePrecision-1)<<6|2)); err != nil {
+		t.Fatal("A valid sparse encoding was rejected: ", err)
+	}
+	for i := 0; i < 100; i++ {
+		c.Add(i)
+	}
+	if c.Sparse() {
+		t.Error("Adding to an unmarshaled sketch didn't switch to dense!")
+	}
+}
+
+func TestClearHyperLogLog(t *testing.T) {
+	s := NewHyperLogLog(4)
+	for i := 0; i < 100; i++ {
+		s.Add(i)
+	}
+	s.Clear()
+
+	if !s.Empty() || !s.Sparse() || s.Count() != 0 {
+		t.Error("Clear did not empty the sketch!")
+	}
+}
+
+func BenchmarkAddHyperLogLog(b *testing.B) {
+	s := NewHyperLogLogUnsafe(14)
+
+	for i := 0; i < b.N; i++ {
+		s.Add(i)
+	}
+}
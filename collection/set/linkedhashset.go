@@ -0,0 +1,400 @@
+// This module implements a LinkedHashSet, conforming to set.Interface.
+
+package set
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"github.com/michalpiszczek/nonstdlib/collection/dictionary"
+)
+
+// A LinkedHashSet implements set.Interface, with the additional guarantee of
+// Map(), Slice() and String() visiting items in the order they were first
+// inserted. Re-inserting an item does not change its position.
+//
+// Like a HashSet, a LinkedHashSet may hold nil. Since First() and Last()
+// also return nil for an empty Set, check Size() to tell the two apart.
+//
+// Sets returned by Union(), Intersection() and Difference() are also
+// LinkedHashSets, ordered as this Set, followed by any new items from the
+// given Set, in its order.
+//
+// Behavior unspecified if a LinkedHashSet is not created using NewLinkedHashSet()
+// or if LinkedHashSet.Init() is not first called on a new &LinkedHashSet{}.
+//
+type LinkedHashSet struct {
+	collection.Base
+	m *dictionary.LinkedHashMap
+}
+
+// Stands in for nil in the LinkedHashMap, which doesn't accept nil keys.
+type nilItem struct{}
+
+// Returns the key the given item is stored under.
+func linkedKey(item interface{}) interface{} {
+	if item == nil {
+		return nilItem{}
+	}
+	return item
+}
+
+// Returns the item stored under the given key.
+func linkedItem(key interface{}) interface{} {
+	if key == (nilItem{}) {
+		return nil
+	}
+	return key
+}
+
+// Returns a pointer to a new LinkedHashSet containing the given items,
+// in the given order.
+func NewLinkedHashSet(items ...interface{}) *LinkedHashSet {
+	s := &LinkedHashSet{}
+	s.Init()
+	s.Insert(items...)
+	return s
+}
+
+// Returns a pointer to a new unsafe LinkedHashSet containing the given items,
+// in the given order.
+func NewLinkedHashSetUnsafe(items ...interface{}) *LinkedHashSet {
+	s := &LinkedHashSet{}
+	s.InitUnsafe()
+	s.Insert(items...)
+	return s
+}
+
+func (s *LinkedHashSet) Init() {
+	s.InitBase()
+
+	s.m = dictionary.NewLinkedHashMapUnsafe()
+}
+
+func (s *LinkedHashSet) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.m = dictionary.NewLinkedHashMapUnsafe()
+}
+
+// New items are inserted at the back.
+func (s *LinkedHashSet) Insert(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		if old := s.m.Insert(linkedKey(item), present); old == nil {
+			s.Sizeb += 1
+		}
+	}
+}
+
+func (s *LinkedHashSet) Remove(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		if old := s.m.Remove(linkedKey(item)); old != nil {
+			s.Sizeb -= 1
+		}
+	}
+}
+
+func (s *LinkedHashSet) Contains(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		if !s.m.Contains(linkedKey(item)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Moves the given item to the front. Returns false if it is not in this Set.
+func (s *LinkedHashSet) MoveToFront(item interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	return s.m.MoveToFront(linkedKey(item))
+}
+
+// Moves the given item to the back. Returns false if it is not in this Set.
+func (s *LinkedHashSet) MoveToBack(item interface{}) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	return s.m.MoveToBack(linkedKey(item))
+}
+
+// Returns the item at the front, or nil if this Set is empty.
+func (s *LinkedHashSet) First() interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if kv := s.m.First(); kv != nil {
+		return linkedItem(kv.Key)
+	}
+	return nil
+}
+
+// Returns the item at the back, or nil if this Set is empty.
+func (s *LinkedHashSet) Last() interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if kv := s.m.Last(); kv != nil {
+		return linkedItem(kv.Key)
+	}
+	return nil
+}
+
+// Returns a pointer to a new Set containing all the items in either this
+// Set or the given Set.
+func (s *LinkedHashSet) Union(o Set) Set {
+	s.CheckInit()
+
+	result := s.Copy()
+
+	o.Map(func(item interface{}) bool {
+		result.Insert(item)
+		return true
+	})
+
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in both this Set
+// and the given Set.
+func (s *LinkedHashSet) Intersection(o Set) Set {
+	s.CheckInit()
+
+	result := s.empty()
+
+	s.Map(func(item interface{}) bool {
+		if o.Contains(item) {
+			result.Insert(item)
+		}
+		return true
+	})
+
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in this Set that
+// are not in the given Set.
+func (s *LinkedHashSet) Difference(o Set) Set {
+	s.CheckInit()
+
+	result := s.empty()
+
+	s.Map(func(item interface{}) bool {
+		if !o.Contains(item) {
+			result.Insert(item)
+		}
+		return true
+	})
+
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in exactly one of
+// this Set and the given Set.
+func (s *LinkedHashSet) SymmetricDifference(o Set) Set {
+	s.CheckInit()
+
+	return symmetricDifference(s, o)
+}
+
+// Returns true if this Set and the given Set have no items in common, false
+// otherwise.
+func (s *LinkedHashSet) IsDisjoint(o Set) bool {
+	s.CheckInit()
+
+	return isDisjoint(s, o)
+}
+
+// Adds all the items in the given Set to this Set.
+func (s *LinkedHashSet) UnionWith(o Set) {
+	s.CheckInit()
+
+	s.Insert(*o.Slice()...)
+}
+
+// Removes all the items not in the given Set from this Set.
+func (s *LinkedHashSet) IntersectWith(o Set) {
+	s.CheckInit()
+
+	intersectWith(s, o)
+}
+
+// Removes all the items in the given Set from this Set.
+func (s *LinkedHashSet) DifferenceWith(o Set) {
+	s.CheckInit()
+
+	s.Remove(*o.Slice()...)
+}
+
+// Returns true if this Set and the given Set contain exactly the same items,
+// false otherwise. Order is not considered.
+func (s *LinkedHashSet) Equal(o Set) bool {
+	s.CheckInit()
+
+	if s.Size() != o.Size() {
+		return false
+	}
+
+	equal := true
+	o.Map(func(item interface{}) bool {
+		equal = s.Contains(item)
+		return equal
+	})
+
+	return equal
+}
+
+// The first bool returned is true if this Set is a subset of the given Set,
+// false otherwise. If the first returned bool is true, then the second bool
+// will be false if these two sets are equal, true otherwise.
+//
+// true, true -> s is a proper subset of o
+// true, false -> s is equal to o
+// false, true -> s is not a subset of o
+// false, false -> s is not a subset of o
+func (s *LinkedHashSet) Subset(o Set) (subset bool, proper bool) {
+	s.CheckInit()
+
+	proper = s.Size() != o.Size()
+
+	subset = true
+	s.Map(func(item interface{}) bool {
+		subset = o.Contains(item)
+		return subset
+	})
+
+	return
+}
+
+// The first bool returned is true if this Set is a superset of the given
+// Set, false otherwise. If the first returned bool is true, then the
+// second bool will be false if these two sets are equal, true otherwise.
+//
+// true, true -> s is a proper superset of o
+// true, false -> s is equal to o
+// false, true -> s is not a superset of o
+// false, false -> s is not a superset of o
+func (s *LinkedHashSet) Superset(o Set) (superset bool, proper bool) {
+	s.CheckInit()
+
+	proper = s.Size() != o.Size()
+
+	superset = true
+	o.Map(func(item interface{}) bool {
+		superset = s.Contains(item)
+		return superset
+	})
+
+	return
+}
+
+// Returns a pointer to a new Set that is a copy of this Set, in the same order.
+func (s *LinkedHashSet) Copy() Set {
+	s.CheckInit()
+
+	c := s.empty()
+	s.Map(func(item interface{}) bool {
+		c.Insert(item)
+		return true
+	})
+
+	return c
+}
+
+// Attempts to apply the given function to every item in this Set, front to
+// back. Stops once all elements have been processed, or once the function
+// returns false, whichever occurs first.
+func (s *LinkedHashSet) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.m.Map(func(kv interface{}) bool {
+		return f(linkedItem(kv.(*dictionary.KeyValue).Key))
+	})
+}
+
+// Returns a slice of all the items in this Set, front to back.
+func (s *LinkedHashSet) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	s.m.Map(func(kv interface{}) bool {
+		slice = append(slice, linkedItem(kv.(*dictionary.KeyValue).Key))
+		return true
+	})
+
+	return &slice
+}
+
+// Removes all items from this Set.
+func (s *LinkedHashSet) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.m = dictionary.NewLinkedHashMapUnsafe()
+	s.Sizeb = 0
+}
+
+func (s *LinkedHashSet) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Returns a new, empty LinkedHashSet, as thread-safe as this one.
+func (s *LinkedHashSet) empty() *LinkedHashSet {
+	if s.Threadsafe() {
+		return NewLinkedHashSet()
+	}
+	return NewLinkedHashSetUnsafe()
+}
@@ -0,0 +1,141 @@
+// This module contains tests for linkedhashset.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewEmptyLinkedHashSet(t *testing.T) {
+	s := NewLinkedHashSet()
+
+	if s.Size() != 0 || s.Empty() != true {
+		t.Error("NewLinkedHashSet with 0 args does not create an empty set!")
+	}
+	if s.First() != nil || s.Last() != nil {
+		t.Error("Empty set has a first or last item!")
+	}
+}
+
+func TestInsertionOrderLinkedHashSet(t *testing.T) {
+	s := NewLinkedHashSet("c", "a", "d", "b", "a")
+
+	if s.Size() != 4 {
+		t.Error("Wrong size!")
+	}
+	if fmt.Sprint(*s.Slice()) != "[c a d b]" {
+		t.Errorf("Items out of order: %v", *s.Slice())
+	}
+
+	s.Remove("a")
+	s.Insert("a")
+	if fmt.Sprint(*s.Slice()) != "[c d b a]" {
+		t.Errorf("Items out of order: %v", *s.Slice())
+	}
+	if s.First() != "c" || s.Last() != "a" {
+		t.Error("Wrong first or last item!")
+	}
+}
+
+func TestMoveLinkedHashSet(t *testing.T) {
+	s := NewLinkedHashSet("a", "b", "c")
+
+	s.MoveToFront("c")
+	s.MoveToBack("a")
+	if fmt.Sprint(*s.Slice()) != "[c b a]" {
+		t.Errorf("Items out of order: %v", *s.Slice())
+	}
+	if s.MoveToFront("d") {
+		t.Error("Moved a missing item!")
+	}
+}
+
+func TestNilLinkedHashSet(t *testing.T) {
+	s := NewLinkedHashSet("a", nil, "b")
+
+	if s.Size() != 3 || !s.Contains(nil, "a") {
+		t.Error("Nil was not inserted!")
+	}
+	if !s.MoveToBack(nil) || s.Last() != nil {
+		t.Error("Nil was not moved!")
+	}
+	if fmt.Sprint(*s.Slice()) != "[a b <nil>]" {
+		t.Errorf("Items out of order: %v", *s.Slice())
+	}
+
+	s.Remove(nil)
+	if s.Size() != 2 || s.Contains(nil) {
+		t.Error("Nil was not removed!")
+	}
+}
+
+func TestSetOperationsLinkedHashSet(t *testing.T) {
+	s := NewLinkedHashSet("d", "a", "c")
+	o := NewLinkedHashSet("b", "c", "e")
+
+	if u := s.Union(o); fmt.Sprint(*u.Slice()) != "[d a c b e]" {
+		t.Errorf("Wrong union: %v", *u.Slice())
+	}
+	if i := s.Intersection(o); fmt.Sprint(*i.Slice()) != "[c]" {
+		t.Errorf("Wrong intersection: %v", *i.Slice())
+	}
+	if d := s.Difference(o); fmt.Sprint(*d.Slice()) != "[d a]" {
+		t.Errorf("Wrong difference: %v", *d.Slice())
+	}
+	if !s.Equal(NewHashSet("a", "c", "d")) || s.Equal(o) {
+		t.Error("Wrong equality!")
+	}
+	if sub, proper := NewLinkedHashSet("a").Subset(s); !sub || !proper {
+		t.Error("Wrong subset!")
+	}
+	if sup, proper := s.Superset(NewHashSet("a", "c", "d")); !sup || proper {
+		t.Error("Wrong superset!")
+	}
+}
+
+func TestCopyLinkedHashSet(t *testing.T) {
+	s := NewLinkedHashSet("b", "a", "c")
+	c := s.Copy()
+	c.Remove("a")
+
+	if fmt.Sprint(*c.Slice()) != "[b c]" || fmt.Sprint(*s.Slice()) != "[b a c]" {
+		t.Errorf("Bad copy: %v of %v", *c.Slice(), *s.Slice())
+	}
+}
+
+func TestClearLinkedHashSet(t *testing.T) {
+	s := NewLinkedHashSet("a", "b")
+	s.Clear()
+	s.Insert("c")
+
+	if s.Size() != 1 || fmt.Sprint(*s.Slice()) != "[c]" {
+		t.Error("Clear did not empty the set!")
+	}
+}
+
+func TestInPlaceOperationsLinkedHashSet(t *testing.T) {
+	s := NewLinkedHashSet("c", "a", "b")
+
+	if fmt.Sprint(*s.SymmetricDifference(NewLinkedHashSet("d", "a")).Slice()) != "[c b d]" {
+		t.Errorf("SymmetricDifference is wrong: %v", *s.SymmetricDifference(NewLinkedHashSet("d", "a")).Slice())
+	}
+	if s.IsDisjoint(NewHashSet("a")) || !s.IsDisjoint(NewHashSet("d")) {
+		t.Error("IsDisjoint is wrong!")
+	}
+
+	s.UnionWith(NewLinkedHashSet("e", "a", "d"))
+	if fmt.Sprint(*s.Slice()) != "[c a b e d]" {
+		t.Errorf("UnionWith did not append new items in order: %v", *s.Slice())
+	}
+
+	s.IntersectWith(NewHashSet("a", "d", "e", "z"))
+	s.DifferenceWith(NewHashSet("e"))
+	if fmt.Sprint(*s.Slice()) != "[a d]" {
+		t.Errorf("In-place operations should give [a d], not: %v", *s.Slice())
+	}
+}
@@ -0,0 +1,168 @@
+// This module defines the MultiSet interface, and the multiset algebra shared
+// by all types implementing MultiSet.
+
+package set
+
+// Defines the MultiSet interface. MultiSets, or bags, are Sets that count how
+// many times each item was inserted.
+//
+// As Sets, Insert() adds one to an item's count and Remove() takes one off,
+// Size() is the sum of all counts, and Map() and Slice() visit each item as
+// many times as its count. Union() keeps the larger count of each item,
+// Intersection() the smaller, Difference() subtracts counts, stopping at
+// zero, and SymmetricDifference() keeps how far apart the counts are. The
+// in-place UnionWith(), IntersectWith() and DifferenceWith() match their
+// counterparts. The given Set may be a plain Set, whose items each count
+// once.
+//
+type MultiSet interface {
+	Set
+
+	// Returns the number of times the given item is in this MultiSet.
+	//
+	// Panics if this MultiSet has not been initialized.
+	Count(item interface{}) int
+
+	// Sets the number of times the given item is in this MultiSet, removing
+	// it if count is 0, and returns its previous count.
+	//
+	// Panics if this MultiSet has not been initialized, or count is negative.
+	SetCount(item interface{}, count int) int
+
+	// Returns the number of distinct items in this MultiSet.
+	//
+	// Panics if this MultiSet has not been initialized.
+	DistinctSize() int
+
+	// Returns a new MultiSet in which each item's count is the sum of its
+	// counts in this MultiSet and the other given Set.
+	//
+	// Panics if this MultiSet or the given other Set have not been
+	// initialized.
+	Sum(o Set) MultiSet
+
+	// Attempts to apply the given function to every distinct item in this
+	// MultiSet, with its count. Stops once all items have been processed, or
+	// once the function returns false, whichever occurs first.
+	//
+	// Panics if this MultiSet has not been initialized.
+	MapCounts(f func(item interface{}, count int) bool) bool
+}
+
+// Returns the number of times the given item is in the given Set.
+func countIn(s Set, item interface{}) int {
+	if ms, ok := s.(MultiSet); ok {
+		return ms.Count(item)
+	}
+	if s.Contains(item) {
+		return 1
+	}
+	return 0
+}
+
+// Applies the given function to every distinct item in the given Set, with
+// its count, as MultiSet.MapCounts() does. Callers shouldn't rely on the
+// result, since TreeSet.Map() returns true even when stopped early.
+func mapCounts(s Set, f func(item interface{}, count int) bool) bool {
+	if ms, ok := s.(MultiSet); ok {
+		return ms.MapCounts(f)
+	}
+	return s.Map(func(item interface{}) bool {
+		return f(item, 1)
+	})
+}
+
+// A distinct item, and its count.
+type itemCount struct {
+	item  interface{}
+	count int
+}
+
+// Returns the distinct items of the given Set, with their counts.
+func countsOf(s Set) []itemCount {
+	var counts []itemCount
+	mapCounts(s, func(item interface{}, count int) bool {
+		counts = append(counts, itemCount{item, count})
+		return true
+	})
+	return counts
+}
+
+// Sets each item's count in c to the larger of its counts in c and o, and
+// returns c.
+func multiUnion(c MultiSet, o Set) MultiSet {
+	for _, ic := range countsOf(o) {
+		if ic.count > c.Count(ic.item) {
+			c.SetCount(ic.item, ic.count)
+		}
+	}
+	return c
+}
+
+// Sets each item's count in c to the sum of its counts in c and o, and
+// returns c.
+func multiSum(c MultiSet, o Set) MultiSet {
+	for _, ic := range countsOf(o) {
+		c.SetCount(ic.item, c.Count(ic.item)+ic.count)
+	}
+	return c
+}
+
+// Sets each item's count in c to the smaller of its counts in c and o, and
+// returns c.
+func multiIntersection(c MultiSet, o Set) MultiSet {
+	for _, ic := range countsOf(c) {
+		if other := countIn(o, ic.item); other < ic.count {
+			c.SetCount(ic.item, other)
+		}
+	}
+	return c
+}
+
+// Reduces each item's count in c by its count in o, down to zero, and
+// returns c.
+func multiDifference(c MultiSet, o Set) MultiSet {
+	for _, ic := range countsOf(o) {
+		if remaining := c.Count(ic.item) - ic.count; remaining > 0 {
+			c.SetCount(ic.item, remaining)
+		} else {
+			c.SetCount(ic.item, 0)
+		}
+	}
+	return c
+}
+
+// Returns true if s and o hold every item the same number of times.
+func multiEqual(s MultiSet, o Set) bool {
+	if s.Size() != o.Size() {
+		return false
+	}
+	equal := true
+	mapCounts(o, func(item interface{}, count int) bool {
+		equal = s.Count(item) == count
+		return equal
+	})
+	return equal
+}
+
+// Returns true if s holds no item more times than o does, and whether they
+// differ in size.
+func multiSubset(s MultiSet, o Set) (subset bool, proper bool) {
+	subset = true
+	s.MapCounts(func(item interface{}, count int) bool {
+		subset = count <= countIn(o, item)
+		return subset
+	})
+	return subset, s.Size() != o.Size()
+}
+
+// Returns true if s holds every item at least as many times as o does, and
+// whether they differ in size.
+func multiSuperset(s MultiSet, o Set) (superset bool, proper bool) {
+	superset = true
+	mapCounts(o, func(item interface{}, count int) bool {
+		superset = count <= s.Count(item)
+		return superset
+	})
+	return superset, s.Size() != o.Size()
+}
@@ -0,0 +1,179 @@
+// This module implements a PersistentHashSet, an immutable set whose updates
+// return new versions sharing structure with the old, and a TransientHashSet,
+// for building one up efficiently in place.
+
+package set
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection/dictionary"
+)
+
+// A PersistentHashSet is an immutable set, backed by a
+// dictionary.PersistentHashMap. Items must be comparable, as for a HashSet.
+//
+// Insert() and Remove() leave the PersistentHashSet they are called on
+// untouched, and return a new version sharing structure with it. Every
+// version stays readable forever, and may be read, or updated, from any
+// number of goroutines at once without locks.
+//
+// To make many updates at once, use Transient().
+//
+// Since it is never modified, a PersistentHashSet is not a Set, and needs no
+// Unsafe variant. The zero value is an empty PersistentHashSet.
+//
+type PersistentHashSet struct {
+	m dictionary.PersistentHashMap
+}
+
+// Returns a pointer to a new PersistentHashSet containing the given items.
+func NewPersistentHashSet(items ...interface{}) *PersistentHashSet {
+	return (&PersistentHashSet{}).Insert(items...)
+}
+
+// Returns a PersistentHashSet with the given items added.
+//
+// Panics if any given item is nil.
+func (s *PersistentHashSet) Insert(items ...interface{}) *PersistentHashSet {
+	if len(items) == 1 {
+		m, _ := s.m.Insert(items[0], true)
+		return &PersistentHashSet{*m}
+	}
+
+	t := s.Transient()
+	t.Insert(items...)
+	return t.Persistent()
+}
+
+// Returns a PersistentHashSet with the given items removed.
+//
+// Panics if any given item is nil.
+func (s *PersistentHashSet) Remove(items ...interface{}) *PersistentHashSet {
+	t := s.Transient()
+	t.Remove(items...)
+	return t.Persistent()
+}
+
+// Returns true if this PersistentHashSet contains all the given items, false
+// otherwise.
+func (s *PersistentHashSet) Contains(items ...interface{}) bool {
+	return s.m.Contains(items...)
+}
+
+// Returns the number of items in this PersistentHashSet.
+func (s *PersistentHashSet) Size() int {
+	return s.m.Size()
+}
+
+// Returns true if this PersistentHashSet has no items, false otherwise.
+func (s *PersistentHashSet) Empty() bool {
+	return s.m.Empty()
+}
+
+// Returns true if this PersistentHashSet and the given one contain exactly the
+// same items, false otherwise. Skips structure both share.
+func (s *PersistentHashSet) Equal(o *PersistentHashSet) bool {
+	return s.m.Equal(&o.m)
+}
+
+// Attempts to apply the given function to every item in exactly one of this
+// PersistentHashSet and the given one, in no particular order, with true if
+// it is in this one. Skips structure both share. Stops once all items have
+// been processed, or once the function returns false, whichever occurs
+// first.
+func (s *PersistentHashSet) Diff(o *PersistentHashSet, f func(item interface{}, inS bool) bool) bool {
+	return s.m.Diff(&o.m, func(item, before, after interface{}) bool {
+		return f(item, before != nil)
+	})
+}
+
+// Returns a TransientHashSet containing the same items as this
+// PersistentHashSet, which is left untouched.
+func (s *PersistentHashSet) Transient() *TransientHashSet {
+	return &TransientHashSet{s.m.Transient()}
+}
+
+// Attempts to apply the given function to every item in this
+// PersistentHashSet, in no particular order. Stops once all items have been
+// processed, or once the function returns false, whichever occurs first.
+func (s *PersistentHashSet) Map(f func(item interface{}) bool) bool {
+	return s.m.Map(func(kv interface{}) bool {
+		return f(kv.(*dictionary.KeyValue).Key)
+	})
+}
+
+// Returns a slice of all the items in this PersistentHashSet in no particular
+// order.
+func (s *PersistentHashSet) Slice() *[]interface{} {
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(item interface{}) bool {
+		slice = append(slice, item)
+		return true
+	})
+	return &slice
+}
+
+func (s *PersistentHashSet) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// A TransientHashSet is a mutable view of a PersistentHashSet, for making many
+// updates at once.
+//
+// A TransientHashSet is not thread-safe, and may not be used after
+// Persistent() is called on it.
+//
+type TransientHashSet struct {
+	m *dictionary.TransientHashMap
+}
+
+// Returns a pointer to a new TransientHashSet containing the given items.
+func NewTransientHashSet(items ...interface{}) *TransientHashSet {
+	s := &TransientHashSet{dictionary.NewTransientHashMap()}
+	s.Insert(items...)
+	return s
+}
+
+// Adds the given items to this TransientHashSet.
+//
+// Panics if any given item is nil.
+func (s *TransientHashSet) Insert(items ...interface{}) {
+	for _, item := range items {
+		s.m.Insert(item, true)
+	}
+}
+
+// Removes the given items from this TransientHashSet.
+//
+// Panics if any given item is nil.
+func (s *TransientHashSet) Remove(items ...interface{}) {
+	for _, item := range items {
+		s.m.Remove(item)
+	}
+}
+
+// Returns true if this TransientHashSet contains all the given items, false
+// otherwise.
+func (s *TransientHashSet) Contains(items ...interface{}) bool {
+	if len(items) == 0 {
+		return false
+	}
+
+	for _, item := range items {
+		if s.m.Locate(item) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the number of items in this TransientHashSet.
+func (s *TransientHashSet) Size() int {
+	return s.m.Size()
+}
+
+// Returns a PersistentHashSet containing the items of this TransientHashSet,
+// which may not be used afterwards.
+func (s *TransientHashSet) Persistent() *PersistentHashSet {
+	return &PersistentHashSet{*s.m.Persistent()}
+}
@@ -0,0 +1,126 @@
+// This module contains tests for persistenthashset.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestNewEmptyPersistentHashSet(t *testing.T) {
+	s := NewPersistentHashSet()
+
+	if s.Size() != 0 || !s.Empty() || s.Contains("a") {
+		t.Error("NewPersistentHashSet with 0 args does not create an empty set!")
+	}
+
+	var zero PersistentHashSet
+	if !zero.Insert("a").Contains("a") {
+		t.Error("The zero PersistentHashSet is not usable!")
+	}
+}
+
+func TestVersionsPersistentHashSet(t *testing.T) {
+	s0 := NewPersistentHashSet("a", "b")
+	s1 := s0.Insert("c")
+	s2 := s1.Remove("a", "z")
+	s3 := s2.Insert("d", "e", "d")
+
+	if sortedItems(s0) != "[a b]" || sortedItems(s1) != "[a b c]" || sortedItems(s2) != "[b c]" ||
+		sortedItems(s3) != "[b c d e]" {
+		t.Errorf("Versions are wrong: %v %v %v %v", s0, s1, s2, s3)
+	}
+	if s3.Size() != 4 || !s3.Contains("b", "e") || s3.Contains("a") {
+		t.Error("Size or Contains is wrong!")
+	}
+}
+
+func TestPointerItemsPersistentHashSet(t *testing.T) {
+	type box struct{ N int }
+	a, b := &box{1}, &box{1}
+	s := NewPersistentHashSet(a)
+
+	a.N = 2
+	if !s.Contains(a) {
+		t.Error("Changing a pointer item's target lost it!")
+	}
+	if s.Contains(b) || s.Insert(b).Size() != 2 {
+		t.Error("Pointers to equal values are the same item!")
+	}
+}
+
+func TestTransientPersistentHashSet(t *testing.T) {
+	s := NewPersistentHashSet(1, 2, 3)
+	tr := s.Transient()
+	tr.Insert(4, 5)
+	tr.Remove(1)
+
+	if tr.Size() != 4 || !tr.Contains(2, 5) || tr.Contains(1) {
+		t.Error("TransientHashSet is wrong!")
+	}
+	if p := tr.Persistent(); p.Size() != 4 || !p.Contains(2, 3, 4, 5) || p.Contains(1) {
+		t.Errorf("Persistent() is wrong: %v", p)
+	}
+	if s.Size() != 3 || !s.Contains(1) || s.Contains(4) {
+		t.Error("TransientHashSet modified the version it came from!")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Using a TransientHashSet after Persistent() did not panic!")
+		}
+	}()
+	tr.Insert(6)
+}
+
+func TestDiffEqualPersistentHashSet(t *testing.T) {
+	r := rand.New(rand.NewSource(45))
+	t0 := NewTransientHashSet()
+	for i := 0; i < 500; i++ {
+		t0.Insert(i)
+	}
+	s := t0.Persistent()
+
+	o := s
+	in, out := make(map[interface{}]bool), make(map[interface{}]bool)
+	for i := 0; i < 20; i++ {
+		x := r.Intn(600)
+		if r.Intn(2) == 0 {
+			o = o.Insert(x)
+		} else {
+			o = o.Remove(x)
+		}
+	}
+	s.Map(func(item interface{}) bool {
+		if !o.Contains(item) {
+			in[item] = true
+		}
+		return true
+	})
+	o.Map(func(item interface{}) bool {
+		if !s.Contains(item) {
+			out[item] = true
+		}
+		return true
+	})
+
+	s.Diff(o, func(item interface{}, inS bool) bool {
+		if inS && !in[item] || !inS && !out[item] {
+			t.Errorf("Diff reported %v, %v", item, inS)
+		}
+		delete(in, item)
+		delete(out, item)
+		return true
+	})
+	if len(in) != 0 || len(out) != 0 {
+		t.Errorf("Diff missed %v and %v", in, out)
+	}
+
+	if !s.Equal(NewPersistentHashSet(*s.Slice()...)) || s.Equal(s.Insert(-1)) {
+		t.Error("Equal is wrong!")
+	}
+}
@@ -0,0 +1,1148 @@
+// This module implements a RoaringBitmap, conforming to set.Interface.
+//
+// See Lemire et al., "Consistently faster and smaller compressed bitmaps with
+// Roaring", 2016, and https://github.com/RoaringBitmap/RoaringFormatSpec for
+// the portable serialization format.
+
+package set
+
+import (
+	"encoding/binary"
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"log"
+	"math/bits"
+	"sort"
+)
+
+// The kinds of container of a RoaringBitmap.
+const (
+	arrayContainer = iota
+	bitmapContainer
+	runContainer
+)
+
+const (
+	// The most items an array container holds. Past this, a bitmap
+	// container is smaller.
+	arrayMax = 4096
+
+	// The number of words in a bitmap container.
+	bitmapWords = 1 << 16 / 64
+
+	// The cookies beginning the portable format, without and with run
+	// containers.
+	serialCookieNoRuns = 12346
+	serialCookie       = 12347
+
+	// With run containers, offsets are only written for at least this many
+	// containers.
+	noOffsetThreshold = 4
+)
+
+// A run of consecutive items, first to last inclusive.
+type run struct {
+	first, last uint16
+}
+
+// A container holds the low 16 bits of the items sharing their high 16 bits,
+// as a sorted array, a bitmap, or sorted runs.
+type container struct {
+	kind   int
+	array  []uint16
+	bitmap []uint64
+	runs   []run
+	card   int
+}
+
+// A RoaringBitmap implements set.Interface, for uint32s, with the additional
+// guarantee of storing its items in ascending order.
+//
+// It splits its items by their high 16 bits into containers, each stored as
+// whichever of a sorted array, a bitmap or, after RunOptimize(), a list of
+// runs is smallest. So it stays compact for sparse, dense, and clustered
+// sets alike, and operations with another RoaringBitmap work a container,
+// and often a word, at a time.
+//
+// MarshalBinary() and UnmarshalBinary() use the portable Roaring format,
+// shared with the Java, C and Go Roaring libraries.
+//
+// Behavior unspecified if a RoaringBitmap is not created using
+// NewRoaringBitmap(), NewRoaringBitmapUnsafe() or if RoaringBitmap.Init() /
+// RoaringBitmap.InitUnsafe(), is not first called on a new &RoaringBitmap{}.
+//
+type RoaringBitmap struct {
+	collection.Base
+	keys       []uint16 // The high 16 bits of each container's items, ascending, non-nil.
+	containers []*container
+}
+
+// Returns a pointer to a new RoaringBitmap containing the given uint32s.
+//
+// Panics if any of the given items is not a uint32.
+func NewRoaringBitmap(items ...interface{}) *RoaringBitmap {
+	s := &RoaringBitmap{}
+	s.Init()
+	s.Insert(items...)
+	return s
+}
+
+// Returns a pointer to a new unsafe RoaringBitmap containing the given
+// uint32s.
+//
+// Panics if any of the given items is not a uint32.
+func NewRoaringBitmapUnsafe(items ...interface{}) *RoaringBitmap {
+	s := &RoaringBitmap{}
+	s.InitUnsafe()
+	s.Insert(items...)
+	return s
+}
+
+func (s *RoaringBitmap) Init() {
+	s.InitBase()
+
+	s.keys, s.containers = []uint16{}, nil
+}
+
+func (s *RoaringBitmap) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.keys, s.containers = []uint16{}, nil
+}
+
+// Panics if any of the given items is not a uint32.
+func (s *RoaringBitmap) Insert(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		x := uint32Item(item)
+		i, ok := s.find(uint16(x >> 16))
+		if !ok {
+			s.keys = append(s.keys, 0)
+			copy(s.keys[i+1:], s.keys[i:])
+			s.keys[i] = uint16(x >> 16)
+			s.containers = append(s.containers, nil)
+			copy(s.containers[i+1:], s.containers[i:])
+			s.containers[i] = &container{kind: arrayContainer}
+		}
+		if s.containers[i].add(uint16(x)) {
+			s.Sizeb += 1
+		}
+	}
+}
+
+// Panics if any of the given items is not a uint32.
+func (s *RoaringBitmap) Remove(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		x := uint32Item(item)
+		i, ok := s.find(uint16(x >> 16))
+		if !ok || !s.containers[i].remove(uint16(x)) {
+			continue
+		}
+		s.Sizeb -= 1
+		if s.containers[i].card == 0 {
+			s.keys = append(s.keys[:i], s.keys[i+1:]...)
+			s.containers = append(s.containers[:i], s.containers[i+1:]...)
+		}
+	}
+}
+
+// Returns false if any of the given items is not a uint32.
+func (s *RoaringBitmap) Contains(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		x, ok := item.(uint32)
+		if !ok {
+			return false
+		}
+		i, ok := s.find(uint16(x >> 16))
+		if !ok || !s.containers[i].contains(uint16(x)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a pointer to a new RoaringBitmap containing all the items in either
+// this RoaringBitmap or the given RoaringBitmap.
+func (s *RoaringBitmap) Or(o *RoaringBitmap) *RoaringBitmap {
+	return s.combine(o, true, true, or)
+}
+
+// Returns a pointer to a new RoaringBitmap containing all the items in both
+// this RoaringBitmap and the given RoaringBitmap.
+func (s *RoaringBitmap) And(o *RoaringBitmap) *RoaringBitmap {
+	return s.combine(o, false, false, and)
+}
+
+// Returns a pointer to a new RoaringBitmap containing all the items in this
+// RoaringBitmap that are not in the given RoaringBitmap.
+func (s *RoaringBitmap) AndNot(o *RoaringBitmap) *RoaringBitmap {
+	return s.combine(o, true, false, andNot)
+}
+
+// Returns a pointer to a new RoaringBitmap containing all the items in
+// exactly one of this RoaringBitmap and the given RoaringBitmap.
+func (s *RoaringBitmap) Xor(o *RoaringBitmap) *RoaringBitmap {
+	return s.combine(o, true, true, xor)
+}
+
+// Converts each container to a list of runs, if that is smaller. Worthwhile
+// before serializing a RoaringBitmap holding long runs of consecutive items.
+func (s *RoaringBitmap) RunOptimize() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, c := range s.containers {
+		if c.kind == runContainer {
+			continue
+		}
+		runs := c.toRuns()
+		if 2+4*len(runs) < c.plainBytes() {
+			c.kind, c.array, c.bitmap, c.runs = runContainer, nil, nil, runs
+		}
+	}
+}
+
+// Returns a pointer to a new Set containing all the items in either this
+// Set or the given Set. The result is a RoaringBitmap.
+//
+// Panics if the given Set holds an item that is not a uint32.
+func (s *RoaringBitmap) Union(o Set) Set {
+	s.CheckInit()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		return s.Or(ob)
+	}
+
+	result := s.copyRoaring()
+	o.Map(func(item interface{}) bool {
+		result.Insert(item)
+		return true
+	})
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in both this Set
+// and the given Set. The result is a RoaringBitmap.
+func (s *RoaringBitmap) Intersection(o Set) Set {
+	s.CheckInit()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		return s.And(ob)
+	}
+	return s.filter(o, true)
+}
+
+// Returns a pointer to a new Set containing all the items in this Set that
+// are not in the given Set. The result is a RoaringBitmap.
+func (s *RoaringBitmap) Difference(o Set) Set {
+	s.CheckInit()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		return s.AndNot(ob)
+	}
+	return s.filter(o, false)
+}
+
+// Returns a pointer to a new Set containing all the items in exactly one of
+// this Set and the given Set. The result is a RoaringBitmap.
+//
+// Panics if the given Set holds an item that is not a uint32.
+func (s *RoaringBitmap) SymmetricDifference(o Set) Set {
+	s.CheckInit()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		return s.Xor(ob)
+	}
+	return symmetricDifference(s, o)
+}
+
+// Returns true if this Set and the given Set have no items in common, false
+// otherwise.
+func (s *RoaringBitmap) IsDisjoint(o Set) bool {
+	s.CheckInit()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		return s.And(ob).Empty()
+	}
+	return isDisjoint(s, o)
+}
+
+// Adds all the items in the given Set to this RoaringBitmap.
+//
+// Panics if the given Set holds an item that is not a uint32.
+func (s *RoaringBitmap) UnionWith(o Set) {
+	s.CheckInit()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		s.combineWith(ob, true, true, or)
+		return
+	}
+	s.Insert(*o.Slice()...)
+}
+
+// Removes all the items not in the given Set from this RoaringBitmap.
+func (s *RoaringBitmap) IntersectWith(o Set) {
+	s.CheckInit()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		s.combineWith(ob, false, false, and)
+		return
+	}
+	intersectWith(s, o)
+}
+
+// Removes all the items in the given Set from this RoaringBitmap.
+func (s *RoaringBitmap) DifferenceWith(o Set) {
+	s.CheckInit()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		s.combineWith(ob, true, false, andNot)
+		return
+	}
+
+	// Only items that might be in this RoaringBitmap matter.
+	var remove []interface{}
+	o.Map(func(item interface{}) bool {
+		if _, ok := item.(uint32); ok {
+			remove = append(remove, item)
+		}
+		return true
+	})
+	s.Remove(remove...)
+}
+
+// Returns true if this Set and the given Set contain exactly the same items,
+// false otherwise.
+func (s *RoaringBitmap) Equal(o Set) bool {
+	s.CheckInit()
+
+	if s.Size() != o.Size() {
+		return false
+	}
+	if ob, ok := o.(*RoaringBitmap); ok {
+		return s.Xor(ob).Empty()
+	}
+
+	equal := true
+	o.Map(func(item interface{}) bool {
+		equal = s.Contains(item)
+		return equal
+	})
+	return equal
+}
+
+// The first bool returned is true if this Set is a subset of the given Set,
+// false otherwise. If the first returned bool is true, then the second bool
+// will be false if these two sets are equal, true otherwise.
+//
+// true, true -> s is a proper subset of o
+// true, false -> s is equal to o
+// false, true -> s is not a subset of o
+// false, false -> s is not a subset of o
+func (s *RoaringBitmap) Subset(o Set) (subset bool, proper bool) {
+	s.CheckInit()
+
+	proper = s.Size() != o.Size()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		return s.AndNot(ob).Empty(), proper
+	}
+
+	subset = true
+	s.Map(func(item interface{}) bool {
+		subset = o.Contains(item)
+		return subset
+	})
+	return
+}
+
+// The first bool returned is true if this Set is a superset of the given
+// Set, false otherwise. If the first returned bool is true, then the
+// second bool will be false if these two sets are equal, true otherwise.
+//
+// true, true -> s is a proper superset of o
+// true, false -> s is equal to o
+// false, true -> s is not a superset of o
+// false, false -> s is not a superset of o
+func (s *RoaringBitmap) Superset(o Set) (superset bool, proper bool) {
+	s.CheckInit()
+
+	proper = s.Size() != o.Size()
+
+	if ob, ok := o.(*RoaringBitmap); ok {
+		return ob.AndNot(s).Empty(), proper
+	}
+
+	superset = true
+	o.Map(func(item interface{}) bool {
+		superset = s.Contains(item)
+		return superset
+	})
+	return
+}
+
+// Returns a pointer to a new Set that is a copy of this Set.
+func (s *RoaringBitmap) Copy() Set {
+	return s.copyRoaring()
+}
+
+// Attempts to apply the given function to every item in this Set, in
+// ascending order. Stops once all elements have been processed, or once
+// the function returns false, whichever occurs first.
+func (s *RoaringBitmap) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for i, c := range s.containers {
+		if !c.iterate(uint32(s.keys[i])<<16, func(x uint32) bool { return f(x) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns a slice of all the items in this Set, in ascending order.
+func (s *RoaringBitmap) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	for i, c := range s.containers {
+		c.iterate(uint32(s.keys[i])<<16, func(x uint32) bool {
+			slice = append(slice, x)
+			return true
+		})
+	}
+	return &slice
+}
+
+// Removes all items from this Set.
+func (s *RoaringBitmap) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.keys, s.containers = []uint16{}, nil
+	s.Sizeb = 0
+}
+
+func (s *RoaringBitmap) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Implements encoding.BinaryMarshaler, using the portable Roaring format.
+func (s *RoaringBitmap) MarshalBinary() ([]byte, error) {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	n := len(s.containers)
+	hasRuns := false
+	for _, c := range s.containers {
+		hasRuns = hasRuns || c.kind == runContainer
+	}
+
+	header := 8
+	if hasRuns {
+		header = 4 + (n+7)/8
+	}
+	withOffsets := !hasRuns || n >= noOffsetThreshold
+	body := header + 4*n
+	if withOffsets {
+		body += 4 * n
+	}
+	size := body
+	for _, c := range s.containers {
+		size += c.serializedBytes()
+	}
+
+	data := make([]byte, size)
+	le := binary.LittleEndian
+	if hasRuns {
+		le.PutUint16(data, serialCookie)
+		le.PutUint16(data[2:], uint16(n-1))
+		for i, c := range s.containers {
+			if c.kind == runContainer {
+				data[4+i/8] |= 1 << (i % 8)
+			}
+		}
+	} else {
+		le.PutUint32(data, serialCookieNoRuns)
+		le.PutUint32(data[4:], uint32(n))
+	}
+
+	for i, c := range s.containers {
+		le.PutUint16(data[header+4*i:], s.keys[i])
+		le.PutUint16(data[header+4*i+2:], uint16(c.card-1))
+	}
+
+	// The containers follow the offsets, if any, in order.
+	off := body
+	for i, c := range s.containers {
+		if withOffsets {
+			le.PutUint32(data[header+4*n+4*i:], uint32(off))
+		}
+		switch c.kind {
+		case arrayContainer:
+			for j, x := range c.array {
+				le.PutUint16(data[off+2*j:], x)
+			}
+		case bitmapContainer:
+			for j, w := range c.bitmap {
+				le.PutUint64(data[off+8*j:], w)
+			}
+		case runContainer:
+			le.PutUint16(data[off:], uint16(len(c.runs)))
+			for j, r := range c.runs {
+				le.PutUint16(data[off+2+4*j:], r.first)
+				le.PutUint16(data[off+4+4*j:], r.last-r.first)
+			}
+		}
+		off += c.serializedBytes()
+	}
+	return data, nil
+}
+
+// Implements encoding.BinaryUnmarshaler, replacing the contents of this
+// RoaringBitmap with those encoded in the given bytes, in the portable
+// Roaring format. A new &RoaringBitmap{} is initialized by this, and will
+// be thread-safe.
+//
+// Returns ErrInvalidEncoding if the given bytes aren't a valid RoaringBitmap,
+// in which case this RoaringBitmap is left unchanged.
+func (s *RoaringBitmap) UnmarshalBinary(data []byte) error {
+	keys, containers, size, err := decodeRoaring(data)
+	if err != nil {
+		return err
+	}
+
+	// A RoaringBitmap can't have been initialized without setting keys.
+	if s.keys == nil {
+		s.InitBase()
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.keys, s.containers, s.Sizeb = keys, containers, size
+	return nil
+}
+
+// Returns the index of the container for the given high bits, or where it
+// would be inserted, and whether it exists.
+func (s *RoaringBitmap) find(key uint16) (int, bool) {
+	i := sort.Search(len(s.keys), func(i int) bool { return s.keys[i] >= key })
+	return i, i < len(s.keys) && s.keys[i] == key
+}
+
+// Returns a new RoaringBitmap, as thread-safe as this one, with the same items.
+func (s *RoaringBitmap) copyRoaring() *RoaringBitmap {
+	s.CheckInit()
+
+	c := &RoaringBitmap{}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.keys = append([]uint16{}, s.keys...)
+	c.containers = make([]*container, len(s.containers))
+	for i, sc := range s.containers {
+		c.containers[i] = sc.clone()
+	}
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Returns a new RoaringBitmap combining this one and the given one, a
+// container at a time, with the given function. Containers only in this
+// one, or only in the given one, are kept if the corresponding flag is set.
+func (s *RoaringBitmap) combine(o *RoaringBitmap, keepS bool, keepO bool, f func(a *container, b *container) *container) *RoaringBitmap {
+	s.CheckInit()
+
+	// Snapshot o first, so the two are never locked at once.
+	o = o.copyRoaring()
+
+	result := &RoaringBitmap{}
+	if s.Threadsafe() {
+		result.Init()
+	} else {
+		result.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	result.keys, result.containers, result.Sizeb = s.merge(o, keepS, keepO, f)
+	return result
+}
+
+// Like combine(), but replaces the contents of this RoaringBitmap with the
+// result.
+func (s *RoaringBitmap) combineWith(o *RoaringBitmap, keepS bool, keepO bool, f func(a *container, b *container) *container) {
+	s.CheckInit()
+
+	o = o.copyRoaring()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.keys, s.containers, s.Sizeb = s.merge(o, keepS, keepO, f)
+}
+
+// Returns the containers, and their keys and total size, combining this
+// RoaringBitmap and the given one as combine() describes. The given one's
+// containers are reused, so it must not be shared.
+func (s *RoaringBitmap) merge(o *RoaringBitmap, keepS bool, keepO bool, f func(a *container, b *container) *container) (keys []uint16, containers []*container, size int) {
+	keys = []uint16{}
+	add := func(key uint16, c *container) {
+		if c != nil && c.card > 0 {
+			keys = append(keys, key)
+			containers = append(containers, c)
+			size += c.card
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(s.keys) || j < len(o.keys) {
+		switch {
+		case j == len(o.keys) || (i < len(s.keys) && s.keys[i] < o.keys[j]):
+			if keepS {
+				add(s.keys[i], s.containers[i].clone())
+			}
+			i += 1
+		case i == len(s.keys) || o.keys[j] < s.keys[i]:
+			if keepO {
+				add(o.keys[j], o.containers[j])
+			}
+			j += 1
+		default:
+			add(s.keys[i], f(s.containers[i].plain(), o.containers[j].plain()))
+			i += 1
+			j += 1
+		}
+	}
+	return keys, containers, size
+}
+
+// Returns a new RoaringBitmap, with the items of this one that are (keep) or
+// are not (!keep) in the given Set.
+func (s *RoaringBitmap) filter(o Set, keep bool) *RoaringBitmap {
+	result := &RoaringBitmap{}
+	if s.Threadsafe() {
+		result.Init()
+	} else {
+		result.InitUnsafe()
+	}
+
+	s.Map(func(item interface{}) bool {
+		if o.Contains(item) == keep {
+			result.Insert(item)
+		}
+		return true
+	})
+	return result
+}
+
+// Decodes the portable Roaring format.
+func decodeRoaring(data []byte) (keys []uint16, containers []*container, size int, err error) {
+	le := binary.LittleEndian
+	if len(data) < 4 {
+		return nil, nil, 0, ErrInvalidEncoding
+	}
+
+	var n int
+	var runFlags []byte
+	pos := 0
+	cookie := le.Uint32(data)
+	switch {
+	case cookie == serialCookieNoRuns:
+		if len(data) < 8 {
+			return nil, nil, 0, ErrInvalidEncoding
+		}
+		n = int(le.Uint32(data[4:]))
+		pos = 8
+	case cookie&0xFFFF == serialCookie:
+		n = int(cookie>>16) + 1
+		pos = 4 + (n+7)/8
+		if len(data) < pos {
+			return nil, nil, 0, ErrInvalidEncoding
+		}
+		runFlags = data[4:pos]
+	default:
+		return nil, nil, 0, ErrInvalidEncoding
+	}
+	if n > 1<<16 || len(data)-pos < 4*n {
+		return nil, nil, 0, ErrInvalidEncoding
+	}
+
+	keys = make([]uint16, n)
+	cards := make([]int, n)
+	for i := 0; i < n; i++ {
+		keys[i] = le.Uint16(data[pos+4*i:])
+		cards[i] = int(le.Uint16(data[pos+4*i+2:])) + 1
+		if i > 0 && keys[i] <= keys[i-1] {
+			return nil, nil, 0, ErrInvalidEncoding
+		}
+	}
+	pos += 4 * n
+
+	var offsets []byte
+	if runFlags == nil || n >= noOffsetThreshold {
+		if len(data)-pos < 4*n {
+			return nil, nil, 0, ErrInvalidEncoding
+		}
+		offsets = data[pos : pos+4*n]
+		pos += 4 * n
+	}
+
+	containers = make([]*container, n)
+	for i := 0; i < n; i++ {
+		if offsets != nil && int(le.Uint32(offsets[4*i:])) != pos {
+			return nil, nil, 0, ErrInvalidEncoding
+		}
+
+		c := &container{card: cards[i]}
+		switch {
+		case runFlags != nil && runFlags[i/8]&(1<<(i%8)) != 0:
+			c.kind = runContainer
+			if len(data)-pos < 2 {
+				return nil, nil, 0, ErrInvalidEncoding
+			}
+			nruns := int(le.Uint16(data[pos:]))
+			pos += 2
+			if len(data)-pos < 4*nruns {
+				return nil, nil, 0, ErrInvalidEncoding
+			}
+			card := 0
+			c.runs = make([]run, nruns)
+			for r := range c.runs {
+				first, length := le.Uint16(data[pos:]), le.Uint16(data[pos+2:])
+				pos += 4
+				if int(first)+int(length) > 0xFFFF || (r > 0 && int(first) <= int(c.runs[r-1].last)+1) {
+					return nil, nil, 0, ErrInvalidEncoding
+				}
+				c.runs[r] = run{first, first + length}
+				card += int(length) + 1
+			}
+			if card != c.card {
+				return nil, nil, 0, ErrInvalidEncoding
+			}
+		case c.card <= arrayMax:
+			c.kind = arrayContainer
+			if len(data)-pos < 2*c.card {
+				return nil, nil, 0, ErrInvalidEncoding
+			}
+			c.array = make([]uint16, c.card)
+			for k := range c.array {
+				c.array[k] = le.Uint16(data[pos+2*k:])
+				if k > 0 && c.array[k] <= c.array[k-1] {
+					return nil, nil, 0, ErrInvalidEncoding
+				}
+			}
+			pos += 2 * c.card
+		default:
+			c.kind = bitmapContainer
+			if len(data)-pos < 8*bitmapWords {
+				return nil, nil, 0, ErrInvalidEncoding
+			}
+			c.bitmap = make([]uint64, bitmapWords)
+			for k := range c.bitmap {
+				c.bitmap[k] = le.Uint64(data[pos+8*k:])
+			}
+			pos += 8 * bitmapWords
+			if popcount(c.bitmap) != c.card {
+				return nil, nil, 0, ErrInvalidEncoding
+			}
+		}
+		containers[i] = c
+		size += c.card
+	}
+
+	if pos != len(data) {
+		return nil, nil, 0, ErrInvalidEncoding
+	}
+	return keys, containers, size, nil
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// container operations.
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+func (c *container) contains(x uint16) bool {
+	switch c.kind {
+	case arrayContainer:
+		i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= x })
+		return i < len(c.array) && c.array[i] == x
+	case bitmapContainer:
+		return c.bitmap[x/64]&(1<<(x%64)) != 0
+	default:
+		i := sort.Search(len(c.runs), func(i int) bool { return c.runs[i].last >= x })
+		return i < len(c.runs) && c.runs[i].first <= x
+	}
+}
+
+// Adds x to this container. Returns false if it was already there.
+func (c *container) add(x uint16) bool {
+	if c.kind == runContainer {
+		if c.contains(x) {
+			return false
+		}
+		*c = *c.plain()
+	}
+
+	if c.kind == bitmapContainer {
+		if c.bitmap[x/64]&(1<<(x%64)) != 0 {
+			return false
+		}
+		c.bitmap[x/64] |= 1 << (x % 64)
+		c.card += 1
+		return true
+	}
+
+	i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= x })
+	if i < len(c.array) && c.array[i] == x {
+		return false
+	}
+	c.array = append(c.array, 0)
+	copy(c.array[i+1:], c.array[i:])
+	c.array[i] = x
+	c.card += 1
+	c.normalize()
+	return true
+}
+
+// Removes x from this container. Returns false if it wasn't there.
+func (c *container) remove(x uint16) bool {
+	if !c.contains(x) {
+		return false
+	}
+	if c.kind == runContainer {
+		*c = *c.plain()
+	}
+
+	if c.kind == bitmapContainer {
+		c.bitmap[x/64] &^= 1 << (x % 64)
+	} else {
+		i := sort.Search(len(c.array), func(i int) bool { return c.array[i] >= x })
+		c.array = append(c.array[:i], c.array[i+1:]...)
+	}
+	c.card -= 1
+	c.normalize()
+	return true
+}
+
+// Applies the given function to every item in this container, with the
+// given high bits, in ascending order, stopping if it returns false. Returns
+// false if it did.
+func (c *container) iterate(high uint32, f func(x uint32) bool) bool {
+	switch c.kind {
+	case arrayContainer:
+		for _, x := range c.array {
+			if !f(high | uint32(x)) {
+				return false
+			}
+		}
+	case bitmapContainer:
+		for i, w := range c.bitmap {
+			for w != 0 {
+				if !f(high | uint32(64*i+bits.TrailingZeros64(w))) {
+					return false
+				}
+				w &= w - 1
+			}
+		}
+	default:
+		for _, r := range c.runs {
+			for x := uint32(r.first); x <= uint32(r.last); x++ {
+				if !f(high | x) {
+					return false
+				}
+			}
+		}
+	}
+	return true
+}
+
+func (c *container) clone() *container {
+	d := &container{kind: c.kind, card: c.card}
+	d.array = append([]uint16(nil), c.array...)
+	d.bitmap = append([]uint64(nil), c.bitmap...)
+	d.runs = append([]run(nil), c.runs...)
+	return d
+}
+
+// Returns this container if it is an array or bitmap, or else a new one
+// holding its items as whichever is smaller.
+func (c *container) plain() *container {
+	if c.kind != runContainer {
+		return c
+	}
+
+	d := &container{kind: arrayContainer, card: c.card}
+	if c.card > arrayMax {
+		d.kind = bitmapContainer
+		d.bitmap = make([]uint64, bitmapWords)
+	} else {
+		d.array = make([]uint16, 0, c.card)
+	}
+	c.iterate(0, func(x uint32) bool {
+		if d.kind == bitmapContainer {
+			d.bitmap[x/64] |= 1 << (x % 64)
+		} else {
+			d.array = append(d.array, uint16(x))
+		}
+		return true
+	})
+	return d
+}
+
+// Converts an array or bitmap container to whichever is smaller for its
+// cardinality.
+func (c *container) normalize() {
+	switch {
+	case c.kind == arrayContainer && c.card > arrayMax:
+		c.bitmap = make([]uint64, bitmapWords)
+		for _, x := range c.array {
+			c.bitmap[x/64] |= 1 << (x % 64)
+		}
+		c.kind, c.array = bitmapContainer, nil
+	case c.kind == bitmapContainer && c.card <= arrayMax:
+		c.array = make([]uint16, 0, c.card)
+		c.iterate(0, func(x uint32) bool {
+			c.array = append(c.array, uint16(x))
+			return true
+		})
+		c.kind, c.bitmap = arrayContainer, nil
+	}
+}
+
+// Returns the runs of this array or bitmap container's items.
+func (c *container) toRuns() []run {
+	var runs []run
+	c.iterate(0, func(x uint32) bool {
+		if n := len(runs); n > 0 && uint32(runs[n-1].last)+1 == x {
+			runs[n-1].last = uint16(x)
+		} else {
+			runs = append(runs, run{uint16(x), uint16(x)})
+		}
+		return true
+	})
+	return runs
+}
+
+// Returns the serialized size of this container, as an array or bitmap.
+func (c *container) plainBytes() int {
+	if c.card <= arrayMax {
+		return 2 * c.card
+	}
+	return 8 * bitmapWords
+}
+
+// Returns the serialized size of this container.
+func (c *container) serializedBytes() int {
+	if c.kind == runContainer {
+		return 2 + 4*len(c.runs)
+	}
+	return c.plainBytes()
+}
+
+// Returns a new bitmap container with the given words.
+func newBitmapContainer(words []uint64) *container {
+	c := &container{kind: bitmapContainer, bitmap: words, card: popcount(words)}
+	c.normalize()
+	return c
+}
+
+// Returns the bitmap of the given array or bitmap container, copied.
+func bitmapOf(c *container) []uint64 {
+	if c.kind == bitmapContainer {
+		return append([]uint64(nil), c.bitmap...)
+	}
+	words := make([]uint64, bitmapWords)
+	for _, x := range c.array {
+		words[x/64] |= 1 << (x % 64)
+	}
+	return words
+}
+
+// Merges two array containers, keeping the items in a only (inA), in both
+// (inBoth) and in b only (inB).
+func mergeArrays(a *container, b *container, inA bool, inBoth bool, inB bool) *container {
+	result := make([]uint16, 0, len(a.array)+len(b.array))
+	i, j := 0, 0
+	for i < len(a.array) || j < len(b.array) {
+		switch {
+		case j == len(b.array) || (i < len(a.array) && a.array[i] < b.array[j]):
+			if inA {
+				result = append(result, a.array[i])
+			}
+			i += 1
+		case i == len(a.array) || b.array[j] < a.array[i]:
+			if inB {
+				result = append(result, b.array[j])
+			}
+			j += 1
+		default:
+			if inBoth {
+				result = append(result, a.array[i])
+			}
+			i += 1
+			j += 1
+		}
+	}
+	c := &container{kind: arrayContainer, array: result, card: len(result)}
+	c.normalize()
+	return c
+}
+
+// Keeps the items of array container a that are (keep) or are not (!keep)
+// in bitmap container b.
+func filterArray(a *container, b *container, keep bool) *container {
+	result := make([]uint16, 0, len(a.array))
+	for _, x := range a.array {
+		if (b.bitmap[x/64]&(1<<(x%64)) != 0) == keep {
+			result = append(result, x)
+		}
+	}
+	return &container{kind: arrayContainer, array: result, card: len(result)}
+}
+
+func or(a *container, b *container) *container {
+	if a.kind == arrayContainer && b.kind == arrayContainer {
+		return mergeArrays(a, b, true, true, true)
+	}
+	words := bitmapOf(a)
+	if b.kind == bitmapContainer {
+		for i, w := range b.bitmap {
+			words[i] |= w
+		}
+	} else {
+		for _, x := range b.array {
+			words[x/64] |= 1 << (x % 64)
+		}
+	}
+	return newBitmapContainer(words)
+}
+
+func and(a *container, b *container) *container {
+	switch {
+	case a.kind == arrayContainer && b.kind == arrayContainer:
+		return mergeArrays(a, b, false, true, false)
+	case a.kind == arrayContainer:
+		return filterArray(a, b, true)
+	case b.kind == arrayContainer:
+		return filterArray(b, a, true)
+	}
+	words := make([]uint64, bitmapWords)
+	for i := range words {
+		words[i] = a.bitmap[i] & b.bitmap[i]
+	}
+	return newBitmapContainer(words)
+}
+
+func andNot(a *container, b *container) *container {
+	switch {
+	case a.kind == arrayContainer && b.kind == arrayContainer:
+		return mergeArrays(a, b, true, false, false)
+	case a.kind == arrayContainer:
+		return filterArray(a, b, false)
+	}
+	words := bitmapOf(a)
+	if b.kind == bitmapContainer {
+		for i, w := range b.bitmap {
+			words[i] &^= w
+		}
+	} else {
+		for _, x := range b.array {
+			words[x/64] &^= 1 << (x % 64)
+		}
+	}
+	return newBitmapContainer(words)
+}
+
+func xor(a *container, b *container) *container {
+	if a.kind == arrayContainer && b.kind == arrayContainer {
+		return mergeArrays(a, b, true, false, true)
+	}
+	if a.kind == arrayContainer {
+		a, b = b, a
+	}
+	words := bitmapOf(a)
+	if b.kind == bitmapContainer {
+		for i, w := range b.bitmap {
+			words[i] ^= w
+		}
+	} else {
+		for _, x := range b.array {
+			words[x/64] ^= 1 << (x % 64)
+		}
+	}
+	return newBitmapContainer(words)
+}
+
+// Returns the given item as a uint32.
+//
+// Panics if the given item is not a uint32.
+func uint32Item(item interface{}) uint32 {
+	x, ok := item.(uint32)
+	if !ok {
+		log.Panicf("Item %v is not a uint32.", item)
+	}
+	return x
+}
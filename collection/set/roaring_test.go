@@ -0,0 +1,509 @@
+// This module contains tests for roaring.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"bytes"
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Returns the given uint32s as items.
+func roaringItems(xs ...uint32) []interface{} {
+	items := make([]interface{}, len(xs))
+	for i, x := range xs {
+		items[i] = x
+	}
+	return items
+}
+
+// Returns the uint32s from first to last inclusive, in steps of step.
+func roaringRange(first uint32, last uint32, step uint32) []interface{} {
+	var items []interface{}
+	for x := first; x <= last; x += step {
+		items = append(items, x)
+	}
+	return items
+}
+
+// Checks that the given RoaringBitmap holds exactly the given items, and that
+// its containers are well formed.
+func checkRoaring(t *testing.T, s *RoaringBitmap, expected map[uint32]bool) {
+	want := make([]uint32, 0, len(expected))
+	for x := range expected {
+		want = append(want, x)
+	}
+	sort.Slice(want, func(i, j int) bool { return want[i] < want[j] })
+
+	got := *s.Slice()
+	if len(got) != len(want) || s.Size() != len(want) {
+		t.Fatalf("Expected %d items, got %d with Size() %d", len(want), len(got), s.Size())
+	}
+	for i, x := range got {
+		if x.(uint32) != want[i] {
+			t.Fatalf("Item %d is %v, expected %d", i, x, want[i])
+		}
+	}
+
+	for i, c := range s.containers {
+		if c.card == 0 {
+			t.Errorf("Container %d is empty!", i)
+		}
+		if i > 0 && s.keys[i] <= s.keys[i-1] {
+			t.Errorf("Container keys not ascending at %d!", i)
+		}
+		switch c.kind {
+		case arrayContainer:
+			if len(c.array) != c.card || c.card > arrayMax {
+				t.Errorf("Array container %d has %d items, cardinality %d", i, len(c.array), c.card)
+			}
+		case bitmapContainer:
+			if popcount(c.bitmap) != c.card || c.card <= arrayMax {
+				t.Errorf("Bitmap container %d has %d items, cardinality %d", i, popcount(c.bitmap), c.card)
+			}
+		}
+	}
+}
+
+func TestNewEmptyRoaringBitmap(t *testing.T) {
+	s := NewRoaringBitmap()
+
+	if s.Size() != 0 || !s.Empty() {
+		t.Error("NewRoaringBitmap with 0 args does not create an empty set!")
+	}
+	if s.Contains(uint32(0)) {
+		t.Error("Empty set contains 0!")
+	}
+}
+
+func TestInsertRemoveContainsRoaringBitmap(t *testing.T) {
+	s := NewRoaringBitmap(roaringItems(1, 2, 3, 1<<16, 1<<31, 1<<32-1)...)
+
+	s.Insert(uint32(2), uint32(7))
+	s.Remove(uint32(1<<16), uint32(9))
+
+	if fmt.Sprint(*s.Slice()) != "[1 2 3 7 2147483648 4294967295]" || s.Size() != 6 {
+		t.Errorf("Wrong items: %v", *s.Slice())
+	}
+	if !s.Contains(uint32(1), uint32(1<<32-1)) || s.Contains(uint32(1<<16)) || s.Contains(1) {
+		t.Error("Contains is wrong!")
+	}
+	if len(s.containers) != 3 {
+		t.Errorf("Removing the only item of a container did not drop it: %d containers", len(s.containers))
+	}
+}
+
+func TestInvalidItemRoaringBitmap(t *testing.T) {
+	for _, item := range []interface{}{-1, "a", uint64(1), 1} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Inserting %v did not panic!", item)
+				}
+			}()
+			NewRoaringBitmap(item)
+		}()
+	}
+}
+
+func TestContainerConversionRoaringBitmap(t *testing.T) {
+	s := NewRoaringBitmap()
+	expected := make(map[uint32]bool)
+
+	for x := uint32(0); x <= 2*arrayMax; x += 2 {
+		s.Insert(x)
+		expected[x] = true
+	}
+	if s.containers[0].kind != bitmapContainer {
+		t.Error("Array container past arrayMax items did not become a bitmap!")
+	}
+	checkRoaring(t, s, expected)
+
+	for x := uint32(0); x <= 2*arrayMax; x += 4 {
+		s.Remove(x)
+		delete(expected, x)
+	}
+	if s.containers[0].kind != arrayContainer {
+		t.Error("Bitmap container with few items did not become an array!")
+	}
+	checkRoaring(t, s, expected)
+}
+
+func TestRunOptimizeRoaringBitmap(t *testing.T) {
+	s := NewRoaringBitmap(roaringRange(0, 9999, 1)...)
+	s.Insert(roaringItems(70000, 70002, 70004)...)
+	s.RunOptimize()
+
+	if s.containers[0].kind != runContainer || len(s.containers[0].runs) != 1 {
+		t.Error("Container of one long run was not converted to runs!")
+	}
+	if s.containers[1].kind != arrayContainer {
+		t.Error("Container without runs was converted to runs!")
+	}
+
+	expected := make(map[uint32]bool)
+	for _, item := range *s.Slice() {
+		expected[item.(uint32)] = true
+	}
+	if len(expected) != 10003 || !s.Contains(uint32(5000), uint32(70002)) || s.Contains(uint32(10000)) {
+		t.Error("RunOptimize changed the items!")
+	}
+
+	s.Insert(uint32(20000))
+	s.Remove(uint32(10))
+	expected[20000] = true
+	delete(expected, 10)
+	checkRoaring(t, s, expected)
+}
+
+func TestSetOperationsRoaringBitmap(t *testing.T) {
+	a := NewRoaringBitmap(roaringItems(1, 2, 3, 1<<20)...)
+	b := NewRoaringBitmap(roaringItems(2, 3, 4, 1<<30)...)
+
+	cases := []struct {
+		got      *RoaringBitmap
+		expected string
+	}{
+		{a.Or(b), "[1 2 3 4 1048576 1073741824]"},
+		{a.And(b), "[2 3]"},
+		{a.AndNot(b), "[1 1048576]"},
+		{b.AndNot(a), "[4 1073741824]"},
+		{a.Xor(b), "[1 4 1048576 1073741824]"},
+	}
+	for i, c := range cases {
+		if fmt.Sprint(*c.got.Slice()) != c.expected {
+			t.Errorf("Case %d: expected %s, got %v", i, c.expected, *c.got.Slice())
+		}
+	}
+	if fmt.Sprint(*a.Slice()) != "[1 2 3 1048576]" {
+		t.Errorf("Operations changed their receiver: %v", *a.Slice())
+	}
+}
+
+func TestRandomOperationsRoaringBitmap(t *testing.T) {
+	r := rand.New(rand.NewSource(37))
+
+	// Mixes sparse, dense, and run heavy containers.
+	random := func() (*RoaringBitmap, map[uint32]bool) {
+		s := NewRoaringBitmap()
+		m := make(map[uint32]bool)
+		for key := uint32(0); key < 6; key++ {
+			switch r.Intn(4) {
+			case 0:
+				for i := 0; i < 100; i++ {
+					m[key<<16|uint32(r.Intn(1<<16))] = true
+				}
+			case 1:
+				for i := 0; i < 6000; i++ {
+					m[key<<16|uint32(r.Intn(1<<16))] = true
+				}
+			case 2:
+				first := uint32(r.Intn(1 << 15))
+				for x := first; x < first+uint32(r.Intn(10000)); x++ {
+					m[key<<16|x] = true
+				}
+			}
+		}
+		for x := range m {
+			s.Insert(x)
+		}
+		if r.Intn(2) == 0 {
+			s.RunOptimize()
+		}
+		return s, m
+	}
+
+	for i := 0; i < 20; i++ {
+		a, am := random()
+		b, bm := random()
+
+		or, and, andNot, xor := make(map[uint32]bool), make(map[uint32]bool), make(map[uint32]bool), make(map[uint32]bool)
+		for x := range am {
+			or[x] = true
+			if bm[x] {
+				and[x] = true
+			} else {
+				andNot[x] = true
+				xor[x] = true
+			}
+		}
+		for x := range bm {
+			or[x] = true
+			if !am[x] {
+				xor[x] = true
+			}
+		}
+
+		checkRoaring(t, a.Or(b), or)
+		checkRoaring(t, a.And(b), and)
+		checkRoaring(t, a.AndNot(b), andNot)
+		checkRoaring(t, a.Xor(b), xor)
+		checkRoaring(t, a, am)
+
+		data, _ := a.MarshalBinary()
+		c := &RoaringBitmap{}
+		if err := c.UnmarshalBinary(data); err != nil {
+			t.Fatalf("Unmarshaling a marshaled RoaringBitmap failed: %v", err)
+		}
+		checkRoaring(t, c, am)
+	}
+}
+
+func TestOtherSetsRoaringBitmap(t *testing.T) {
+	s := NewRoaringBitmap(roaringItems(1, 2, 3)...)
+	o := NewHashSet(roaringItems(2, 3, 4)...)
+
+	if fmt.Sprint(*s.Union(o).Slice()) != "[1 2 3 4]" {
+		t.Errorf("Union with a HashSet is wrong: %v", *s.Union(o).Slice())
+	}
+	if fmt.Sprint(*s.Intersection(o).Slice()) != "[2 3]" {
+		t.Errorf("Intersection with a HashSet is wrong: %v", *s.Intersection(o).Slice())
+	}
+	if fmt.Sprint(*s.Difference(o).Slice()) != "[1]" {
+		t.Errorf("Difference with a HashSet is wrong: %v", *s.Difference(o).Slice())
+	}
+
+	if !s.Equal(NewHashSet(roaringItems(3, 2, 1)...)) || s.Equal(o) {
+		t.Error("Equal with a HashSet is wrong!")
+	}
+	if !s.Equal(NewRoaringBitmap(roaringItems(3, 2, 1)...)) || s.Equal(NewRoaringBitmap(roaringItems(1, 2, 4)...)) {
+		t.Error("Equal with a RoaringBitmap is wrong!")
+	}
+
+	sup := NewRoaringBitmap(roaringItems(1, 2, 3, 1<<20)...)
+	if sub, proper := s.Subset(sup); !sub || !proper {
+		t.Error("Subset with a RoaringBitmap is wrong!")
+	}
+	if sub, _ := sup.Subset(s); sub {
+		t.Error("Subset with a RoaringBitmap is wrong!")
+	}
+	if super, proper := sup.Superset(s); !super || !proper {
+		t.Error("Superset with a RoaringBitmap is wrong!")
+	}
+	if sub, proper := s.Subset(NewHashSet(roaringItems(1, 2, 3)...)); !sub || proper {
+		t.Error("Subset with a HashSet is wrong!")
+	}
+	if super, _ := s.Superset(o); super {
+		t.Error("Superset with a HashSet is wrong!")
+	}
+}
+
+func TestCopyClearRoaringBitmap(t *testing.T) {
+	s := NewRoaringBitmap(roaringRange(0, 10000, 1)...)
+	s.RunOptimize()
+	c := s.Copy().(*RoaringBitmap)
+
+	s.Insert(uint32(20000))
+	c.Remove(uint32(0))
+
+	if s.Size() != 10002 || c.Size() != 10000 || s.Contains(uint32(20000)) == c.Contains(uint32(20000)) {
+		t.Error("Copy shares state with the original!")
+	}
+
+	s.Clear()
+	if !s.Empty() || len(s.containers) != 0 || c.Empty() {
+		t.Error("Clear is wrong!")
+	}
+}
+
+// Fixtures hand-encoded from the Roaring format spec,
+// https://github.com/RoaringBitmap/RoaringFormatSpec.
+var roaringFixtures = []struct {
+	name     string
+	items    []interface{}
+	optimize bool
+	data     []byte
+}{
+	{"empty", nil, false, []byte{
+		0x3A, 0x30, 0x00, 0x00, // cookie, no runs
+		0x00, 0x00, 0x00, 0x00, // 0 containers
+	}},
+	{"array", roaringItems(1, 2, 3, 1000), false, []byte{
+		0x3A, 0x30, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00, // 1 container
+		0x00, 0x00, 0x03, 0x00, // key 0, 4 items
+		0x10, 0x00, 0x00, 0x00, // at 16
+		0x01, 0x00, 0x02, 0x00, 0x03, 0x00, 0xE8, 0x03,
+	}},
+	{"arrays", roaringItems(5, 1<<16|7), false, []byte{
+		0x3A, 0x30, 0x00, 0x00,
+		0x02, 0x00, 0x00, 0x00, // 2 containers
+		0x00, 0x00, 0x00, 0x00, // key 0, 1 item
+		0x01, 0x00, 0x00, 0x00, // key 1, 1 item
+		0x18, 0x00, 0x00, 0x00, // at 24
+		0x1A, 0x00, 0x00, 0x00, // at 26
+		0x05, 0x00,
+		0x07, 0x00,
+	}},
+	{"run", roaringRange(0, 99, 1), true, []byte{
+		0x3B, 0x30, 0x00, 0x00, // cookie, 1 container
+		0x01,                   // container 0 is runs
+		0x00, 0x00, 0x63, 0x00, // key 0, 100 items
+		0x01, 0x00, // 1 run
+		0x00, 0x00, 0x63, 0x00, // from 0, 100 long
+	}},
+	{"full run", roaringRange(0, 1<<16-1, 1), true, []byte{
+		0x3B, 0x30, 0x00, 0x00,
+		0x01,
+		0x00, 0x00, 0xFF, 0xFF, // key 0, 65536 items
+		0x01, 0x00,
+		0x00, 0x00, 0xFF, 0xFF,
+	}},
+	{"run and array", append(roaringRange(0, 99, 1), uint32(1<<16|5)), true, []byte{
+		0x3B, 0x30, 0x01, 0x00, // cookie, 2 containers
+		0x01,                   // only container 0 is runs
+		0x00, 0x00, 0x63, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00, 0x63, 0x00,
+		0x05, 0x00,
+	}},
+	{"runs with offsets", append(append(append(roaringRange(10, 19, 1), roaringRange(1<<16|10, 1<<16|19, 1)...),
+		roaringRange(2<<16|10, 2<<16|19, 1)...), roaringRange(3<<16|10, 3<<16|19, 1)...), true, []byte{
+		0x3B, 0x30, 0x03, 0x00, // cookie, 4 containers
+		0x0F,                   // all runs
+		0x00, 0x00, 0x09, 0x00,
+		0x01, 0x00, 0x09, 0x00,
+		0x02, 0x00, 0x09, 0x00,
+		0x03, 0x00, 0x09, 0x00,
+		0x25, 0x00, 0x00, 0x00, // at 37
+		0x2B, 0x00, 0x00, 0x00,
+		0x31, 0x00, 0x00, 0x00,
+		0x37, 0x00, 0x00, 0x00,
+		0x01, 0x00, 0x0A, 0x00, 0x09, 0x00,
+		0x01, 0x00, 0x0A, 0x00, 0x09, 0x00,
+		0x01, 0x00, 0x0A, 0x00, 0x09, 0x00,
+		0x01, 0x00, 0x0A, 0x00, 0x09, 0x00,
+	}},
+	{"bitmap", roaringRange(0, 1<<16-2, 2), false, append([]byte{
+		0x3A, 0x30, 0x00, 0x00,
+		0x01, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0xFF, 0x7F, // key 0, 32768 items
+		0x10, 0x00, 0x00, 0x00,
+	}, bytes.Repeat([]byte{0x55}, 8192)...)},
+}
+
+func TestMarshalFixturesRoaringBitmap(t *testing.T) {
+	for _, f := range roaringFixtures {
+		s := NewRoaringBitmap(f.items...)
+		if f.optimize {
+			s.RunOptimize()
+		}
+
+		data, err := s.MarshalBinary()
+		if err != nil || !bytes.Equal(data, f.data) {
+			t.Errorf("%s: expected % X, got % X (%v)", f.name, f.data, data, err)
+		}
+	}
+}
+
+func TestUnmarshalFixturesRoaringBitmap(t *testing.T) {
+	for _, f := range roaringFixtures {
+		s := &RoaringBitmap{}
+		if err := s.UnmarshalBinary(f.data); err != nil {
+			t.Errorf("%s: %v", f.name, err)
+			continue
+		}
+
+		expected := make(map[uint32]bool)
+		for _, item := range f.items {
+			expected[item.(uint32)] = true
+		}
+		checkRoaring(t, s, expected)
+
+		data, _ := s.MarshalBinary()
+		if !bytes.Equal(data, f.data) {
+			t.Errorf("%s: did not marshal back to the same bytes", f.name)
+		}
+	}
+}
+
+func TestUnmarshalInvalidRoaringBitmap(t *testing.T) {
+	valid := roaringFixtures[2].data
+	corrupt := func(i int, b byte) []byte {
+		data := append([]byte(nil), valid...)
+		data[i] = b
+		return data
+	}
+
+	cases := [][]byte{
+		nil,
+		valid[:3],
+		valid[:len(valid)-1],
+		append(append([]byte(nil), valid...), 0x00),
+		corrupt(0, 0x00),      // cookie
+		corrupt(12, 0x00),     // keys not ascending
+		corrupt(16, 0x19),     // offset
+		corrupt(10, 0x01),     // cardinality
+		roaringFixtures[3].data[:10],
+	}
+
+	s := NewRoaringBitmap(uint32(42))
+	for i, data := range cases {
+		if err := s.UnmarshalBinary(data); err != ErrInvalidEncoding {
+			t.Errorf("Case %d: expected ErrInvalidEncoding, got %v", i, err)
+		}
+	}
+	if fmt.Sprint(*s.Slice()) != "[42]" {
+		t.Errorf("Failed UnmarshalBinary changed the set: %v", *s.Slice())
+	}
+}
+
+func TestInPlaceOperationsRoaringBitmap(t *testing.T) {
+	a := NewRoaringBitmap(roaringRange(0, 9999, 1)...)
+	a.RunOptimize()
+	b := NewRoaringBitmap(roaringRange(5000, 70000, 2)...)
+
+	expected := make(map[uint32]bool)
+	for x := uint32(0); x < 10000; x++ {
+		expected[x] = true
+	}
+	for x := uint32(5000); x <= 70000; x += 2 {
+		expected[x] = !expected[x]
+		if !expected[x] {
+			delete(expected, x)
+		}
+	}
+	checkRoaring(t, a.SymmetricDifference(b).(*RoaringBitmap), expected)
+
+	if a.IsDisjoint(b) || !a.IsDisjoint(NewRoaringBitmap(uint32(10000))) {
+		t.Error("IsDisjoint with a RoaringBitmap is wrong!")
+	}
+	if a.IsDisjoint(NewHashSet(uint32(5), "a")) || !a.IsDisjoint(NewHashSet(5, "a")) {
+		t.Error("IsDisjoint with a HashSet is wrong!")
+	}
+
+	s := a.Copy().(*RoaringBitmap)
+	s.UnionWith(b)
+	s.IntersectWith(NewRoaringBitmap(roaringRange(0, 80000, 3)...))
+	s.DifferenceWith(NewRoaringBitmap(roaringRange(0, 80000, 4)...))
+	s.DifferenceWith(NewHashSet(uint32(3), "a"))
+	s.UnionWith(NewHashSet(uint32(1 << 30)))
+	s.IntersectWith(NewHashSet(uint32(9), uint32(10), uint32(5004), uint32(5010), uint32(1 << 30), 1))
+
+	if fmt.Sprint(*s.Slice()) != "[9 5010 1073741824]" {
+		t.Errorf("In-place operations are wrong: %v", *s.Slice())
+	}
+	if a.Size() != 10000 || b.Size() != 32501 {
+		t.Error("In-place operations changed their operands!")
+	}
+}
+
+func BenchmarkAndRoaringBitmap(b *testing.B) {
+	r := rand.New(rand.NewSource(37))
+	s, o := NewRoaringBitmapUnsafe(), NewRoaringBitmapUnsafe()
+	for i := 0; i < 100000; i++ {
+		s.Insert(uint32(r.Intn(1 << 22)))
+		o.Insert(uint32(r.Intn(1 << 22)))
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s.And(o)
+	}
+}
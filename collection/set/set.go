@@ -52,6 +52,33 @@ type Set interface {
 	// Panics if this Set or the given other Set have not been initialized.
 	Difference(o Set) Set
 
+	// Returns a new Set containing all the items in exactly one of this Set
+	// and the other given Set.
+	//
+	// Panics if this Set or the given other Set have not been initialized.
+	SymmetricDifference(o Set) Set
+
+	// Returns true if this Set and the other given Set have no items in
+	// common, false otherwise.
+	//
+	// Panics if this Set or the given other Set have not been initialized.
+	IsDisjoint(o Set) bool
+
+	// Inserts all the items in the other given Set into this Set.
+	//
+	// Panics if this Set or the given other Set have not been initialized.
+	UnionWith(o Set)
+
+	// Removes all the items not in the other given Set from this Set.
+	//
+	// Panics if this Set or the given other Set have not been initialized.
+	IntersectWith(o Set)
+
+	// Removes all the items in the other given Set from this Set.
+	//
+	// Panics if this Set or the given other Set have not been initialized.
+	DifferenceWith(o Set)
+
 	// Returns true if this Set and the other given Set have the exact same
 	// contents, false otherwise.
 	//
@@ -91,6 +118,19 @@ type Set interface {
 	Copy() Set
 }
 
+// Defines the Sorted interface. Sorted Sets visit their items in ascending
+// order, as defined by their Compare() method (items should implement
+// collection.Comparer), in Map() and Slice().
+//
+// Operations between two Sorted Sets may merge them in linear time.
+//
+type Sorted interface {
+	Set
+
+	// Does nothing, but marks this Set as Sorted.
+	Sorted()
+}
+
 // ****************************************************************************
 //
 //  Convenience functions to be used as so: set = function(set).
@@ -171,6 +211,64 @@ func Difference(s Set, o Set) (this Set, other Set, difference Set) {
 	return
 }
 
+// Returns the given Sets, and:
+//
+// Returns a new Set containing all the items in exactly one of the given
+// Sets.
+//
+// Panics if either given Set has not been initialized.
+func SymmetricDifference(s Set, o Set) (this Set, other Set, symmetricDifference Set) {
+	this = s
+	other = o
+	symmetricDifference = s.SymmetricDifference(o)
+	return
+}
+
+// Returns the given Sets, and:
+//
+// Returns true if the given Sets have no items in common, false otherwise.
+//
+// Panics if either given Set has not been initialized.
+func IsDisjoint(s Set, o Set) (this Set, other Set, disjoint bool) {
+	this = s
+	other = o
+	disjoint = s.IsDisjoint(o)
+	return
+}
+
+// Returns the first given Set, after:
+//
+// Inserting all the items in the other given Set into it.
+//
+// Panics if either given Set has not been initialized.
+func UnionWith(s Set, o Set) (this Set) {
+	this = s
+	s.UnionWith(o)
+	return
+}
+
+// Returns the first given Set, after:
+//
+// Removing all the items not in the other given Set from it.
+//
+// Panics if either given Set has not been initialized.
+func IntersectWith(s Set, o Set) (this Set) {
+	this = s
+	s.IntersectWith(o)
+	return
+}
+
+// Returns the first given Set, after:
+//
+// Removing all the items in the other given Set from it.
+//
+// Panics if either given Set has not been initialized.
+func DifferenceWith(s Set, o Set) (this Set) {
+	this = s
+	s.DifferenceWith(o)
+	return
+}
+
 // Returns the given Sets, and:
 //
 // Returns true if this Set and the other given Set have the exact same
@@ -235,3 +333,102 @@ func Copy(c Set) (this Set, copy Set) {
 	copy = c.Copy()
 	return
 }
+
+// Returns a new Set containing all the items in any of the given Sets, or
+// nil if given no Sets.
+//
+// Copies the largest given Set, so only the smaller ones are walked, and the
+// result is of its type.
+//
+// Panics if any given Set has not been initialized.
+func UnionAll(sets ...Set) Set {
+	if len(sets) == 0 {
+		return nil
+	}
+
+	largest := 0
+	for i, s := range sets {
+		if s.Size() > sets[largest].Size() {
+			largest = i
+		}
+	}
+
+	result := sets[largest].Copy()
+	for i, s := range sets {
+		if i != largest {
+			result.UnionWith(s)
+		}
+	}
+	return result
+}
+
+// Returns a new Set containing the items in all of the given Sets, or nil if
+// given no Sets.
+//
+// Walks the smallest given Set, so the result is of its type, and stops
+// early once the result is empty.
+//
+// Panics if any given Set has not been initialized.
+func IntersectAll(sets ...Set) Set {
+	if len(sets) == 0 {
+		return nil
+	}
+
+	smallest := 0
+	for i, s := range sets {
+		if s.Size() < sets[smallest].Size() {
+			smallest = i
+		}
+	}
+
+	result := sets[smallest].Copy()
+	for i, s := range sets {
+		if result.Empty() {
+			break
+		}
+		if i != smallest {
+			result.IntersectWith(s)
+		}
+	}
+	return result
+}
+
+// ****************************************************************************
+//
+//  Helpers for types implementing Set, in terms of the rest of Set.
+//
+// ****************************************************************************
+
+// Returns a new Set, of the type s.Difference() returns, with the items in
+// exactly one of s and o.
+func symmetricDifference(s Set, o Set) Set {
+	result := s.Difference(o)
+	result.UnionWith(o.Difference(s))
+	return result
+}
+
+// Returns true if s and o have no items in common, walking the smaller.
+func isDisjoint(s Set, o Set) bool {
+	if s.Size() > o.Size() {
+		s, o = o, s
+	}
+
+	disjoint := true
+	s.Map(func(item interface{}) bool {
+		disjoint = !o.Contains(item)
+		return disjoint
+	})
+	return disjoint
+}
+
+// Removes the items of s that are not in o.
+func intersectWith(s Set, o Set) {
+	var remove []interface{}
+	s.Map(func(item interface{}) bool {
+		if !o.Contains(item) {
+			remove = append(remove, item)
+		}
+		return true
+	})
+	s.Remove(remove...)
+}
@@ -1,6 +1,7 @@
 package set
 
 import (
+	"fmt"
 	"testing"
 )
 
@@ -10,4 +11,66 @@ func TestSet(t *testing.T) {
 
     ts := NewTreeSet()
     var _ Set = ts
+
+	lhs := NewLinkedHashSet()
+	var _ Set = lhs
+
+	sls := NewSkipListSet()
+	var _ Set = sls
+
+	bs := NewBitSet()
+	var _ Set = bs
+
+	rb := NewRoaringBitmap()
+	var _ Set = rb
+
+	hms := NewHashMultiSet()
+	var _ MultiSet = hms
+
+	tms := NewTreeMultiSet()
+	var _ MultiSet = tms
+}
+
+func TestUnionAllIntersectAll(t *testing.T) {
+	a := NewTreeSet(compInt{1}, compInt{2}, compInt{3}, compInt{4})
+	b := NewSkipListSet(compInt{2}, compInt{3}, compInt{5})
+	c := NewTreeSet(compInt{3}, compInt{2}, compInt{6}, compInt{7}, compInt{8})
+
+	union := UnionAll(a, b, c)
+	if _, ok := union.(*TreeSet); !ok || fmt.Sprint(*union.Slice()) != "[{1} {2} {3} {4} {5} {6} {7} {8}]" {
+		t.Errorf("UnionAll should copy the largest Set, got %T %v", union, *union.Slice())
+	}
+
+	intersection := IntersectAll(a, b, c)
+	if _, ok := intersection.(*SkipListSet); !ok || fmt.Sprint(*intersection.Slice()) != "[{2} {3}]" {
+		t.Errorf("IntersectAll should copy the smallest Set, got %T %v", intersection, *intersection.Slice())
+	}
+
+	if !IntersectAll(a, b, NewTreeSet(compInt{9})).Empty() {
+		t.Error("IntersectAll with a disjoint Set should be empty!")
+	}
+	if UnionAll() != nil || IntersectAll() != nil {
+		t.Error("UnionAll and IntersectAll of no Sets should be nil!")
+	}
+	if UnionAll(a) == Set(a) || !UnionAll(a).Equal(a) || !IntersectAll(a).Equal(a) {
+		t.Error("UnionAll and IntersectAll of one Set should copy it!")
+	}
+	if a.Size() != 4 || b.Size() != 3 || c.Size() != 5 {
+		t.Error("UnionAll or IntersectAll changed their operands!")
+	}
+}
+
+func TestInPlaceConvenienceFunctions(t *testing.T) {
+	s := NewHashSet(1, 2, 3)
+
+	_, _, symmetricDifference := SymmetricDifference(s, NewHashSet(3, 4))
+	_, _, disjoint := IsDisjoint(s, NewHashSet(4))
+	if !symmetricDifference.Equal(NewHashSet(1, 2, 4)) || !disjoint {
+		t.Error("SymmetricDifference or IsDisjoint is wrong!")
+	}
+
+	s = DifferenceWith(IntersectWith(UnionWith(s, NewHashSet(4, 5)), NewHashSet(1, 4, 5)), NewHashSet(5)).(*HashSet)
+	if !s.Equal(NewHashSet(1, 4)) {
+		t.Error("Chained in-place functions should give {1, 4}, not: ", s)
+	}
 }
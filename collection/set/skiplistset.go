@@ -0,0 +1,415 @@
+// This module implements a SkipListSet, conforming to set.Interface.
+
+package set
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"github.com/michalpiszczek/nonstdlib/collection/dictionary"
+	"log"
+)
+
+// A SkipListSet implements set.Interface, with the additional guarantee of
+// storing its items in sorted order, as defined by their Compare() method
+// (items should implement collection.Comparer).
+//
+// It offers the same ordered behavior as TreeSet, backed by a
+// dictionary.SkipListMap instead of an AVL tree.
+//
+// Behavior unspecified if a SkipListSet is not created using NewSkipListSet() or
+// if SkipListSet.Init() is not first called on a new &SkipListSet{}.
+//
+type SkipListSet struct {
+	collection.Base
+	m *dictionary.SkipListMap
+	p float64
+}
+
+// Returns a pointer to a new SkipListSet containing the given items.
+func NewSkipListSet(items ...interface{}) *SkipListSet {
+	s := &SkipListSet{}
+	s.Init()
+	s.Insert(items...)
+	return s
+}
+
+// Returns a pointer to a new unsafe SkipListSet containing the given items.
+func NewSkipListSetUnsafe(items ...interface{}) *SkipListSet {
+	s := &SkipListSet{}
+	s.InitUnsafe()
+	s.Insert(items...)
+	return s
+}
+
+// Returns a pointer to a new SkipListSet with the given level probability,
+// containing the given items.
+//
+// Panics if p is not strictly between 0 and 1.
+func NewSkipListSetWithProbability(p float64, items ...interface{}) *SkipListSet {
+	s := &SkipListSet{p: p}
+	s.Init()
+	s.Insert(items...)
+	return s
+}
+
+func (s *SkipListSet) Init() {
+	s.InitBase()
+
+	s.m = s.newMap()
+}
+
+func (s *SkipListSet) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.m = s.newMap()
+}
+
+func (s *SkipListSet) Insert(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		if old := s.m.Insert(comparerItem(item), present); old == nil {
+			s.Sizeb += 1
+		}
+	}
+}
+
+func (s *SkipListSet) Remove(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		if old := s.m.Remove(comparerItem(item)); old != nil {
+			s.Sizeb -= 1
+		}
+	}
+}
+
+func (s *SkipListSet) Contains(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.m.Contains(items...)
+}
+
+// Marks a SkipListSet as Sorted.
+func (s *SkipListSet) Sorted() {}
+
+// Returns the smallest item in this Set, or nil if it is empty.
+func (s *SkipListSet) First() interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return keyOf(s.m.First())
+}
+
+// Returns the largest item in this Set, or nil if it is empty.
+func (s *SkipListSet) Last() interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return keyOf(s.m.Last())
+}
+
+// Returns the smallest item in this Set greater than or equal to the given
+// item, or nil if there is none.
+func (s *SkipListSet) Ceiling(item interface{}) interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return keyOf(s.m.Ceiling(item))
+}
+
+// Returns the largest item in this Set less than or equal to the given
+// item, or nil if there is none.
+func (s *SkipListSet) Floor(item interface{}) interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return keyOf(s.m.Floor(item))
+}
+
+// Returns a pointer to a new Set containing all the items in either this
+// Set or the given Set.
+func (s *SkipListSet) Union(o Set) Set {
+	s.CheckInit()
+
+	result := s.Copy()
+
+	o.Map(func(item interface{}) bool {
+		result.Insert(item)
+		return true
+	})
+
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in both this Set
+// and the given Set.
+func (s *SkipListSet) Intersection(o Set) Set {
+	s.CheckInit()
+
+	result := s.empty()
+
+	s.Map(func(item interface{}) bool {
+		if o.Contains(item) {
+			result.Insert(item)
+		}
+		return true
+	})
+
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in this Set that
+// are not in the given Set.
+func (s *SkipListSet) Difference(o Set) Set {
+	s.CheckInit()
+
+	result := s.empty()
+
+	s.Map(func(item interface{}) bool {
+		if !o.Contains(item) {
+			result.Insert(item)
+		}
+		return true
+	})
+
+	return result
+}
+
+// Returns a pointer to a new Set containing all the items in exactly one of
+// this Set and the given Set.
+func (s *SkipListSet) SymmetricDifference(o Set) Set {
+	s.CheckInit()
+
+	return symmetricDifference(s, o)
+}
+
+// Returns true if this Set and the given Set have no items in common, false
+// otherwise.
+func (s *SkipListSet) IsDisjoint(o Set) bool {
+	s.CheckInit()
+
+	return isDisjoint(s, o)
+}
+
+// Adds all the items in the given Set to this Set.
+func (s *SkipListSet) UnionWith(o Set) {
+	s.CheckInit()
+
+	s.Insert(*o.Slice()...)
+}
+
+// Removes all the items not in the given Set from this Set.
+func (s *SkipListSet) IntersectWith(o Set) {
+	s.CheckInit()
+
+	intersectWith(s, o)
+}
+
+// Removes all the items in the given Set from this Set.
+func (s *SkipListSet) DifferenceWith(o Set) {
+	s.CheckInit()
+
+	s.Remove(*o.Slice()...)
+}
+
+// Returns true if this Set and the given Set contain exactly the same items,
+// false otherwise.
+func (s *SkipListSet) Equal(o Set) bool {
+	s.CheckInit()
+
+	if s.Size() != o.Size() {
+		return false
+	}
+
+	equal := true
+	o.Map(func(item interface{}) bool {
+		equal = s.Contains(item)
+		return equal
+	})
+
+	return equal
+}
+
+// The first bool returned is true if this Set is a subset of the given Set,
+// false otherwise. If the first returned bool is true, then the second bool
+// will be false if these two sets are equal, true otherwise.
+//
+// true, true -> s is a proper subset of o
+// true, false -> s is equal to o
+// false, true -> s is not a subset of o
+// false, false -> s is not a subset of o
+func (s *SkipListSet) Subset(o Set) (subset bool, proper bool) {
+	s.CheckInit()
+
+	proper = s.Size() != o.Size()
+
+	subset = true
+	s.Map(func(item interface{}) bool {
+		subset = o.Contains(item)
+		return subset
+	})
+
+	return
+}
+
+// The first bool returned is true if this Set is a superset of the given
+// Set, false otherwise. If the first returned bool is true, then the
+// second bool will be false if these two sets are equal, true otherwise.
+//
+// true, true -> s is a proper superset of o
+// true, false -> s is equal to o
+// false, true -> s is not a superset of o
+// false, false -> s is not a superset of o
+func (s *SkipListSet) Superset(o Set) (superset bool, proper bool) {
+	s.CheckInit()
+
+	proper = s.Size() != o.Size()
+
+	superset = true
+	o.Map(func(item interface{}) bool {
+		superset = s.Contains(item)
+		return superset
+	})
+
+	return
+}
+
+// Returns a pointer to a new Set that is a copy of this Set.
+func (s *SkipListSet) Copy() Set {
+	s.CheckInit()
+
+	c := s.empty()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.m = s.m.Copy().(*dictionary.SkipListMap)
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to every item in this Set, in
+// ascending order. Stops once all elements have been processed, or once
+// the function returns false, whichever occurs first.
+func (s *SkipListSet) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.m.Map(func(kv interface{}) bool {
+		return f(kv.(*dictionary.KeyValue).Key)
+	})
+}
+
+// Returns a slice of all the items in this Set, in ascending order.
+func (s *SkipListSet) Slice() *[]interface{} {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	slice := make([]interface{}, 0, s.Sizeb)
+	s.m.Map(func(kv interface{}) bool {
+		slice = append(slice, kv.(*dictionary.KeyValue).Key)
+		return true
+	})
+
+	return &slice
+}
+
+// Removes all items from this Set.
+func (s *SkipListSet) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.m = s.newMap()
+	s.Sizeb = 0
+}
+
+func (s *SkipListSet) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Returns a new, empty SkipListSet, as thread-safe as this one, with the
+// same level probability.
+func (s *SkipListSet) empty() *SkipListSet {
+	c := &SkipListSet{p: s.p}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+	return c
+}
+
+// Returns a new, empty backing map, with this Set's level probability.
+func (s *SkipListSet) newMap() *dictionary.SkipListMap {
+	if s.p == 0 {
+		return dictionary.NewSkipListMapUnsafe()
+	}
+	return dictionary.NewSkipListMapWithProbabilityUnsafe(s.p)
+}
+
+// Returns the key of the given KeyValue, or nil if it is nil.
+func keyOf(kv *dictionary.KeyValue) interface{} {
+	if kv == nil {
+		return nil
+	}
+	return kv.Key
+}
+
+// Returns the given item as a collection.Comparer.
+//
+// Panics if the given item doesn't implement collection.Comparer.
+func comparerItem(item interface{}) collection.Comparer {
+	itemc, ok := item.(collection.Comparer)
+	if !ok {
+		log.Panic("Item doesn't implement collection.Comparer.")
+	}
+	return itemc
+}
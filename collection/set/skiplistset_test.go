@@ -0,0 +1,112 @@
+// This module contains tests for skiplistset.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestNewEmptySkipListSet(t *testing.T) {
+	s := NewSkipListSet()
+
+	if s.Size() != 0 || s.Empty() != true {
+		t.Error("NewSkipListSet with 0 args does not create an empty set!")
+	}
+	if s.First() != nil || s.Last() != nil {
+		t.Error("Empty set has a first or last item!")
+	}
+}
+
+func TestOrderingSkipListSet(t *testing.T) {
+	s := NewSkipListSet(compInt{3}, compInt{1}, compInt{2}, compInt{1})
+
+	if s.Size() != 3 {
+		t.Error("Wrong size!")
+	}
+	if fmt.Sprint(*s.Slice()) != "[{1} {2} {3}]" {
+		t.Errorf("Items out of order: %v", *s.Slice())
+	}
+	if s.First() != (compInt{1}) || s.Last() != (compInt{3}) {
+		t.Error("Wrong first or last item!")
+	}
+	if s.Ceiling(compInt{0}) != (compInt{1}) || s.Floor(compInt{4}) != (compInt{3}) {
+		t.Error("Wrong ceiling or floor!")
+	}
+}
+
+func TestRemoveSkipListSet(t *testing.T) {
+	s := NewSkipListSet(compInt{1}, compInt{2}, compInt{3})
+
+	s.Remove(compInt{2}, compInt{4})
+	if s.Size() != 2 || s.Contains(compInt{2}) || !s.Contains(compInt{1}, compInt{3}) {
+		t.Error("Wrong items after removing!")
+	}
+}
+
+func TestSetOperationsSkipListSet(t *testing.T) {
+	s := NewSkipListSet(compInt{1}, compInt{2}, compInt{3})
+	o := NewTreeSet(compInt{2}, compInt{3}, compInt{4})
+
+	if u := s.Union(o); fmt.Sprint(*u.Slice()) != "[{1} {2} {3} {4}]" {
+		t.Errorf("Wrong union: %v", *u.Slice())
+	}
+	if i := s.Intersection(o); fmt.Sprint(*i.Slice()) != "[{2} {3}]" {
+		t.Errorf("Wrong intersection: %v", *i.Slice())
+	}
+	if d := s.Difference(o); fmt.Sprint(*d.Slice()) != "[{1}]" {
+		t.Errorf("Wrong difference: %v", *d.Slice())
+	}
+	if !s.Equal(NewTreeSet(compInt{3}, compInt{2}, compInt{1})) || s.Equal(o) {
+		t.Error("Wrong equality!")
+	}
+	if sub, proper := NewSkipListSet(compInt{1}).Subset(s); !sub || !proper {
+		t.Error("Wrong subset!")
+	}
+	if sup, _ := s.Superset(o); sup {
+		t.Error("Wrong superset!")
+	}
+}
+
+func TestCopySkipListSet(t *testing.T) {
+	s := NewSkipListSetWithProbability(0.25, compInt{1}, compInt{2})
+	c := s.Copy()
+	c.Remove(compInt{1})
+	c.Insert(compInt{3})
+
+	if fmt.Sprint(*c.Slice()) != "[{2} {3}]" || fmt.Sprint(*s.Slice()) != "[{1} {2}]" {
+		t.Errorf("Bad copy: %v of %v", *c.Slice(), *s.Slice())
+	}
+}
+
+func TestClearSkipListSet(t *testing.T) {
+	s := NewSkipListSet(compInt{1}, compInt{2})
+	s.Clear()
+
+	if s.Size() != 0 || s.Contains(compInt{1}) {
+		t.Error("Clear did not empty the set!")
+	}
+}
+
+func TestInPlaceOperationsSkipListSet(t *testing.T) {
+	s := NewSkipListSet(compInt{1}, compInt{2}, compInt{3})
+
+	if fmt.Sprint(*s.SymmetricDifference(NewSkipListSet(compInt{3}, compInt{4})).Slice()) != "[{1} {2} {4}]" {
+		t.Error("SymmetricDifference is wrong!")
+	}
+	if s.IsDisjoint(NewTreeSet(compInt{2})) || !s.IsDisjoint(NewTreeSet(compInt{5})) {
+		t.Error("IsDisjoint is wrong!")
+	}
+
+	s.UnionWith(NewTreeSet(compInt{5}, compInt{0}))
+	s.IntersectWith(NewSkipListSet(compInt{0}, compInt{1}, compInt{5}, compInt{6}))
+	s.DifferenceWith(NewSkipListSet(compInt{1}))
+
+	if fmt.Sprint(*s.Slice()) != "[{0} {5}]" || s.Size() != 2 {
+		t.Errorf("In-place operations should give [{0} {5}], not: %v", *s.Slice())
+	}
+}
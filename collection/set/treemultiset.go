@@ -0,0 +1,363 @@
+// This module implements a TreeMultiSet, conforming to set.MultiSet.
+
+package set
+
+import (
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"github.com/michalpiszczek/nonstdlib/collection/dictionary"
+	"log"
+)
+
+// A TreeMultiSet implements set.MultiSet, with the additional guarantee of
+// storing its items in sorted order, as defined by their Compare() method
+// (items should implement collection.Comparer).
+//
+// Behavior unspecified if a TreeMultiSet is not created using
+// NewTreeMultiSet(), NewTreeMultiSetUnsafe() or if TreeMultiSet.Init() /
+// TreeMultiSet.InitUnsafe(), is not first called on a new &TreeMultiSet{}.
+//
+type TreeMultiSet struct {
+	collection.Base
+	m *dictionary.TreeMap // item -> count
+}
+
+// Returns a pointer to a new TreeMultiSet containing the given items, each
+// as many times as it is given.
+func NewTreeMultiSet(items ...interface{}) *TreeMultiSet {
+	s := &TreeMultiSet{}
+	s.Init()
+	s.Insert(items...)
+	return s
+}
+
+// Returns a pointer to a new unsafe TreeMultiSet containing the given items,
+// each as many times as it is given.
+func NewTreeMultiSetUnsafe(items ...interface{}) *TreeMultiSet {
+	s := &TreeMultiSet{}
+	s.InitUnsafe()
+	s.Insert(items...)
+	return s
+}
+
+func (s *TreeMultiSet) Init() {
+	s.InitBase()
+
+	s.m = dictionary.NewTreeMapUnsafe()
+}
+
+func (s *TreeMultiSet) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.m = dictionary.NewTreeMapUnsafe()
+}
+
+// Adds one to the count of each given item.
+//
+// Panics if any given item doesn't implement collection.Comparer.
+func (s *TreeMultiSet) Insert(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		itemc := comparerItem(item)
+		s.m.Insert(itemc, s.count(itemc)+1)
+		s.Sizeb += 1
+	}
+}
+
+// Takes one off the count of each given item, if it is in this MultiSet.
+//
+// Panics if any given item doesn't implement collection.Comparer.
+func (s *TreeMultiSet) Remove(items ...interface{}) {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return
+	}
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	for _, item := range items {
+		itemc := comparerItem(item)
+		switch count := s.count(itemc); count {
+		case 0:
+			continue
+		case 1:
+			s.m.Remove(itemc)
+		default:
+			s.m.Insert(itemc, count-1)
+		}
+		s.Sizeb -= 1
+	}
+}
+
+// Panics if any given item doesn't implement collection.Comparer.
+func (s *TreeMultiSet) Contains(items ...interface{}) bool {
+	s.CheckInit()
+
+	if len(items) == 0 {
+		return false
+	}
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, item := range items {
+		if s.count(comparerItem(item)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Panics if the given item doesn't implement collection.Comparer.
+func (s *TreeMultiSet) Count(item interface{}) int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.count(comparerItem(item))
+}
+
+// Panics if the given item doesn't implement collection.Comparer.
+func (s *TreeMultiSet) SetCount(item interface{}, count int) int {
+	s.CheckInit()
+
+	if count < 0 {
+		log.Panicf("Cannot set a negative count: %d.", count)
+	}
+	itemc := comparerItem(item)
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	old := s.count(itemc)
+	if count == 0 {
+		s.m.Remove(itemc)
+	} else {
+		s.m.Insert(itemc, count)
+	}
+	s.Sizeb += count - old
+	return old
+}
+
+func (s *TreeMultiSet) DistinctSize() int {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.m.Size()
+}
+
+// Returns a pointer to a new TreeMultiSet with each item's count the larger
+// of its counts in this MultiSet and the given Set.
+func (s *TreeMultiSet) Union(o Set) Set {
+	return multiUnion(s.copyMulti(), o)
+}
+
+// Returns a pointer to a new TreeMultiSet with each item's count the smaller
+// of its counts in this MultiSet and the given Set.
+func (s *TreeMultiSet) Intersection(o Set) Set {
+	return multiIntersection(s.copyMulti(), o)
+}
+
+// Returns a pointer to a new TreeMultiSet with each item's count in this
+// MultiSet reduced by its count in the given Set, down to zero.
+func (s *TreeMultiSet) Difference(o Set) Set {
+	return multiDifference(s.copyMulti(), o)
+}
+
+// Returns a pointer to a new TreeMultiSet with each item's count the sum of
+// its counts in this MultiSet and the given Set.
+func (s *TreeMultiSet) Sum(o Set) MultiSet {
+	return multiSum(s.copyMulti(), o)
+}
+
+// Returns a pointer to a new TreeMultiSet with each item's count the
+// difference between its counts in this MultiSet and the given Set.
+func (s *TreeMultiSet) SymmetricDifference(o Set) Set {
+	s.CheckInit()
+
+	return symmetricDifference(s, o)
+}
+
+// Returns true if this MultiSet and the given Set have no items in common,
+// false otherwise.
+func (s *TreeMultiSet) IsDisjoint(o Set) bool {
+	s.CheckInit()
+
+	return isDisjoint(s, o)
+}
+
+// Sets each item's count to the larger of its counts in this MultiSet and
+// the given Set.
+func (s *TreeMultiSet) UnionWith(o Set) {
+	s.CheckInit()
+
+	multiUnion(s, o)
+}
+
+// Sets each item's count to the smaller of its counts in this MultiSet and
+// the given Set.
+func (s *TreeMultiSet) IntersectWith(o Set) {
+	s.CheckInit()
+
+	multiIntersection(s, o)
+}
+
+// Reduces each item's count by its count in the given Set, down to zero.
+func (s *TreeMultiSet) DifferenceWith(o Set) {
+	s.CheckInit()
+
+	multiDifference(s, o)
+}
+
+// Returns true if this MultiSet and the given Set hold each item the same
+// number of times, false otherwise.
+func (s *TreeMultiSet) Equal(o Set) bool {
+	s.CheckInit()
+
+	return multiEqual(s, o)
+}
+
+// The first bool returned is true if this MultiSet holds no item more times
+// than the given Set, false otherwise. If the first returned bool is true,
+// then the second bool will be false if these two sets are equal, true
+// otherwise.
+//
+// true, true -> s is a proper subset of o
+// true, false -> s is equal to o
+// false, true -> s is not a subset of o
+// false, false -> s is not a subset of o
+func (s *TreeMultiSet) Subset(o Set) (subset bool, proper bool) {
+	s.CheckInit()
+
+	return multiSubset(s, o)
+}
+
+// The first bool returned is true if this MultiSet holds every item at least
+// as many times as the given Set, false otherwise. If the first returned
+// bool is true, then the second bool will be false if these two sets are
+// equal, true otherwise.
+//
+// true, true -> s is a proper superset of o
+// true, false -> s is equal to o
+// false, true -> s is not a superset of o
+// false, false -> s is not a superset of o
+func (s *TreeMultiSet) Superset(o Set) (superset bool, proper bool) {
+	s.CheckInit()
+
+	return multiSuperset(s, o)
+}
+
+// Returns a pointer to a new TreeMultiSet that is a copy of this MultiSet.
+func (s *TreeMultiSet) Copy() Set {
+	return s.copyMulti()
+}
+
+// Attempts to apply the given function to every item in this MultiSet, as
+// many times as its count, in ascending order. Stops once all items have
+// been processed, or once the function returns false, whichever occurs
+// first.
+func (s *TreeMultiSet) Map(f func(item interface{}) bool) bool {
+	return s.MapCounts(func(item interface{}, count int) bool {
+		for i := 0; i < count; i++ {
+			if !f(item) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Maps over distinct items in ascending order.
+func (s *TreeMultiSet) MapCounts(f func(item interface{}, count int) bool) bool {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return s.m.Map(func(item interface{}) bool {
+		kv := item.(*dictionary.KeyValue)
+		return f(kv.Key, kv.Value.(int))
+	})
+}
+
+// Returns a slice of all the items in this MultiSet, each as many times as
+// its count, in ascending order.
+func (s *TreeMultiSet) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(item interface{}) bool {
+		slice = append(slice, item)
+		return true
+	})
+	return &slice
+}
+
+// Removes all items from this MultiSet.
+func (s *TreeMultiSet) Clear() {
+	s.CheckInit()
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.m.Clear()
+	s.Sizeb = 0
+}
+
+func (s *TreeMultiSet) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+// Returns the count of the given item.
+func (s *TreeMultiSet) count(itemc collection.Comparer) int {
+	if count := s.m.Locate(itemc); count != nil {
+		return count.(int)
+	}
+	return 0
+}
+
+// Returns a new TreeMultiSet, as thread-safe as this one, with no items.
+func (s *TreeMultiSet) empty() *TreeMultiSet {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		return NewTreeMultiSet()
+	}
+	return NewTreeMultiSetUnsafe()
+}
+
+// Returns a new TreeMultiSet, as thread-safe as this one, with the same
+// counts.
+func (s *TreeMultiSet) copyMulti() *TreeMultiSet {
+	c := s.empty()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.m = s.m.Copy().(*dictionary.TreeMap)
+	c.Sizeb = s.Sizeb
+	return c
+}
@@ -0,0 +1,172 @@
+// This module contains tests for treemultiset.go
+//
+// Note:
+// 	These tests are not ordered by reliance.
+// 	Some possible concurrency issues are not covered by this test suite.
+
+package set
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+func TestNewEmptyTreeMultiSet(t *testing.T) {
+	s := NewTreeMultiSet()
+
+	if s.Size() != 0 || !s.Empty() || s.DistinctSize() != 0 {
+		t.Error("NewTreeMultiSet with 0 args does not create an empty set!")
+	}
+	if s.Count(compInt{1}) != 0 || s.Contains(compInt{1}) {
+		t.Error("Empty set contains an item!")
+	}
+}
+
+func TestInsertRemoveTreeMultiSet(t *testing.T) {
+	s := NewTreeMultiSet(compInt{3}, compInt{1}, compInt{3}, compInt{2}, compInt{3})
+
+	if s.Size() != 5 || s.DistinctSize() != 3 || s.Count(compInt{3}) != 3 {
+		t.Errorf("Wrong counts: %v", s)
+	}
+	if fmt.Sprint(*s.Slice()) != "[{1} {2} {3} {3} {3}]" {
+		t.Errorf("Items are not in ascending order: %v", *s.Slice())
+	}
+
+	s.Remove(compInt{3}, compInt{1}, compInt{4})
+	if fmt.Sprint(*s.Slice()) != "[{2} {3} {3}]" || s.Size() != 3 || s.DistinctSize() != 2 {
+		t.Errorf("Wrong items after Remove: %v", *s.Slice())
+	}
+	if !s.Contains(compInt{2}, compInt{3}) || s.Contains(compInt{1}) {
+		t.Error("Contains is wrong!")
+	}
+}
+
+func TestInvalidItemTreeMultiSet(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Inserting an item that isn't a Comparer did not panic!")
+		}
+	}()
+	NewTreeMultiSet("a")
+}
+
+func TestSetCountTreeMultiSet(t *testing.T) {
+	s := NewTreeMultiSet(compInt{1})
+
+	if old := s.SetCount(compInt{1}, 3); old != 1 || s.Size() != 3 {
+		t.Errorf("SetCount({1}, 3) returned %d, left %v", old, s)
+	}
+	if old := s.SetCount(compInt{0}, 1); old != 0 || fmt.Sprint(*s.Slice()) != "[{0} {1} {1} {1}]" {
+		t.Errorf("SetCount({0}, 1) returned %d, left %v", old, s)
+	}
+	if old := s.SetCount(compInt{1}, 0); old != 3 || s.Size() != 1 || s.DistinctSize() != 1 {
+		t.Errorf("SetCount({1}, 0) returned %d, left %v", old, s)
+	}
+}
+
+func TestAlgebraTreeMultiSet(t *testing.T) {
+	s := NewTreeMultiSet(compInt{1}, compInt{1}, compInt{1}, compInt{2}, compInt{3})
+	o := NewHashMultiSet(compInt{1}, compInt{2}, compInt{2}, compInt{4})
+
+	cases := []struct {
+		name     string
+		got      Set
+		expected string
+	}{
+		{"Union", s.Union(o), "[{1} {1} {1} {2} {2} {3} {4}]"},
+		{"Sum", s.Sum(o), "[{1} {1} {1} {1} {2} {2} {2} {3} {4}]"},
+		{"Intersection", s.Intersection(o), "[{1} {2}]"},
+		{"Difference", s.Difference(o), "[{1} {1} {3}]"},
+		{"Difference", s.Difference(NewTreeSet(compInt{1}, compInt{3})), "[{1} {1} {2}]"},
+	}
+	for _, c := range cases {
+		if got := fmt.Sprint(*c.got.Slice()); got != c.expected {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, got)
+		}
+	}
+	if fmt.Sprint(*s.Slice()) != "[{1} {1} {1} {2} {3}]" {
+		t.Error("Operations changed their receiver!")
+	}
+}
+
+func TestRandomAlgebraTreeMultiSet(t *testing.T) {
+	r := rand.New(rand.NewSource(38))
+
+	for i := 0; i < 50; i++ {
+		s, o := NewTreeMultiSet(), NewTreeMultiSet()
+		sc, oc := make(map[int]int), make(map[int]int)
+		for j := 0; j < 40; j++ {
+			x, y := r.Intn(10), r.Intn(10)
+			s.Insert(compInt{x})
+			o.Insert(compInt{y})
+			sc[x] += 1
+			oc[y] += 1
+		}
+
+		union, sum := s.Union(o).(MultiSet), s.Sum(o)
+		intersection, difference := s.Intersection(o).(MultiSet), s.Difference(o).(MultiSet)
+		for x := 0; x < 10; x++ {
+			max, min, diff := sc[x], oc[x], sc[x]-oc[x]
+			if oc[x] > max {
+				max, min = oc[x], sc[x]
+			}
+			if diff < 0 {
+				diff = 0
+			}
+
+			if union.Count(compInt{x}) != max || sum.Count(compInt{x}) != sc[x]+oc[x] ||
+				intersection.Count(compInt{x}) != min || difference.Count(compInt{x}) != diff {
+				t.Fatalf("Wrong counts for %d: %d %d, got union %d sum %d intersection %d difference %d",
+					x, sc[x], oc[x], union.Count(compInt{x}), sum.Count(compInt{x}),
+					intersection.Count(compInt{x}), difference.Count(compInt{x}))
+			}
+		}
+
+		if sub, _ := intersection.Subset(s); !sub {
+			t.Error("Intersection is not a Subset!")
+		}
+		if super, _ := union.Superset(o); !super {
+			t.Error("Union is not a Superset!")
+		}
+		if !difference.Sum(intersection).Equal(s) {
+			t.Error("Difference plus Intersection does not equal the original!")
+		}
+		if !s.SymmetricDifference(o).Equal(union.Difference(intersection)) {
+			t.Error("SymmetricDifference does not equal Union minus Intersection!")
+		}
+
+		inPlace := s.Copy().(*TreeMultiSet)
+		inPlace.UnionWith(o)
+		if !inPlace.Equal(union) {
+			t.Error("UnionWith does not match Union!")
+		}
+		inPlace = s.Copy().(*TreeMultiSet)
+		inPlace.IntersectWith(o)
+		if !inPlace.Equal(intersection) {
+			t.Error("IntersectWith does not match Intersection!")
+		}
+		inPlace = s.Copy().(*TreeMultiSet)
+		inPlace.DifferenceWith(o)
+		if !inPlace.Equal(difference) {
+			t.Error("DifferenceWith does not match Difference!")
+		}
+	}
+}
+
+func TestCopyClearTreeMultiSet(t *testing.T) {
+	s := NewTreeMultiSet(compInt{1}, compInt{1}, compInt{2})
+	c := s.Copy().(*TreeMultiSet)
+
+	s.Insert(compInt{1})
+	c.Remove(compInt{2})
+
+	if s.Count(compInt{1}) != 3 || c.Count(compInt{1}) != 2 || !s.Contains(compInt{2}) || c.Contains(compInt{2}) {
+		t.Error("Copy shares state with the original!")
+	}
+
+	s.Clear()
+	if !s.Empty() || s.DistinctSize() != 0 || c.Empty() {
+		t.Error("Clear is wrong!")
+	}
+}
@@ -56,8 +56,8 @@ func (s *TreeSet) Insert(items ...interface{}) {
         return
     }
     if s.Threadsafe() {
-        s.Lock()
-        defer s.Unlock()
+        s.Lockb.Lock()
+        defer s.Lockb.Unlock()
     }
 
     for _, item := range items {
@@ -80,8 +80,10 @@ func (s *TreeSet) Remove(items ...interface{}) {
         return
     }
 
-    s.Lockb.Lock()
-    defer s.Lockb.Unlock()
+    if s.Threadsafe() {
+        s.Lockb.Lock()
+        defer s.Lockb.Unlock()
+    }
 
     for _, item := range items {
         itemc, ok := item.(collection.Comparer)
@@ -102,17 +104,25 @@ func (s *TreeSet) Contains(items ...interface{}) bool {
         return false
     }
 
-    s.Lockb.RLock()
-    defer s.RUnlock()
+    if s.Threadsafe() {
+        s.Lockb.RLock()
+        defer s.Lockb.RUnlock()
+    }
 
     return s.m.Contains(items...)
 }
 
 // Returns a pointer to a new Set containing all the items in either this
 // Set or the given Set.
+//
+// If the given Set is Sorted, merges the two in O(n+m).
 func (s *TreeSet) Union(o Set) Set {
     s.CheckInit()
 
+    if _, ok := o.(Sorted); ok {
+        return s.merge(o, true, true, true)
+    }
+
     result := s.Copy()
 
     o.Map(func(item interface{}) bool {
@@ -126,9 +136,15 @@ func (s *TreeSet) Union(o Set) Set {
 
 // Returns a pointer to a new Set containing all the items in both this Set
 // and the given Set.
+//
+// If the given Set is Sorted, merges the two in O(n+m).
 func (s *TreeSet) Intersection(o Set) Set {
     s.CheckInit()
 
+    if _, ok := o.(Sorted); ok {
+        return s.merge(o, false, true, false)
+    }
+
     result := NewTreeSet()
 
     var iter Set = s
@@ -152,9 +168,15 @@ func (s *TreeSet) Intersection(o Set) Set {
 
 // Returns a pointer to a new Set containing all the items in this Set that
 // are not in the given Set.
+//
+// If the given Set is Sorted, merges the two in O(n+m).
 func (s *TreeSet) Difference(o Set) Set {
     s.CheckInit()
 
+    if _, ok := o.(Sorted); ok {
+        return s.merge(o, true, false, false)
+    }
+
     result := s.Copy()
 
     o.Map(func(item interface{}) bool {
@@ -166,8 +188,53 @@ func (s *TreeSet) Difference(o Set) Set {
     return result
 }
 
+// Returns a pointer to a new Set containing all the items in exactly one of
+// this Set and the given Set.
+//
+// If the given Set is Sorted, merges the two in O(n+m).
+func (s *TreeSet) SymmetricDifference(o Set) Set {
+    s.CheckInit()
+
+    if _, ok := o.(Sorted); ok {
+        return s.merge(o, true, false, true)
+    }
+
+    return symmetricDifference(s, o)
+}
+
+// Returns true if this Set and the given Set have no items in common, false
+// otherwise.
+func (s *TreeSet) IsDisjoint(o Set) bool {
+    s.CheckInit()
+
+    return isDisjoint(s, o)
+}
+
+// Adds all the items in the given Set to this Set.
+func (s *TreeSet) UnionWith(o Set) {
+    s.CheckInit()
+
+    s.Insert(*o.Slice()...)
+}
+
+// Removes all the items not in the given Set from this Set.
+func (s *TreeSet) IntersectWith(o Set) {
+    s.CheckInit()
+
+    intersectWith(s, o)
+}
+
+// Removes all the items in the given Set from this Set.
+func (s *TreeSet) DifferenceWith(o Set) {
+    s.CheckInit()
+
+    s.Remove(*o.Slice()...)
+}
+
 // Returns true if this Set and the given Set contain exactly the same items,
 // false otherwise.
+//
+// If the given Set is Sorted, compares the two in O(n).
 func (s *TreeSet) Equal(o Set) bool {
     s.CheckInit()
 
@@ -176,12 +243,27 @@ func (s *TreeSet) Equal(o Set) bool {
         return false
     }
 
-    s.RLock()
-    defer s.RUnlock()
+    if _, ok := o.(Sorted); ok {
+        a, b := *s.Slice(), *o.Slice()
+        if len(a) != len(b) {
+            return false
+        }
+        for i := range a {
+            if a[i].(collection.Comparer).Compare(b[i]) != 0 {
+                return false
+            }
+        }
+        return true
+    }
+
+    if s.Threadsafe() {
+        s.Lockb.RLock()
+        defer s.Lockb.RUnlock()
+    }
     equal := true
     o.Map(func(item interface{}) bool {
         itemc, _  := item.(collection.Comparer)
-        equal = s.Contains(itemc)
+        equal = s.m.Contains(itemc)
         return equal
     })
 
@@ -242,15 +324,51 @@ func (s *TreeSet) Superset(o Set) (superset bool, proper bool) {
     return
 }
 
+// Marks a TreeSet as Sorted.
+func (s *TreeSet) Sorted() {}
+
 // Returns a pointer to a new Set that is a copy of this Set.
 func (s *TreeSet) Copy() Set {
     s.CheckInit()
 
+    c := &TreeSet{}
     if s.Threadsafe() {
-        return NewTreeSet(*s.Slice()...)
+        c.Init()
     } else {
-        return NewTreeSetUnsafe(*s.Slice()...)
+        c.InitUnsafe()
+    }
+
+    if s.Threadsafe() {
+        s.Lockb.RLock()
+        defer s.Lockb.RUnlock()
+    }
+
+    c.m = s.m.Copy().(*dictionary.TreeMap)
+    c.Sizeb = s.Sizeb
+    return c
+}
+
+// Replaces the items in this Set with the given items, which must be in
+// strictly ascending order. Builds a balanced tree directly, in O(n) time.
+//
+// Panics if the items aren't in strictly ascending order, or any doesn't
+// implement collection.Comparer.
+func (s *TreeSet) FromSorted(items ...interface{}) {
+    s.CheckInit()
+
+    kvs := make([]*dictionary.KeyValue, len(items))
+    for i, item := range items {
+        kvs[i] = &dictionary.KeyValue{Key: item, Value: true}
     }
+    m := dictionary.NewTreeMapFromSortedUnsafe(kvs)
+
+    if s.Threadsafe() {
+        s.Lockb.Lock()
+        defer s.Lockb.Unlock()
+    }
+
+    s.m = m
+    s.Sizeb = len(items)
 }
 
 // Attempts to apply the given function to every items in this Set.
@@ -259,8 +377,10 @@ func (s *TreeSet) Copy() Set {
 func (s *TreeSet) Map(f func(item interface{}) bool) bool {
     s.CheckInit()
 
-    s.RLock()
-    defer s.RUnlock()
+    if s.Threadsafe() {
+        s.Lockb.RLock()
+        defer s.Lockb.RUnlock()
+    }
 
     ok := true
     s.m.Map(func(kv interface{}) bool {
@@ -281,8 +401,10 @@ func (s *TreeSet) Map(f func(item interface{}) bool) bool {
 func (s *TreeSet) Slice() *[]interface{} {
     s.CheckInit()
 
-    s.RLock()
-    defer s.RUnlock()
+    if s.Threadsafe() {
+        s.Lockb.RLock()
+        defer s.Lockb.RUnlock()
+    }
 
     slice := make([]interface{}, 0, s.Sizeb)
 
@@ -303,8 +425,10 @@ func (s *TreeSet) Slice() *[]interface{} {
 func (s *TreeSet) Clear() {
     s.CheckInit()
 
-    s.Lock()
-    defer s.Unlock()
+    if s.Threadsafe() {
+        s.Lockb.Lock()
+        defer s.Lockb.Unlock()
+    }
 
     s.m = dictionary.NewTreeMapUnsafe()
     s.Sizeb = 0
@@ -313,3 +437,55 @@ func (s *TreeSet) Clear() {
 func (s *TreeSet) String() string {
     return fmt.Sprintf("%v", s.Slice())
 }
+
+// Returns a pointer to a new TreeSet, as thread-safe as this one, built in
+// bulk from a merge walk over this Set and the given Sorted Set. Keeps the
+// items only in this Set if inS, those in both if inBoth, and those only in
+// the given Set if inO.
+func (s *TreeSet) merge(o Set, inS, inBoth, inO bool) *TreeSet {
+    b := *o.Slice()
+    a := *s.Slice()
+
+    kvs := make([]*dictionary.KeyValue, 0, len(a)+len(b))
+    keep := func(item interface{}) {
+        kvs = append(kvs, &dictionary.KeyValue{Key: item, Value: true})
+    }
+
+    i, j := 0, 0
+    for i < len(a) && j < len(b) {
+        switch c := a[i].(collection.Comparer).Compare(b[j]); {
+        case c < 0:
+            if inS {
+                keep(a[i])
+            }
+            i++
+        case c > 0:
+            if inO {
+                keep(b[j])
+            }
+            j++
+        default:
+            if inBoth {
+                keep(a[i])
+            }
+            i++
+            j++
+        }
+    }
+    for ; inS && i < len(a); i++ {
+        keep(a[i])
+    }
+    for ; inO && j < len(b); j++ {
+        keep(b[j])
+    }
+
+    result := &TreeSet{}
+    if s.Threadsafe() {
+        result.Init()
+    } else {
+        result.InitUnsafe()
+    }
+    result.m = dictionary.NewTreeMapFromSortedUnsafe(kvs)
+    result.Sizeb = len(kvs)
+    return result
+}
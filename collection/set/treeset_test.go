@@ -7,6 +7,8 @@
 package set
 
 import (
+    "fmt"
+    "math/rand"
     "sync" // for sync.WaitGroup
     "testing"
     "github.com/michalpiszczek/nonstdlib/util/math"
@@ -730,3 +732,107 @@ func TestClearToEmptyTreeSet(t *testing.T) {
         t.Error("{1, 2, 3}.Clear() should yield {}")
     }
 }
+
+func TestInPlaceOperationsTreeSet(t *testing.T) {
+    s := NewTreeSet(compInt{1}, compInt{2}, compInt{3})
+
+    if !s.SymmetricDifference(NewTreeSet(compInt{3}, compInt{4})).Equal(NewTreeSet(compInt{1}, compInt{2}, compInt{4})) {
+        t.Error("SymmetricDifference is wrong!")
+    }
+    if s.IsDisjoint(NewTreeSet(compInt{3})) || !s.IsDisjoint(NewTreeSet(compInt{4})) {
+        t.Error("IsDisjoint is wrong!")
+    }
+
+    s.UnionWith(NewHashSet(compInt{0}, compInt{4}))
+    s.IntersectWith(NewTreeSet(compInt{0}, compInt{2}, compInt{3}, compInt{4}))
+    s.DifferenceWith(NewTreeSet(compInt{3}))
+
+    if fmt.Sprint(*s.Slice()) != "[{0} {2} {4}]" || s.Size() != 3 {
+        t.Error("In-place operations should give {0, 2, 4}, not: ", *s.Slice())
+    }
+}
+
+func TestMergeOperationsTreeSet(t *testing.T) {
+    s := NewTreeSet(compInt{1}, compInt{2}, compInt{3}, compInt{5})
+    o := NewSkipListSet(compInt{2}, compInt{4}, compInt{5}, compInt{6})
+
+    cases := []struct {
+        name     string
+        got      Set
+        expected string
+    }{
+        {"Union", s.Union(o), "[{1} {2} {3} {4} {5} {6}]"},
+        {"Intersection", s.Intersection(o), "[{2} {5}]"},
+        {"Difference", s.Difference(o), "[{1} {3}]"},
+        {"SymmetricDifference", s.SymmetricDifference(o), "[{1} {3} {4} {6}]"},
+        {"Union with empty", s.Union(NewTreeSet()), "[{1} {2} {3} {5}]"},
+        {"Intersection with empty", s.Intersection(NewTreeSet()), "[]"},
+    }
+    for _, c := range cases {
+        if got := fmt.Sprint(*c.got.Slice()); got != c.expected {
+            t.Errorf("%s: expected %s, got %s", c.name, c.expected, got)
+        }
+        if c.got.Size() != len(*c.got.Slice()) {
+            t.Errorf("%s: Size does not match the number of items", c.name)
+        }
+    }
+
+    if !s.Equal(NewSkipListSet(compInt{5}, compInt{3}, compInt{2}, compInt{1})) {
+        t.Error("Equal Sorted Sets should be Equal")
+    }
+    if s.Equal(NewTreeSet(compInt{1}, compInt{2}, compInt{3}, compInt{4})) {
+        t.Error("Sorted Sets with different items should not be Equal")
+    }
+
+    u := s.Union(o)
+    u.Insert(compInt{0})
+    u.Remove(compInt{4})
+    if fmt.Sprint(*u.Slice()) != "[{0} {1} {2} {3} {5} {6}]" {
+        t.Error("A merged TreeSet is not usable: ", *u.Slice())
+    }
+}
+
+func TestRandomMergeOperationsTreeSet(t *testing.T) {
+    r := rand.New(rand.NewSource(41))
+
+    for i := 0; i < 50; i++ {
+        s, o := NewTreeSet(), NewTreeSet()
+        hs, ho := NewHashSet(), NewHashSet()
+        for j := 0; j < 100; j++ {
+            x, y := compInt{r.Intn(150)}, compInt{r.Intn(150)}
+            s.Insert(x)
+            hs.Insert(x)
+            o.Insert(y)
+            ho.Insert(y)
+        }
+
+        if !hs.Union(ho).Equal(s.Union(o)) || !hs.Intersection(ho).Equal(s.Intersection(o)) ||
+            !hs.Difference(ho).Equal(s.Difference(o)) ||
+            !hs.SymmetricDifference(ho).Equal(s.SymmetricDifference(o)) {
+            t.Fatal("Merged results differ from HashSet results")
+        }
+        if !s.Equal(s.Copy()) || s.Equal(o) != hs.Equal(ho) {
+            t.Fatal("Equal on Sorted Sets is wrong")
+        }
+    }
+}
+
+func TestFromSortedTreeSet(t *testing.T) {
+    s := NewTreeSet(compInt{9})
+    s.FromSorted(compInt{1}, compInt{2}, compInt{4})
+
+    if fmt.Sprint(*s.Slice()) != "[{1} {2} {4}]" || s.Size() != 3 {
+        t.Error("FromSorted({1}, {2}, {4}) should yield {1, 2, 4}, not: ", *s.Slice())
+    }
+    s.Insert(compInt{3})
+    if !s.Contains(compInt{3}, compInt{4}) || s.Contains(compInt{9}) || s.Size() != 4 {
+        t.Error("A TreeSet loaded FromSorted is not usable: ", *s.Slice())
+    }
+
+    defer func() {
+        if recover() == nil {
+            t.Error("FromSorted with unsorted items did not panic")
+        }
+    }()
+    s.FromSorted(compInt{2}, compInt{2})
+}
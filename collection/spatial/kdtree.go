@@ -0,0 +1,596 @@
+// This module implements a KDTree, a spatial index of Points.
+
+package spatial
+
+import (
+	"container/heap"
+	"fmt" // To help with String().
+	"github.com/michalpiszczek/nonstdlib/collection"
+	"github.com/michalpiszczek/nonstdlib/util/math"
+	"log"
+	"sort"
+)
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// kdnode definition, and associated helper functions.
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// The node struct for the KDTree. A node at depth d splits its subtree on
+// axis d % dims: Points in C[0] have a smaller coordinate on that axis, and
+// Points in C[1] have one at least as large.
+type kdnode struct {
+	P Point
+	V interface{}
+	C [2]*kdnode
+}
+
+// Returns the side of the given node, splitting on the given axis, the given
+// Point belongs on.
+func side(p Point, n *kdnode, axis int) int {
+	if p[axis] < n.P[axis] {
+		return 0
+	}
+	return 1
+}
+
+// Returns the node in the subtree rooted at n, at the given depth, with the
+// smallest coordinate on the given axis, or nil if the subtree is empty.
+func minNode(n *kdnode, axis int, depth int, dims int) *kdnode {
+	if n == nil {
+		return nil
+	}
+	if depth%dims == axis {
+		if n.C[0] == nil {
+			return n
+		}
+		return minNode(n.C[0], axis, depth+1, dims)
+	}
+
+	min := n
+	for _, c := range n.C {
+		if m := minNode(c, axis, depth+1, dims); m != nil && m.P[axis] < min.P[axis] {
+			min = m
+		}
+	}
+	return min
+}
+
+// Appends every node in the subtree rooted at n to the given slice.
+func appendKDNodes(nodes []*kdnode, n *kdnode) []*kdnode {
+	if n == nil {
+		return nodes
+	}
+	nodes = append(nodes, n)
+	return appendKDNodes(appendKDNodes(nodes, n.C[0]), n.C[1])
+}
+
+// Applies the given function to an Entry for every node in the subtree
+// rooted at n. Stops once the function returns false, and returns false if
+// it did.
+func mapKDNodes(n *kdnode, f func(item interface{}) bool) bool {
+	if n == nil {
+		return true
+	}
+	return f(&Entry{n.P, n.V}) && mapKDNodes(n.C[0], f) && mapKDNodes(n.C[1], f)
+}
+
+// A candidate for Nearest(), and its squared distance from the query Point.
+type neighbour struct {
+	n  *kdnode
+	d2 float64
+}
+
+// A max-heap of neighbours, ordered by distance. Implements heap.Interface.
+type neighbourHeap []neighbour
+
+func (h neighbourHeap) Len() int {
+	return len(h)
+}
+
+func (h neighbourHeap) Less(i, j int) bool {
+	return h[i].d2 > h[j].d2
+}
+
+func (h neighbourHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *neighbourHeap) Push(x interface{}) {
+	*h = append(*h, x.(neighbour))
+}
+
+func (h *neighbourHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+//
+// end node stuff
+//
+// * * * * * * * * * * * * * * * * * * * * * * * * * *
+
+// A KDTree maps Points, of a fixed number of dimensions, to values, and
+// finds the Points nearest to a given Point, within a given distance of it,
+// or within a given box. Distances are Euclidean.
+//
+// It is a binary tree that splits on each axis in turn, so queries skip the
+// subtrees on the far side of any split too distant to hold a match. Like a
+// binary search tree, it is balanced for Points inserted in random order,
+// and may be rebalanced with Rebuild() when they are not.
+//
+// Points are copied on Insert(). Two Points are the same if all their
+// coordinates are equal.
+//
+// Behavior unspecified if a KDTree is not created using NewKDTree(), or
+// NewKDTreeUnsafe().
+//
+type KDTree struct {
+	collection.Base
+	dims int
+	root *kdnode
+}
+
+// Returns a pointer to a new KDTree of Points with the given number of
+// dimensions.
+//
+// Panics if dims is not positive.
+func NewKDTree(dims int) *KDTree {
+	s := &KDTree{dims: dims}
+	s.Init()
+	return s
+}
+
+// Returns a pointer to a new unsafe KDTree of Points with the given number
+// of dimensions.
+//
+// Panics if dims is not positive.
+func NewKDTreeUnsafe(dims int) *KDTree {
+	s := &KDTree{dims: dims}
+	s.InitUnsafe()
+	return s
+}
+
+func (s *KDTree) Init() {
+	s.InitBase()
+
+	s.setup()
+}
+
+func (s *KDTree) InitUnsafe() {
+	s.InitBaseUnsafe()
+
+	s.setup()
+}
+
+// Returns the number of dimensions of the Points in this KDTree.
+func (s *KDTree) Dims() int {
+	return s.dims
+}
+
+// Associates the given value with the given Point. Returns the previous
+// value associated with it, or nil, if none existed.
+//
+// Panics if the given Point has the wrong number of dimensions, or a NaN
+// coordinate.
+func (s *KDTree) Insert(point Point, value interface{}) interface{} {
+	s.CheckInit()
+	p := checkPoint(point, s.dims)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	link := &s.root
+	for depth := 0; *link != nil; depth++ {
+		n := *link
+		if samePoint(n.P, p) {
+			old := n.V
+			n.V = value
+			return old
+		}
+		link = &n.C[side(p, n, depth%s.dims)]
+	}
+
+	*link = &kdnode{P: p, V: value}
+	s.Sizeb += 1
+	return nil
+}
+
+// Returns the value associated with the given Point, or nil, if none is.
+//
+// Panics if the given Point has the wrong number of dimensions, or a NaN
+// coordinate.
+func (s *KDTree) Locate(point Point) interface{} {
+	s.CheckInit()
+	checkPoint(point, s.dims)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	if n := s.locate(point); n != nil {
+		return n.V
+	}
+	return nil
+}
+
+// Removes and returns the value associated with the given Point, or nil, if
+// none is.
+//
+// Panics if the given Point has the wrong number of dimensions, or a NaN
+// coordinate.
+func (s *KDTree) Remove(point Point) interface{} {
+	s.CheckInit()
+	checkPoint(point, s.dims)
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	var value interface{}
+	var ok bool
+	s.root, value, ok = s.remove(s.root, point, 0)
+	if ok {
+		s.Sizeb -= 1
+	}
+	return value
+}
+
+// Returns true if this KDTree contains all the given Points, false
+// otherwise.
+//
+// Panics if any given Point has the wrong number of dimensions, or a NaN
+// coordinate.
+func (s *KDTree) Contains(points ...Point) bool {
+	s.CheckInit()
+	if len(points) == 0 {
+		return false
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	for _, p := range points {
+		checkPoint(p, s.dims)
+		if s.locate(p) == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns pointers to Entries for the k Points nearest the given Point, or
+// all of them if there are fewer than k, nearest first. Ties are broken
+// arbitrarily.
+//
+// Panics if k is negative, or the given Point has the wrong number of
+// dimensions, or a NaN coordinate.
+func (s *KDTree) Nearest(point Point, k int) []*Entry {
+	s.CheckInit()
+	checkPoint(point, s.dims)
+	if k < 0 {
+		log.Panic("Cannot find a negative number of neighbours.")
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	// k may be far larger than this tree, so only allocate for the Points it holds.
+	h := make(neighbourHeap, 0, math.Min(k, s.Sizeb))
+	if k > 0 {
+		s.nearest(s.root, point, 0, k, &h)
+	}
+
+	entries := make([]*Entry, h.Len())
+	for i := len(entries) - 1; i >= 0; i-- {
+		nb := heap.Pop(&h).(neighbour)
+		entries[i] = &Entry{nb.n.P, nb.n.V}
+	}
+	return entries
+}
+
+// Returns pointers to Entries for every Point no further than the given
+// radius from the given Point, nearest first.
+//
+// Panics if the given Point has the wrong number of dimensions, or a NaN
+// coordinate.
+func (s *KDTree) WithinRadius(point Point, radius float64) []*Entry {
+	s.CheckInit()
+	checkPoint(point, s.dims)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	var found []neighbour
+	if radius >= 0 {
+		s.withinRadius(s.root, point, 0, radius*radius, &found)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].d2 < found[j].d2
+	})
+
+	entries := make([]*Entry, len(found))
+	for i, nb := range found {
+		entries[i] = &Entry{nb.n.P, nb.n.V}
+	}
+	return entries
+}
+
+// Returns pointers to Entries for every Point within the box from min to
+// max, inclusive, on every axis, in no particular order.
+//
+// Panics if min or max has the wrong number of dimensions, or a NaN
+// coordinate.
+func (s *KDTree) RangeBox(min Point, max Point) []*Entry {
+	s.CheckInit()
+	checkPoint(min, s.dims)
+	checkPoint(max, s.dims)
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	var entries []*Entry
+	s.rangeBox(s.root, min, max, 0, &entries)
+	return entries
+}
+
+// Rebuilds this KDTree to be balanced, splitting each subtree at the median
+// of its Points, in O(n log^2 n) time.
+func (s *KDTree) Rebuild() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.root = s.build(appendKDNodes(make([]*kdnode, 0, s.Sizeb), s.root), 0)
+}
+
+// Returns a pointer to a new KDTree containing the same Points and values.
+func (s *KDTree) Copy() *KDTree {
+	s.CheckInit()
+
+	c := &KDTree{dims: s.dims}
+	if s.Threadsafe() {
+		c.Init()
+	} else {
+		c.InitUnsafe()
+	}
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	c.root = copyKDNodes(s.root)
+	c.Sizeb = s.Sizeb
+	return c
+}
+
+// Attempts to apply the given function to a pointer to an Entry for every
+// Point in this KDTree, in no particular order. Stops once all Points have
+// been processed, or once the function returns false, whichever occurs
+// first.
+func (s *KDTree) Map(f func(item interface{}) bool) bool {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.RLock()
+		defer s.Lockb.RUnlock()
+	}
+
+	return mapKDNodes(s.root, f)
+}
+
+// Returns a slice of pointers to Entries, in no particular order.
+func (s *KDTree) Slice() *[]interface{} {
+	s.CheckInit()
+
+	slice := make([]interface{}, 0, s.Size())
+	s.Map(func(e interface{}) bool {
+		slice = append(slice, e)
+		return true
+	})
+	return &slice
+}
+
+func (s *KDTree) Clear() {
+	s.CheckInit()
+
+	if s.Threadsafe() {
+		s.Lockb.Lock()
+		defer s.Lockb.Unlock()
+	}
+
+	s.root = nil
+	s.Sizeb = 0
+}
+
+func (s *KDTree) String() string {
+	return fmt.Sprintf("%v", s.Slice())
+}
+
+func (s *KDTree) setup() {
+	if s.dims < 1 {
+		log.Panic("A KDTree needs at least one dimension.")
+	}
+}
+
+// Returns the node for the given Point, or nil, if it isn't in this KDTree.
+func (s *KDTree) locate(p Point) *kdnode {
+	n := s.root
+	for depth := 0; n != nil; depth++ {
+		if samePoint(n.P, p) {
+			return n
+		}
+		n = n.C[side(p, n, depth%s.dims)]
+	}
+	return nil
+}
+
+// Removes the given Point from the subtree rooted at n, at the given depth.
+// Returns the new root of the subtree, the Point's value, and true if it was
+// present.
+//
+// A removed node with children is replaced by the node with the smallest
+// coordinate on its axis from its right subtree, or, if it has none, from
+// its left, which then becomes its right. Either way, every Point left on
+// its right is at least as large on its axis.
+func (s *KDTree) remove(n *kdnode, p Point, depth int) (*kdnode, interface{}, bool) {
+	if n == nil {
+		return nil, nil, false
+	}
+
+	axis := depth % s.dims
+	if !samePoint(n.P, p) {
+		dir := side(p, n, axis)
+		var value interface{}
+		var ok bool
+		n.C[dir], value, ok = s.remove(n.C[dir], p, depth+1)
+		return n, value, ok
+	}
+
+	value := n.V
+	switch {
+	case n.C[1] != nil:
+		m := minNode(n.C[1], axis, depth+1, s.dims)
+		n.P, n.V = m.P, m.V
+		n.C[1], _, _ = s.remove(n.C[1], m.P, depth+1)
+	case n.C[0] != nil:
+		m := minNode(n.C[0], axis, depth+1, s.dims)
+		n.P, n.V = m.P, m.V
+		n.C[1], _, _ = s.remove(n.C[0], m.P, depth+1)
+		n.C[0] = nil
+	default:
+		return nil, value, true
+	}
+	return n, value, true
+}
+
+// Adds the nodes in the subtree rooted at n, at the given depth, nearer the
+// given Point than the furthest in the given heap to it, keeping at most k.
+// Searches the side of each split the Point is on first, and the other only
+// if the split is nearer than the furthest neighbour found.
+func (s *KDTree) nearest(n *kdnode, p Point, depth int, k int, h *neighbourHeap) {
+	if n == nil {
+		return
+	}
+
+	d2 := distance2(p, n.P)
+	if h.Len() < k {
+		heap.Push(h, neighbour{n, d2})
+	} else if d2 < (*h)[0].d2 {
+		(*h)[0] = neighbour{n, d2}
+		heap.Fix(h, 0)
+	}
+
+	axis := depth % s.dims
+	near := side(p, n, axis)
+	s.nearest(n.C[near], p, depth+1, k, h)
+
+	diff := p[axis] - n.P[axis]
+	if h.Len() < k || diff*diff < (*h)[0].d2 {
+		s.nearest(n.C[1-near], p, depth+1, k, h)
+	}
+}
+
+// Appends the nodes in the subtree rooted at n, at the given depth, within
+// the squared radius r2 of the given Point to found.
+func (s *KDTree) withinRadius(n *kdnode, p Point, depth int, r2 float64, found *[]neighbour) {
+	if n == nil {
+		return
+	}
+
+	if d2 := distance2(p, n.P); d2 <= r2 {
+		*found = append(*found, neighbour{n, d2})
+	}
+
+	axis := depth % s.dims
+	near := side(p, n, axis)
+	s.withinRadius(n.C[near], p, depth+1, r2, found)
+
+	diff := p[axis] - n.P[axis]
+	if diff*diff <= r2 {
+		s.withinRadius(n.C[1-near], p, depth+1, r2, found)
+	}
+}
+
+// Appends Entries for the nodes in the subtree rooted at n, at the given
+// depth, within the box from min to max to entries.
+func (s *KDTree) rangeBox(n *kdnode, min Point, max Point, depth int, entries *[]*Entry) {
+	if n == nil {
+		return
+	}
+
+	in := true
+	for i := range n.P {
+		if n.P[i] < min[i] || n.P[i] > max[i] {
+			in = false
+			break
+		}
+	}
+	if in {
+		*entries = append(*entries, &Entry{n.P, n.V})
+	}
+
+	axis := depth % s.dims
+	if min[axis] < n.P[axis] {
+		s.rangeBox(n.C[0], min, max, depth+1, entries)
+	}
+	if max[axis] >= n.P[axis] {
+		s.rangeBox(n.C[1], min, max, depth+1, entries)
+	}
+}
+
+// Returns the root of a balanced subtree, at the given depth, of the given
+// nodes.
+func (s *KDTree) build(nodes []*kdnode, depth int) *kdnode {
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	axis := depth % s.dims
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].P[axis] < nodes[j].P[axis]
+	})
+
+	// Points equal to the median on this axis must go on its right.
+	m := len(nodes) / 2
+	for m > 0 && nodes[m-1].P[axis] == nodes[m].P[axis] {
+		m--
+	}
+
+	n := nodes[m]
+	n.C[0] = s.build(nodes[:m], depth+1)
+	n.C[1] = s.build(nodes[m+1:], depth+1)
+	return n
+}
+
+// Returns a copy of the subtree rooted at n.
+func copyKDNodes(n *kdnode) *kdnode {
+	if n == nil {
+		return nil
+	}
+
+	c := *n
+	c.C[0], c.C[1] = copyKDNodes(n.C[0]), copyKDNodes(n.C[1])
+	return &c
+}
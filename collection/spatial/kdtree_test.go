@@ -0,0 +1,199 @@
+// This module contains tests for kdtree.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+//  Some possible concurrency issues are not covered by this test suite.
+
+package spatial
+
+import (
+	"fmt"
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+// Checks that every Point below n is on the right side of each split above
+// it, given the bounds those splits put on each axis. Returns the number of
+// Points below n, and its height.
+func checkKD(t *testing.T, s *KDTree, n *kdnode, depth int, lo Point, hi Point) (int, int) {
+	if n == nil {
+		return 0, 0
+	}
+	for i, x := range n.P {
+		if x < lo[i] || x >= hi[i] {
+			t.Fatalf("Point %v is on the wrong side of a split.", n.P)
+		}
+	}
+
+	axis := depth % s.dims
+	lhi := append(Point(nil), hi...)
+	lhi[axis] = n.P[axis]
+	rlo := append(Point(nil), lo...)
+	rlo[axis] = n.P[axis]
+
+	ls, lh := checkKD(t, s, n.C[0], depth+1, lo, lhi)
+	rs, rh := checkKD(t, s, n.C[1], depth+1, rlo, hi)
+	return ls + rs + 1, int(math.Max(float64(lh), float64(rh))) + 1
+}
+
+// Checks the whole KDTree. Returns its height.
+func checkKDTree(t *testing.T, s *KDTree) int {
+	lo, hi := make(Point, s.dims), make(Point, s.dims)
+	for i := range lo {
+		lo[i], hi[i] = math.Inf(-1), math.Inf(1)
+	}
+	size, height := checkKD(t, s, s.root, 0, lo, hi)
+	test.AssertEqual(t, size, s.Size(), "Tree holds the wrong number of Points.")
+	return height
+}
+
+// Returns the given Entries' Points, formatted for comparison.
+func pointsOf(entries []*Entry) string {
+	points := make([]Point, len(entries))
+	for i, e := range entries {
+		points[i] = e.Point
+	}
+	return fmt.Sprint(points)
+}
+
+func TestNewEmptyKDTree(t *testing.T) {
+	s := NewKDTree(2)
+
+	test.AssertEqual(t, s.Size(), 0, "New tree has size != 0.")
+	test.AssertTrue(t, s.Empty(), "New tree is not empty.")
+	test.AssertEqual(t, s.Dims(), 2, "New tree has the wrong dimensions.")
+	test.AssertEqual(t, len(s.Nearest(Point{0, 0}, 3)), 0, "Empty tree has neighbours.")
+	test.AssertNil(t, s.Remove(Point{0, 0}), "Removed from an empty tree.")
+}
+
+func TestBadDimensionsPanicKDTree(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("A zero-dimensional tree did not panic.")
+		}
+	}()
+	NewKDTree(0)
+}
+
+func TestSimpleKDTree(t *testing.T) {
+	s := NewKDTreeUnsafe(2)
+	for i, p := range []Point{{2, 3}, {5, 4}, {9, 6}, {4, 7}, {8, 1}, {7, 2}} {
+		test.AssertNil(t, s.Insert(p, i), "New Point had a value.")
+	}
+	test.AssertEqual(t, s.Insert(Point{9, 6}, "x"), 2, "Replaced the wrong value.")
+	test.AssertEqual(t, s.Size(), 6, "Replacing a value changed the size.")
+	test.AssertEqual(t, s.Locate(Point{9, 6}), "x", "Located the wrong value.")
+	test.AssertTrue(t, s.Contains(Point{2, 3}, Point{8, 1}), "Missing an inserted Point.")
+	test.AssertFalse(t, s.Contains(Point{2, 3}, Point{3, 2}), "Contains a missing Point.")
+
+	test.AssertEqual(t, pointsOf(s.Nearest(Point{9, 2}, 2)), "[[8 1] [7 2]]", "Wrong nearest neighbours.")
+	test.AssertEqual(t, len(s.Nearest(Point{9, 2}, 10)), 6, "Nearest() didn't return every Point.")
+	test.AssertEqual(t, len(s.Nearest(Point{9, 2}, int(^uint(0)>>1))), 6, "Nearest() didn't return every Point.")
+	test.AssertEqual(t, pointsOf(s.WithinRadius(Point{5, 4}, 3)), "[[5 4] [7 2]]", "Wrong Points within the radius.")
+	test.AssertEqual(t, len(s.WithinRadius(Point{0, 0}, -1)), 0, "Points within a negative radius.")
+
+	box := s.RangeBox(Point{4, 1}, Point{8, 4})
+	sort.Slice(box, func(i, j int) bool { return box[i].Point[0] < box[j].Point[0] })
+	test.AssertEqual(t, pointsOf(box), "[[5 4] [7 2] [8 1]]", "Wrong Points in the box.")
+
+	test.AssertEqual(t, s.Remove(Point{2, 3}), 0, "Removed the wrong value.")
+	test.AssertNil(t, s.Remove(Point{2, 3}), "Removed a Point twice.")
+	test.AssertEqual(t, s.Size(), 5, "Removing didn't shrink the tree.")
+	checkKDTree(t, s)
+}
+
+func TestCopyKDTree(t *testing.T) {
+	s := NewKDTree(1)
+	s.Insert(Point{1}, 1)
+	s.Insert(Point{2}, 2)
+
+	c := s.Copy()
+	c.Remove(Point{1})
+	c.Insert(Point{2}, 3)
+
+	test.AssertEqual(t, s.Size(), 2, "Changing a copy changed the original.")
+	test.AssertEqual(t, s.Locate(Point{2}), 2, "Changing a copy changed the original.")
+	test.AssertEqual(t, c.Size(), 1, "Copy has the wrong size.")
+	checkKDTree(t, s)
+	checkKDTree(t, c)
+}
+
+func TestRebuildKDTree(t *testing.T) {
+	s := NewKDTree(2)
+	for i := 0; i < 1023; i++ {
+		// Sorted, and with many ties on the first axis.
+		s.Insert(Point{float64(i / 4), float64(i)}, i)
+	}
+	test.AssertTrue(t, checkKDTree(t, s) > 100, "Sorted inserts left the tree balanced.")
+
+	s.Rebuild()
+	test.AssertTrue(t, checkKDTree(t, s) <= 12, "Rebuild() didn't balance the tree.")
+	test.AssertEqual(t, s.Locate(Point{100, 400}), 400, "Rebuild() lost a value.")
+}
+
+func TestLargeRandomKDTree(t *testing.T) {
+	r := rand.New(rand.NewSource(50))
+	s := NewKDTree(3)
+	points := make(map[[3]float64]int)
+
+	randomPoint := func() Point {
+		// A coarse grid, so that coordinates often tie.
+		return Point{float64(r.Intn(20)), float64(r.Intn(20)), float64(r.Intn(20))}
+	}
+
+	for j := 0; j < 4000; j++ {
+		p := randomPoint()
+		key := [3]float64{p[0], p[1], p[2]}
+		if r.Intn(3) == 0 {
+			_, ok := points[key]
+			test.AssertEqual(t, s.Remove(p) != nil, ok, "Removed a missing Point, or missed one.")
+			delete(points, key)
+		} else {
+			s.Insert(p, j+1)
+			points[key] = j + 1
+		}
+	}
+	checkKDTree(t, s)
+	test.AssertEqual(t, s.Size(), len(points), "Size doesn't match the Points.")
+
+	for j := 0; j < 200; j++ {
+		q := randomPoint()
+		var d2s []float64
+		for key := range points {
+			d2s = append(d2s, distance2(q, key[:]))
+		}
+		sort.Float64s(d2s)
+
+		k := r.Intn(10)
+		nearest := s.Nearest(q, k)
+		test.AssertEqual(t, len(nearest), k, "Found the wrong number of neighbours.")
+		for i, e := range nearest {
+			test.AssertEqual(t, distance2(q, e.Point), d2s[i], "Neighbour is at the wrong distance.")
+		}
+
+		radius := float64(r.Intn(5))
+		within := 0
+		for _, d2 := range d2s {
+			if d2 <= radius*radius {
+				within++
+			}
+		}
+		test.AssertEqual(t, len(s.WithinRadius(q, radius)), within, "Found the wrong number of Points within the radius.")
+
+		min := Point{q[0] - 3, q[1] - 3, q[2] - 3}
+		inBox := 0
+		for key := range points {
+			if math.Abs(key[0]-q[0]) <= 3 && math.Abs(key[1]-q[1]) <= 3 && math.Abs(key[2]-q[2]) <= 3 {
+				inBox++
+			}
+		}
+		box := s.RangeBox(min, Point{q[0] + 3, q[1] + 3, q[2] + 3})
+		test.AssertEqual(t, len(box), inBox, "Found the wrong number of Points in the box.")
+		for _, e := range box {
+			test.AssertEqual(t, e.Value, points[[3]float64{e.Point[0], e.Point[1], e.Point[2]}], "Point in the box has the wrong value.")
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Package spatial defines Collections that index points in space, and find
+// those nearest to, or within some region around, a given point.
+//
+package spatial
+
+import (
+	"log"
+	"math"
+)
+
+// A Point is a position in space, one coordinate per dimension.
+type Point []float64
+
+// An Entry is a Point, and the value stored with it.
+type Entry struct {
+	Point Point
+	Value interface{}
+}
+
+// Returns the squared Euclidean distance between the given Points, which
+// have the same number of dimensions.
+func distance2(a Point, b Point) float64 {
+	d := 0.0
+	for i := range a {
+		d += (a[i] - b[i]) * (a[i] - b[i])
+	}
+	return d
+}
+
+// Returns a copy of the given Point.
+//
+// Panics if it doesn't have the given number of dimensions, or has a NaN
+// coordinate.
+func checkPoint(p Point, dims int) Point {
+	if len(p) != dims {
+		log.Panicf("Point has %d dimensions, not %d.", len(p), dims)
+	}
+	for _, x := range p {
+		if math.IsNaN(x) {
+			log.Panic("Point has a NaN coordinate.")
+		}
+	}
+	return append(Point(nil), p...)
+}
+
+// Returns true if the given Points have the same coordinates.
+func samePoint(a Point, b Point) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
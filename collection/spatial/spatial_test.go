@@ -0,0 +1,35 @@
+// This module contains tests for spatial.go
+//
+// Note:
+//  These tests are not ordered by reliance.
+
+package spatial
+
+import (
+	"github.com/michalpiszczek/nonstdlib/util/test"
+	"math"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	test.AssertEqual(t, distance2(Point{0, 0}, Point{3, 4}), 25.0, "Wrong squared distance.")
+	test.AssertEqual(t, distance2(Point{1, 2, 3}, Point{1, 2, 3}), 0.0, "Point is not at distance 0 from itself.")
+}
+
+func TestCheckPoint(t *testing.T) {
+	p := Point{1, 2}
+	c := checkPoint(p, 2)
+	p[0] = 5
+	test.AssertEqual(t, c[0], 1.0, "Checked Point is not a copy.")
+
+	for _, bad := range []Point{{1}, {1, 2, 3}, {math.NaN(), 1}} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Point %v did not panic.", bad)
+				}
+			}()
+			checkPoint(bad, 2)
+		}()
+	}
+}
@@ -150,7 +150,7 @@ func (s *Queue) Slice() *[]interface{} {
 		defer s.Lockb.RUnlock()
 	}
 
-	slice := make([]interface{}, 0, s.Size())
+	slice := make([]interface{}, 0, s.Sizeb)
 
 	curr := s.back
 	for curr != nil {
@@ -139,7 +139,7 @@ func (s *Stack) Slice() *[]interface{} {
 		defer s.Lockb.RUnlock()
 	}
 
-	slice := make([]interface{}, 0, s.Size())
+	slice := make([]interface{}, 0, s.Sizeb)
 
 	curr := s.front
 	for curr != nil {